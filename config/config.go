@@ -0,0 +1,566 @@
+// Package config charge la configuration de l'API au démarrage : valeurs par défaut,
+// fichier JSON facultatif, variables d'environnement (et fichier .env) puis options de
+// ligne de commande, chaque source remplaçant les précédentes.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Pilotes de base de données (paramètre db_driver)
+const (
+	PilotePostgres = "postgres"
+	PiloteSQLite   = "sqlite"
+)
+
+// Stockages des compteurs du limiteur de débit (paramètre rate_limit_stockage)
+const (
+	StockageMemoire = "memoire"
+	StockageBase    = "base"
+)
+
+// Sources d'une valeur, affichées par Afficher
+const (
+	sourceDefaut  = "défaut"
+	sourceFichier = "fichier"
+	sourceEnv     = "environnement"
+	sourceOption  = "option"
+)
+
+// Préfixe des surcharges du limiteur de débit : rate_limit_<budget> et rate_limit_<budget>_ip
+const prefixeLimites = "rate_limit_"
+
+// Suffixe des paramètres secrets lus dans un fichier (secrets Docker ou Kubernetes)
+const suffixeFichier = "_file"
+
+// Config est la configuration complète de l'API
+type Config struct {
+	Serveur     Serveur
+	Base        Base
+	Auth        Auth
+	Moderation  Moderation
+	Limites     Limites
+	Idempotence Idempotence
+	Metriques   Metriques
+
+	// sources indique, pour chaque paramètre, la source de sa valeur
+	sources map[string]string
+}
+
+// Serveur : adresse d'écoute HTTP, dossier des fichiers envoyés et délais du serveur HTTP
+type Serveur struct {
+	Adresse        string
+	DossierUploads string
+	// DelaiLecture et DelaiEcriture bornent la lecture d'une requête et l'écriture de sa
+	// réponse (les flux temps réel en sont exemptés), DelaiInactivite la durée de vie d'une
+	// connexion keep-alive inutilisée
+	DelaiLecture    time.Duration
+	DelaiEcriture   time.Duration
+	DelaiInactivite time.Duration
+	// DelaiArret : temps laissé aux requêtes en cours et aux workers pour se terminer
+	// après SIGTERM
+	DelaiArret time.Duration
+	// ProxiesDeConfiance : adresses ou plages CIDR des proxies dont l'en-tête
+	// X-Forwarded-For est cru pour l'adresse du client ; aucun par défaut
+	ProxiesDeConfiance []string
+}
+
+// Base : connexion à la base de données
+type Base struct {
+	Pilote        string
+	Hote          string
+	Port          string
+	Utilisateur   string
+	MotDePasse    string
+	Nom           string
+	SSLMode       string
+	FichierSQLite string
+}
+
+// Auth : secret partagé avec l'API d'authentification, profils des administrateurs et des
+// partenaires (seuls autorisés à enregistrer des webhooks, avec les administrateurs)
+type Auth struct {
+	SecretJWT           string
+	AdminProfilIDs      []int
+	PartenaireProfilIDs []int
+}
+
+// Moderation : nombre de signalements en attente au-delà duquel une annonce est masquée
+type Moderation struct {
+	SeuilSignalements int64
+}
+
+// Limites : stockage des compteurs et règles remplaçant celles des budgets par défaut,
+// par nom (<budget> pour les utilisateurs authentifiés, <budget>_ip pour les anonymes)
+type Limites struct {
+	Stockage string
+	Regles   map[string]RegleLimite
+}
+
+// Idempotence : durée de conservation des clés Idempotency-Key
+type Idempotence struct {
+	Duree time.Duration
+}
+
+// Metriques : jeton exigé pour lire /metrics (route ouverte s'il est vide)
+type Metriques struct {
+	Jeton string
+}
+
+// Defaut renvoie la configuration par défaut
+func Defaut() Config {
+	return Config{
+		Serveur: Serveur{
+			Adresse:         "0.0.0.0:8080",
+			DossierUploads:  "./uploads",
+			DelaiLecture:    30 * time.Second,
+			DelaiEcriture:   time.Minute,
+			DelaiInactivite: 2 * time.Minute,
+			DelaiArret:      30 * time.Second,
+		},
+		Base: Base{
+			Pilote:        PilotePostgres,
+			Port:          "5432",
+			SSLMode:       "disable",
+			FichierSQLite: "annonces.db",
+		},
+		Moderation:  Moderation{SeuilSignalements: 3},
+		Limites:     Limites{Stockage: StockageMemoire, Regles: map[string]RegleLimite{}},
+		Idempotence: Idempotence{Duree: 24 * time.Hour},
+		sources:     map[string]string{},
+	}
+}
+
+// parametre décrit un paramètre de configuration. Son nom est à la fois celui de la
+// variable d'environnement (lue aussi en majuscules), de la clé du fichier JSON et de
+// l'option de ligne de commande.
+type parametre struct {
+	nom  string
+	aide string
+	// secret : valeur masquée à l'affichage, lisible dans un fichier (<nom>_file) et
+	// jamais passée en option de ligne de commande
+	secret bool
+	// anciens : noms de variables d'environnement encore acceptés (fichiers .env existants)
+	anciens []string
+	lire    func(c *Config, v string) error
+	valeur  func(c *Config) string
+}
+
+// variables renvoie les variables d'environnement du paramètre, par priorité : son nom,
+// son nom en majuscules (JWT_SECRET, DB_HOST…) puis ses anciens noms
+func (p parametre) variables() []string {
+	return append([]string{p.nom, strings.ToUpper(p.nom)}, p.anciens...)
+}
+
+// chaine décrit un paramètre texte
+func chaine(nom, aide string, secret bool, champ func(c *Config) *string, anciens ...string) parametre {
+	return parametre{
+		nom:     nom,
+		aide:    aide,
+		secret:  secret,
+		anciens: anciens,
+		lire: func(c *Config, v string) error {
+			*champ(c) = v
+			return nil
+		},
+		valeur: func(c *Config) string { return *champ(c) },
+	}
+}
+
+// duree décrit un paramètre durée, strictement positive (ex. 30s, 48h)
+func duree(nom, aide string, champ func(c *Config) *time.Duration) parametre {
+	return parametre{
+		nom:  nom,
+		aide: aide,
+		lire: func(c *Config, v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("durée positive attendue : %q", v)
+			}
+			*champ(c) = d
+			return nil
+		},
+		valeur: func(c *Config) string { return champ(c).String() },
+	}
+}
+
+// identifiants décrit une liste d'entiers séparés par des virgules
+func identifiants(nom, aide string, champ func(c *Config) *[]int) parametre {
+	return parametre{
+		nom:  nom,
+		aide: aide,
+		lire: func(c *Config, v string) error {
+			ids := []int{}
+			for _, morceau := range strings.Split(v, ",") {
+				if morceau = strings.TrimSpace(morceau); morceau == "" {
+					continue
+				}
+				id, err := strconv.Atoi(morceau)
+				if err != nil {
+					return fmt.Errorf("identifiant invalide : %q", morceau)
+				}
+				ids = append(ids, id)
+			}
+			*champ(c) = ids
+			return nil
+		},
+		valeur: func(c *Config) string {
+			ids := make([]string, len(*champ(c)))
+			for i, id := range *champ(c) {
+				ids[i] = strconv.Itoa(id)
+			}
+			return strings.Join(ids, ",")
+		},
+	}
+}
+
+var parametres = construireParametres()
+
+func construireParametres() []parametre {
+	return []parametre{
+		chaine("adresse", "adresse d'écoute HTTP", false, func(c *Config) *string { return &c.Serveur.Adresse }),
+		chaine("dossier_uploads", "dossier des fichiers envoyés (servi sous /static)", false, func(c *Config) *string { return &c.Serveur.DossierUploads }),
+		duree("delai_lecture", "durée maximale de lecture d'une requête", func(c *Config) *time.Duration { return &c.Serveur.DelaiLecture }),
+		duree("delai_ecriture", "durée maximale d'écriture d'une réponse (hors flux temps réel)", func(c *Config) *time.Duration { return &c.Serveur.DelaiEcriture }),
+		duree("delai_inactivite", "durée de vie d'une connexion keep-alive inutilisée", func(c *Config) *time.Duration { return &c.Serveur.DelaiInactivite }),
+		duree("delai_arret", "temps laissé aux requêtes en cours et aux workers à l'arrêt (SIGTERM)", func(c *Config) *time.Duration { return &c.Serveur.DelaiArret }),
+		parametre{nom: "proxies_confiance", aide: "adresses ou plages CIDR des proxies dont X-Forwarded-For est cru, séparées par des virgules",
+			lire: func(c *Config, v string) error {
+				proxies := []string{}
+				for _, morceau := range strings.Split(v, ",") {
+					if morceau = strings.TrimSpace(morceau); morceau == "" {
+						continue
+					}
+					if _, _, err := net.ParseCIDR(morceau); err != nil && net.ParseIP(morceau) == nil {
+						return fmt.Errorf("adresse ou plage CIDR invalide : %q", morceau)
+					}
+					proxies = append(proxies, morceau)
+				}
+				c.Serveur.ProxiesDeConfiance = proxies
+				return nil
+			},
+			valeur: func(c *Config) string { return strings.Join(c.Serveur.ProxiesDeConfiance, ",") },
+		},
+
+		chaine("db_driver", "base de données : postgres ou sqlite", false, func(c *Config) *string { return &c.Base.Pilote }),
+		chaine("db_host", "hôte Postgres", false, func(c *Config) *string { return &c.Base.Hote }, "host"),
+		chaine("db_port", "port Postgres", false, func(c *Config) *string { return &c.Base.Port }, "port"),
+		chaine("db_user", "utilisateur Postgres", false, func(c *Config) *string { return &c.Base.Utilisateur }, "user", "User"),
+		chaine("db_password", "mot de passe Postgres", true, func(c *Config) *string { return &c.Base.MotDePasse }, "password"),
+		chaine("db_name", "nom de la base Postgres", false, func(c *Config) *string { return &c.Base.Nom }, "dbname"),
+		chaine("db_sslmode", "mode TLS de la connexion Postgres (disable, require, verify-full…)", false, func(c *Config) *string { return &c.Base.SSLMode }, "sslmode"),
+		chaine("sqlite_fichier", "fichier de la base SQLite", false, func(c *Config) *string { return &c.Base.FichierSQLite }),
+
+		chaine("jwt_secret", "secret des tokens JWT, partagé avec l'API d'authentification", true, func(c *Config) *string { return &c.Auth.SecretJWT }),
+		identifiants("admin_profil_ids", "profil_id des administrateurs, séparés par des virgules", func(c *Config) *[]int { return &c.Auth.AdminProfilIDs }),
+		identifiants("partenaire_profil_ids", "profil_id des partenaires autorisés à enregistrer des webhooks, séparés par des virgules", func(c *Config) *[]int { return &c.Auth.PartenaireProfilIDs }),
+
+		parametre{nom: "seuil_signalements", aide: "signalements en attente avant masquage automatique d'une annonce",
+			lire: func(c *Config, v string) error {
+				seuil, err := strconv.ParseInt(v, 10, 64)
+				if err != nil || seuil <= 0 {
+					return fmt.Errorf("entier positif attendu : %q", v)
+				}
+				c.Moderation.SeuilSignalements = seuil
+				return nil
+			},
+			valeur: func(c *Config) string { return strconv.FormatInt(c.Moderation.SeuilSignalements, 10) },
+		},
+		chaine("rate_limit_stockage", "stockage des compteurs du limiteur de débit : memoire ou base", false, func(c *Config) *string { return &c.Limites.Stockage }),
+		chaine("metriques_jeton", "jeton exigé pour lire /metrics (Authorization: Bearer), route ouverte si vide", true, func(c *Config) *string { return &c.Metriques.Jeton }),
+		duree("idempotence_duree", "durée de conservation des clés Idempotency-Key (ex. 48h)", func(c *Config) *time.Duration { return &c.Idempotence.Duree }),
+	}
+}
+
+func trouverParametre(nom string) (parametre, bool) {
+	for _, p := range parametres {
+		if p.nom == nom {
+			return p, true
+		}
+	}
+	return parametre{}, false
+}
+
+// appliquer donne au paramètre nom la valeur v. nom peut désigner un secret lu dans un
+// fichier (<nom>_file) ou une règle du limiteur de débit (rate_limit_<budget>[_ip]).
+func (c *Config) appliquer(nom, v, source string) error {
+	if p, ok := trouverParametre(nom); ok {
+		if !p.secret {
+			v = strings.TrimSpace(v)
+		}
+		if err := p.lire(c, v); err != nil {
+			return fmt.Errorf("%s (%s) : %w", nom, source, err)
+		}
+		c.sources[nom] = source
+		return nil
+	}
+
+	if base, ok := strings.CutSuffix(nom, suffixeFichier); ok {
+		if p, ok := trouverParametre(base); ok && p.secret {
+			contenu, err := os.ReadFile(strings.TrimSpace(v))
+			if err != nil {
+				return fmt.Errorf("%s (%s) : %w", nom, source, err)
+			}
+			// Les fichiers de secrets se terminent souvent par un saut de ligne
+			return c.appliquer(base, strings.TrimRight(string(contenu), "\r\n"), source+" "+nom)
+		}
+	}
+
+	if budget, ok := strings.CutPrefix(nom, prefixeLimites); ok && budget != "" {
+		regle, err := ParseRegleLimite(v)
+		if err != nil {
+			return fmt.Errorf("%s (%s) : %w", nom, source, err)
+		}
+		c.Limites.Regles[budget] = regle
+		c.sources[nom] = source
+		return nil
+	}
+
+	return fmt.Errorf("paramètre inconnu : %s (%s)", nom, source)
+}
+
+// Charger lit la configuration. Les options de ligne de commande sont déclarées sur
+// options (avec fichier_config, le fichier JSON à lire) puis analysées avec args. Le
+// fichier .env, s'il existe, complète les variables d'environnement.
+func Charger(options *flag.FlagSet, args []string) (Config, error) {
+	c := Defaut()
+	// Options déclarées ici ; les autres appartiennent à la commande appelante
+	propres := map[string]bool{}
+	for _, p := range parametres {
+		if p.secret {
+			propres[p.nom+suffixeFichier] = true
+		} else {
+			propres[p.nom] = true
+		}
+	}
+	fichier := options.String("fichier_config", "", "fichier de configuration JSON (clés : noms des paramètres)")
+	for _, p := range parametres {
+		if p.secret {
+			options.String(p.nom+suffixeFichier, "", "fichier contenant le paramètre secret "+p.nom+" : "+p.aide)
+		} else {
+			options.String(p.nom, "", fmt.Sprintf("%s (défaut %q)", p.aide, p.valeur(&c)))
+		}
+	}
+	regles := &listeRegles{}
+	options.Var(regles, "rate_limit", "règle du limiteur de débit <budget>[_ip]=<capacite>/<periode>, répétable")
+	if err := options.Parse(args); err != nil {
+		return c, err
+	}
+
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return c, fmt.Errorf(".env : %w", err)
+	}
+
+	// Fichier JSON
+	chemin := *fichier
+	if chemin == "" {
+		chemin = os.Getenv("fichier_config")
+	}
+	if chemin != "" {
+		if err := c.lireFichier(chemin); err != nil {
+			return c, err
+		}
+	}
+
+	// Variables d'environnement : la première variable définie parmi celles du paramètre
+	for _, p := range parametres {
+		for _, variable := range p.variables() {
+			if v, ok := os.LookupEnv(variable); ok {
+				source := sourceEnv
+				if variable != p.nom {
+					source += " " + variable
+				}
+				if err := c.appliquer(p.nom, v, source); err != nil {
+					return c, err
+				}
+				break
+			}
+		}
+		if !p.secret {
+			continue
+		}
+		for _, variable := range []string{p.nom + suffixeFichier, strings.ToUpper(p.nom + suffixeFichier)} {
+			if v, ok := os.LookupEnv(variable); ok {
+				if err := c.appliquer(p.nom+suffixeFichier, v, sourceEnv); err != nil {
+					return c, err
+				}
+				break
+			}
+		}
+	}
+	for _, variable := range os.Environ() {
+		nom, v, _ := strings.Cut(variable, "=")
+		if _, connu := trouverParametre(nom); !connu && strings.HasPrefix(nom, prefixeLimites) {
+			if err := c.appliquer(nom, v, sourceEnv); err != nil {
+				return c, err
+			}
+		}
+	}
+
+	// Options de ligne de commande
+	var err error
+	options.Visit(func(f *flag.Flag) {
+		if err != nil || !propres[f.Name] && f.Value != regles {
+			return
+		}
+		if f.Value == regles {
+			for _, r := range *regles {
+				nom, v, _ := strings.Cut(r, "=")
+				if err = c.appliquer(prefixeLimites+nom, v, sourceOption); err != nil {
+					return
+				}
+			}
+			return
+		}
+		err = c.appliquer(f.Name, f.Value.String(), sourceOption)
+	})
+	if err != nil {
+		return c, err
+	}
+
+	return c, c.Valider()
+}
+
+// lireFichier applique les paramètres d'un fichier JSON plat : {"db_host": "...", "db_port": 5432}
+func (c *Config) lireFichier(chemin string) error {
+	contenu, err := os.ReadFile(chemin)
+	if err != nil {
+		return fmt.Errorf("fichier de configuration : %w", err)
+	}
+	var valeurs map[string]interface{}
+	if err := json.Unmarshal(contenu, &valeurs); err != nil {
+		return fmt.Errorf("fichier de configuration %s : %w", chemin, err)
+	}
+
+	noms := make([]string, 0, len(valeurs))
+	for nom := range valeurs {
+		noms = append(noms, nom)
+	}
+	sort.Strings(noms)
+	for _, nom := range noms {
+		if err := c.appliquer(nom, valeurJSON(valeurs[nom]), sourceFichier); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// valeurJSON convertit une valeur du fichier JSON en texte (une liste devient une liste
+// séparée par des virgules)
+func valeurJSON(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		morceaux := make([]string, len(v))
+		for i, e := range v {
+			morceaux[i] = valeurJSON(e)
+		}
+		return strings.Join(morceaux, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Valider vérifie la cohérence de la configuration
+func (c Config) Valider() error {
+	var problemes []string
+	switch c.Base.Pilote {
+	case PilotePostgres:
+		if c.Base.Hote == "" || c.Base.Nom == "" {
+			problemes = append(problemes, "db_host et db_name sont requis avec db_driver=postgres")
+		}
+		switch c.Base.SSLMode {
+		case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			problemes = append(problemes, fmt.Sprintf("db_sslmode inconnu : %q", c.Base.SSLMode))
+		}
+	case PiloteSQLite:
+		if c.Base.FichierSQLite == "" {
+			problemes = append(problemes, "sqlite_fichier est requis avec db_driver=sqlite")
+		}
+	default:
+		problemes = append(problemes, fmt.Sprintf("db_driver inconnu : %q (attendu %s ou %s)", c.Base.Pilote, PilotePostgres, PiloteSQLite))
+	}
+	if c.Limites.Stockage != StockageMemoire && c.Limites.Stockage != StockageBase {
+		problemes = append(problemes, fmt.Sprintf("rate_limit_stockage inconnu : %q (attendu %s ou %s)", c.Limites.Stockage, StockageMemoire, StockageBase))
+	}
+	if c.Serveur.Adresse == "" {
+		problemes = append(problemes, "adresse est requise")
+	}
+	if c.Serveur.DossierUploads == "" {
+		problemes = append(problemes, "dossier_uploads est requis")
+	}
+
+	if len(problemes) > 0 {
+		return errors.New("configuration invalide : " + strings.Join(problemes, " ; "))
+	}
+	return nil
+}
+
+// ValiderServeur complète Valider pour le démarrage de l'API : le secret JWT, inutile aux
+// commandes migrate et seed, est alors requis
+func (c Config) ValiderServeur() error {
+	if c.Auth.SecretJWT == "" {
+		return errors.New("configuration invalide : jwt_secret est requis (JWT_SECRET, ou jwt_secret_file)")
+	}
+	return nil
+}
+
+// Afficher écrit la configuration effective, un paramètre par ligne avec sa source ; les
+// secrets sont masqués
+func (c Config) Afficher(w io.Writer) {
+	for _, p := range parametres {
+		v := p.valeur(&c)
+		if p.secret && v != "" {
+			v = "********"
+		}
+		fmt.Fprintf(w, "%-22s %-30q %s\n", p.nom, v, c.source(p.nom))
+	}
+
+	budgets := make([]string, 0, len(c.Limites.Regles))
+	for budget := range c.Limites.Regles {
+		budgets = append(budgets, budget)
+	}
+	sort.Strings(budgets)
+	for _, budget := range budgets {
+		r := c.Limites.Regles[budget]
+		fmt.Fprintf(w, "%-22s %-30q %s\n", prefixeLimites+budget, fmt.Sprintf("%d/%s", r.Capacite, r.Periode), c.source(prefixeLimites+budget))
+	}
+}
+
+func (c Config) source(nom string) string {
+	if s, ok := c.sources[nom]; ok {
+		return s
+	}
+	return sourceDefaut
+}
+
+// listeRegles est la valeur de l'option répétable -rate_limit
+type listeRegles []string
+
+func (l *listeRegles) String() string { return strings.Join(*l, " ") }
+
+func (l *listeRegles) Set(v string) error {
+	if nom, _, ok := strings.Cut(v, "="); !ok || nom == "" {
+		return fmt.Errorf("format attendu <budget>[_ip]=<capacite>/<periode> : %q", v)
+	}
+	*l = append(*l, v)
+	return nil
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func ecrireFichier(t *testing.T, nom, contenu string) string {
+	t.Helper()
+	chemin := filepath.Join(t.TempDir(), nom)
+	if err := os.WriteFile(chemin, []byte(contenu), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return chemin
+}
+
+func charger(t *testing.T, args ...string) (Config, *flag.FlagSet, error) {
+	t.Helper()
+	options := flag.NewFlagSet("test", flag.ContinueOnError)
+	options.Bool("migrer", false, "option de la commande")
+	cfg, err := Charger(options, args)
+	return cfg, options, err
+}
+
+// Chaque source remplace les précédentes : défaut, fichier, environnement, options
+func TestChargerPriorite(t *testing.T) {
+	fichier := ecrireFichier(t, "config.json", `{
+		"db_driver": "sqlite",
+		"sqlite_fichier": "fichier.db",
+		"adresse": ":7000",
+		"seuil_signalements": 5,
+		"admin_profil_ids": [4, 7],
+		"rate_limit_lecture": "100/1m"
+	}`)
+	t.Setenv("fichier_config", fichier)
+	t.Setenv("sqlite_fichier", "env.db")
+	t.Setenv("adresse", ":8000")
+	t.Setenv("rate_limit_api_ip", "10/1s")
+
+	cfg, options, err := charger(t, "-adresse", ":9000", "-rate_limit", "creation=2/1h", "-migrer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cas := []struct {
+		nom           string
+		obtenu, voulu interface{}
+	}{
+		{"db_driver", cfg.Base.Pilote, PiloteSQLite},
+		{"sqlite_fichier", cfg.Base.FichierSQLite, "env.db"},
+		{"adresse", cfg.Serveur.Adresse, ":9000"},
+		{"dossier_uploads", cfg.Serveur.DossierUploads, "./uploads"},
+		{"seuil_signalements", cfg.Moderation.SeuilSignalements, int64(5)},
+		{"admin_profil_ids", len(cfg.Auth.AdminProfilIDs), 2},
+		{"idempotence_duree", cfg.Idempotence.Duree, 24 * time.Hour},
+		{"rate_limit_lecture", cfg.Limites.Regles["lecture"], RegleLimite{Capacite: 100, Periode: time.Minute}},
+		{"rate_limit_api_ip", cfg.Limites.Regles["api_ip"], RegleLimite{Capacite: 10, Periode: time.Second}},
+		{"rate_limit_creation", cfg.Limites.Regles["creation"], RegleLimite{Capacite: 2, Periode: time.Hour}},
+	}
+	for _, c := range cas {
+		if c.obtenu != c.voulu {
+			t.Errorf("%s = %v, attendu %v", c.nom, c.obtenu, c.voulu)
+		}
+	}
+	// Les options de la commande restent disponibles
+	if options.Lookup("migrer").Value.String() != "true" {
+		t.Error("option -migrer perdue")
+	}
+}
+
+// Les secrets se lisent dans un fichier et sont masqués à l'affichage
+func TestChargerSecrets(t *testing.T) {
+	t.Setenv("db_driver", PiloteSQLite)
+	t.Setenv("jwt_secret_file", ecrireFichier(t, "jwt", "s3cr3t\n"))
+
+	cfg, _, err := charger(t, "-db_password_file", ecrireFichier(t, "password", "mot de passe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Auth.SecretJWT != "s3cr3t" || cfg.Base.MotDePasse != "mot de passe" {
+		t.Errorf("secrets lus : %q, %q", cfg.Auth.SecretJWT, cfg.Base.MotDePasse)
+	}
+	if err := cfg.ValiderServeur(); err != nil {
+		t.Error(err)
+	}
+
+	var sortie strings.Builder
+	cfg.Afficher(&sortie)
+	if strings.Contains(sortie.String(), "s3cr3t") || strings.Contains(sortie.String(), "mot de passe") {
+		t.Errorf("secret affiché :\n%s", sortie.String())
+	}
+
+	// Un secret n'est jamais passé en option de ligne de commande
+	if _, _, err := charger(t, "-jwt_secret", "s3cr3t"); err == nil {
+		t.Error("option -jwt_secret acceptée")
+	}
+}
+
+func TestChargerInvalide(t *testing.T) {
+	cas := []struct {
+		nom  string
+		env  map[string]string
+		args []string
+	}{
+		{"postgres sans hôte", nil, nil},
+		{"pilote inconnu", map[string]string{"db_driver": "mysql"}, nil},
+		{"sslmode inconnu", map[string]string{"db_host": "db", "db_name": "annonces", "db_sslmode": "oui"}, nil},
+		{"seuil négatif", map[string]string{"db_driver": "sqlite", "seuil_signalements": "-1"}, nil},
+		{"durée invalide", map[string]string{"db_driver": "sqlite"}, []string{"-idempotence_duree", "demain"}},
+		{"règle invalide", map[string]string{"db_driver": "sqlite", "rate_limit_lecture": "beaucoup"}, nil},
+		{"stockage inconnu", map[string]string{"db_driver": "sqlite", "rate_limit_stockage": "redis"}, nil},
+		{"clé de fichier inconnue", map[string]string{"db_driver": "sqlite", "fichier_config": ecrireFichier(t, "c.json", `{"hote": "db"}`)}, nil},
+		{"fichier de secret absent", map[string]string{"db_driver": "sqlite", "jwt_secret_file": "/introuvable"}, nil},
+	}
+	for _, c := range cas {
+		t.Run(c.nom, func(t *testing.T) {
+			for nom, v := range c.env {
+				t.Setenv(nom, v)
+			}
+			if _, _, err := charger(t, c.args...); err == nil {
+				t.Error("configuration acceptée")
+			}
+		})
+	}
+
+	t.Setenv("db_driver", PiloteSQLite)
+	cfg, _, err := charger(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ValiderServeur() == nil {
+		t.Error("démarrage accepté sans jwt_secret")
+	}
+}
+
+// Les variables se lisent sous leur nom, en majuscules ou sous leur ancien nom
+func TestChargerNomsVariables(t *testing.T) {
+	t.Setenv("JWT_SECRET", "majuscules")
+	t.Setenv("DB_HOST", "db")
+	t.Setenv("db_host", "prioritaire")
+	t.Setenv("dbname", "annonces")
+
+	cfg, _, err := charger(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Auth.SecretJWT != "majuscules" || cfg.Base.Hote != "prioritaire" || cfg.Base.Nom != "annonces" {
+		t.Errorf("valeurs lues : %q, %q, %q", cfg.Auth.SecretJWT, cfg.Base.Hote, cfg.Base.Nom)
+	}
+}
+
+// Le fichier .env du dépôt suffit à démarrer l'API
+func TestChargerEnvDepot(t *testing.T) {
+	valeurs, err := godotenv.Read("../.env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Les variables chargées depuis .env sont retirées après le test
+	for nom := range valeurs {
+		t.Setenv(nom, "")
+		os.Unsetenv(nom)
+	}
+	dossier, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(dossier) })
+
+	cfg, _, err := charger(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ValiderServeur(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Auth.SecretJWT != valeurs["JWT_SECRET"] || cfg.Base.Hote != valeurs["host"] || cfg.Base.Nom != valeurs["dbname"] {
+		t.Errorf(".env mal lu : hôte %q, base %q", cfg.Base.Hote, cfg.Base.Nom)
+	}
+}
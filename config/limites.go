@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RegleLimite est un budget de seau à jetons du limiteur de débit : Capacite requêtes,
+// rechargées progressivement sur Periode
+type RegleLimite struct {
+	Capacite int
+	Periode  time.Duration
+}
+
+// ParseRegleLimite lit une règle au format "<capacite>/<periode>"
+func ParseRegleLimite(v string) (RegleLimite, error) {
+	parts := strings.SplitN(v, "/", 2)
+	if len(parts) != 2 {
+		return RegleLimite{}, fmt.Errorf("format attendu <capacite>/<periode> : %q", v)
+	}
+	capacite, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || capacite <= 0 {
+		return RegleLimite{}, fmt.Errorf("capacité invalide : %q", parts[0])
+	}
+	periode, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || periode <= 0 {
+		return RegleLimite{}, fmt.Errorf("période invalide : %q", parts[1])
+	}
+	return RegleLimite{Capacite: capacite, Periode: periode}, nil
+}
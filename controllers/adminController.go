@@ -0,0 +1,734 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/services"
+)
+
+const (
+	adminLimiteDefaut = 50
+	adminLimiteMax    = 200
+)
+
+// AdminAnnonceInput contient les champs modifiables d'office par un administrateur.
+// Seuls les champs fournis sont modifiés ; le motif est obligatoire et journalisé.
+type AdminAnnonceInput struct {
+	Statut        *string    `json:"statut"`
+	Description   *string    `json:"description" binding:"omitempty,max=2000"`
+	Prix          *Nombre    `json:"prix" binding:"omitempty,nombre,gt=0,lte=10000000"`
+	Quantite      *Nombre    `json:"quantite" binding:"omitempty,nombre,gt=0,lte=100000000"`
+	TypeCultureID *uuid.UUID `json:"type_culture_id"`
+	Motif         string     `json:"motif" binding:"required"`
+}
+
+type TypeCultureInput struct {
+	Libelle string `json:"libelle" binding:"required"`
+}
+
+type SuspensionInput struct {
+	Motif      string `json:"motif" binding:"required"`
+	DureeJours int    `json:"duree_jours"`
+}
+
+// pagination lit les paramètres limit et offset des listes d'administration
+func pagination(c *gin.Context) (int, int) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(adminLimiteDefaut)))
+	if err != nil || limit <= 0 || limit > adminLimiteMax {
+		limit = adminLimiteDefaut
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// journaliserAdmin enregistre une action d'administration, avec les valeurs modifiées en JSON
+func journaliserAdmin(tx *gorm.DB, c *gin.Context, action string, cibleType string, cibleID uuid.UUID, annonceType string, motif string, details interface{}) error {
+	acteurID, _ := optionalUserID(c)
+	entree := models.JournalAudit{
+		ActeurID:    acteurID,
+		Action:      action,
+		CibleType:   cibleType,
+		CibleID:     cibleID,
+		AnnonceType: annonceType,
+		Motif:       motif,
+	}
+	if details != nil {
+		if b, err := json.Marshal(details); err == nil {
+			entree.Details = string(b)
+		}
+	}
+	return services.Journaliser(tx, entree)
+}
+
+// Lister les annonces d'un type, masquées comprises. Filtres : ceux des listes publiques,
+// q (recherche dans la description), moderation (état de modération), limit et offset.
+func AdminGetAnnonces(c *gin.Context) {
+	annonceType := c.Param("type")
+	modele, ok := tableAnnonce(annonceType)
+	if !ok {
+		erreurs.Repondre(c, erreurs.TypeAnnonceInconnu)
+		return
+	}
+
+	var filtre models.FiltreAnnonce
+	if err := c.ShouldBindQuery(&filtre); err != nil {
+		erreurs.Repondre(c, erreurs.FiltreInvalide)
+		return
+	}
+	if err := validerFiltre(filtre, annonceType); err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+	// La liste est paginée en SQL, où seul le rectangle englobant le cercle est connu
+	if filtre.AvecDistance() {
+		erreurs.Repondre(c, erreurs.FiltreInvalide.Champ("rayon_km", erreurs.DetailInconnu))
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	colonnePrix := "prix_kg"
+	if annonceType == models.AnnonceTypePref {
+		colonnePrix = "prix_kg_pref"
+	}
+	query := depots.AppliquerFiltre(db.Model(modele), filtre, colonnePrix, annonceType != models.AnnonceTypeAchat)
+	if q := c.Query("q"); q != "" {
+		query = query.Where("LOWER(description) LIKE ?", "%"+strings.ToLower(q)+"%")
+	}
+	if moderation := c.Query("moderation"); moderation != "" {
+		query = query.Where("id IN (?)", db.Model(&models.ModerationAnnonce{}).Select("annonce_id").
+			Where("annonce_type = ? AND statut = ?", annonceType, moderation))
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	limit, offset := pagination(c)
+	page := query.Preload("User").Preload("TypeCulture").Order("id").Limit(limit).Offset(offset)
+
+	var err error
+	var result interface{}
+	switch annonceType {
+	case models.AnnonceTypeVente:
+		var annonces []models.AnnonceVente
+		err = page.Preload("Parcelle").Find(&annonces).Error
+		dtos := []models.ListeAnnonceVente{}
+		for _, a := range annonces {
+			dtos = append(dtos, toAnnonceDTO(a))
+		}
+		result = dtos
+	case models.AnnonceTypeAchat:
+		var annonces []models.AnnonceAchat
+		err = page.Find(&annonces).Error
+		dtos := []models.ListeAnnonceAchat{}
+		for _, a := range annonces {
+			dtos = append(dtos, toAnnonceAchatDTO(a))
+		}
+		result = dtos
+	case models.AnnonceTypePref:
+		var annonces []models.AnnoncePrefinancement
+		err = page.Preload("Parcelle").Find(&annonces).Error
+		dtos := []models.LiteAnnoncePrefinancement{}
+		for _, a := range annonces {
+			dtos = append(dtos, toAnnoncePrefDTO(a))
+		}
+		result = dtos
+	}
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"total": total, "annonces": renduAnnonces(c, result)})
+}
+
+// annonceModifiee décrit le résultat d'une modification d'office, pour les événements
+type annonceModifiee struct {
+	AncienStatut  string
+	Statut        string
+	TypeCultureID uuid.UUID
+	Adresse       string
+	DTO           interface{}
+	// Montant du préfinancement (annonces de préfinancement)
+	Montant float64
+}
+
+// modifierAnnonceAdmin applique les champs fournis à l'annonce dans la transaction
+// et émet les événements de webhook correspondants
+func modifierAnnonceAdmin(tx *gorm.DB, annonceType string, id uuid.UUID, input AdminAnnonceInput) (annonceModifiee, error) {
+	var r annonceModifiee
+
+	switch annonceType {
+	case models.AnnonceTypeVente:
+		var a models.AnnonceVente
+		if err := tx.First(&a, "id = ?", id).Error; err != nil {
+			return r, err
+		}
+		r.AncienStatut = a.Statut
+		if input.Statut != nil {
+			a.Statut = *input.Statut
+		}
+		if input.Description != nil {
+			a.Description = *input.Description
+		}
+		if input.Prix != nil {
+			a.PrixKg = float64(*input.Prix)
+		}
+		if input.Quantite != nil {
+			a.Quantite = float64(*input.Quantite)
+		}
+		if input.TypeCultureID != nil {
+			a.TypeCultureID = *input.TypeCultureID
+		}
+		a.Version++
+		if err := tx.Save(&a).Error; err != nil {
+			return r, err
+		}
+		tx.Preload("User").Preload("TypeCulture").Preload("Parcelle").First(&a)
+		r.Statut, r.TypeCultureID, r.Adresse, r.DTO = a.Statut, a.TypeCultureID, a.Parcelle.Adresse, toAnnonceDTO(a)
+
+	case models.AnnonceTypeAchat:
+		var a models.AnnonceAchat
+		if err := tx.First(&a, "id = ?", id).Error; err != nil {
+			return r, err
+		}
+		r.AncienStatut = a.Statut
+		if input.Statut != nil {
+			a.Statut = *input.Statut
+		}
+		if input.Description != nil {
+			a.Description = *input.Description
+		}
+		if input.Prix != nil {
+			a.Prix = float64(*input.Prix)
+		}
+		if input.Quantite != nil {
+			a.Quantite = float64(*input.Quantite)
+		}
+		if input.TypeCultureID != nil {
+			a.TypeCultureID = *input.TypeCultureID
+		}
+		a.Version++
+		if err := tx.Save(&a).Error; err != nil {
+			return r, err
+		}
+		tx.Preload("User").Preload("TypeCulture").First(&a)
+		r.Statut, r.TypeCultureID, r.DTO = a.Statut, a.TypeCultureID, toAnnonceAchatDTO(a)
+
+	case models.AnnonceTypePref:
+		var a models.AnnoncePrefinancement
+		if err := tx.First(&a, "id = ?", id).Error; err != nil {
+			return r, err
+		}
+		r.AncienStatut = a.Statut
+		if input.Statut != nil {
+			a.Statut = *input.Statut
+		}
+		if input.Description != nil {
+			a.Description = *input.Description
+		}
+		if input.Prix != nil {
+			a.Prix = float64(*input.Prix)
+		}
+		if input.Quantite != nil {
+			a.Quantite = float64(*input.Quantite)
+		}
+		if input.TypeCultureID != nil {
+			a.TypeCultureID = *input.TypeCultureID
+		}
+		a.MontantPrefinancement = a.Prix * a.Quantite
+		a.Version++
+		if err := tx.Save(&a).Error; err != nil {
+			return r, err
+		}
+		tx.Preload("User").Preload("TypeCulture").Preload("Parcelle").First(&a)
+		r.Statut, r.TypeCultureID, r.Adresse, r.DTO = a.Statut, a.TypeCultureID, a.Parcelle.Adresse, toAnnoncePrefDTO(a)
+		r.Montant = a.MontantPrefinancement
+
+	default:
+		return r, erreurs.TypeAnnonceInconnu
+	}
+
+	return r, services.EmettreModification(tx, annonceType, id, r.AncienStatut, r.Statut, r.DTO)
+}
+
+// Modifier d'office une annonce (motif obligatoire)
+func AdminUpdateAnnonce(c *gin.Context) {
+	annonceType := c.Param("type")
+	if _, ok := tableAnnonce(annonceType); !ok {
+		erreurs.Repondre(c, erreurs.TypeAnnonceInconnu)
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+
+	var input AdminAnnonceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		erreurs.Repondre(c, erreurs.Validation(err))
+		return
+	}
+	// Les statuts acceptés dépendent du type d'annonce
+	if statuts := models.StatutsAnnonce(annonceType); input.Statut != nil && !contient(statuts, *input.Statut) {
+		erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("statut", erreurs.DetailValeurInconnue, statuts...))
+		return
+	}
+	if input.TypeCultureID != nil {
+		if _, err := depotsDe(c).TypesCulture.Trouver(*input.TypeCultureID); err != nil {
+			erreurs.Repondre(c, erreurs.TypeCultureInconnu)
+			return
+		}
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	var r annonceModifiee
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if r, err = modifierAnnonceAdmin(tx, annonceType, id, input); err != nil {
+			return err
+		}
+		return journaliserAdmin(tx, c, models.AuditModifierAnnonce, models.CibleAnnonce, id, annonceType, input.Motif, input)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		erreurs.Repondre(c, erreurs.AnnonceIntrouvable)
+		return
+	}
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	publierAnnonce(annonceType, id, r.TypeCultureID, r.Adresse, &r.AncienStatut, r.Statut, r.DTO)
+	compterAnnonce(annonceType, "", &r.AncienStatut, r.Statut, r.Montant)
+
+	c.JSON(http.StatusOK, renduAnnonces(c, r.DTO))
+}
+
+// Supprimer d'office une annonce (motif obligatoire, paramètre motif)
+func AdminDeleteAnnonce(c *gin.Context) {
+	annonceType := c.Param("type")
+	modele, ok := tableAnnonce(annonceType)
+	if !ok {
+		erreurs.Repondre(c, erreurs.TypeAnnonceInconnu)
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+	motif := strings.TrimSpace(c.Query("motif"))
+	if motif == "" {
+		erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("motif", erreurs.DetailObligatoire))
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(modele, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return journaliserAdmin(tx, c, models.AuditSupprimerAnnonce, models.CibleAnnonce, id, annonceType, motif, nil)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		erreurs.Repondre(c, erreurs.AnnonceIntrouvable)
+		return
+	}
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+	supprimerFavorisAnnonce(db, annonceType, id)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Annonce supprimée avec succès"})
+}
+
+// Lister le catalogue des types de culture
+func AdminGetTypesCulture(c *gin.Context) {
+	types, err := depotsDe(c).TypesCulture.Lister()
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types)
+}
+
+// libelleDisponible vérifie qu'aucun autre type de culture ne porte déjà ce libellé
+func libelleDisponible(c *gin.Context, db *gorm.DB, libelle string, id uuid.UUID) bool {
+	var total int64
+	db.Model(&models.TypeCulture{}).Where("LOWER(libelle) = ? AND id <> ?", strings.ToLower(libelle), id).Count(&total)
+	if total > 0 {
+		erreurs.Repondre(c, erreurs.TypeCultureExistant)
+		return false
+	}
+	return true
+}
+
+// Ajouter un type de culture
+func AdminCreateTypeCulture(c *gin.Context) {
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	var input TypeCultureInput
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Libelle) == "" {
+		erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("libelle", erreurs.DetailObligatoire))
+		return
+	}
+	tc := models.TypeCulture{ID: uuid.New(), Libelle: strings.TrimSpace(input.Libelle)}
+	if !libelleDisponible(c, db, tc.Libelle, tc.ID) {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tc).Error; err != nil {
+			return err
+		}
+		return journaliserAdmin(tx, c, models.AuditCreerCulture, models.CibleTypeCulture, tc.ID, "", "", tc)
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tc)
+}
+
+// Renommer un type de culture
+func AdminUpdateTypeCulture(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	tc, err := depotsDe(c).TypesCulture.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurs.TypeCultureIntrouvable)
+		return
+	}
+
+	var input TypeCultureInput
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Libelle) == "" {
+		erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("libelle", erreurs.DetailObligatoire))
+		return
+	}
+	libelle := strings.TrimSpace(input.Libelle)
+	if !libelleDisponible(c, db, libelle, tc.ID) {
+		return
+	}
+
+	details := gin.H{"ancien_libelle": tc.Libelle, "libelle": libelle}
+	tc.Libelle = libelle
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&tc).Where("id = ?", tc.ID).Update("libelle", libelle).Error; err != nil {
+			return err
+		}
+		return journaliserAdmin(tx, c, models.AuditModifierCulture, models.CibleTypeCulture, tc.ID, "", "", details)
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tc)
+}
+
+// Supprimer un type de culture non utilisé par des annonces
+func AdminDeleteTypeCulture(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	tc, err := depotsDe(c).TypesCulture.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurs.TypeCultureIntrouvable)
+		return
+	}
+
+	for _, modele := range []interface{}{&models.AnnonceVente{}, &models.AnnonceAchat{}, &models.AnnoncePrefinancement{}} {
+		var total int64
+		db.Model(modele).Where("type_culture_id = ?", id).Count(&total)
+		if total > 0 {
+			erreurs.Repondre(c, erreurs.TypeCultureUtilise)
+			return
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.TypeCulture{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return journaliserAdmin(tx, c, models.AuditSupprimerCulture, models.CibleTypeCulture, id, "", "", tc)
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Type de culture supprimé avec succès"})
+}
+
+// Lister les utilisateurs (filtres facultatifs : q sur le nom, suspendu=true, limit, offset)
+func AdminGetUtilisateurs(c *gin.Context) {
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	query := db.Model(&models.User{})
+	if q := c.Query("q"); q != "" {
+		query = query.Where("LOWER(nom) LIKE ?", "%"+strings.ToLower(q)+"%")
+	}
+	if c.Query("suspendu") == "true" {
+		query = query.Where("id IN (?)", db.Model(&models.Suspension{}).Select("user_id").
+			Where("fin_le IS NULL OR fin_le > ?", time.Now()))
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	limit, offset := pagination(c)
+	users := []models.User{}
+	if err := query.Order("nom").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"total": total, "utilisateurs": users})
+}
+
+// Consulter l'activité d'un utilisateur
+func AdminGetUtilisateur(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide.Champ("user_id", erreurs.DetailFormat, "uuid"))
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	var activite models.ActiviteUtilisateur
+	if activite.User, err = depotsDe(c).Utilisateurs.Trouver(id); err != nil {
+		erreurs.Repondre(c, erreurs.UtilisateurIntrouvable)
+		return
+	}
+
+	db.Model(&models.AnnonceVente{}).Where("user_id = ?", id).Count(&activite.NbAnnoncesVente)
+	db.Model(&models.AnnonceAchat{}).Where("user_id = ?", id).Count(&activite.NbAnnoncesAchat)
+	db.Model(&models.AnnoncePrefinancement{}).Where("user_id = ?", id).Count(&activite.NbAnnoncesPref)
+	db.Model(&models.Conversation{}).Where("proprietaire_id = ? OR interlocuteur_id = ?", id, id).Count(&activite.NbConversations)
+	db.Model(&models.Message{}).Where("auteur_id = ?", id).Count(&activite.NbMessages)
+	db.Model(&models.Signalement{}).Where("auteur_id = ?", id).Count(&activite.NbSignalementsEmis)
+
+	for annonceType, modele := range map[string]interface{}{
+		models.AnnonceTypeVente: &models.AnnonceVente{},
+		models.AnnonceTypeAchat: &models.AnnonceAchat{},
+		models.AnnonceTypePref:  &models.AnnoncePrefinancement{},
+	} {
+		var total int64
+		db.Model(&models.Signalement{}).
+			Where("annonce_type = ? AND annonce_id IN (?)", annonceType, db.Model(modele).Select("id").Where("user_id = ?", id)).
+			Count(&total)
+		activite.NbSignalementsRecus += total
+	}
+
+	activite.Note = models.NoteUtilisateur{UserID: id.String()}
+	if notes := notesUtilisateurs(db, []string{id.String()}); len(notes) > 0 {
+		activite.Note = notes[id.String()]
+	}
+
+	var suspension models.Suspension
+	if err := db.Where("user_id = ?", id).Limit(1).Find(&suspension).Error; err == nil && suspension.UserID != uuid.Nil {
+		activite.Suspension = &suspension
+	}
+
+	activite.Audit = []models.JournalAudit{}
+	db.Where("cible_id = ?", id).Order("created_at DESC").Limit(50).Find(&activite.Audit)
+
+	c.JSON(http.StatusOK, activite)
+}
+
+// Suspendre un compte (motif obligatoire ; duree_jours vide ou 0 pour une suspension définitive)
+func AdminSuspendreUtilisateur(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide.Champ("user_id", erreurs.DetailFormat, "uuid"))
+		return
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	if _, err := depotsDe(c).Utilisateurs.Trouver(id); err != nil {
+		erreurs.Repondre(c, erreurs.UtilisateurIntrouvable)
+		return
+	}
+
+	var input SuspensionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		erreurs.Repondre(c, erreurs.Validation(err))
+		return
+	}
+	if input.DureeJours < 0 {
+		erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("duree_jours", erreurs.DetailMinimum, "0"))
+		return
+	}
+
+	adminID, _ := optionalUserID(c)
+	suspension := models.Suspension{
+		UserID:       id,
+		Motif:        input.Motif,
+		ModerateurID: adminID,
+	}
+	if input.DureeJours > 0 {
+		fin := time.Now().AddDate(0, 0, input.DureeJours)
+		suspension.FinLe = &fin
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&suspension).Error; err != nil {
+			return err
+		}
+		return journaliserAdmin(tx, c, models.AuditSuspendre, models.CibleUtilisateur, id, "", input.Motif, gin.H{"fin_le": suspension.FinLe})
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suspension)
+}
+
+// Lever la suspension d'un compte
+func AdminReactiverUtilisateur(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide.Champ("user_id", erreurs.DetailFormat, "uuid"))
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&models.Suspension{}, "user_id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return journaliserAdmin(tx, c, models.AuditReactiver, models.CibleUtilisateur, id, "", c.Query("motif"), nil)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		erreurs.Repondre(c, erreurs.SuspensionIntrouvable)
+		return
+	}
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Suspension levée"})
+}
+
+// Compteurs globaux de la plateforme
+func AdminGetStatistiques(c *gin.Context) {
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	stats := models.StatistiquesPlateforme{AnnoncesParStatut: map[string]map[string]int64{}}
+
+	for annonceType, modele := range map[string]interface{}{
+		models.AnnonceTypeVente: &models.AnnonceVente{},
+		models.AnnonceTypeAchat: &models.AnnonceAchat{},
+		models.AnnonceTypePref:  &models.AnnoncePrefinancement{},
+	} {
+		var lignes []struct {
+			Statut string
+			Total  int64
+		}
+		if err := db.Model(modele).Select("statut, COUNT(*) AS total").Group("statut").Scan(&lignes).Error; err != nil {
+			erreurs.Repondre(c, err)
+			return
+		}
+		parStatut := map[string]int64{}
+		for _, l := range lignes {
+			parStatut[l.Statut] = l.Total
+		}
+		stats.AnnoncesParStatut[annonceType] = parStatut
+	}
+
+	db.Model(&models.User{}).Count(&stats.Utilisateurs)
+	db.Model(&models.Conversation{}).Count(&stats.Conversations)
+	db.Model(&models.Message{}).Count(&stats.Messages)
+	db.Model(&models.Avis{}).Count(&stats.Avis)
+	db.Model(&models.Signalement{}).Where("statut = ?", models.SignalementEnAttente).Count(&stats.SignalementsEnAttente)
+	db.Model(&models.ModerationAnnonce{}).Where("statut IN ?", models.ModerationsMasquees).Count(&stats.AnnoncesMasquees)
+	db.Model(&models.Suspension{}).Where("fin_le IS NULL OR fin_le > ?", time.Now()).Count(&stats.SuspensionsActives)
+	db.Model(&models.WebhookLivraison{}).Where("statut = ?", models.LivraisonEnAttente).Count(&stats.LivraisonsEnAttente)
+	db.Model(&models.WebhookLivraison{}).Where("statut = ?", models.LivraisonAbandonne).Count(&stats.LivraisonsAbandonnees)
+
+	c.JSON(http.StatusOK, stats)
+}
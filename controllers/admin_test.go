@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+func (e *environnement) monterAdmin() {
+	admin := e.admin()
+	admin.GET("/audit", GetJournalAudit)
+	admin.GET("/annonces/:type", AdminGetAnnonces)
+	admin.PUT("/annonces/:type/:id", AdminUpdateAnnonce)
+	admin.DELETE("/annonces/:type/:id", AdminDeleteAnnonce)
+	admin.GET("/types_culture", AdminGetTypesCulture)
+	admin.POST("/types_culture", AdminCreateTypeCulture)
+	admin.PUT("/types_culture/:id", AdminUpdateTypeCulture)
+	admin.DELETE("/types_culture/:id", AdminDeleteTypeCulture)
+	admin.GET("/utilisateurs", AdminGetUtilisateurs)
+	admin.GET("/utilisateurs/:id", AdminGetUtilisateur)
+	admin.POST("/utilisateurs/:id/suspension", AdminSuspendreUtilisateur)
+	admin.DELETE("/utilisateurs/:id/suspension", AdminReactiverUtilisateur)
+	admin.GET("/statistiques", AdminGetStatistiques)
+}
+
+// nbAudit compte les entrées du journal d'audit pour une action
+func (e *environnement) nbAudit(t *testing.T, administrateur uuid.UUID, action string) int {
+	t.Helper()
+	return len(decoderListe(t, e.requete(http.MethodGet, "/v1/admin/audit?action="+action, administrateur, nil)))
+}
+
+// L'administration liste les annonces masquées comprises, les modifie et les supprime
+// d'office avec un motif journalisé
+func TestAdminAnnonces(t *testing.T) {
+	e := nouvelEnvironnementBase(t)
+	e.monterAdmin()
+	administrateur := e.ajouterUtilisateur(t, "Administrateur")
+	vente := e.creer(t, typesTest[0], e.vendeur.ID, nil)["id"].(string)
+	pref := e.creer(t, typesTest[2], e.vendeur.ID, nil)["id"].(string)
+
+	masquage := models.ModerationAnnonce{AnnonceType: models.AnnonceTypeVente, AnnonceID: uuid.MustParse(vente), Statut: models.ModerationMasquee}
+	if err := e.base.Create(&masquage).Error; err != nil {
+		t.Fatal(err)
+	}
+	for requete, attendu := range map[string]float64{
+		"": 1,
+		"?moderation=" + models.ModerationMasquee:  1,
+		"?moderation=" + models.ModerationSignalee: 0,
+		"?q=" + url.QueryEscape("SÉCHÉES"):         1,
+		"?q=anacarde":                              0,
+	} {
+		w := e.requete(http.MethodGet, "/v1/admin/annonces/"+models.AnnonceTypeVente+requete, administrateur.ID, nil)
+		if total := decoder(t, w)["total"]; total != attendu {
+			t.Errorf("GET /admin/annonces/vente%s : total %v, attendu %v", requete, total, attendu)
+		}
+	}
+	w := e.requete(http.MethodGet, "/v1/admin/annonces/troc", administrateur.ID, nil)
+	verifierErreur(t, w, http.StatusBadRequest, "type_annonce_inconnu")
+	w = e.requete(http.MethodGet, "/v1/admin/annonces/vente?lat=7&lng=-6&rayon_km=10", administrateur.ID, nil)
+	verifierErreur(t, w, http.StatusBadRequest, "filtre_invalide")
+
+	// Modification d'office : motif obligatoire, statuts du type, montant recalculé
+	chemin := "/v1/admin/annonces/" + models.AnnonceTypePref + "/" + pref
+	w = e.requete(http.MethodPut, chemin, administrateur.ID, map[string]interface{}{"prix": 1200})
+	verifierErreur(t, w, http.StatusBadRequest, "donnees_invalides")
+	w = e.requete(http.MethodPut, chemin, administrateur.ID, map[string]interface{}{"statut": models.StatutVendue, "motif": "Correction"})
+	verifierErreur(t, w, http.StatusBadRequest, "donnees_invalides")
+	w = e.requete(http.MethodPut, chemin, administrateur.ID, map[string]interface{}{"prix": 1200, "motif": "Prix erroné"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT : statut %d (%s)", w.Code, w.Body.String())
+	}
+	if annonce := decoder(t, w); annonce["montant_pref"] != float64(1200*800) || annonce["version"] != float64(2) {
+		t.Errorf("annonce %v : montant ou version non mis à jour", annonce)
+	}
+
+	chemin = "/v1/admin/annonces/" + models.AnnonceTypeVente + "/" + vente
+	w = e.requete(http.MethodDelete, chemin, administrateur.ID, nil)
+	verifierErreur(t, w, http.StatusBadRequest, "donnees_invalides")
+	if w = e.requete(http.MethodDelete, chemin+"?motif=Doublon", administrateur.ID, nil); w.Code != http.StatusOK {
+		t.Fatalf("DELETE : statut %d (%s)", w.Code, w.Body.String())
+	}
+	w = e.requete(http.MethodDelete, chemin+"?motif=Doublon", administrateur.ID, nil)
+	verifierErreur(t, w, http.StatusNotFound, "annonce_introuvable")
+
+	if n := e.nbAudit(t, administrateur.ID, models.AuditModifierAnnonce); n != 1 {
+		t.Errorf("%d modification(s) journalisée(s), attendu 1", n)
+	}
+	if n := e.nbAudit(t, administrateur.ID, models.AuditSupprimerAnnonce); n != 1 {
+		t.Errorf("%d suppression(s) journalisée(s), attendu 1", n)
+	}
+}
+
+func TestAdminTypesCulture(t *testing.T) {
+	e := nouvelEnvironnementBase(t)
+	e.monterAdmin()
+	administrateur := e.ajouterUtilisateur(t, "Administrateur")
+	e.creer(t, typesTest[0], e.vendeur.ID, nil)
+
+	w := e.requete(http.MethodPost, "/v1/admin/types_culture", administrateur.ID, map[string]interface{}{"libelle": " Hévéa "})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST : statut %d (%s)", w.Code, w.Body.String())
+	}
+	hevea := decoder(t, w)
+	if hevea["libelle"] != "Hévéa" {
+		t.Errorf("libellé %q, attendu sans espaces", hevea["libelle"])
+	}
+	w = e.requete(http.MethodPost, "/v1/admin/types_culture", administrateur.ID, map[string]interface{}{"libelle": "HÉVÉA"})
+	verifierErreur(t, w, http.StatusConflict, "type_culture_existant")
+	w = e.requete(http.MethodPost, "/v1/admin/types_culture", administrateur.ID, map[string]interface{}{"libelle": "  "})
+	verifierErreur(t, w, http.StatusBadRequest, "donnees_invalides")
+
+	anacarde := "/v1/admin/types_culture/" + e.anacarde.ID.String()
+	w = e.requete(http.MethodPut, anacarde, administrateur.ID, map[string]interface{}{"libelle": "cacao"})
+	verifierErreur(t, w, http.StatusConflict, "type_culture_existant")
+	if w = e.requete(http.MethodPut, anacarde, administrateur.ID, map[string]interface{}{"libelle": "Noix de cajou"}); w.Code != http.StatusOK {
+		t.Fatalf("PUT : statut %d (%s)", w.Code, w.Body.String())
+	}
+
+	// Le cacao est utilisé par une annonce
+	w = e.requete(http.MethodDelete, "/v1/admin/types_culture/"+e.cacao.ID.String(), administrateur.ID, nil)
+	verifierErreur(t, w, http.StatusConflict, "type_culture_utilise")
+	if w = e.requete(http.MethodDelete, "/v1/admin/types_culture/"+hevea["id"].(string), administrateur.ID, nil); w.Code != http.StatusOK {
+		t.Fatalf("DELETE : statut %d (%s)", w.Code, w.Body.String())
+	}
+	w = e.requete(http.MethodDelete, "/v1/admin/types_culture/"+uuid.NewString(), administrateur.ID, nil)
+	verifierErreur(t, w, http.StatusNotFound, "type_culture_introuvable")
+
+	libelles := map[interface{}]bool{}
+	for _, tc := range decoderListe(t, e.requete(http.MethodGet, "/v1/admin/types_culture", administrateur.ID, nil)) {
+		libelles[tc["libelle"]] = true
+	}
+	if len(libelles) != 2 || !libelles["Cacao"] || !libelles["Noix de cajou"] {
+		t.Errorf("catalogue %v, attendu Cacao et Noix de cajou", libelles)
+	}
+	for action, attendu := range map[string]int{models.AuditCreerCulture: 1, models.AuditModifierCulture: 1, models.AuditSupprimerCulture: 1} {
+		if n := e.nbAudit(t, administrateur.ID, action); n != attendu {
+			t.Errorf("journal d'audit : %d entrée(s) %s, attendu %d", n, action, attendu)
+		}
+	}
+}
+
+func TestAdminUtilisateurs(t *testing.T) {
+	e := nouvelEnvironnementBase(t)
+	e.monterAdmin()
+	administrateur := e.ajouterUtilisateur(t, "Administrateur")
+	e.creer(t, typesTest[1], e.acheteur.ID, nil)
+	e.creer(t, typesTest[0], e.vendeur.ID, nil)
+
+	suspension := "/v1/admin/utilisateurs/" + e.acheteur.ID.String() + "/suspension"
+	w := e.requete(http.MethodPost, "/v1/admin/utilisateurs/"+uuid.NewString()+"/suspension", administrateur.ID, map[string]interface{}{"motif": "Fraude"})
+	verifierErreur(t, w, http.StatusNotFound, "utilisateur_introuvable")
+	w = e.requete(http.MethodPost, suspension, administrateur.ID, map[string]interface{}{})
+	verifierErreur(t, w, http.StatusBadRequest, "donnees_invalides")
+	w = e.requete(http.MethodPost, suspension, administrateur.ID, map[string]interface{}{"motif": "Fraude", "duree_jours": 3})
+	if w.Code != http.StatusOK || decoder(t, w)["fin_le"] == nil {
+		t.Fatalf("suspension : statut %d (%s)", w.Code, w.Body.String())
+	}
+
+	w = e.requete(http.MethodPost, "/v1/annonces_achat", e.acheteur.ID, typesTest[1].corps(e))
+	verifierErreur(t, w, http.StatusForbidden, "compte_suspendu")
+	w = e.requete(http.MethodGet, "/v1/admin/utilisateurs?suspendu=true", administrateur.ID, nil)
+	if liste := decoder(t, w); liste["total"] != float64(1) {
+		t.Errorf("%v utilisateur(s) suspendu(s), attendu 1", liste["total"])
+	}
+	w = e.requete(http.MethodGet, "/v1/admin/utilisateurs?q=YAO", administrateur.ID, nil)
+	if liste := decoder(t, w); liste["total"] != float64(1) {
+		t.Errorf("recherche par nom : %v utilisateur(s), attendu 1", liste["total"])
+	}
+
+	w = e.requete(http.MethodGet, "/v1/admin/utilisateurs/"+e.acheteur.ID.String(), administrateur.ID, nil)
+	fiche := decoder(t, w)
+	if fiche["nom"] != e.acheteur.Nom || fiche["nb_annonces_achat"] != float64(1) || fiche["suspension"] == nil || len(fiche["audit"].([]interface{})) != 1 {
+		t.Errorf("fiche %v : nom, annonces, suspension ou audit inattendu", fiche)
+	}
+	w = e.requete(http.MethodGet, "/v1/admin/statistiques", administrateur.ID, nil)
+	stats := decoder(t, w)
+	parStatut := stats["annonces_par_statut"].(map[string]interface{})
+	if stats["utilisateurs"] != float64(3) || stats["suspensions_actives"] != float64(1) ||
+		parStatut[models.AnnonceTypeVente].(map[string]interface{})[models.StatutActive] != float64(1) {
+		t.Errorf("statistiques %v", stats)
+	}
+
+	if w = e.requete(http.MethodDelete, suspension, administrateur.ID, nil); w.Code != http.StatusOK {
+		t.Fatalf("réactivation : statut %d (%s)", w.Code, w.Body.String())
+	}
+	w = e.requete(http.MethodDelete, suspension, administrateur.ID, nil)
+	verifierErreur(t, w, http.StatusNotFound, "suspension_introuvable")
+	if w = e.requete(http.MethodPost, "/v1/annonces_achat", e.acheteur.ID, typesTest[1].corps(e)); w.Code != http.StatusCreated {
+		t.Errorf("création après réactivation : statut %d (%s)", w.Code, w.Body.String())
+	}
+}
@@ -1,53 +1,59 @@
 package controllers
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
-	"github.com/Steph-business/annonce_de_vente/database"
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/erreurs"
 	"github.com/Steph-business/annonce_de_vente/models"
 )
 
+// Fonction utilitaire : transforme une AnnonceAchat en ListeAnnonceAchat
+func toAnnonceAchatDTO(a models.AnnonceAchat) models.ListeAnnonceAchat {
+	return models.ListeAnnonceAchat{
+		ID:                 a.ID.String(),
+		UserID:             a.UserID.String(),
+		Version:            a.Version,
+		Statut:             a.Statut,
+		Prix:               a.Prix,
+		Description:        a.Description,
+		Quantite:           a.Quantite,
+		UserNom:            a.User.Nom,
+		TypeCultureLibelle: a.TypeCulture.Libelle,
+		TypeCultureID:      a.TypeCultureID.String(),
+		CreatedAt:          a.CreatedAt,
+	}
+}
+
 // Liste toutes les annonces avec filtres facultatifs
 func GetAllAnnonceAchat(c *gin.Context) {
-	var achats []models.AnnonceAchat
-
-	// Construire la requête dynamique
-	query := database.DB.Preload("TypeCulture").Preload("User")
-
-	if userID := c.Query("user_id"); userID != "" {
-		query = query.Where("user_id = ?", userID)
-	}
-	if statut := c.Query("statut"); statut != "" {
-		query = query.Where("statut = ?", statut)
+	var filtre models.FiltreAnnonce
+	if err := c.ShouldBindQuery(&filtre); err != nil {
+		erreurs.Repondre(c, erreurs.FiltreInvalide)
+		return
 	}
-	if typeCultureID := c.Query("type_culture_id"); typeCultureID != "" {
-		query = query.Where("type_culture_id = ?", typeCultureID)
+	if err := validerFiltre(filtre, models.AnnonceTypeAchat); err != nil {
+		erreurs.Repondre(c, err)
+		return
 	}
 
-	// Exécuter la requête
-	if err := query.Find(&achats).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	achats, err := depotsDe(c).Achats.Lister(filtre)
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
 
 	var result []models.ListeAnnonceAchat
 	for _, a := range achats {
-		result = append(result, models.ListeAnnonceAchat{
-			ID:                 a.ID.String(),
-			Statut:             a.Statut,
-			Prix:               a.Prix,
-			Description:        a.Description,
-			Quantite:           a.Quantite,
-			UserNom:            a.User.Nom,
-			TypeCultureLibelle: a.TypeCulture.Libelle,
-		})
-	}
-
-	c.JSON(http.StatusOK, result)
+		result = append(result, toAnnonceAchatDTO(a))
+	}
+	enrichirNotesAchat(c, result)
+
+	c.JSON(http.StatusOK, renduAnnonces(c, result))
 }
 
 // Afficher toutes les annonces d'achat d'un utilisateur
@@ -57,25 +63,38 @@ func GetAnnoncesAchatByUserID(c *gin.Context) {
 	// Vérifier que c'est un UUID valide
 	userID, err := uuid.Parse(userIDParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID utilisateur invalide"})
+		erreurs.Repondre(c, erreurs.IDInvalide.Champ("user_id", erreurs.DetailFormat, "uuid"))
 		return
 	}
 
-	var annonces []models.AnnonceAchat
-	if err := database.DB.Where("user_id = ?", userID).Find(&annonces).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des annonces : " + err.Error()})
+	annonces, err := depotsDe(c).Achats.Lister(models.FiltreAnnonce{UserID: userID.String()})
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, annonces)
 }
 
+// CreateAnnonceAchatInput : corps de création et de remplacement (PUT) d'une annonce d'achat.
+// Les règles sont décrites dans validation.go.
 type CreateAnnonceAchatInput struct {
-	Statut        string  `json:"statut" binding:"required"`
-	Prix          float64 `json:"prix_kg" binding:"required"`
-	Description   string  `json:"description" binding:"required"`
-	TypeCultureID string  `json:"type_culture_id" binding:"required"`
-	Quantite      float64 `json:"quantite" binding:"required"`
+	Statut        string `json:"statut" binding:"required,oneof=active en_pause vendue expiree"`
+	Prix          Nombre `json:"prix_kg" binding:"required,nombre,gt=0,lte=10000000"`
+	Description   string `json:"description" binding:"required,max=2000"`
+	TypeCultureID string `json:"type_culture_id" binding:"required,uuid"`
+	Quantite      Nombre `json:"quantite" binding:"required,nombre,gt=0,lte=100000000"`
+}
+
+// entreeAchat reconstruit l'entrée d'une annonce modifiée, pour lui appliquer les mêmes règles
+func entreeAchat(achats models.AnnonceAchat) CreateAnnonceAchatInput {
+	return CreateAnnonceAchatInput{
+		Statut:        achats.Statut,
+		Prix:          Nombre(achats.Prix),
+		Description:   achats.Description,
+		TypeCultureID: achats.TypeCultureID.String(),
+		Quantite:      Nombre(achats.Quantite),
+	}
 }
 
 // Créer une nouvelle annonce_achat
@@ -83,27 +102,12 @@ func CreateAnnonceAchat(c *gin.Context) {
 	var input CreateAnnonceAchatInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Erreur de format JSON: " + err.Error()})
-		return
-	}
-
-	typeCultureID, err := uuid.Parse(input.TypeCultureID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID type culture invalide"})
+		erreurs.Repondre(c, erreurs.Validation(err))
 		return
 	}
 
-	// Récupérer l'ID utilisateur depuis le contexte (mis par le middleware)
-	userIDFloat, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
-		return
-	}
-
-	// Convertir l'ID utilisateur en UUID
-	userID, err := uuid.Parse(fmt.Sprintf("%.0f", userIDFloat))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID utilisateur invalide"})
+	userID, ok := currentUserID(c)
+	if !ok {
 		return
 	}
 
@@ -111,33 +115,33 @@ func CreateAnnonceAchat(c *gin.Context) {
 		ID:            uuid.New(),
 		UserID:        userID,
 		Statut:        input.Statut,
-		Prix:          input.Prix,
+		Prix:          float64(input.Prix),
 		Description:   input.Description,
-		TypeCultureID: typeCultureID,
-		Quantite:      input.Quantite,
+		TypeCultureID: uuid.MustParse(input.TypeCultureID),
+		Quantite:      float64(input.Quantite),
 	}
-
-	if err := database.DB.Create(&achats).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur création : " + err.Error()})
+	if !verifierReferencesAnnonce(c, achats.UserID, achats.TypeCultureID, nil) {
 		return
 	}
 
-	if err := database.DB.Preload("User").Preload("TypeCulture").First(&achats, "id = ?", achats.ID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur récupération après création : " + err.Error()})
+	err := depotsDe(c).Transaction(func(tx depots.Depots) error {
+		if err := tx.Achats.Creer(&achats); err != nil {
+			return err
+		}
+		return tx.Evenements.Emettre(models.EvenementAnnonceCreee, models.AnnonceTypeAchat, achats.ID, toAnnonceAchatDTO(achats))
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
+	alerterRecherches(baseDe(c), models.AnnonceTypeAchat, achats.ID, achats.Description, achats.Criteres(), nil)
 
-	result := models.ListeAnnonceAchat{
-		ID:                 achats.ID.String(),
-		Statut:             achats.Statut,
-		Description:        achats.Description,
-		Prix:               achats.Prix,
-		Quantite:           achats.Quantite,
-		UserNom:            achats.User.Nom,
-		TypeCultureLibelle: achats.TypeCulture.Libelle,
-	}
+	result := toAnnonceAchatDTO(achats)
+	publierAnnonce(models.AnnonceTypeAchat, achats.ID, achats.TypeCultureID, "", nil, achats.Statut, result)
+	compterAnnonce(models.AnnonceTypeAchat, achats.TypeCulture.Libelle, nil, achats.Statut, 0)
 
-	c.JSON(http.StatusCreated, result)
+	c.Header("ETag", etagVersion(achats.Version))
+	c.JSON(http.StatusCreated, renduAnnonces(c, result))
 }
 
 // Récupération d'une annonce par son Identifiant
@@ -145,110 +149,132 @@ func GetAnnonceAchatByID(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		erreurs.Repondre(c, erreurs.IDInvalide)
 		return
 	}
 
-	var achats models.AnnonceAchat
-	if err := database.DB.First(&achats, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Annonce_achat non trouvée"})
+	achats, err := depotsDe(c).Achats.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
 		return
 	}
 
-	result := models.ListeAnnonceAchat{
-		ID:                 achats.ID.String(),
-		Statut:             achats.Statut,
-		Description:        achats.Description,
-		Prix:               achats.Prix,
-		Quantite:           achats.Quantite,
-		UserNom:            achats.User.Nom,
-		TypeCultureLibelle: achats.TypeCulture.Libelle,
-	}
+	result := []models.ListeAnnonceAchat{toAnnonceAchatDTO(achats)}
+	enrichirNotesAchat(c, result)
+	c.Header("ETag", etagVersion(achats.Version))
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, renduAnnonces(c, result[0]))
 }
 
-type UpdateAnnonceAchatInput struct {
-	Statut        string  `json:"statut" binding:"required"`
-	Prix          float64 `json:"prix_kg" binding:"required"`
-	Description   string  `json:"description" binding:"required"`
-	TypeCultureID string  `json:"type_culture_id" binding:"required"`
-	Quantite      float64 `json:"quantite" binding:"required"`
+// chargerAchatModifiable charge l'annonce de l'URL et vérifie l'en-tête If-Match
+func chargerAchatModifiable(c *gin.Context) (models.AnnonceAchat, int64, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return models.AnnonceAchat{}, 0, false
+	}
+	achats, err := depotsDe(c).Achats.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return achats, 0, false
+	}
+	attendue, ok := versionAttendue(c, achats.Version)
+	if !ok {
+		return achats, 0, false
+	}
+	if attendue != achats.Version {
+		achatPerime(c, id)
+		return achats, 0, false
+	}
+	return achats, attendue, true
 }
 
-// Modifier une annonce existante
+// Remplacer une annonce (PUT) : tous les champs modifiables doivent être fournis
 func UpdateAnnonceAchat(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := uuid.Parse(idParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+	achats, attendue, ok := chargerAchatModifiable(c)
+	if !ok {
 		return
 	}
+	avant := achats
 
-	// Récupération de l'annonce existante
-	var achats models.AnnonceAchat
-	if err := database.DB.First(&achats, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Annonce_achat non trouvée"})
+	var input CreateAnnonceAchatInput
+	if err := lireRemplacement(c, &input); err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
 
-	var input struct {
-		Statut        string  `json:"statut"`
-		Prix          float64 `json:"prix_kg"`
-		Description   string  `json:"description"`
-		TypeCultureID string  `json:"type_culture_id"`
-		Quantite      float64 `json:"quantite"`
-	}
+	achats.Statut = input.Statut
+	achats.Prix = float64(input.Prix)
+	achats.Description = input.Description
+	achats.TypeCultureID = uuid.MustParse(input.TypeCultureID)
+	achats.Quantite = float64(input.Quantite)
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Erreur de format JSON: " + err.Error()})
+	enregistrerModificationAchat(c, achats, avant, attendue)
+}
+
+// Modifier partiellement une annonce (PATCH, JSON Merge Patch - RFC 7396) :
+// seuls les champs présents sont modifiés, null vide la description
+func PatchAnnonceAchat(c *gin.Context) {
+	achats, attendue, ok := chargerAchatModifiable(c)
+	if !ok {
 		return
 	}
+	avant := achats
 
-	// Mise à jour des champs si fournis
-	if input.Statut != "" {
-		achats.Statut = input.Statut
-	}
-	if input.Prix != 0 {
-		achats.Prix = input.Prix
+	patch, ok := lireMergePatch(c)
+	if !ok {
+		return
 	}
-	if input.Description != "" {
-		achats.Description = input.Description
+	err := appliquerMergePatch(patch, map[string]setterPatch{
+		"statut":          patchTexte(&achats.Statut, false),
+		"description":     patchTexte(&achats.Description, true),
+		"prix_kg":         patchNombre(&achats.Prix),
+		"quantite":        patchNombre(&achats.Quantite),
+		"type_culture_id": patchUUID(&achats.TypeCultureID),
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
 	}
-	if input.TypeCultureID != "" {
-		typeCultureID, err := uuid.Parse(input.TypeCultureID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "ID type culture invalide"})
-			return
-		}
-		achats.TypeCultureID = typeCultureID
+
+	enregistrerModificationAchat(c, achats, avant, attendue)
+}
+
+// enregistrerModificationAchat valide et enregistre une annonce modifiée par PUT ou PATCH,
+// puis émet les événements (webhooks, alertes, flux temps réel)
+func enregistrerModificationAchat(c *gin.Context, achats models.AnnonceAchat, avant models.AnnonceAchat, attendue int64) {
+	entree := entreeAchat(achats)
+	if err := validerEntree(&entree); err != nil {
+		erreurs.Repondre(c, err)
+		return
 	}
-	if input.Quantite != 0 {
-		achats.Quantite = input.Quantite
+	if !verifierReferencesAnnonce(c, achats.UserID, achats.TypeCultureID, nil) {
+		return
 	}
 
-	if err := database.DB.Save(&achats).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour : " + err.Error()})
+	err := depotsDe(c).Transaction(func(tx depots.Depots) error {
+		if err := tx.Achats.Enregistrer(&achats, attendue); err != nil {
+			return err
+		}
+		return tx.Evenements.EmettreModification(models.AnnonceTypeAchat, achats.ID, avant.Statut, achats.Statut, toAnnonceAchatDTO(achats))
+	})
+	if errors.Is(err, erreurs.VersionPerimee) {
+		achatPerime(c, achats.ID)
 		return
 	}
-
-	// Recharger avec les relations
-	if err := database.DB.Preload("User").Preload("TypeCulture").First(&achats, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du chargement des relations : " + err.Error()})
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
+	criteresAvant := avant.Criteres()
+	alerterRecherches(baseDe(c), models.AnnonceTypeAchat, achats.ID, achats.Description, achats.Criteres(), &criteresAvant)
 
-	result := models.ListeAnnonceAchat{
-		ID:                 achats.ID.String(),
-		Statut:             achats.Statut,
-		Description:        achats.Description,
-		Prix:               achats.Prix,
-		Quantite:           achats.Quantite,
-		UserNom:            achats.User.Nom,
-		TypeCultureLibelle: achats.TypeCulture.Libelle,
-	}
+	result := toAnnonceAchatDTO(achats)
+	publierAnnonce(models.AnnonceTypeAchat, achats.ID, achats.TypeCultureID, "", &avant.Statut, achats.Statut, result)
+	compterAnnonce(models.AnnonceTypeAchat, achats.TypeCulture.Libelle, &avant.Statut, achats.Statut, 0)
 
-	c.JSON(http.StatusOK, result)
+	c.Header("ETag", etagVersion(achats.Version))
+	c.JSON(http.StatusOK, renduAnnonces(c, result))
 }
 
 // Suppression d'une annonce par son ID
@@ -256,14 +282,39 @@ func DeleteAnnonceAchat(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "L'identifant est invalide"})
+		erreurs.Repondre(c, erreurs.IDInvalide)
 		return
 	}
 
-	if err := database.DB.Delete(&models.AnnonceAchat{}, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression : " + err.Error()})
+	achats, err := depotsDe(c).Achats.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return
+	}
+	attendue, ok := versionAttendue(c, achats.Version)
+	if !ok {
+		return
+	}
+
+	err = depotsDe(c).Achats.Supprimer(id, attendue)
+	if errors.Is(err, erreurs.VersionPerimee) {
+		achatPerime(c, id)
+		return
+	}
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Annonce supprimée avec succès"})
 }
+
+// achatPerime répond 412 avec l'état courant d'une annonce d'achat
+func achatPerime(c *gin.Context, id uuid.UUID) {
+	achats, err := depotsDe(c).Achats.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return
+	}
+	preconditionEchouee(c, achats.Version, toAnnonceAchatDTO(achats))
+}
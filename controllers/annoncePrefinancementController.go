@@ -1,62 +1,83 @@
 package controllers
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 
-	"github.com/Steph-business/annonce_de_vente/database"
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/erreurs"
 	"github.com/Steph-business/annonce_de_vente/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// Fonction utilitaire : transforme une AnnoncePrefinancement en LiteAnnoncePrefinancement
+func toAnnoncePrefDTO(a models.AnnoncePrefinancement) models.LiteAnnoncePrefinancement {
+	return models.LiteAnnoncePrefinancement{
+		ID:                 a.ID.String(),
+		UserID:             a.UserID.String(),
+		Version:            a.Version,
+		Statut:             a.Statut,
+		Description:        a.Description,
+		MontantPref:        a.MontantPrefinancement,
+		PrixKgPref:         a.Prix,
+		Quantite:           a.Quantite,
+		UserNom:            a.User.Nom,
+		TypeCultureLibelle: a.TypeCulture.Libelle,
+		ParcelleAdresse:    a.Parcelle.Adresse,
+		ParcelleSuf:        a.Parcelle.Surface,
+		TypeCultureID:      a.TypeCultureID.String(),
+		ParcelleID:         a.ParcelleID.String(),
+		CreatedAt:          a.CreatedAt,
+	}
+}
+
 // 🔹 Lister toutes les annonces de préfinancement avec relations
 func GetAllAnnoncePref(c *gin.Context) {
-	var annonces []models.AnnoncePrefinancement
-	query := database.DB.Preload("User").Preload("TypeCulture").Preload("Parcelle")
-
-	if userID := c.Query("user_id"); userID != "" {
-		query = query.Where("user_id = ?", userID)
-	}
-	if statut := c.Query("statut"); statut != "" {
-		query = query.Where("statut = ?", statut)
-	}
-	if typeCultureID := c.Query("type_culture_id"); typeCultureID != "" {
-		query = query.Where("type_culture_id = ?", typeCultureID)
+	filtre := models.FiltreAnnonce{
+		UserID:        c.Query("user_id"),
+		Statut:        c.Query("statut"),
+		TypeCultureID: c.Query("type_culture_id"),
 	}
 
-	if err := query.Find(&annonces).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	annonces, err := depotsDe(c).Prefs.Lister(filtre)
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
 
 	var result []models.LiteAnnoncePrefinancement
 	for _, a := range annonces {
-		result = append(result, models.LiteAnnoncePrefinancement{
-			ID:                 a.ID.String(),
-			Statut:             a.Statut,
-			Description:        a.Description,
-			MontantPref:        a.MontantPrefinancement,
-			PrixKgPref:         a.Prix,
-			Quantite:           a.Quantite,
-			UserNom:            a.User.Nom,
-			TypeCultureLibelle: a.TypeCulture.Libelle,
-			ParcelleAdresse:    a.Parcelle.Adresse,
-			ParcelleSuf:        a.Parcelle.Surface,
-		})
-	}
-
-	c.JSON(http.StatusOK, result)
-}
+		result = append(result, toAnnoncePrefDTO(a))
+	}
+	enrichirFavorisPref(c, result)
+	enrichirNotesPref(c, result)
 
+	c.JSON(http.StatusOK, renduAnnonces(c, result))
+}
 
+// CreateAnnoncePrefInput : corps de création et de remplacement (PUT) d'une annonce de
+// préfinancement. Le prix porte le même nom que dans les réponses (prix_kg_pref) ; les règles
+// sont décrites dans validation.go.
 type CreateAnnoncePrefInput struct {
-	Statut        string  `json:"statut" binding:"required"`
-	Description   string  `json:"description" binding:"required"`
-	TypeCultureID string  `json:"type_culture_id" binding:"required"`
-	ParcelleID    string  `json:"parcelle_id" binding:"required"`
-	Quantite      float64 `json:"quantite" binding:"required"`
-	Prix         float64 `json:"prix" binding:"required"`
+	Statut        string `json:"statut" binding:"required,oneof=active en_pause financee expiree"`
+	Description   string `json:"description" binding:"required,max=2000"`
+	TypeCultureID string `json:"type_culture_id" binding:"required,uuid"`
+	ParcelleID    string `json:"parcelle_id" binding:"required,uuid"`
+	Quantite      Nombre `json:"quantite" binding:"required,nombre,gt=0,lte=100000000"`
+	PrixKgPref    Nombre `json:"prix_kg_pref" binding:"required,nombre,gt=0,lte=10000000"`
+}
+
+// entreePref reconstruit l'entrée d'une annonce modifiée, pour lui appliquer les mêmes règles
+func entreePref(annonce models.AnnoncePrefinancement) CreateAnnoncePrefInput {
+	return CreateAnnoncePrefInput{
+		Statut:        annonce.Statut,
+		Description:   annonce.Description,
+		TypeCultureID: annonce.TypeCultureID.String(),
+		ParcelleID:    annonce.ParcelleID.String(),
+		Quantite:      Nombre(annonce.Quantite),
+		PrixKgPref:    Nombre(annonce.Prix),
+	}
 }
 
 // Créer une nouvelle annonce de préfinancement
@@ -64,87 +85,54 @@ func CreateAnnoncePref(c *gin.Context) {
 	var input CreateAnnoncePrefInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Données JSON invalides ou manquantes"})
+		erreurs.Repondre(c, erreurs.Validation(err))
 		return
 	}
 
-	// Récupérer l'ID utilisateur depuis le contexte (mis par le middleware)
-	userIDFloat, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+	userID, ok := currentUserID(c)
+	if !ok {
 		return
 	}
+	typeCultureID := uuid.MustParse(input.TypeCultureID)
+	parcelleID := uuid.MustParse(input.ParcelleID)
 
-	// Convertir l'ID utilisateur en UUID
-	userID, err := uuid.Parse(fmt.Sprintf("%.0f", userIDFloat))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID utilisateur invalide"})
-		return
-	}
-	typeCultureID, err := uuid.Parse(input.TypeCultureID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID type culture invalide"})
+	if !verifierReferencesAnnonce(c, userID, typeCultureID, &parcelleID) {
 		return
 	}
-	parcelleID, err := uuid.Parse(input.ParcelleID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID parcelle invalide"})
-		return
-	}
-
-	var tc models.TypeCulture
-	if err := database.DB.First(&tc, "id = ?", typeCultureID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Type de culture introuvable"})
-		return
-	}
-	var parcelle models.Parcelle
-	if err := database.DB.First(&parcelle, "id = ?", parcelleID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Parcelle introuvable"})
-		return
-	}
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Utilisateur introuvable"})
-		return
-	}
-
-	if input.Prix <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Prix par kg doit être supérieur à zéro"})
+	if _, err := depotsDe(c).Utilisateurs.Trouver(userID); err != nil {
+		erreurs.Repondre(c, erreurs.UtilisateurInconnu)
 		return
 	}
 
 	annonce := models.AnnoncePrefinancement{
-		ID:                   uuid.New(),
-		Statut:               input.Statut,
-		Description:          input.Description,
-		UserID:               userID,
-		TypeCultureID:        typeCultureID,
-		ParcelleID:           parcelleID,
-		Quantite:             input.Quantite,
-		Prix:                 input.Prix,
-		MontantPrefinancement: input.Prix * input.Quantite,
-	}
-
-	if err := database.DB.Create(&annonce).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur création : " + err.Error()})
+		ID:                    uuid.New(),
+		Statut:                input.Statut,
+		Description:           input.Description,
+		UserID:                userID,
+		TypeCultureID:         typeCultureID,
+		ParcelleID:            parcelleID,
+		Quantite:              float64(input.Quantite),
+		Prix:                  float64(input.PrixKgPref),
+		MontantPrefinancement: float64(input.PrixKgPref * input.Quantite),
+	}
+
+	err := depotsDe(c).Transaction(func(tx depots.Depots) error {
+		if err := tx.Prefs.Creer(&annonce); err != nil {
+			return err
+		}
+		return tx.Evenements.Emettre(models.EvenementAnnonceCreee, models.AnnonceTypePref, annonce.ID, toAnnoncePrefDTO(annonce))
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
-	database.DB.Preload("User").Preload("Parcelle").Preload("TypeCulture").First(&annonce)
 
-	result := models.LiteAnnoncePrefinancement{
-		ID:                 annonce.ID.String(),
-		Statut:             annonce.Statut,
-		Description:        annonce.Description,
-		MontantPref:        annonce.MontantPrefinancement,
-		PrixKgPref:         annonce.Prix,
-		Quantite:           annonce.Quantite,
-		UserNom:            user.Nom,
-		TypeCultureLibelle: tc.Libelle,
-		ParcelleAdresse:    parcelle.Adresse,
-		ParcelleSuf:        parcelle.Surface,
-	}
+	result := toAnnoncePrefDTO(annonce)
+	publierAnnonce(models.AnnonceTypePref, annonce.ID, annonce.TypeCultureID, annonce.Parcelle.Adresse, nil, annonce.Statut, result)
+	compterAnnonce(models.AnnonceTypePref, annonce.TypeCulture.Libelle, nil, annonce.Statut, annonce.MontantPrefinancement)
 
-	c.JSON(http.StatusCreated, result)
+	c.Header("ETag", etagVersion(annonce.Version))
+	c.JSON(http.StatusCreated, renduAnnonces(c, result))
 }
 
 // 🔹 Récupérer les annonces d’un utilisateur
@@ -152,13 +140,13 @@ func GetPrefinancementsByUserID(c *gin.Context) {
 	userIDParam := c.Param("user_id")
 	userID, err := uuid.Parse(userIDParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID utilisateur invalide"})
+		erreurs.Repondre(c, erreurs.IDInvalide.Champ("user_id", erreurs.DetailFormat, "uuid"))
 		return
 	}
 
-	var annonces []models.AnnoncePrefinancement
-	if err := database.DB.Where("user_id = ?", userID).Find(&annonces).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération : " + err.Error()})
+	annonces, err := depotsDe(c).Prefs.Lister(models.FiltreAnnonce{UserID: userID.String()})
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
 
@@ -170,103 +158,134 @@ func GetAnnoncePrefByID(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		erreurs.Repondre(c, erreurs.IDInvalide)
 		return
 	}
 
-	var annonce models.AnnoncePrefinancement
-	if err := database.DB.Preload("User").Preload("Parcelle").Preload("TypeCulture").First(&annonce, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Annonce non trouvée"})
+	annonce, err := depotsDe(c).Prefs.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
 		return
 	}
 
-	result := models.LiteAnnoncePrefinancement{
-		ID:                 annonce.ID.String(),
-		Statut:             annonce.Statut,
-		Description:        annonce.Description,
-		MontantPref:        annonce.MontantPrefinancement,
-		PrixKgPref:         annonce.Prix,
-		Quantite:           annonce.Quantite,
-		UserNom:            annonce.User.Nom,
-		TypeCultureLibelle: annonce.TypeCulture.Libelle,
-		ParcelleAdresse:    annonce.Parcelle.Adresse,
-		ParcelleSuf:        annonce.Parcelle.Surface,
-	}
+	result := []models.LiteAnnoncePrefinancement{toAnnoncePrefDTO(annonce)}
+	enrichirFavorisPref(c, result)
+	enrichirNotesPref(c, result)
+	c.Header("ETag", etagVersion(annonce.Version))
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, renduAnnonces(c, result[0]))
 }
 
-// 🔹 Modifier une annonce existante
-func UpdateAnnoncePref(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := uuid.Parse(idParam)
+// chargerPrefModifiable charge l'annonce de l'URL et vérifie l'en-tête If-Match
+func chargerPrefModifiable(c *gin.Context) (models.AnnoncePrefinancement, int64, bool) {
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
-		return
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return models.AnnoncePrefinancement{}, 0, false
+	}
+	annonce, err := depotsDe(c).Prefs.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return annonce, 0, false
 	}
+	attendue, ok := versionAttendue(c, annonce.Version)
+	if !ok {
+		return annonce, 0, false
+	}
+	if attendue != annonce.Version {
+		prefPerimee(c, id)
+		return annonce, 0, false
+	}
+	return annonce, attendue, true
+}
 
-	var annonce models.AnnoncePrefinancement
-	if err := database.DB.First(&annonce, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Annonce non trouvée"})
+// 🔹 Remplacer une annonce (PUT) : tous les champs modifiables doivent être fournis
+func UpdateAnnoncePref(c *gin.Context) {
+	annonce, attendue, ok := chargerPrefModifiable(c)
+	if !ok {
 		return
 	}
+	avant := annonce
 
-	var input models.AnnoncePrefinancement
-	body, err := c.GetRawData()
-	if err != nil || len(body) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Requête invalide : corps vide"})
+	var input CreateAnnoncePrefInput
+	if err := lireRemplacement(c, &input); err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Requête invalide : " + err.Error()})
+
+	annonce.Statut = input.Statut
+	annonce.Description = input.Description
+	annonce.Quantite = float64(input.Quantite)
+	annonce.Prix = float64(input.PrixKgPref)
+	annonce.TypeCultureID = uuid.MustParse(input.TypeCultureID)
+	annonce.ParcelleID = uuid.MustParse(input.ParcelleID)
+
+	enregistrerModificationPref(c, annonce, avant, attendue)
+}
+
+// 🔹 Modifier partiellement une annonce (PATCH, JSON Merge Patch - RFC 7396) :
+// seuls les champs présents sont modifiés, null vide la description
+func PatchAnnoncePref(c *gin.Context) {
+	annonce, attendue, ok := chargerPrefModifiable(c)
+	if !ok {
 		return
 	}
+	avant := annonce
 
-	var tc models.TypeCulture
-	if err := database.DB.First(&tc, "id = ?", input.TypeCultureID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Type de culture introuvable"})
+	patch, ok := lireMergePatch(c)
+	if !ok {
 		return
 	}
-	if input.ParcelleID == uuid.Nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ParcelleID manquant"})
+	err := appliquerMergePatch(patch, map[string]setterPatch{
+		"statut":          patchTexte(&annonce.Statut, false),
+		"description":     patchTexte(&annonce.Description, true),
+		"quantite":        patchNombre(&annonce.Quantite),
+		"prix_kg_pref":    patchNombre(&annonce.Prix),
+		"type_culture_id": patchUUID(&annonce.TypeCultureID),
+		"parcelle_id":     patchUUID(&annonce.ParcelleID),
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
-	var parcelle models.Parcelle
-	if err := database.DB.First(&parcelle, "id = ?", input.ParcelleID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Parcelle introuvable"})
+
+	enregistrerModificationPref(c, annonce, avant, attendue)
+}
+
+// enregistrerModificationPref valide et enregistre une annonce modifiée par PUT ou PATCH,
+// recalcule le montant du préfinancement puis émet les événements
+func enregistrerModificationPref(c *gin.Context, annonce models.AnnoncePrefinancement, avant models.AnnoncePrefinancement, attendue int64) {
+	entree := entreePref(annonce)
+	if err := validerEntree(&entree); err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
-
-	annonce.Statut = input.Statut
-	annonce.Description = input.Description
-	annonce.Quantite = input.Quantite
-	annonce.Prix = input.Prix
-	annonce.TypeCultureID = input.TypeCultureID
-	annonce.ParcelleID = input.ParcelleID
-	annonce.UserID = input.UserID
-	annonce.MontantPrefinancement = input.Prix * input.Quantite
-
-	if err := database.DB.Save(&annonce).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur mise à jour : " + err.Error()})
+	if !verifierReferencesAnnonce(c, annonce.UserID, annonce.TypeCultureID, &annonce.ParcelleID) {
 		return
 	}
+	annonce.MontantPrefinancement = annonce.Prix * annonce.Quantite
 
-	database.DB.Preload("User").Preload("Parcelle").Preload("TypeCulture").First(&annonce)
-
-	result := models.LiteAnnoncePrefinancement{
-		ID:                 annonce.ID.String(),
-		Statut:             annonce.Statut,
-		Description:        annonce.Description,
-		MontantPref:        annonce.MontantPrefinancement,
-		PrixKgPref:         annonce.Prix,
-		Quantite:           annonce.Quantite,
-		UserNom:            annonce.User.Nom,
-		TypeCultureLibelle: annonce.TypeCulture.Libelle,
-		ParcelleAdresse:    annonce.Parcelle.Adresse,
-		ParcelleSuf:        annonce.Parcelle.Surface,
+	err := depotsDe(c).Transaction(func(tx depots.Depots) error {
+		if err := tx.Prefs.Enregistrer(&annonce, attendue); err != nil {
+			return err
+		}
+		return tx.Evenements.EmettreModification(models.AnnonceTypePref, annonce.ID, avant.Statut, annonce.Statut, toAnnoncePrefDTO(annonce))
+	})
+	if errors.Is(err, erreurs.VersionPerimee) {
+		prefPerimee(c, annonce.ID)
+		return
 	}
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	result := toAnnoncePrefDTO(annonce)
+	publierAnnonce(models.AnnonceTypePref, annonce.ID, annonce.TypeCultureID, annonce.Parcelle.Adresse, &avant.Statut, annonce.Statut, result)
+	compterAnnonce(models.AnnonceTypePref, annonce.TypeCulture.Libelle, &avant.Statut, annonce.Statut, annonce.MontantPrefinancement)
 
-	c.JSON(http.StatusOK, result)
+	c.Header("ETag", etagVersion(annonce.Version))
+	c.JSON(http.StatusOK, renduAnnonces(c, result))
 }
 
 // 🔹 Supprimer une annonce
@@ -274,14 +293,40 @@ func DeleteAnnoncePref(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		erreurs.Repondre(c, erreurs.IDInvalide)
 		return
 	}
 
-	if err := database.DB.Delete(&models.AnnoncePrefinancement{}, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur suppression : " + err.Error()})
+	annonce, err := depotsDe(c).Prefs.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return
+	}
+	attendue, ok := versionAttendue(c, annonce.Version)
+	if !ok {
 		return
 	}
 
+	err = depotsDe(c).Prefs.Supprimer(id, attendue)
+	if errors.Is(err, erreurs.VersionPerimee) {
+		prefPerimee(c, id)
+		return
+	}
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+	supprimerFavorisAnnonce(baseDe(c), models.AnnonceTypePref, id)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Annonce supprimée avec succès"})
 }
+
+// prefPerimee répond 412 avec l'état courant d'une annonce de préfinancement
+func prefPerimee(c *gin.Context, id uuid.UUID) {
+	annonce, err := depotsDe(c).Prefs.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return
+	}
+	preconditionEchouee(c, annonce.Version, toAnnoncePrefDTO(annonce))
+}
@@ -1,15 +1,14 @@
 package controllers
 
 import (
-	"fmt"
-	"log"
+	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
-	"github.com/Steph-business/annonce_de_vente/database"
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/erreurs"
 	"github.com/Steph-business/annonce_de_vente/models"
 )
 
@@ -17,6 +16,8 @@ import (
 func toAnnonceDTO(a models.AnnonceVente) models.ListeAnnonceVente {
 	return models.ListeAnnonceVente{
 		ID:                 a.ID.String(),
+		UserID:             a.UserID.String(),
+		Version:            a.Version,
 		Photo:              a.Photo,
 		Statut:             a.Statut,
 		Description:        a.Description,
@@ -25,52 +26,28 @@ func toAnnonceDTO(a models.AnnonceVente) models.ListeAnnonceVente {
 		UserNom:            a.User.Nom,
 		TypeCultureLibelle: a.TypeCulture.Libelle,
 		ParcelleAdresse:    a.Parcelle.Adresse,
+		TypeCultureID:      a.TypeCultureID.String(),
+		ParcelleID:         a.ParcelleID.String(),
+		ParcelleSurface:    a.Parcelle.Surface,
+		CreatedAt:          a.CreatedAt,
 	}
 }
 
-// validateParcelleID vérifie que le parcelleID est valide et existe en base
-// Returns true if parcelleID is valid or empty (for optional updates)
-func validateParcelleID(c *gin.Context, parcelleIDStr string) (uuid.UUID, bool) {
-	if parcelleIDStr == "" {
-		// Allow empty ParcelleID - will use existing value
-		return uuid.Nil, true
-	}
-
-	log.Printf("Validation ParcelleID : valeur reçue '%s'\n", parcelleIDStr)
-	parcelleID, err := uuid.Parse(parcelleIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ParcelleID invalide - format UUID attendu"})
-		log.Printf("Validation ParcelleID échouée : parsing UUID invalide pour %s\n", parcelleIDStr)
-		return uuid.Nil, false
-	}
-	var parcelle models.Parcelle
-	if err := database.DB.First(&parcelle, "id = ?", parcelleID).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Parcelle introuvable avec l'ID fourni"})
-		log.Printf("Validation ParcelleID échouée : parcelle introuvable pour ID %s\n", parcelleID.String())
-		return uuid.Nil, false
-	}
-	log.Printf("Validation ParcelleID réussie pour ID %s\n", parcelleID.String())
-	return parcelleID, true
-}
-
 // Liste toutes les annonces avec filtres facultatifs
 func GetAllAnnonceVente(c *gin.Context) {
-	var annonces []models.AnnonceVente
-
-	query := database.DB.Preload("User").Preload("TypeCulture").Preload("Parcelle")
-
-	if userID := c.Query("user_id"); userID != "" {
-		query = query.Where("user_id = ?", userID)
-	}
-	if statut := c.Query("statut"); statut != "" {
-		query = query.Where("statut = ?", statut)
+	var filtre models.FiltreAnnonce
+	if err := c.ShouldBindQuery(&filtre); err != nil {
+		erreurs.Repondre(c, erreurs.FiltreInvalide)
+		return
 	}
-	if typeCultureID := c.Query("type_culture_id"); typeCultureID != "" {
-		query = query.Where("type_culture_id = ?", typeCultureID)
+	if err := validerFiltre(filtre, models.AnnonceTypeVente); err != nil {
+		erreurs.Repondre(c, err)
+		return
 	}
 
-	if err := query.Find(&annonces).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	annonces, err := depotsDe(c).Ventes.Lister(filtre)
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
 
@@ -78,18 +55,35 @@ func GetAllAnnonceVente(c *gin.Context) {
 	for _, a := range annonces {
 		result = append(result, toAnnonceDTO(a))
 	}
+	enrichirFavorisVente(c, result)
+	enrichirNotesVente(c, result)
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, renduAnnonces(c, result))
 }
 
+// CreateAnnonceVenteInput : corps de création et de remplacement (PUT) d'une annonce de vente.
+// Les règles sont décrites dans validation.go.
 type CreateAnnonceVenteInput struct {
-	Statut        string `json:"statut" binding:"required"`
-	Description   string `json:"description" binding:"required"`
-	TypeCultureID string `json:"type_culture_id" binding:"required"`
-	ParcelleID    string `json:"parcelle_id" binding:"required"`
-	Quantite      string `json:"quantite" binding:"required"`
-	PrixKg        string `json:"prix_kg" binding:"required"`
-	Photo         string `json:"photo"` // Optional, handle separately if needed
+	Statut        string `json:"statut" binding:"required,oneof=active en_pause vendue expiree"`
+	Description   string `json:"description" binding:"required,max=2000"`
+	TypeCultureID string `json:"type_culture_id" binding:"required,uuid"`
+	ParcelleID    string `json:"parcelle_id" binding:"required,uuid"`
+	Quantite      Nombre `json:"quantite" binding:"required,nombre,gt=0,lte=100000000"`
+	PrixKg        Nombre `json:"prix_kg" binding:"required,nombre,gt=0,lte=10000000"`
+	Photo         string `json:"photo" binding:"max=500"`
+}
+
+// entreeVente reconstruit l'entrée d'une annonce modifiée, pour lui appliquer les mêmes règles
+func entreeVente(annonce models.AnnonceVente) CreateAnnonceVenteInput {
+	return CreateAnnonceVenteInput{
+		Statut:        annonce.Statut,
+		Description:   annonce.Description,
+		TypeCultureID: annonce.TypeCultureID.String(),
+		ParcelleID:    annonce.ParcelleID.String(),
+		Quantite:      Nombre(annonce.Quantite),
+		PrixKg:        Nombre(annonce.PrixKg),
+		Photo:         annonce.Photo,
+	}
 }
 
 // Créer une nouvelle annonce
@@ -97,73 +91,48 @@ func CreateAnnonceVente(c *gin.Context) {
 	var input CreateAnnonceVenteInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Données JSON invalides ou manquantes"})
-		return
-	}
-
-	// Récupérer l'ID utilisateur depuis le contexte (mis par le middleware)
-	userIDFloat, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
-		return
-	}
-
-	// Convertir l'ID utilisateur en UUID
-	userID, err := uuid.Parse(fmt.Sprintf("%.0f", userIDFloat))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID utilisateur invalide"})
-		return
-	}
-	typeCultureID, err := uuid.Parse(input.TypeCultureID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID type culture invalide"})
-		return
-	}
-	parcelleID, err := uuid.Parse(input.ParcelleID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID parcelle invalide"})
+		erreurs.Repondre(c, erreurs.Validation(err))
 		return
 	}
 
-	// Validate parcelle existence
-	_, ok := validateParcelleID(c, input.ParcelleID)
+	userID, ok := currentUserID(c)
 	if !ok {
 		return
 	}
 
-	quantite, err := strconv.ParseFloat(input.Quantite, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Quantité invalide"})
-		return
-	}
-
-	prixKg, err := strconv.ParseFloat(input.PrixKg, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Prix au kg invalide"})
-		return
-	}
-
 	annonce := models.AnnonceVente{
 		ID:            uuid.New(),
 		Statut:        input.Statut,
 		Description:   input.Description,
 		UserID:        userID,
-		TypeCultureID: typeCultureID,
-		ParcelleID:    parcelleID,
-		Quantite:      quantite,
-		PrixKg:        prixKg,
+		TypeCultureID: uuid.MustParse(input.TypeCultureID),
+		ParcelleID:    uuid.MustParse(input.ParcelleID),
+		Quantite:      float64(input.Quantite),
+		PrixKg:        float64(input.PrixKg),
 		Photo:         input.Photo,
 	}
+	if !verifierReferencesAnnonce(c, annonce.UserID, annonce.TypeCultureID, &annonce.ParcelleID) {
+		return
+	}
 
-	if err := database.DB.Create(&annonce).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur création : " + err.Error()})
+	err := depotsDe(c).Transaction(func(tx depots.Depots) error {
+		if err := tx.Ventes.Creer(&annonce); err != nil {
+			return err
+		}
+		return tx.Evenements.Emettre(models.EvenementAnnonceCreee, models.AnnonceTypeVente, annonce.ID, toAnnonceDTO(annonce))
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
 
-	database.DB.Preload("User").Preload("TypeCulture").Preload("Parcelle").First(&annonce)
+	alerterRecherches(baseDe(c), models.AnnonceTypeVente, annonce.ID, annonce.Description, annonce.Criteres(), nil)
 	result := toAnnonceDTO(annonce)
+	publierAnnonce(models.AnnonceTypeVente, annonce.ID, annonce.TypeCultureID, annonce.Parcelle.Adresse, nil, annonce.Statut, result)
+	compterAnnonce(models.AnnonceTypeVente, annonce.TypeCulture.Libelle, nil, annonce.Statut, 0)
 
-	c.JSON(http.StatusCreated, result)
+	c.Header("ETag", etagVersion(annonce.Version))
+	c.JSON(http.StatusCreated, renduAnnonces(c, result))
 }
 
 // Récupérer une annonce par ID
@@ -171,109 +140,138 @@ func GetAnnonceByID(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		erreurs.Repondre(c, erreurs.IDInvalide)
 		return
 	}
 
-	var annonce models.AnnonceVente
-	if err := database.DB.Preload("User").Preload("TypeCulture").Preload("Parcelle").
-		First(&annonce, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Annonce non trouvée"})
+	annonce, err := depotsDe(c).Ventes.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
 		return
 	}
 
-	result := toAnnonceDTO(annonce)
-	c.JSON(http.StatusOK, result)
+	result := []models.ListeAnnonceVente{toAnnonceDTO(annonce)}
+	enrichirFavorisVente(c, result)
+	enrichirNotesVente(c, result)
+	c.Header("ETag", etagVersion(annonce.Version))
+	c.JSON(http.StatusOK, renduAnnonces(c, result[0]))
 }
 
-// Modifier une annonce existante
-func UpdateAnnonceVente(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := uuid.Parse(idParam)
+// chargerVenteModifiable charge l'annonce de l'URL et vérifie l'en-tête If-Match
+func chargerVenteModifiable(c *gin.Context) (models.AnnonceVente, int64, bool) {
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
-		return
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return models.AnnonceVente{}, 0, false
 	}
-
-	var annonce models.AnnonceVente
-	if err := database.DB.First(&annonce, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Annonce non trouvée"})
-		return
+	annonce, err := depotsDe(c).Ventes.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return annonce, 0, false
 	}
+	attendue, ok := versionAttendue(c, annonce.Version)
+	if !ok {
+		return annonce, 0, false
+	}
+	if attendue != annonce.Version {
+		ventePerimee(c, id)
+		return annonce, 0, false
+	}
+	return annonce, attendue, true
+}
 
-	// Structure pour recevoir les données JSON
-	var input struct {
-		Statut        string `json:"statut"`
-		Description   string `json:"description"`
-		TypeCultureID string `json:"type_culture_id"`
-		ParcelleID    string `json:"parcelle_id"`
-		Quantite      string `json:"quantite"`
-		PrixKg        string `json:"prix_kg"`
-		Photo         string `json:"photo"`
+// Remplacer une annonce (PUT) : tous les champs modifiables doivent être fournis,
+// un champ facultatif absent (photo) est vidé
+func UpdateAnnonceVente(c *gin.Context) {
+	annonce, attendue, ok := chargerVenteModifiable(c)
+	if !ok {
+		return
 	}
+	avant := annonce
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Données JSON invalides ou manquantes"})
+	var input CreateAnnonceVenteInput
+	if err := lireRemplacement(c, &input); err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
 
-	// Log the received payload for debugging
-	log.Printf("Update request payload: %+v", input)
+	annonce.Statut = input.Statut
+	annonce.Description = input.Description
+	annonce.TypeCultureID = uuid.MustParse(input.TypeCultureID)
+	annonce.ParcelleID = uuid.MustParse(input.ParcelleID)
+	annonce.Quantite = float64(input.Quantite)
+	annonce.PrixKg = float64(input.PrixKg)
+	annonce.Photo = input.Photo
 
-	// Mise à jour des champs si fournis
-	if input.Statut != "" {
-		annonce.Statut = input.Statut
+	enregistrerModificationVente(c, annonce, avant, attendue)
+}
+
+// Modifier partiellement une annonce (PATCH, JSON Merge Patch - RFC 7396) :
+// seuls les champs présents sont modifiés, null vide un champ facultatif (photo, description)
+func PatchAnnonceVente(c *gin.Context) {
+	annonce, attendue, ok := chargerVenteModifiable(c)
+	if !ok {
+		return
 	}
-	if input.Description != "" {
-		annonce.Description = input.Description
+	avant := annonce
+
+	patch, ok := lireMergePatch(c)
+	if !ok {
+		return
 	}
-	if input.TypeCultureID != "" {
-		typeCultureID, err := uuid.Parse(input.TypeCultureID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "ID type culture invalide"})
-			return
-		}
-		annonce.TypeCultureID = typeCultureID
+	err := appliquerMergePatch(patch, map[string]setterPatch{
+		"statut":          patchTexte(&annonce.Statut, false),
+		"description":     patchTexte(&annonce.Description, true),
+		"photo":           patchTexte(&annonce.Photo, true),
+		"quantite":        patchNombre(&annonce.Quantite),
+		"prix_kg":         patchNombre(&annonce.PrixKg),
+		"type_culture_id": patchUUID(&annonce.TypeCultureID),
+		"parcelle_id":     patchUUID(&annonce.ParcelleID),
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
 	}
-	if input.ParcelleID != "" {
-		parcelleID, ok := validateParcelleID(c, input.ParcelleID)
-		if !ok {
-			return
-		}
-		// Only update if a valid parcelleID was provided
-		if parcelleID != uuid.Nil {
-			annonce.ParcelleID = parcelleID
-		}
+
+	enregistrerModificationVente(c, annonce, avant, attendue)
+}
+
+// enregistrerModificationVente valide et enregistre une annonce modifiée par PUT ou PATCH,
+// puis émet les événements (webhooks, alertes, flux temps réel)
+func enregistrerModificationVente(c *gin.Context, annonce models.AnnonceVente, avant models.AnnonceVente, attendue int64) {
+	entree := entreeVente(annonce)
+	if err := validerEntree(&entree); err != nil {
+		erreurs.Repondre(c, err)
+		return
 	}
-	if input.Quantite != "" {
-		quantite, err := strconv.ParseFloat(input.Quantite, 64)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Quantité invalide"})
-			return
-		}
-		annonce.Quantite = quantite
+	if !verifierReferencesAnnonce(c, annonce.UserID, annonce.TypeCultureID, &annonce.ParcelleID) {
+		return
 	}
-	if input.PrixKg != "" {
-		prixKg, err := strconv.ParseFloat(input.PrixKg, 64)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Prix au kg invalide"})
-			return
+
+	// Sauvegarde (refusée si l'annonce a été modifiée depuis la lecture du client)
+	err := depotsDe(c).Transaction(func(tx depots.Depots) error {
+		if err := tx.Ventes.Enregistrer(&annonce, attendue); err != nil {
+			return err
 		}
-		annonce.PrixKg = prixKg
-	}
-	if input.Photo != "" {
-		annonce.Photo = input.Photo
+		return tx.Evenements.EmettreModification(models.AnnonceTypeVente, annonce.ID, avant.Statut, annonce.Statut, toAnnonceDTO(annonce))
+	})
+	if errors.Is(err, erreurs.VersionPerimee) {
+		ventePerimee(c, annonce.ID)
+		return
 	}
-
-	// Sauvegarde
-	if err := database.DB.Save(&annonce).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur mise à jour : " + err.Error()})
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
 
-	database.DB.Preload("User").Preload("TypeCulture").Preload("Parcelle").First(&annonce)
+	criteresAvant := avant.Criteres()
+	alerterRecherches(baseDe(c), models.AnnonceTypeVente, annonce.ID, annonce.Description, annonce.Criteres(), &criteresAvant)
+
 	result := toAnnonceDTO(annonce)
-	c.JSON(http.StatusOK, result)
+	publierAnnonce(models.AnnonceTypeVente, annonce.ID, annonce.TypeCultureID, annonce.Parcelle.Adresse, &avant.Statut, annonce.Statut, result)
+	compterAnnonce(models.AnnonceTypeVente, annonce.TypeCulture.Libelle, &avant.Statut, annonce.Statut, 0)
+	c.Header("ETag", etagVersion(annonce.Version))
+	c.JSON(http.StatusOK, renduAnnonces(c, result))
 }
 
 // Supprimer une annonce par ID
@@ -281,14 +279,40 @@ func DeleteAnnonceVente(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+
+	annonce, err := depotsDe(c).Ventes.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return
+	}
+	attendue, ok := versionAttendue(c, annonce.Version)
+	if !ok {
 		return
 	}
 
-	if err := database.DB.Delete(&models.AnnonceVente{}, "id = ?", id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression : " + err.Error()})
+	err = depotsDe(c).Ventes.Supprimer(id, attendue)
+	if errors.Is(err, erreurs.VersionPerimee) {
+		ventePerimee(c, id)
+		return
+	}
+	if err != nil {
+		erreurs.Repondre(c, err)
 		return
 	}
+	supprimerFavorisAnnonce(baseDe(c), models.AnnonceTypeVente, id)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Annonce supprimée avec succès"})
 }
+
+// ventePerimee répond 412 avec l'état courant d'une annonce de vente
+func ventePerimee(c *gin.Context, id uuid.UUID) {
+	annonce, err := depotsDe(c).Ventes.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return
+	}
+	preconditionEchouee(c, annonce.Version, toAnnonceDTO(annonce))
+}
@@ -0,0 +1,659 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/Steph-business/annonce_de_vente/config"
+	"github.com/Steph-business/annonce_de_vente/database"
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/middleware"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// Les tests des annonces passent par les dépôts en mémoire, sans base de données, sauf
+// ceux des fonctionnalités qui la requièrent (nouvelEnvironnementBase). L'utilisateur
+// authentifié est indiqué par l'en-tête enteteUtilisateur, à la place du token JWT.
+const enteteUtilisateur = "X-Utilisateur"
+
+// environnement : routeur de test et données de référence
+type environnement struct {
+	memoire  *depots.Memoire
+	base     *gorm.DB
+	routeur  *gin.Engine
+	vendeur  models.User
+	acheteur models.User
+	cacao    models.TypeCulture
+	anacarde models.TypeCulture
+	daloa    models.Parcelle
+	korhogo  models.Parcelle
+}
+
+// donneesReference crée les utilisateurs, cultures et parcelles de l'environnement
+func donneesReference() *environnement {
+	e := &environnement{
+		vendeur:  models.User{ID: uuid.New(), Nom: "Awa Koné"},
+		acheteur: models.User{ID: uuid.New(), Nom: "Yao Kouassi"},
+		cacao:    models.TypeCulture{ID: uuid.New(), Libelle: "Cacao"},
+		anacarde: models.TypeCulture{ID: uuid.New(), Libelle: "Anacarde"},
+		daloa:    models.Parcelle{ID: uuid.New(), Adresse: "Daloa, Haut-Sassandra", Surface: "4 ha"},
+		korhogo:  models.Parcelle{ID: uuid.New(), Adresse: "Korhogo, Poro", Surface: "2 ha"},
+	}
+	e.daloa.UserID, e.korhogo.UserID = &e.vendeur.ID, &e.acheteur.ID
+	e.daloa.Latitude, e.daloa.Longitude = coordonnee(6.877), coordonnee(-6.450)
+	e.korhogo.Latitude, e.korhogo.Longitude = coordonnee(9.458), coordonnee(-5.630)
+	return e
+}
+
+func nouvelEnvironnement(t *testing.T) *environnement {
+	t.Helper()
+	e := donneesReference()
+	e.memoire = depots.NouvelleMemoire()
+	e.memoire.AjouterUtilisateur(e.vendeur)
+	e.memoire.AjouterUtilisateur(e.acheteur)
+	e.memoire.AjouterTypeCulture(e.cacao)
+	e.memoire.AjouterTypeCulture(e.anacarde)
+	e.memoire.AjouterParcelle(e.daloa)
+	e.memoire.AjouterParcelle(e.korhogo)
+
+	e.monter(Dependances{Depots: e.memoire.Depots()})
+	return e
+}
+
+// nouvelEnvironnementBase monte les mêmes routes sur une base SQLite créée par les
+// migrations, pour les fonctionnalités qui requièrent la base (favoris, notifications,
+// modération, administration)
+func nouvelEnvironnementBase(t *testing.T) *environnement {
+	t.Helper()
+	e := donneesReference()
+	e.base = database.Connecter(config.Base{Pilote: database.PiloteSQLite, FichierSQLite: filepath.Join(t.TempDir(), "test.db")})
+	e.base.Logger = logger.Discard
+	if _, err := database.MigrerHaut(e.base, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := e.base.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	for _, ligne := range []interface{}{&e.vendeur, &e.acheteur, &e.cacao, &e.anacarde, &e.daloa, &e.korhogo} {
+		if err := e.base.Create(ligne).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e.monter(Dependances{Depots: depots.Gorm(e.base), DB: e.base})
+	return e
+}
+
+// authentificationTest authentifie l'utilisateur de l'en-tête enteteUtilisateur
+func authentificationTest(c *gin.Context) {
+	if id := c.GetHeader(enteteUtilisateur); id != "" {
+		c.Set("user_id", id)
+	}
+	c.Next()
+}
+
+// groupe renvoie les routes authentifiées d'une version, où un test ajoute les siennes
+func (e *environnement) groupe(version int) *gin.RouterGroup {
+	return e.routeur.Group(fmt.Sprintf("/v%d", version), middleware.VersionAPIMiddleware(version), authentificationTest)
+}
+
+// monter crée le routeur des annonces sur les dépendances données
+func (e *environnement) monter(dependances Dependances) {
+	gin.SetMode(gin.TestMode)
+	e.routeur = gin.New()
+	e.routeur.Use(Injecter(dependances))
+	suspension := middleware.SuspensionMiddleware(dependances.Depots.Utilisateurs)
+
+	for _, version := range []int{models.VersionAPI1, models.VersionAPI2} {
+		g := e.groupe(version)
+		g.GET("/annonces_vente", GetAllAnnonceVente)
+		g.GET("/annonces_vente/:id", GetAnnonceByID)
+		g.POST("/annonces_vente", suspension, CreateAnnonceVente)
+		g.PUT("/annonces_vente/:id", suspension, UpdateAnnonceVente)
+		g.PATCH("/annonces_vente/:id", suspension, PatchAnnonceVente)
+		g.DELETE("/annonces_vente/:id", suspension, DeleteAnnonceVente)
+
+		g.GET("/annonces_achat", GetAllAnnonceAchat)
+		g.GET("/annonces_achat/:id", GetAnnonceAchatByID)
+		g.POST("/annonces_achat", suspension, CreateAnnonceAchat)
+		g.PUT("/annonces_achat/:id", suspension, UpdateAnnonceAchat)
+		g.PATCH("/annonces_achat/:id", suspension, PatchAnnonceAchat)
+		g.DELETE("/annonces_achat/:id", suspension, DeleteAnnonceAchat)
+
+		g.GET("/annonces_pref", GetAllAnnoncePref)
+		g.GET("/annonces_pref/:id", GetAnnoncePrefByID)
+		g.POST("/annonces_pref", suspension, CreateAnnoncePref)
+		g.PUT("/annonces_pref/:id", suspension, UpdateAnnoncePref)
+		g.PATCH("/annonces_pref/:id", suspension, PatchAnnoncePref)
+		g.DELETE("/annonces_pref/:id", suspension, DeleteAnnoncePref)
+	}
+}
+
+// requete exécute une requête ; utilisateur vaut uuid.Nil pour une requête anonyme
+func (e *environnement) requete(methode string, chemin string, utilisateur uuid.UUID, corps interface{}, entetes ...string) *httptest.ResponseRecorder {
+	var lecteur *bytes.Reader
+	if corps != nil {
+		donnees, _ := json.Marshal(corps)
+		lecteur = bytes.NewReader(donnees)
+	} else {
+		lecteur = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(methode, chemin, lecteur)
+	req.Header.Set("Content-Type", "application/json")
+	if utilisateur != uuid.Nil {
+		req.Header.Set(enteteUtilisateur, utilisateur.String())
+	}
+	for i := 0; i+1 < len(entetes); i += 2 {
+		req.Header.Set(entetes[i], entetes[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	e.routeur.ServeHTTP(w, req)
+	return w
+}
+
+// typeTest décrit un type d'annonce pour les tests communs aux trois types
+type typeTest struct {
+	annonceType string
+	chemin      string
+	champPrix   string
+	// statutFinal déclenche l'événement webhook de fin d'annonce (vendue ou financée)
+	statutFinal    string
+	evenementFinal string
+	avecParcelle   bool
+	corps          func(e *environnement) map[string]interface{}
+}
+
+var typesTest = []typeTest{
+	{
+		annonceType:    models.AnnonceTypeVente,
+		chemin:         "/annonces_vente",
+		champPrix:      "prix_kg",
+		statutFinal:    models.StatutVendue,
+		evenementFinal: models.EvenementAnnonceVendue,
+		avecParcelle:   true,
+		corps: func(e *environnement) map[string]interface{} {
+			return map[string]interface{}{
+				"statut":          models.StatutActive,
+				"description":     "Fèves de cacao séchées",
+				"type_culture_id": e.cacao.ID,
+				"parcelle_id":     e.daloa.ID,
+				"quantite":        1200,
+				"prix_kg":         1500,
+				"photo":           "cacao.jpg",
+			}
+		},
+	},
+	{
+		annonceType:    models.AnnonceTypeAchat,
+		chemin:         "/annonces_achat",
+		champPrix:      "prix_kg",
+		statutFinal:    models.StatutVendue,
+		evenementFinal: models.EvenementAnnonceVendue,
+		corps: func(e *environnement) map[string]interface{} {
+			return map[string]interface{}{
+				"statut":          models.StatutActive,
+				"description":     "Achat de cacao grade 1",
+				"type_culture_id": e.cacao.ID,
+				"quantite":        5000,
+				"prix_kg":         1400,
+			}
+		},
+	},
+	{
+		annonceType:    models.AnnonceTypePref,
+		chemin:         "/annonces_pref",
+		champPrix:      "prix_kg_pref",
+		statutFinal:    models.StatutFinancee,
+		evenementFinal: models.EvenementAnnonceFinancee,
+		avecParcelle:   true,
+		corps: func(e *environnement) map[string]interface{} {
+			return map[string]interface{}{
+				"statut":          models.StatutActive,
+				"description":     "Préfinancement de la récolte de cacao",
+				"type_culture_id": e.cacao.ID,
+				"parcelle_id":     e.daloa.ID,
+				"quantite":        800,
+				"prix_kg_pref":    1000,
+			}
+		},
+	},
+}
+
+// creer crée une annonce et renvoie sa représentation v1
+func (e *environnement) creer(t *testing.T, tt typeTest, auteur uuid.UUID, modifs map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	corps := tt.corps(e)
+	for cle, valeur := range modifs {
+		corps[cle] = valeur
+	}
+	w := e.requete(http.MethodPost, "/v1"+tt.chemin, auteur, corps)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST %s : statut %d, corps %s", tt.chemin, w.Code, w.Body.String())
+	}
+	return decoder(t, w)
+}
+
+func decoder(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var v map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("réponse JSON invalide : %v (%s)", err, w.Body.String())
+	}
+	return v
+}
+
+func decoderListe(t *testing.T, w *httptest.ResponseRecorder) []map[string]interface{} {
+	t.Helper()
+	var v []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("réponse JSON invalide : %v (%s)", err, w.Body.String())
+	}
+	return v
+}
+
+// codeErreur renvoie le code de l'enveloppe d'erreur d'une réponse
+func codeErreur(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var v struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("enveloppe d'erreur invalide : %v (%s)", err, w.Body.String())
+	}
+	return v.Error.Code
+}
+
+func verifierErreur(t *testing.T, w *httptest.ResponseRecorder, statut int, code string) {
+	t.Helper()
+	if w.Code != statut {
+		t.Fatalf("statut %d, attendu %d (%s)", w.Code, statut, w.Body.String())
+	}
+	if c := codeErreur(t, w); c != code {
+		t.Errorf("code d'erreur %q, attendu %q", c, code)
+	}
+}
+
+// evenementsDe renvoie les noms des événements webhook émis pour une annonce
+func (e *environnement) evenementsDe(id string) []string {
+	var noms []string
+	for _, ev := range e.memoire.Evenements() {
+		if ev.AnnonceID.String() == id {
+			noms = append(noms, ev.Evenement)
+		}
+	}
+	return noms
+}
+
+func TestCreerAnnonce(t *testing.T) {
+	for _, tt := range typesTest {
+		t.Run(tt.annonceType, func(t *testing.T) {
+			e := nouvelEnvironnement(t)
+
+			w := e.requete(http.MethodPost, "/v1"+tt.chemin, e.vendeur.ID, tt.corps(e))
+			if w.Code != http.StatusCreated {
+				t.Fatalf("statut %d (%s)", w.Code, w.Body.String())
+			}
+			if etag := w.Header().Get("ETag"); etag != `"1"` {
+				t.Errorf("ETag %s, attendu \"1\"", etag)
+			}
+			annonce := decoder(t, w)
+			if annonce["user_id"] != e.vendeur.ID.String() || annonce["nom"] != e.vendeur.Nom {
+				t.Errorf("auteur %v (%v), attendu %s", annonce["user_id"], annonce["nom"], e.vendeur.ID)
+			}
+			if annonce["libelle"] != e.cacao.Libelle {
+				t.Errorf("type de culture %v, attendu %s", annonce["libelle"], e.cacao.Libelle)
+			}
+			if tt.avecParcelle && annonce["adresse"] != e.daloa.Adresse {
+				t.Errorf("parcelle %v, attendue %s", annonce["adresse"], e.daloa.Adresse)
+			}
+			if tt.annonceType == models.AnnonceTypePref && annonce["montant_pref"] != float64(800*1000) {
+				t.Errorf("montant du préfinancement %v, attendu %d", annonce["montant_pref"], 800*1000)
+			}
+
+			id := annonce["id"].(string)
+			if ev := e.evenementsDe(id); len(ev) != 1 || ev[0] != models.EvenementAnnonceCreee {
+				t.Errorf("événements %v, attendu [%s]", ev, models.EvenementAnnonceCreee)
+			}
+
+			w = e.requete(http.MethodGet, "/v1"+tt.chemin+"/"+id, uuid.Nil, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET : statut %d (%s)", w.Code, w.Body.String())
+			}
+			if etag := w.Header().Get("ETag"); etag != `"1"` {
+				t.Errorf("GET : ETag %s, attendu \"1\"", etag)
+			}
+			if lue := decoder(t, w); lue["description"] != annonce["description"] {
+				t.Errorf("GET : description %v, attendue %v", lue["description"], annonce["description"])
+			}
+		})
+	}
+}
+
+func TestCreerAnnonceRefusee(t *testing.T) {
+	for _, tt := range typesTest {
+		t.Run(tt.annonceType, func(t *testing.T) {
+			e := nouvelEnvironnement(t)
+
+			w := e.requete(http.MethodPost, "/v1"+tt.chemin, uuid.Nil, tt.corps(e))
+			verifierErreur(t, w, http.StatusUnauthorized, "non_authentifie")
+
+			corps := tt.corps(e)
+			delete(corps, "description")
+			corps[tt.champPrix] = -5
+			w = e.requete(http.MethodPost, "/v1"+tt.chemin, e.vendeur.ID, corps)
+			verifierErreur(t, w, http.StatusBadRequest, "donnees_invalides")
+			champs := map[string]bool{}
+			for _, d := range decoder(t, w)["error"].(map[string]interface{})["details"].([]interface{}) {
+				champs[d.(map[string]interface{})["field"].(string)] = true
+			}
+			if !champs["description"] || !champs[tt.champPrix] {
+				t.Errorf("champs en erreur %v, attendus description et %s", champs, tt.champPrix)
+			}
+
+			corps = tt.corps(e)
+			corps["type_culture_id"] = uuid.New()
+			w = e.requete(http.MethodPost, "/v1"+tt.chemin, e.vendeur.ID, corps)
+			verifierErreur(t, w, http.StatusBadRequest, "type_culture_inconnu")
+
+			if tt.avecParcelle {
+				corps = tt.corps(e)
+				corps["parcelle_id"] = uuid.New()
+				w = e.requete(http.MethodPost, "/v1"+tt.chemin, e.vendeur.ID, corps)
+				verifierErreur(t, w, http.StatusBadRequest, "parcelle_inconnue")
+
+				corps = tt.corps(e)
+				corps["parcelle_id"] = e.korhogo.ID
+				w = e.requete(http.MethodPost, "/v1"+tt.chemin, e.vendeur.ID, corps)
+				verifierErreur(t, w, http.StatusForbidden, "parcelle_interdite")
+			}
+
+			if n := len(e.memoire.Evenements()); n != 0 {
+				t.Errorf("%d événements émis pour des créations refusées", n)
+			}
+
+			// Une parcelle sans exploitant renseigné reste utilisable par tous
+			if tt.avecParcelle {
+				sansExploitant := models.Parcelle{ID: uuid.New(), Adresse: "Gagnoa, Gôh", Surface: "3 ha"}
+				e.memoire.AjouterParcelle(sansExploitant)
+				e.creer(t, tt, e.acheteur.ID, map[string]interface{}{"parcelle_id": sansExploitant.ID})
+			}
+		})
+	}
+}
+
+// casFiltre : paramètres de requête d'une liste et nombre d'annonces attendues
+type casFiltre struct {
+	requete string
+	nombre  int
+}
+
+func TestListerAnnonces(t *testing.T) {
+	for _, tt := range typesTest {
+		t.Run(tt.annonceType, func(t *testing.T) {
+			e := nouvelEnvironnement(t)
+			e.creer(t, tt, e.vendeur.ID, nil)
+			e.creer(t, tt, e.vendeur.ID, map[string]interface{}{"statut": models.StatutEnPause, tt.champPrix: 900})
+			e.creer(t, tt, e.acheteur.ID, map[string]interface{}{"type_culture_id": e.anacarde.ID, "parcelle_id": e.korhogo.ID})
+
+			cas := []casFiltre{
+				{"", 3},
+				{"?statut=en_pause", 1},
+				{"?user_id=" + e.vendeur.ID.String(), 2},
+				{"?type_culture_id=" + e.anacarde.ID.String(), 1},
+				{"?statut=vendue", 0},
+			}
+			if tt.annonceType != models.AnnonceTypePref {
+				cas = append(cas, casFiltre{"?prix_min=1000", 2}, casFiltre{"?prix_max=1000&quantite_min=100", 1})
+			}
+			if tt.annonceType == models.AnnonceTypeVente {
+				cas = append(cas,
+					casFiltre{"?region=poro", 1},
+					// Depuis Bouaké : Daloa est à ~181 km, Korhogo à ~207 km
+					casFiltre{"?lat=7.69&lng=-5.03&rayon_km=210", 3},
+					casFiltre{"?lat=7.69&lng=-5.03&rayon_km=190", 2},
+					casFiltre{"?lat=7.69&lng=-5.03&rayon_km=100", 0},
+					casFiltre{"?lat=9.4&lng=-5.6&rayon_km=20", 1},
+				)
+			}
+
+			for _, c := range cas {
+				w := e.requete(http.MethodGet, "/v2"+tt.chemin+c.requete, uuid.Nil, nil)
+				if w.Code != http.StatusOK {
+					t.Fatalf("GET %s : statut %d (%s)", c.requete, w.Code, w.Body.String())
+				}
+				if n := len(decoderListe(t, w)); n != c.nombre {
+					t.Errorf("GET %s : %d annonces, attendu %d", c.requete, n, c.nombre)
+				}
+			}
+		})
+	}
+}
+
+func TestListerAnnoncesFiltreInvalide(t *testing.T) {
+	e := nouvelEnvironnement(t)
+
+	w := e.requete(http.MethodGet, "/v1/annonces_vente?prix_min=20&prix_max=10", uuid.Nil, nil)
+	verifierErreur(t, w, http.StatusBadRequest, "filtre_invalide")
+
+	w = e.requete(http.MethodGet, "/v1/annonces_achat?region=poro", uuid.Nil, nil)
+	verifierErreur(t, w, http.StatusBadRequest, "filtre_invalide")
+
+	for _, requete := range []string{
+		"/v1/annonces_vente?lat=7.69&lng=-5.03",
+		"/v1/annonces_vente?lat=97&lng=-5.03&rayon_km=10",
+		"/v1/annonces_vente?lat=7.69&lng=-5.03&rayon_km=0",
+		"/v1/annonces_vente?lat=7.69&lng=-5.03&rayon_km=5000",
+		"/v1/annonces_achat?lat=7.69&lng=-5.03&rayon_km=10",
+	} {
+		w = e.requete(http.MethodGet, requete, uuid.Nil, nil)
+		verifierErreur(t, w, http.StatusBadRequest, "filtre_invalide")
+	}
+}
+
+func coordonnee(v float64) *float64 { return &v }
+
+func TestRemplacerAnnonce(t *testing.T) {
+	for _, tt := range typesTest {
+		t.Run(tt.annonceType, func(t *testing.T) {
+			e := nouvelEnvironnement(t)
+			id := e.creer(t, tt, e.vendeur.ID, nil)["id"].(string)
+			chemin := "/v1" + tt.chemin + "/" + id
+
+			corps := tt.corps(e)
+			corps["statut"] = tt.statutFinal
+			corps["description"] = "Lot réservé"
+
+			w := e.requete(http.MethodPut, chemin, e.vendeur.ID, corps)
+			verifierErreur(t, w, http.StatusPreconditionRequired, "if_match_requis")
+
+			w = e.requete(http.MethodPut, chemin, e.vendeur.ID, corps, "If-Match", `"1"`)
+			if w.Code != http.StatusOK {
+				t.Fatalf("PUT : statut %d (%s)", w.Code, w.Body.String())
+			}
+			if etag := w.Header().Get("ETag"); etag != `"2"` {
+				t.Errorf("ETag %s, attendu \"2\"", etag)
+			}
+			if annonce := decoder(t, w); annonce["statut"] != tt.statutFinal || annonce["description"] != "Lot réservé" {
+				t.Errorf("annonce %v non modifiée", annonce)
+			}
+
+			attendus := []string{models.EvenementAnnonceCreee, models.EvenementAnnonceModifiee, tt.evenementFinal}
+			if ev := e.evenementsDe(id); strings.Join(ev, ",") != strings.Join(attendus, ",") {
+				t.Errorf("événements %v, attendus %v", ev, attendus)
+			}
+
+			// La version 1 est périmée : 412 avec l'état courant
+			w = e.requete(http.MethodPut, chemin, e.vendeur.ID, corps, "If-Match", `"1"`)
+			verifierErreur(t, w, http.StatusPreconditionFailed, "version_perimee")
+			if courante := decoder(t, w)["annonce"].(map[string]interface{}); courante["version"] != float64(2) {
+				t.Errorf("412 : version courante %v, attendue 2", courante["version"])
+			}
+			if len(e.evenementsDe(id)) != len(attendus) {
+				t.Errorf("événement émis pour une modification refusée")
+			}
+		})
+	}
+}
+
+func TestPatchAnnonce(t *testing.T) {
+	for _, tt := range typesTest {
+		t.Run(tt.annonceType, func(t *testing.T) {
+			e := nouvelEnvironnement(t)
+			id := e.creer(t, tt, e.vendeur.ID, nil)["id"].(string)
+			chemin := "/v2" + tt.chemin + "/" + id
+
+			patch := map[string]interface{}{"quantite": "2 500", "type_culture_id": e.anacarde.ID}
+			w := e.requete(http.MethodPatch, chemin, e.vendeur.ID, patch)
+			verifierErreur(t, w, http.StatusPreconditionRequired, "if_match_requis")
+
+			w = e.requete(http.MethodPatch, chemin, e.vendeur.ID, patch, "If-Match", "*")
+			if w.Code != http.StatusOK {
+				t.Fatalf("PATCH : statut %d (%s)", w.Code, w.Body.String())
+			}
+			annonce := decoder(t, w)
+			if annonce["quantite"] != float64(2500) || annonce["statut"] != models.StatutActive {
+				t.Errorf("annonce %v : quantité ou statut inattendu", annonce)
+			}
+			if tc := annonce["type_culture"].(map[string]interface{}); tc["libelle"] != e.anacarde.Libelle {
+				t.Errorf("type de culture %v, attendu %s", tc["libelle"], e.anacarde.Libelle)
+			}
+			if tt.annonceType == models.AnnonceTypePref && annonce["montant_pref"] != float64(2500*1000) {
+				t.Errorf("montant du préfinancement %v non recalculé", annonce["montant_pref"])
+			}
+
+			// La version 1 est périmée : 412 avec l'état courant, au contrat v2
+			w = e.requete(http.MethodPatch, chemin, e.vendeur.ID, map[string]interface{}{"quantite": 10}, "If-Match", `"1"`)
+			verifierErreur(t, w, http.StatusPreconditionFailed, "version_perimee")
+			if courante := decoder(t, w)["annonce"].(map[string]interface{}); courante["version"] != float64(2) || courante["quantite"] != float64(2500) {
+				t.Errorf("412 : état courant %v, attendu la version 2", courante)
+			}
+
+			w = e.requete(http.MethodPatch, chemin, e.vendeur.ID, map[string]interface{}{"type_culture_id": uuid.New()}, "If-Match", `"2"`)
+			verifierErreur(t, w, http.StatusBadRequest, "type_culture_inconnu")
+
+			w = e.requete(http.MethodPatch, chemin, e.vendeur.ID, map[string]interface{}{"quantite": 0}, "If-Match", `"2"`)
+			verifierErreur(t, w, http.StatusBadRequest, "donnees_invalides")
+
+			if ev := e.evenementsDe(id); len(ev) != 2 || ev[1] != models.EvenementAnnonceModifiee {
+				t.Errorf("événements %v, attendus [%s %s]", ev, models.EvenementAnnonceCreee, models.EvenementAnnonceModifiee)
+			}
+		})
+	}
+}
+
+func TestSupprimerAnnonce(t *testing.T) {
+	for _, tt := range typesTest {
+		t.Run(tt.annonceType, func(t *testing.T) {
+			e := nouvelEnvironnement(t)
+			id := e.creer(t, tt, e.vendeur.ID, nil)["id"].(string)
+			chemin := "/v1" + tt.chemin + "/" + id
+
+			w := e.requete(http.MethodDelete, chemin, e.vendeur.ID, nil)
+			verifierErreur(t, w, http.StatusPreconditionRequired, "if_match_requis")
+
+			w = e.requete(http.MethodDelete, chemin, e.vendeur.ID, nil, "If-Match", `"3"`)
+			verifierErreur(t, w, http.StatusPreconditionFailed, "version_perimee")
+
+			w = e.requete(http.MethodDelete, chemin, e.vendeur.ID, nil, "If-Match", `"1"`)
+			if w.Code != http.StatusOK {
+				t.Fatalf("DELETE : statut %d (%s)", w.Code, w.Body.String())
+			}
+
+			w = e.requete(http.MethodGet, chemin, uuid.Nil, nil)
+			verifierErreur(t, w, http.StatusNotFound, "annonce_introuvable")
+			w = e.requete(http.MethodDelete, chemin, e.vendeur.ID, nil, "If-Match", "*")
+			verifierErreur(t, w, http.StatusNotFound, "annonce_introuvable")
+		})
+	}
+}
+
+func TestAnnonceIntrouvable(t *testing.T) {
+	e := nouvelEnvironnement(t)
+	for _, tt := range typesTest {
+		w := e.requete(http.MethodGet, "/v1"+tt.chemin+"/"+uuid.NewString(), uuid.Nil, nil)
+		verifierErreur(t, w, http.StatusNotFound, "annonce_introuvable")
+
+		w = e.requete(http.MethodGet, "/v1"+tt.chemin+"/pas-un-uuid", uuid.Nil, nil)
+		verifierErreur(t, w, http.StatusBadRequest, "id_invalide")
+
+		w = e.requete(http.MethodPatch, "/v1"+tt.chemin+"/"+uuid.NewString(), e.vendeur.ID, map[string]interface{}{"quantite": 3}, "If-Match", "*")
+		verifierErreur(t, w, http.StatusNotFound, "annonce_introuvable")
+	}
+}
+
+func TestContratV2(t *testing.T) {
+	e := nouvelEnvironnement(t)
+	vente := typesTest[0]
+	id := e.creer(t, vente, e.vendeur.ID, nil)["id"].(string)
+
+	w := e.requete(http.MethodGet, "/v2/annonces_vente/"+id, uuid.Nil, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("statut %d (%s)", w.Code, w.Body.String())
+	}
+	annonce := decoder(t, w)
+	if annonce["type"] != models.AnnonceTypeVente {
+		t.Errorf("type %v, attendu %s", annonce["type"], models.AnnonceTypeVente)
+	}
+	if auteur := annonce["auteur"].(map[string]interface{}); auteur["nom"] != e.vendeur.Nom {
+		t.Errorf("auteur %v, attendu %s", auteur, e.vendeur.Nom)
+	}
+	if parcelle := annonce["parcelle"].(map[string]interface{}); parcelle["adresse"] != e.daloa.Adresse || parcelle["surface"] != e.daloa.Surface {
+		t.Errorf("parcelle %v, attendue %s", parcelle, e.daloa.Adresse)
+	}
+	if _, ok := annonce["created_at"].(string); !ok {
+		t.Errorf("created_at absent : %v", annonce["created_at"])
+	}
+
+	// Les listes v2 vides sont des tableaux
+	w = e.requete(http.MethodGet, "/v2/annonces_achat", uuid.Nil, nil)
+	if corps := strings.TrimSpace(w.Body.String()); corps != "[]" {
+		t.Errorf("liste vide : %s, attendu []", corps)
+	}
+}
+
+func TestAuteurSuspendu(t *testing.T) {
+	for _, tt := range typesTest {
+		t.Run(tt.annonceType, func(t *testing.T) {
+			e := nouvelEnvironnement(t)
+			id := e.creer(t, tt, e.vendeur.ID, nil)["id"].(string)
+			e.creer(t, tt, e.acheteur.ID, map[string]interface{}{"parcelle_id": e.korhogo.ID})
+
+			e.memoire.Suspendre(models.Suspension{UserID: e.vendeur.ID, Motif: "fraude"})
+
+			w := e.requete(http.MethodGet, "/v2"+tt.chemin, uuid.Nil, nil)
+			annonces := decoderListe(t, w)
+			if len(annonces) != 1 || annonces[0]["id"] == id {
+				t.Errorf("%d annonces listées, attendu celle de l'auteur non suspendu uniquement", len(annonces))
+			}
+
+			w = e.requete(http.MethodPost, "/v1"+tt.chemin, e.vendeur.ID, tt.corps(e))
+			verifierErreur(t, w, http.StatusForbidden, "compte_suspendu")
+
+			// Une suspension terminée ne bloque plus l'auteur
+			fin := time.Now().Add(-time.Hour)
+			e.memoire.Suspendre(models.Suspension{UserID: e.vendeur.ID, Motif: "fraude", FinLe: &fin})
+			w = e.requete(http.MethodGet, "/v2"+tt.chemin, uuid.Nil, nil)
+			if n := len(decoderListe(t, w)); n != 2 {
+				t.Errorf("%d annonces listées après la fin de la suspension, attendu 2", n)
+			}
+		})
+	}
+}
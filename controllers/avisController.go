@@ -0,0 +1,366 @@
+package controllers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+const commentaireTailleMax = 2000
+
+type AvisInput struct {
+	NoteQualite       int    `json:"note_qualite" binding:"required,min=1,max=5"`
+	NotePonctualite   int    `json:"note_ponctualite" binding:"required,min=1,max=5"`
+	NoteCommunication int    `json:"note_communication" binding:"required,min=1,max=5"`
+	Commentaire       string `json:"commentaire"`
+}
+
+type ReponseAvisInput struct {
+	Reponse string `json:"reponse" binding:"required"`
+}
+
+// roleCible renvoie le rôle, dans la transaction, du participant évalué
+func roleCible(conversation models.Conversation, cibleID uuid.UUID) string {
+	if cibleID == conversation.ProprietaireID {
+		return models.RoleProducteur
+	}
+	if conversation.AnnonceType == models.AnnonceTypePref {
+		return models.RoleFinanceur
+	}
+	return models.RoleAcheteur
+}
+
+// validerCommentaire nettoie un commentaire ou une réponse (champ) et vérifie sa longueur
+func validerCommentaire(champ string, texte string) (string, error) {
+	texte = strings.TrimSpace(texte)
+	if len([]rune(texte)) > commentaireTailleMax {
+		return "", erreurs.DonneesInvalides.Champ(champ, erreurs.DetailTropLong, strconv.Itoa(commentaireTailleMax))
+	}
+	return texte, nil
+}
+
+// Conclure la transaction d'une conversation (vente réalisée ou préfinancement livré), en
+// deux temps : le producteur la déclare (202), puis l'interlocuteur la confirme (200).
+// Les avis ne sont ouverts aux deux participants qu'une fois la conclusion confirmée.
+func ConclureConversation(c *gin.Context) {
+	conversation, userID, ok := trouverMaConversation(c)
+	if !ok {
+		return
+	}
+	if conversation.ConclueLe != nil {
+		c.JSON(http.StatusOK, conversation)
+		return
+	}
+
+	maintenant := time.Now()
+	if userID == conversation.ProprietaireID {
+		if conversation.ConclusionDemandeeLe == nil {
+			conversation.ConclusionDemandeeLe = &maintenant
+			if err := baseDe(c).Model(&conversation).Update("conclusion_demandee_le", maintenant).Error; err != nil {
+				erreurs.Repondre(c, err)
+				return
+			}
+		}
+		c.JSON(http.StatusAccepted, conversation)
+		return
+	}
+	if conversation.ConclusionDemandeeLe == nil {
+		erreurs.Repondre(c, erreurs.ConclusionNonDemandee)
+		return
+	}
+
+	// La condition sur conclue_le évite de compter deux fois une confirmation envoyée en double
+	res := baseDe(c).Model(&models.Conversation{}).
+		Where("id = ? AND conclue_le IS NULL", conversation.ID).
+		Update("conclue_le", maintenant)
+	if res.Error != nil {
+		erreurs.Repondre(c, res.Error)
+		return
+	}
+	if res.RowsAffected > 0 {
+		transactionsConclues.Inc(conversation.AnnonceType)
+	}
+	conversation.ConclueLe = &maintenant
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// Laisser un avis sur l'autre participant d'une transaction conclue (un seul par transaction)
+func CreateAvis(c *gin.Context) {
+	conversation, userID, ok := trouverMaConversation(c)
+	if !ok {
+		return
+	}
+	if conversation.ConclueLe == nil {
+		erreurs.Repondre(c, erreurs.TransactionNonConclue)
+		return
+	}
+
+	var input AvisInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		erreurs.Repondre(c, erreurs.Validation(err))
+		return
+	}
+	commentaire, err := validerCommentaire("commentaire", input.Commentaire)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	cibleID := conversation.Destinataire(userID)
+	avis := models.Avis{
+		ID:                uuid.New(),
+		ConversationID:    conversation.ID,
+		AnnonceType:       conversation.AnnonceType,
+		AnnonceID:         conversation.AnnonceID,
+		AuteurID:          userID,
+		CibleID:           cibleID,
+		RoleCible:         roleCible(conversation, cibleID),
+		NoteQualite:       input.NoteQualite,
+		NotePonctualite:   input.NotePonctualite,
+		NoteCommunication: input.NoteCommunication,
+		Commentaire:       commentaire,
+	}
+	// L'index unique (conversation_id, auteur_id) départage deux envois simultanés
+	res := baseDe(c).Clauses(clause.OnConflict{DoNothing: true}).Create(&avis)
+	if res.Error != nil {
+		erreurs.Repondre(c, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		erreurs.Repondre(c, erreurs.AvisExistant)
+		return
+	}
+
+	c.JSON(http.StatusCreated, avis)
+}
+
+// trouverAvis charge l'avis de l'URL. Sans base, elle répond 503 : après elle, baseDe(c)
+// n'est jamais nil.
+func trouverAvis(c *gin.Context) (models.Avis, bool) {
+	var avis models.Avis
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return avis, false
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return avis, false
+	}
+
+	if err := db.First(&avis, "id = ?", id).Error; err != nil {
+		erreurs.Repondre(c, erreurs.AvisIntrouvable)
+		return avis, false
+	}
+	return avis, true
+}
+
+// Modifier son avis, pendant le délai de modification
+func UpdateAvis(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	avis, ok := trouverAvis(c)
+	if !ok {
+		return
+	}
+	if avis.AuteurID != userID {
+		erreurs.Repondre(c, erreurs.ModificationAvisInterdite)
+		return
+	}
+	if !avis.Modifiable(time.Now()) {
+		erreurs.Repondre(c, erreurs.DelaiAvisDepasse)
+		return
+	}
+
+	var input AvisInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		erreurs.Repondre(c, erreurs.Validation(err))
+		return
+	}
+	commentaire, err := validerCommentaire("commentaire", input.Commentaire)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	avis.NoteQualite = input.NoteQualite
+	avis.NotePonctualite = input.NotePonctualite
+	avis.NoteCommunication = input.NoteCommunication
+	avis.Commentaire = commentaire
+
+	if err := baseDe(c).Save(&avis).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, avis)
+}
+
+// Répondre à un avis reçu (une réponse par avis, modifiable)
+func RepondreAvis(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	avis, ok := trouverAvis(c)
+	if !ok {
+		return
+	}
+	if avis.CibleID != userID {
+		erreurs.Repondre(c, erreurs.ReponseAvisInterdite)
+		return
+	}
+
+	var input ReponseAvisInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		erreurs.Repondre(c, erreurs.Validation(err))
+		return
+	}
+	reponse, err := validerCommentaire("reponse", input.Reponse)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+	if reponse == "" {
+		erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("reponse", erreurs.DetailObligatoire))
+		return
+	}
+
+	maintenant := time.Now()
+	avis.Reponse = reponse
+	avis.ReponduLe = &maintenant
+
+	if err := baseDe(c).Save(&avis).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, avis)
+}
+
+// Lister les avis reçus par un utilisateur, avec sa note agrégée (filtre facultatif : role)
+func GetAvisUtilisateur(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide.Champ("user_id", erreurs.DetailFormat, "uuid"))
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	query := db.Where("cible_id = ?", userID)
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role_cible = ?", role)
+	}
+
+	result := models.ListeAvis{Avis: []models.Avis{}}
+	if err := query.Order("created_at DESC").Find(&result.Avis).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	result.Note = models.NoteUtilisateur{UserID: userID.String()}
+	if notes := notesUtilisateurs(db, []string{userID.String()}); len(notes) > 0 {
+		result.Note = notes[userID.String()]
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// notesUtilisateurs calcule en une requête la note agrégée de plusieurs utilisateurs
+func notesUtilisateurs(db *gorm.DB, ids []string) map[string]models.NoteUtilisateur {
+	notes := map[string]models.NoteUtilisateur{}
+	if len(ids) == 0 || db == nil {
+		return notes
+	}
+
+	var lignes []struct {
+		CibleID       uuid.UUID
+		Qualite       float64
+		Ponctualite   float64
+		Communication float64
+		Total         int64
+	}
+	if err := db.Model(&models.Avis{}).
+		Select("cible_id, AVG(note_qualite) AS qualite, AVG(note_ponctualite) AS ponctualite, AVG(note_communication) AS communication, COUNT(*) AS total").
+		Where("cible_id IN ?", ids).
+		Group("cible_id").
+		Scan(&lignes).Error; err != nil {
+		log.Printf("Erreur calcul des notes utilisateurs : %v\n", err)
+	}
+	for _, l := range lignes {
+		notes[l.CibleID.String()] = models.NoteUtilisateur{
+			UserID:        l.CibleID.String(),
+			Moyenne:       arrondiNote((l.Qualite + l.Ponctualite + l.Communication) / 3),
+			Qualite:       arrondiNote(l.Qualite),
+			Ponctualite:   arrondiNote(l.Ponctualite),
+			Communication: arrondiNote(l.Communication),
+			NbAvis:        l.Total,
+		}
+	}
+	return notes
+}
+
+func arrondiNote(note float64) float64 {
+	return math.Round(note*100) / 100
+}
+
+// enrichirNotesVente renseigne la réputation du vendeur sur des annonces de vente
+func enrichirNotesVente(c *gin.Context, annonces []models.ListeAnnonceVente) {
+	ids := make([]string, len(annonces))
+	for i, a := range annonces {
+		ids[i] = a.UserID
+	}
+	notes := notesUtilisateurs(baseDe(c), ids)
+	for i := range annonces {
+		note := notes[annonces[i].UserID]
+		annonces[i].NoteUtilisateur = note.Moyenne
+		annonces[i].NbAvisUtilisateur = note.NbAvis
+	}
+}
+
+// enrichirNotesAchat renseigne la réputation de l'acheteur sur des annonces d'achat
+func enrichirNotesAchat(c *gin.Context, annonces []models.ListeAnnonceAchat) {
+	ids := make([]string, len(annonces))
+	for i, a := range annonces {
+		ids[i] = a.UserID
+	}
+	notes := notesUtilisateurs(baseDe(c), ids)
+	for i := range annonces {
+		note := notes[annonces[i].UserID]
+		annonces[i].NoteUtilisateur = note.Moyenne
+		annonces[i].NbAvisUtilisateur = note.NbAvis
+	}
+}
+
+// enrichirNotesPref renseigne la réputation du producteur sur des annonces de préfinancement
+func enrichirNotesPref(c *gin.Context, annonces []models.LiteAnnoncePrefinancement) {
+	ids := make([]string, len(annonces))
+	for i, a := range annonces {
+		ids[i] = a.UserID
+	}
+	notes := notesUtilisateurs(baseDe(c), ids)
+	for i := range annonces {
+		note := notes[annonces[i].UserID]
+		annonces[i].NoteUtilisateur = note.Moyenne
+		annonces[i].NbAvisUtilisateur = note.NbAvis
+	}
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+func (e *environnement) monterAvis() {
+	g := e.groupe(models.VersionAPI1)
+	g.POST("/annonces_vente/:id/conversations", StartConversationAnnonceVente)
+	g.POST("/conversations/:id/conclure", ConclureConversation)
+	g.POST("/conversations/:id/avis", CreateAvis)
+	g.PUT("/avis/:id", UpdateAvis)
+	g.POST("/avis/:id/reponse", RepondreAvis)
+	g.GET("/utilisateurs/:id/avis", GetAvisUtilisateur)
+}
+
+func notes(qualite, ponctualite, communication int) map[string]interface{} {
+	return map[string]interface{}{"note_qualite": qualite, "note_ponctualite": ponctualite, "note_communication": communication}
+}
+
+// La conclusion se fait en deux temps ; les avis ne sont ouverts qu'ensuite, un par
+// participant, et la note du vendeur apparaît dans les listes
+func TestConclusionEtAvis(t *testing.T) {
+	e := nouvelEnvironnementBase(t)
+	e.monterAvis()
+	vente := e.creer(t, typesTest[0], e.vendeur.ID, nil)["id"].(string)
+
+	w := e.requete(http.MethodPost, "/v1/annonces_vente/"+vente+"/conversations", e.vendeur.ID, nil)
+	verifierErreur(t, w, http.StatusBadRequest, "conversation_propre_annonce")
+	w = e.requete(http.MethodPost, "/v1/annonces_vente/"+vente+"/conversations", e.acheteur.ID, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("conversation : statut %d (%s)", w.Code, w.Body.String())
+	}
+	conversation := "/v1/conversations/" + decoder(t, w)["id"].(string)
+
+	w = e.requete(http.MethodPost, conversation+"/avis", e.acheteur.ID, notes(5, 4, 3))
+	verifierErreur(t, w, http.StatusConflict, "transaction_non_conclue")
+	w = e.requete(http.MethodPost, conversation+"/conclure", e.acheteur.ID, nil)
+	verifierErreur(t, w, http.StatusConflict, "conclusion_non_demandee")
+
+	// Le vendeur déclare la transaction conclue, l'acheteur la confirme
+	if w = e.requete(http.MethodPost, conversation+"/conclure", e.vendeur.ID, nil); w.Code != http.StatusAccepted {
+		t.Fatalf("déclaration : statut %d (%s)", w.Code, w.Body.String())
+	}
+	w = e.requete(http.MethodPost, conversation+"/avis", e.vendeur.ID, notes(4, 4, 4))
+	verifierErreur(t, w, http.StatusConflict, "transaction_non_conclue")
+	w = e.requete(http.MethodPost, conversation+"/conclure", e.acheteur.ID, nil)
+	if w.Code != http.StatusOK || decoder(t, w)["conclue_le"] == nil {
+		t.Fatalf("confirmation : statut %d (%s)", w.Code, w.Body.String())
+	}
+
+	w = e.requete(http.MethodPost, conversation+"/avis", e.acheteur.ID, notes(5, 4, 3))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("avis de l'acheteur : statut %d (%s)", w.Code, w.Body.String())
+	}
+	avisAcheteur := decoder(t, w)
+	if avisAcheteur["cible_id"] != e.vendeur.ID.String() || avisAcheteur["role_cible"] != models.RoleProducteur {
+		t.Errorf("avis %v : cible ou rôle inattendu", avisAcheteur)
+	}
+	w = e.requete(http.MethodPost, conversation+"/avis", e.acheteur.ID, notes(1, 1, 1))
+	verifierErreur(t, w, http.StatusConflict, "avis_existant")
+
+	w = e.requete(http.MethodPost, conversation+"/avis", e.vendeur.ID, notes(6, 4, 4))
+	verifierErreur(t, w, http.StatusBadRequest, "donnees_invalides")
+	w = e.requete(http.MethodPost, conversation+"/avis", e.vendeur.ID, notes(4, 4, 4))
+	if w.Code != http.StatusCreated || decoder(t, w)["role_cible"] != models.RoleAcheteur {
+		t.Fatalf("avis du vendeur : statut %d (%s)", w.Code, w.Body.String())
+	}
+
+	// Seul l'auteur modifie son avis, seul l'évalué y répond
+	avis := "/v1/avis/" + avisAcheteur["id"].(string)
+	w = e.requete(http.MethodPut, avis, e.vendeur.ID, notes(5, 5, 5))
+	verifierErreur(t, w, http.StatusForbidden, "modification_avis_interdite")
+	w = e.requete(http.MethodPut, avis, e.acheteur.ID, notes(5, 5, 2))
+	if w.Code != http.StatusOK {
+		t.Fatalf("modification : statut %d (%s)", w.Code, w.Body.String())
+	}
+	w = e.requete(http.MethodPost, avis+"/reponse", e.acheteur.ID, map[string]interface{}{"reponse": "Merci"})
+	verifierErreur(t, w, http.StatusForbidden, "reponse_avis_interdite")
+	w = e.requete(http.MethodPost, avis+"/reponse", e.vendeur.ID, map[string]interface{}{"reponse": "  Merci pour la confiance  "})
+	if reponse := decoder(t, w); w.Code != http.StatusOK || reponse["reponse"] != "Merci pour la confiance" {
+		t.Fatalf("réponse : statut %d, %v", w.Code, reponse["reponse"])
+	}
+
+	// Passé le délai, l'avis n'est plus modifiable
+	if err := e.base.Model(&models.Avis{}).Where("id = ?", avisAcheteur["id"]).
+		Update("created_at", time.Now().Add(-models.DelaiModificationAvis-time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	w = e.requete(http.MethodPut, avis, e.acheteur.ID, notes(1, 1, 1))
+	verifierErreur(t, w, http.StatusConflict, "delai_avis_depasse")
+
+	w = e.requete(http.MethodGet, "/v1/utilisateurs/"+e.vendeur.ID.String()+"/avis", uuid.Nil, nil)
+	recus := decoder(t, w)
+	note := recus["note"].(map[string]interface{})
+	if n := len(recus["avis"].([]interface{})); n != 1 || note["nb_avis"] != float64(1) || note["moyenne"] != float64(4) {
+		t.Errorf("%d avis reçus, note %v ; attendu 1 avis de moyenne 4", n, note)
+	}
+	w = e.requete(http.MethodGet, "/v1/annonces_vente", uuid.Nil, nil)
+	if annonces := decoderListe(t, w); len(annonces) != 1 || annonces[0]["note_utilisateur"] != float64(4) || annonces[0]["nb_avis_utilisateur"] != float64(1) {
+		t.Errorf("liste : note du vendeur absente (%v)", annonces)
+	}
+}
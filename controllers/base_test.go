@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// Le cycle de vie des annonces passe par les dépôts GORM sur une base SQLite locale :
+// identifiants, versions et If-Match, filtres et recherche sans tenir compte des accents
+func TestAnnoncesBase(t *testing.T) {
+	for _, tt := range typesTest {
+		t.Run(tt.annonceType, func(t *testing.T) {
+			e := nouvelEnvironnementBase(t)
+			seguela := models.Parcelle{ID: uuid.New(), Adresse: "Séguéla, Worodougou", UserID: &e.vendeur.ID}
+			if err := e.base.Create(&seguela).Error; err != nil {
+				t.Fatal(err)
+			}
+			e.creer(t, tt, e.vendeur.ID, map[string]interface{}{"statut": models.StatutEnPause})
+			modifs := map[string]interface{}{tt.champPrix: 2500}
+			if tt.avecParcelle {
+				modifs["parcelle_id"] = seguela.ID
+			}
+			id := e.creer(t, tt, e.vendeur.ID, modifs)["id"].(string)
+			if _, err := uuid.Parse(id); err != nil {
+				t.Fatalf("identifiant %q : %v", id, err)
+			}
+
+			// La liste des préfinancements n'a que les filtres de base
+			filtres := map[string]string{
+				models.AnnonceTypeVente: "?region=" + url.QueryEscape("SÉGUÉLA"),
+				models.AnnonceTypeAchat: "?prix_min=2000",
+				models.AnnonceTypePref:  "?statut=" + models.StatutActive,
+			}
+			filtre := filtres[tt.annonceType]
+			w := e.requete(http.MethodGet, "/v2"+tt.chemin+filtre, uuid.Nil, nil)
+			if annonces := decoderListe(t, w); len(annonces) != 1 || annonces[0]["id"] != id {
+				t.Errorf("GET %s%s : %d annonce(s), attendu %s seule", tt.chemin, filtre, len(annonces), id)
+			}
+
+			chemin := "/v1" + tt.chemin + "/" + id
+			w = e.requete(http.MethodGet, chemin, uuid.Nil, nil)
+			if w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+				t.Fatalf("GET : statut %d, ETag %s", w.Code, w.Header().Get("ETag"))
+			}
+
+			corps := tt.corps(e)
+			corps["description"] = "Lot réservé"
+			w = e.requete(http.MethodPut, chemin, e.vendeur.ID, corps)
+			verifierErreur(t, w, http.StatusPreconditionRequired, "if_match_requis")
+			if w = e.requete(http.MethodPut, chemin, e.vendeur.ID, corps, "If-Match", `"1"`); w.Code != http.StatusOK {
+				t.Fatalf("PUT : statut %d (%s)", w.Code, w.Body.String())
+			}
+
+			// Une écriture sur la version 1, lue avant le PUT, est refusée
+			w = e.requete(http.MethodPatch, chemin, e.vendeur.ID, map[string]interface{}{"quantite": 10}, "If-Match", `"1"`)
+			verifierErreur(t, w, http.StatusPreconditionFailed, "version_perimee")
+			if courante := decoder(t, w)["annonce"].(map[string]interface{}); courante["version"] != float64(2) || courante["description"] != "Lot réservé" {
+				t.Errorf("412 : état courant %v, attendu la version 2", courante)
+			}
+			w = e.requete(http.MethodPatch, chemin, e.vendeur.ID, map[string]interface{}{"quantite": 10}, "If-Match", `"2"`)
+			if w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+				t.Fatalf("PATCH : statut %d, ETag %s (%s)", w.Code, w.Header().Get("ETag"), w.Body.String())
+			}
+
+			w = e.requete(http.MethodDelete, chemin, e.vendeur.ID, nil, "If-Match", `"2"`)
+			verifierErreur(t, w, http.StatusPreconditionFailed, "version_perimee")
+			if w = e.requete(http.MethodDelete, chemin, e.vendeur.ID, nil, "If-Match", `"3"`); w.Code != http.StatusOK {
+				t.Fatalf("DELETE : statut %d (%s)", w.Code, w.Body.String())
+			}
+			w = e.requete(http.MethodGet, chemin, uuid.Nil, nil)
+			verifierErreur(t, w, http.StatusNotFound, "annonce_introuvable")
+		})
+	}
+}
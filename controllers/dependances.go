@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Steph-business/annonce_de_vente/config"
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+)
+
+// Dependances regroupe les sources de données des handlers, construites dans main.go.
+// Les annonces et les données de référence passent par Depots ; DB sert aux tables propres
+// au service (favoris, messagerie, avis, recherches, webhooks, modération, administration).
+// DB est nil avec les dépôts en mémoire : les annonces restent servies, sans favoris, notes
+// ni alertes de recherche, et les autres routes répondent 503 (voir baseRequise).
+// Config est la configuration chargée au démarrage (la configuration par défaut si elle
+// est nil). Arret est fermé à la réception de SIGTERM : la sonde de disponibilité échoue
+// et les flux temps réel se terminent.
+type Dependances struct {
+	Depots depots.Depots
+	DB     *gorm.DB
+	Config *config.Config
+	Arret  <-chan struct{}
+}
+
+const cleDependances = "dependances"
+
+// Injecter rend les dépendances disponibles aux handlers
+func Injecter(d Dependances) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(cleDependances, d)
+		c.Next()
+	}
+}
+
+func dependancesDe(c *gin.Context) Dependances {
+	d, _ := c.MustGet(cleDependances).(Dependances)
+	return d
+}
+
+// depotsDe renvoie les dépôts injectés
+func depotsDe(c *gin.Context) depots.Depots {
+	return dependancesDe(c).Depots
+}
+
+// baseDe renvoie la base de données injectée (nil avec les dépôts en mémoire), pour les
+// compléments facultatifs des annonces (favoris, notes, alertes)
+func baseDe(c *gin.Context) *gorm.DB {
+	return dependancesDe(c).DB
+}
+
+// baseRequise renvoie la base de données des handlers qui ne peuvent s'en passer ; sans
+// base (dépôts en mémoire), elle répond 503 base_indisponible
+func baseRequise(c *gin.Context) (*gorm.DB, bool) {
+	db := dependancesDe(c).DB
+	if db == nil {
+		erreurs.Repondre(c, erreurs.BaseIndisponible)
+		return nil, false
+	}
+	return db, true
+}
+
+// configDe renvoie la configuration injectée
+func configDe(c *gin.Context) config.Config {
+	if cfg := dependancesDe(c).Config; cfg != nil {
+		return *cfg
+	}
+	return config.Defaut()
+}
+
+// erreurAnnonce traduit l'erreur de lecture d'une annonce par un dépôt
+func erreurAnnonce(err error) error {
+	if errors.Is(err, depots.ErrIntrouvable) {
+		return erreurs.AnnonceIntrouvable
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Sans base, les routes propres au service répondent 503 au lieu de paniquer
+func TestBaseIndisponible(t *testing.T) {
+	e := nouvelEnvironnement(t)
+	vente := e.creer(t, typesTest[0], e.vendeur.ID, nil)
+	annonce := "/" + vente["id"].(string)
+
+	g := e.routeur.Group("/sans_base", func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader(enteteUtilisateur))
+	})
+	g.POST("/favoris/vente/:id", AddFavoriAnnonceVente)
+	g.GET("/favoris", GetMesFavoris)
+	g.POST("/conversations/vente/:id", StartConversationAnnonceVente)
+	g.GET("/conversations", GetMesConversations)
+	g.GET("/conversations/:id/messages", GetConversationMessages)
+	g.GET("/utilisateurs/:id/avis", GetAvisUtilisateur)
+	g.GET("/notifications", GetMesNotifications)
+	g.GET("/recherches", GetMesRecherches)
+	g.GET("/recherches/:id", GetRechercheByID)
+	g.GET("/webhooks", GetMesWebhooks)
+	g.GET("/webhooks/:id", GetWebhookByID)
+	g.POST("/signalements/vente/:id", SignalerAnnonceVente)
+	g.GET("/moderation", GetFileModeration)
+	g.POST("/moderation/:type/:id/approuver", ApprouverAnnonce)
+	g.GET("/admin/annonces/:type", AdminGetAnnonces)
+	g.GET("/admin/utilisateurs/:id", AdminGetUtilisateur)
+	g.GET("/admin/statistiques", AdminGetStatistiques)
+
+	requetes := []struct {
+		methode, chemin string
+		corps           interface{}
+	}{
+		{http.MethodPost, "/favoris/vente" + annonce, nil},
+		{http.MethodGet, "/favoris", nil},
+		{http.MethodPost, "/conversations/vente" + annonce, nil},
+		{http.MethodGet, "/conversations", nil},
+		{http.MethodGet, "/conversations/" + uuid.NewString() + "/messages", nil},
+		{http.MethodGet, "/utilisateurs/" + e.vendeur.ID.String() + "/avis", nil},
+		{http.MethodGet, "/notifications", nil},
+		{http.MethodGet, "/recherches", nil},
+		{http.MethodGet, "/recherches/" + uuid.NewString(), nil},
+		{http.MethodGet, "/webhooks", nil},
+		{http.MethodGet, "/webhooks/" + uuid.NewString(), nil},
+		{http.MethodPost, "/signalements/vente" + annonce, map[string]interface{}{"motif": "fraude"}},
+		{http.MethodGet, "/moderation", nil},
+		{http.MethodPost, "/moderation/vente" + annonce + "/approuver", nil},
+		{http.MethodGet, "/admin/annonces/vente", nil},
+		{http.MethodGet, "/admin/utilisateurs/" + e.vendeur.ID.String(), nil},
+		{http.MethodGet, "/admin/statistiques", nil},
+	}
+	for _, r := range requetes {
+		t.Run(r.methode+" "+r.chemin, func(t *testing.T) {
+			w := e.requete(r.methode, "/sans_base"+r.chemin, e.acheteur.ID, r.corps)
+			verifierErreur(t, w, http.StatusServiceUnavailable, "base_indisponible")
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/middleware"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// enveloppe décode le corps d'une réponse d'erreur
+func enveloppe(t *testing.T, corps []byte) erreurs.Corps {
+	t.Helper()
+	var v struct {
+		Error erreurs.Corps `json:"error"`
+	}
+	if err := json.Unmarshal(corps, &v); err != nil {
+		t.Fatalf("enveloppe d'erreur invalide : %v (%s)", err, corps)
+	}
+	return v.Error
+}
+
+// Le code reste stable, le message suit l'en-tête Accept-Language (français par défaut)
+func TestErreursLocalisees(t *testing.T) {
+	e := nouvelEnvironnement(t)
+	chemin := "/v1/annonces_vente/" + uuid.NewString()
+
+	for entete, message := range map[string]string{
+		"":                  "Annonce non trouvée",
+		"en":                "Listing not found",
+		"en-GB,fr;q=0.8":    "Listing not found",
+		"fr-CI, en;q=0.9":   "Annonce non trouvée",
+		"de-DE, en;q=0.5":   "Listing not found",
+		"fr;q=0, en;q=0.1":  "Listing not found",
+		"es, pt":            "Annonce non trouvée",
+		"EN-us;q=0.7, fr-x": "Annonce non trouvée",
+	} {
+		w := e.requete(http.MethodGet, chemin, uuid.Nil, nil, "Accept-Language", entete)
+		corps := enveloppe(t, w.Body.Bytes())
+		if w.Code != http.StatusNotFound || corps.Code != "annonce_introuvable" || corps.Message != message {
+			t.Errorf("Accept-Language %q : %d %s %q, attendu 404 annonce_introuvable %q", entete, w.Code, corps.Code, corps.Message, message)
+		}
+	}
+
+	// Les détails de validation sont traduits aussi
+	corps := typesTest[0].corps(e)
+	corps["quantite"] = -5
+	for langue, message := range map[string]string{"fr": "doit être supérieur à 0", "en": "must be greater than 0"} {
+		w := e.requete(http.MethodPost, "/v1/annonces_vente", e.vendeur.ID, corps, "Accept-Language", langue)
+		erreur := enveloppe(t, w.Body.Bytes())
+		if w.Code != http.StatusBadRequest || erreur.Code != "donnees_invalides" || len(erreur.Details) != 1 {
+			t.Fatalf("%s : %d %+v, attendu 400 donnees_invalides avec un détail", langue, w.Code, erreur)
+		}
+		if d := erreur.Details[0]; d.Champ != "quantite" || d.Code != erreurs.DetailSuperieurA || d.Message != message {
+			t.Errorf("%s : détail %+v, attendu quantite %s %q", langue, d, erreurs.DetailSuperieurA, message)
+		}
+	}
+}
+
+// L'identifiant de requête fourni par le client est repris dans l'en-tête et le corps
+// des erreurs ; un identifiant invalide est remplacé
+func TestErreurRequestID(t *testing.T) {
+	e := nouvelEnvironnement(t)
+	e.routeur.GET("/trace/annonces_vente/:id", middleware.RequestIDMiddleware(), middleware.VersionAPIMiddleware(models.VersionAPI1), GetAnnonceByID)
+
+	w := e.requete(http.MethodGet, "/trace/annonces_vente/pas-un-uuid", uuid.Nil, nil, erreurs.CleRequestID, "trace-42")
+	if corps := enveloppe(t, w.Body.Bytes()); corps.Code != "id_invalide" || corps.RequestID != "trace-42" || w.Header().Get(erreurs.CleRequestID) != "trace-42" {
+		t.Errorf("request_id %q, en-tête %q ; attendu trace-42", corps.RequestID, w.Header().Get(erreurs.CleRequestID))
+	}
+
+	w = e.requete(http.MethodGet, "/trace/annonces_vente/pas-un-uuid", uuid.Nil, nil, erreurs.CleRequestID, "<script>")
+	corps := enveloppe(t, w.Body.Bytes())
+	if _, err := uuid.Parse(corps.RequestID); err != nil || w.Header().Get(erreurs.CleRequestID) != corps.RequestID {
+		t.Errorf("request_id %q, en-tête %q ; attendu un UUID généré", corps.RequestID, w.Header().Get(erreurs.CleRequestID))
+	}
+}
@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// favorisInfos renvoie, pour une liste d'annonces, le nombre de favoris de chacune
+// et celles mises en favori par l'utilisateur courant (s'il est authentifié)
+func favorisInfos(c *gin.Context, annonceType string, ids []string) (map[string]int64, map[string]bool) {
+	compteurs := map[string]int64{}
+	mesFavoris := map[string]bool{}
+	db := baseDe(c)
+	if len(ids) == 0 || db == nil {
+		return compteurs, mesFavoris
+	}
+
+	var lignes []struct {
+		AnnonceID uuid.UUID
+		Total     int64
+	}
+	if err := db.Model(&models.Favori{}).
+		Select("annonce_id, COUNT(*) AS total").
+		Where("annonce_type = ? AND annonce_id IN ?", annonceType, ids).
+		Group("annonce_id").
+		Scan(&lignes).Error; err != nil {
+		log.Printf("Erreur comptage des favoris : %v\n", err)
+	}
+	for _, l := range lignes {
+		compteurs[l.AnnonceID.String()] = l.Total
+	}
+
+	if userID, ok := optionalUserID(c); ok {
+		var favoris []models.Favori
+		if err := db.Where("user_id = ? AND annonce_type = ? AND annonce_id IN ?", userID, annonceType, ids).
+			Find(&favoris).Error; err != nil {
+			log.Printf("Erreur récupération des favoris : %v\n", err)
+		}
+		for _, f := range favoris {
+			mesFavoris[f.AnnonceID.String()] = true
+		}
+	}
+	return compteurs, mesFavoris
+}
+
+// enrichirFavorisVente renseigne nb_favoris et est_favori sur des annonces de vente
+func enrichirFavorisVente(c *gin.Context, annonces []models.ListeAnnonceVente) {
+	ids := make([]string, len(annonces))
+	for i, a := range annonces {
+		ids[i] = a.ID
+	}
+	compteurs, mesFavoris := favorisInfos(c, models.AnnonceTypeVente, ids)
+	for i := range annonces {
+		annonces[i].NbFavoris = compteurs[annonces[i].ID]
+		annonces[i].EstFavori = mesFavoris[annonces[i].ID]
+	}
+}
+
+// enrichirFavorisPref renseigne nb_favoris et est_favori sur des annonces de préfinancement
+func enrichirFavorisPref(c *gin.Context, annonces []models.LiteAnnoncePrefinancement) {
+	ids := make([]string, len(annonces))
+	for i, a := range annonces {
+		ids[i] = a.ID
+	}
+	compteurs, mesFavoris := favorisInfos(c, models.AnnonceTypePref, ids)
+	for i := range annonces {
+		annonces[i].NbFavoris = compteurs[annonces[i].ID]
+		annonces[i].EstFavori = mesFavoris[annonces[i].ID]
+	}
+}
+
+// supprimerFavorisAnnonce retire les favoris d'une annonce supprimée
+func supprimerFavorisAnnonce(db *gorm.DB, annonceType string, annonceID uuid.UUID) {
+	if db == nil {
+		return
+	}
+	if err := db.Where("annonce_type = ? AND annonce_id = ?", annonceType, annonceID).
+		Delete(&models.Favori{}).Error; err != nil {
+		log.Printf("Erreur suppression des favoris de l'annonce %s : %v\n", annonceID, err)
+	}
+}
+
+// enregistrerFavori ajoute l'annonce aux favoris de l'utilisateur. Si elle y est déjà,
+// le prix et le statut de référence sont mis à jour (l'annonce n'est plus signalée comme modifiée).
+func enregistrerFavori(c *gin.Context, annonceType string, annonceID uuid.UUID, prix float64, statut string) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	var favori models.Favori
+	err := db.Where("user_id = ? AND annonce_type = ? AND annonce_id = ?", userID, annonceType, annonceID).
+		First(&favori).Error
+	if err == nil {
+		favori.PrixInitial = prix
+		favori.StatutInitial = statut
+		if err := db.Save(&favori).Error; err != nil {
+			erreurs.Repondre(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, favori)
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	favori = models.Favori{
+		ID:            uuid.New(),
+		UserID:        userID,
+		AnnonceType:   annonceType,
+		AnnonceID:     annonceID,
+		PrixInitial:   prix,
+		StatutInitial: statut,
+	}
+	if err := db.Create(&favori).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, favori)
+}
+
+// retirerFavori supprime l'annonce des favoris de l'utilisateur
+func retirerFavori(c *gin.Context, annonceType string) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	res := db.Where("user_id = ? AND annonce_type = ? AND annonce_id = ?", userID, annonceType, id).
+		Delete(&models.Favori{})
+	if res.Error != nil {
+		erreurs.Repondre(c, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		erreurs.Repondre(c, erreurs.FavoriIntrouvable)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Annonce retirée des favoris"})
+}
+
+// Ajouter une annonce de vente aux favoris
+func AddFavoriAnnonceVente(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+
+	annonce, err := depotsDe(c).Ventes.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return
+	}
+
+	enregistrerFavori(c, models.AnnonceTypeVente, annonce.ID, annonce.PrixKg, annonce.Statut)
+}
+
+// Retirer une annonce de vente des favoris
+func RemoveFavoriAnnonceVente(c *gin.Context) {
+	retirerFavori(c, models.AnnonceTypeVente)
+}
+
+// Ajouter une annonce de préfinancement aux favoris
+func AddFavoriAnnoncePref(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+
+	annonce, err := depotsDe(c).Prefs.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return
+	}
+
+	enregistrerFavori(c, models.AnnonceTypePref, annonce.ID, annonce.Prix, annonce.Statut)
+}
+
+// Retirer une annonce de préfinancement des favoris
+func RemoveFavoriAnnoncePref(c *gin.Context) {
+	retirerFavori(c, models.AnnonceTypePref)
+}
+
+// Lister les favoris de l'utilisateur connecté, du plus récent au plus ancien. Les annonces
+// dont le prix ou le statut a changé depuis leur ajout sont signalées par favori_modifie ;
+// celles masquées par la modération ou d'un auteur suspendu sont omises, comme dans les listes.
+func GetMesFavoris(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	var favoris []models.Favori
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&favoris).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	// rang garde l'ordre des favoris (du plus récent au plus ancien), perdu par le IN
+	favorisParAnnonce := map[uuid.UUID]models.Favori{}
+	rang := map[string]int{}
+	var venteIDs, prefIDs []uuid.UUID
+	for i, f := range favoris {
+		favorisParAnnonce[f.AnnonceID] = f
+		rang[f.AnnonceID.String()] = i
+		if f.AnnonceType == models.AnnonceTypeVente {
+			venteIDs = append(venteIDs, f.AnnonceID)
+		} else {
+			prefIDs = append(prefIDs, f.AnnonceID)
+		}
+	}
+
+	result := models.ListeFavoris{
+		Ventes:          []models.ListeAnnonceVente{},
+		Prefinancements: []models.LiteAnnoncePrefinancement{},
+	}
+
+	if len(venteIDs) > 0 {
+		var ventes []models.AnnonceVente
+		query := db.Preload("User").Preload("TypeCulture").Preload("Parcelle").Where("id IN ?", venteIDs)
+		if err := depots.ExclureAnnoncesMasquees(query, models.AnnonceTypeVente).Find(&ventes).Error; err != nil {
+			erreurs.Repondre(c, err)
+			return
+		}
+		for _, a := range ventes {
+			dto := toAnnonceDTO(a)
+			f := favorisParAnnonce[a.ID]
+			dto.FavoriModifie = a.PrixKg != f.PrixInitial || a.Statut != f.StatutInitial
+			result.Ventes = append(result.Ventes, dto)
+		}
+		sort.SliceStable(result.Ventes, func(i, j int) bool {
+			return rang[result.Ventes[i].ID] < rang[result.Ventes[j].ID]
+		})
+		enrichirFavorisVente(c, result.Ventes)
+		enrichirNotesVente(c, result.Ventes)
+	}
+
+	if len(prefIDs) > 0 {
+		var prefs []models.AnnoncePrefinancement
+		query := db.Preload("User").Preload("TypeCulture").Preload("Parcelle").Where("id IN ?", prefIDs)
+		if err := depots.ExclureAnnoncesMasquees(query, models.AnnonceTypePref).Find(&prefs).Error; err != nil {
+			erreurs.Repondre(c, err)
+			return
+		}
+		for _, a := range prefs {
+			dto := toAnnoncePrefDTO(a)
+			f := favorisParAnnonce[a.ID]
+			dto.FavoriModifie = a.Prix != f.PrixInitial || a.Statut != f.StatutInitial
+			result.Prefinancements = append(result.Prefinancements, dto)
+		}
+		sort.SliceStable(result.Prefinancements, func(i, j int) bool {
+			return rang[result.Prefinancements[i].ID] < rang[result.Prefinancements[j].ID]
+		})
+		enrichirFavorisPref(c, result.Prefinancements)
+		enrichirNotesPref(c, result.Prefinancements)
+	}
+
+	c.JSON(http.StatusOK, renduAnnonces(c, result))
+}
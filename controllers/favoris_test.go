@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+func (e *environnement) monterFavoris() {
+	for _, version := range []int{models.VersionAPI1, models.VersionAPI2} {
+		g := e.groupe(version)
+		g.GET("/favoris", GetMesFavoris)
+		g.POST("/annonces_vente/:id/favori", AddFavoriAnnonceVente)
+		g.DELETE("/annonces_vente/:id/favori", RemoveFavoriAnnonceVente)
+		g.POST("/annonces_pref/:id/favori", AddFavoriAnnoncePref)
+		g.DELETE("/annonces_pref/:id/favori", RemoveFavoriAnnoncePref)
+	}
+}
+
+// mesFavoris renvoie les identifiants des ventes et préfinancements de "mes favoris" (v1),
+// dans l'ordre de la réponse, et les annonces par identifiant
+func (e *environnement) mesFavoris(t *testing.T, utilisateur uuid.UUID) ([]string, []string, map[string]map[string]interface{}) {
+	t.Helper()
+	w := e.requete(http.MethodGet, "/v1/favoris", utilisateur, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /favoris : statut %d (%s)", w.Code, w.Body.String())
+	}
+	var liste struct {
+		Ventes          []map[string]interface{} `json:"ventes"`
+		Prefinancements []map[string]interface{} `json:"prefinancements"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &liste); err != nil {
+		t.Fatal(err)
+	}
+	annonces := map[string]map[string]interface{}{}
+	ids := func(lot []map[string]interface{}) []string {
+		resultat := []string{}
+		for _, a := range lot {
+			resultat = append(resultat, a["id"].(string))
+			annonces[a["id"].(string)] = a
+		}
+		return resultat
+	}
+	return ids(liste.Ventes), ids(liste.Prefinancements), annonces
+}
+
+func TestMesFavoris(t *testing.T) {
+	e := nouvelEnvironnementBase(t)
+	e.monterFavoris()
+	vente, pref := typesTest[0], typesTest[2]
+	v1 := e.creer(t, vente, e.vendeur.ID, nil)["id"].(string)
+	v2 := e.creer(t, vente, e.acheteur.ID, map[string]interface{}{"parcelle_id": e.korhogo.ID})["id"].(string)
+	p1 := e.creer(t, pref, e.vendeur.ID, nil)["id"].(string)
+
+	for _, ajout := range []struct {
+		chemin string
+		statut int
+	}{
+		{"/v1/annonces_vente/" + v1 + "/favori", http.StatusCreated},
+		{"/v1/annonces_pref/" + p1 + "/favori", http.StatusCreated},
+		{"/v1/annonces_vente/" + v2 + "/favori", http.StatusCreated},
+		{"/v1/annonces_vente/" + v1 + "/favori", http.StatusOK},
+	} {
+		if w := e.requete(http.MethodPost, ajout.chemin, e.acheteur.ID, nil); w.Code != ajout.statut {
+			t.Fatalf("POST %s : statut %d, attendu %d (%s)", ajout.chemin, w.Code, ajout.statut, w.Body.String())
+		}
+	}
+	if w := e.requete(http.MethodPost, "/v1/annonces_vente/"+v1+"/favori", e.vendeur.ID, nil); w.Code != http.StatusCreated {
+		t.Fatalf("POST favori du vendeur : statut %d", w.Code)
+	}
+	w := e.requete(http.MethodPost, "/v1/annonces_vente/"+uuid.NewString()+"/favori", e.acheteur.ID, nil)
+	verifierErreur(t, w, http.StatusNotFound, "annonce_introuvable")
+
+	// Le prix de v1 change après l'ajout : l'annonce est signalée dans "mes favoris"
+	w = e.requete(http.MethodPatch, "/v1/annonces_vente/"+v1, e.vendeur.ID, map[string]interface{}{"prix_kg": 1700}, "If-Match", "*")
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH : statut %d (%s)", w.Code, w.Body.String())
+	}
+
+	ventes, prefs, annonces := e.mesFavoris(t, e.acheteur.ID)
+	if len(ventes) != 2 || ventes[0] != v2 || ventes[1] != v1 || len(prefs) != 1 || prefs[0] != p1 {
+		t.Fatalf("favoris %v %v, attendu [%s %s] [%s] du plus récent au plus ancien", ventes, prefs, v2, v1, p1)
+	}
+	if annonces[v1]["favori_modifie"] != true || annonces[v2]["favori_modifie"] != nil || annonces[p1]["favori_modifie"] != nil {
+		t.Errorf("favori_modifie : v1 %v, v2 %v, p1 %v ; attendu seulement v1", annonces[v1]["favori_modifie"], annonces[v2]["favori_modifie"], annonces[p1]["favori_modifie"])
+	}
+	if annonces[v1]["nb_favoris"] != float64(2) || annonces[v1]["est_favori"] != true || annonces[p1]["nb_favoris"] != float64(1) {
+		t.Errorf("compteurs v1 %v/%v, p1 %v", annonces[v1]["nb_favoris"], annonces[v1]["est_favori"], annonces[p1]["nb_favoris"])
+	}
+
+	// Les listes publiques portent les mêmes compteurs
+	w = e.requete(http.MethodGet, "/v1/annonces_vente", e.vendeur.ID, nil)
+	for _, a := range decoderListe(t, w) {
+		if a["id"] == v2 && (a["nb_favoris"] != float64(1) || a["est_favori"] != false) {
+			t.Errorf("liste publique, v2 : %v favori(s), est_favori %v pour le vendeur", a["nb_favoris"], a["est_favori"])
+		}
+	}
+
+	// Une annonce masquée par la modération, puis celles d'un auteur suspendu, disparaissent
+	masquage := models.ModerationAnnonce{AnnonceType: models.AnnonceTypeVente, AnnonceID: uuid.MustParse(v2), Statut: models.ModerationMasquee}
+	if err := e.base.Create(&masquage).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ventes, _, _ := e.mesFavoris(t, e.acheteur.ID); len(ventes) != 1 || ventes[0] != v1 {
+		t.Errorf("ventes %v après masquage de %s, attendu [%s]", ventes, v2, v1)
+	}
+	if err := e.base.Create(&models.Suspension{UserID: e.vendeur.ID, Motif: "fraude", ModerateurID: e.acheteur.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ventes, prefs, _ := e.mesFavoris(t, e.acheteur.ID); len(ventes) != 0 || len(prefs) != 0 {
+		t.Errorf("favoris %v %v après la suspension du vendeur, attendu aucun", ventes, prefs)
+	}
+
+	w = e.requete(http.MethodDelete, "/v1/annonces_pref/"+p1+"/favori", e.acheteur.ID, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("DELETE : statut %d", w.Code)
+	}
+	w = e.requete(http.MethodDelete, "/v1/annonces_pref/"+p1+"/favori", e.acheteur.ID, nil)
+	verifierErreur(t, w, http.StatusNotFound, "favori_introuvable")
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// rayonRechercheMaxKm borne le rayon d'une recherche par distance
+const rayonRechercheMaxKm = 500
+
+// validerFiltre vérifie la cohérence des critères d'un filtre
+func validerFiltre(f models.FiltreAnnonce, annonceType string) error {
+	if f.UserID != "" {
+		if _, err := uuid.Parse(f.UserID); err != nil {
+			return erreurs.FiltreInvalide.Champ("user_id", erreurs.DetailFormat, "uuid")
+		}
+	}
+	if f.TypeCultureID != "" {
+		if _, err := uuid.Parse(f.TypeCultureID); err != nil {
+			return erreurs.FiltreInvalide.Champ("type_culture_id", erreurs.DetailFormat, "uuid")
+		}
+	}
+	if f.PrixMin != nil && f.PrixMax != nil && *f.PrixMin > *f.PrixMax {
+		return erreurs.FiltreInvalide.Champ("prix_min", erreurs.DetailMaximum, "prix_max")
+	}
+	if f.Region != "" && annonceType == models.AnnonceTypeAchat {
+		return erreurs.FiltreInvalide.Champ("region", erreurs.DetailInconnu)
+	}
+	return validerDistance(f, annonceType)
+}
+
+// validerDistance vérifie les critères de recherche par distance : lat, lng et rayon_km
+// vont ensemble et ne s'appliquent qu'aux annonces rattachées à une parcelle
+func validerDistance(f models.FiltreAnnonce, annonceType string) error {
+	criteres := []struct {
+		champ    string
+		valeur   *float64
+		min, max float64
+		// strict : la valeur doit être supérieure à min, et non au moins égale
+		strict bool
+	}{
+		{"lat", f.Lat, -90, 90, false},
+		{"lng", f.Lng, -180, 180, false},
+		{"rayon_km", f.RayonKm, 0, rayonRechercheMaxKm, true},
+	}
+	presents := 0
+	for _, c := range criteres {
+		if c.valeur != nil {
+			presents++
+		}
+	}
+	if presents == 0 {
+		return nil
+	}
+	for _, c := range criteres {
+		switch {
+		case annonceType == models.AnnonceTypeAchat:
+			if c.valeur != nil {
+				return erreurs.FiltreInvalide.Champ(c.champ, erreurs.DetailInconnu)
+			}
+		case c.valeur == nil:
+			return erreurs.FiltreInvalide.Champ(c.champ, erreurs.DetailObligatoire)
+		// Les comparaisons écartent aussi NaN
+		case c.strict && !(*c.valeur > c.min):
+			return erreurs.FiltreInvalide.Champ(c.champ, erreurs.DetailSuperieurA, strconv.FormatFloat(c.min, 'g', -1, 64))
+		case !(*c.valeur >= c.min):
+			return erreurs.FiltreInvalide.Champ(c.champ, erreurs.DetailMinimum, strconv.FormatFloat(c.min, 'g', -1, 64))
+		case !(*c.valeur <= c.max):
+			return erreurs.FiltreInvalide.Champ(c.champ, erreurs.DetailMaximum, strconv.FormatFloat(c.max, 'g', -1, 64))
+		}
+	}
+	return nil
+}
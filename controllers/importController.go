@@ -0,0 +1,429 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+const (
+	importModeDryRun = "dry_run"
+	importModeCommit = "commit"
+
+	// Limites pour éviter qu'un fichier trop volumineux ne bloque le serveur
+	importTailleMax    = 5 << 20
+	importLignesMax    = 2000
+	importChampFichier = "fichier"
+)
+
+// Colonnes obligatoires par type d'annonce (la colonne photo reste facultative)
+var importColonnes = map[string][]string{
+	"vente": {"statut", "description", "type_culture", "parcelle_id", "quantite", "prix_kg"},
+	"achat": {"statut", "description", "type_culture", "quantite", "prix_kg"},
+	"pref":  {"statut", "description", "type_culture", "parcelle_id", "quantite", "prix_kg_pref"},
+}
+
+// ligneCSV associe les valeurs d'une ligne aux noms de colonnes de l'en-tête
+type ligneCSV map[string]string
+
+// importContexte contient les données de référence résolues une seule fois pour tout le fichier
+type importContexte struct {
+	userID       uuid.UUID
+	typesCulture map[string]uuid.UUID
+	parcelles    map[uuid.UUID]models.Parcelle
+}
+
+// ImportAnnonces importe en masse des annonces depuis un fichier CSV.
+// Paramètres : type=vente|achat|pref, mode=dry_run (par défaut)|commit.
+// En mode commit, toutes les lignes sont insérées dans une seule transaction
+// et rien n'est inséré si au moins une ligne est invalide.
+func ImportAnnonces(c *gin.Context) {
+	typeAnnonce := c.Query("type")
+	colonnes, ok := importColonnes[typeAnnonce]
+	if !ok {
+		erreurs.Repondre(c, erreurs.TypeAnnonceInconnu)
+		return
+	}
+
+	mode := c.DefaultQuery("mode", importModeDryRun)
+	if mode != importModeDryRun && mode != importModeCommit {
+		erreurs.Repondre(c, erreurs.ParametreInvalide.Champ("mode", erreurs.DetailValeurInconnue, importModeDryRun, importModeCommit))
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	contenu, err := lireFichierImport(c)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	entete, lignes, err := parseCSVImport(contenu)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+	if manquantes := colonnesManquantes(entete, colonnes); len(manquantes) > 0 {
+		erreurs.Repondre(c, erreurs.CSVColonnesManquantes.Avec(strings.Join(manquantes, ", ")))
+		return
+	}
+
+	ctx, err := chargerContexteImport(depotsDe(c), userID, lignes)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	rapport := models.RapportImport{
+		Type:    typeAnnonce,
+		Mode:    mode,
+		Total:   len(lignes),
+		Erreurs: []models.ErreurLigneImport{},
+	}
+
+	var annonces []interface{}
+	for i, ligne := range lignes {
+		annonce, problemes := construireAnnonceImport(typeAnnonce, ligne, ctx)
+		if len(problemes) > 0 {
+			// +2 : numérotation à partir de 1 et ligne d'en-tête
+			rapport.Erreurs = append(rapport.Erreurs, models.ErreurLigneImport{Ligne: i + 2, Erreurs: problemes})
+			continue
+		}
+		annonces = append(annonces, annonce)
+	}
+	rapport.Valides = len(annonces)
+
+	if mode == importModeDryRun {
+		c.JSON(http.StatusOK, rapport)
+		return
+	}
+
+	if len(rapport.Erreurs) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, rapport)
+		return
+	}
+
+	err = depotsDe(c).Transaction(func(tx depots.Depots) error {
+		for _, annonce := range annonces {
+			if err := creerAnnonceImport(tx, annonce); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	rapport.Importees = len(annonces)
+	alerterRecherchesImport(baseDe(c), annonces)
+	c.JSON(http.StatusCreated, rapport)
+}
+
+// lireFichierImport accepte soit un formulaire multipart (champ "fichier"), soit un corps text/csv brut
+func lireFichierImport(c *gin.Context) ([]byte, error) {
+	var reader io.Reader
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fichier, err := c.FormFile(importChampFichier)
+		if err != nil {
+			return nil, erreurs.CSVManquant
+		}
+		f, err := fichier.Open()
+		if err != nil {
+			return nil, erreurs.CSVIllisible
+		}
+		defer f.Close()
+		reader = f
+	} else {
+		reader = c.Request.Body
+	}
+
+	contenu, err := io.ReadAll(io.LimitReader(reader, importTailleMax+1))
+	if err != nil {
+		return nil, erreurs.CSVIllisible
+	}
+	if len(contenu) > importTailleMax {
+		return nil, erreurs.CSVTropVolumineux
+	}
+	if len(bytes.TrimSpace(contenu)) == 0 {
+		return nil, erreurs.CSVVide
+	}
+	return contenu, nil
+}
+
+// parseCSVImport lit l'en-tête et les lignes du fichier. Le séparateur « ; »
+// (export Excel en français) est détecté automatiquement.
+func parseCSVImport(contenu []byte) ([]string, []ligneCSV, error) {
+	contenu = bytes.TrimPrefix(contenu, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(contenu))
+	premiereLigne, _, _ := bytes.Cut(contenu, []byte("\n"))
+	if bytes.Count(premiereLigne, []byte(";")) > bytes.Count(premiereLigne, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	enregistrements, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, erreurs.CSVInvalide.Avec(err)
+	}
+	if len(enregistrements) < 2 {
+		return nil, nil, erreurs.CSVSansDonnees
+	}
+	if len(enregistrements)-1 > importLignesMax {
+		return nil, nil, erreurs.CSVTropDeLignes.Avec(importLignesMax)
+	}
+
+	entete := make([]string, len(enregistrements[0]))
+	for i, nom := range enregistrements[0] {
+		entete[i] = strings.ToLower(strings.TrimSpace(nom))
+	}
+
+	lignes := make([]ligneCSV, 0, len(enregistrements)-1)
+	for _, enregistrement := range enregistrements[1:] {
+		ligne := ligneCSV{}
+		for i, valeur := range enregistrement {
+			if i < len(entete) {
+				ligne[entete[i]] = strings.TrimSpace(valeur)
+			}
+		}
+		lignes = append(lignes, ligne)
+	}
+	return entete, lignes, nil
+}
+
+func colonnesManquantes(entete []string, attendues []string) []string {
+	presentes := map[string]bool{}
+	for _, nom := range entete {
+		presentes[nom] = true
+	}
+	var manquantes []string
+	for _, nom := range attendues {
+		if !presentes[nom] {
+			manquantes = append(manquantes, nom)
+		}
+	}
+	return manquantes
+}
+
+// chargerContexteImport résout une seule fois les types de culture (par libellé) et les
+// parcelles référencées dans le fichier ; une parcelle absente n'est pas dans le contexte
+func chargerContexteImport(d depots.Depots, userID uuid.UUID, lignes []ligneCSV) (importContexte, error) {
+	ctx := importContexte{
+		userID:       userID,
+		typesCulture: map[string]uuid.UUID{},
+		parcelles:    map[uuid.UUID]models.Parcelle{},
+	}
+
+	types, err := d.TypesCulture.Lister()
+	if err != nil {
+		return ctx, err
+	}
+	for _, t := range types {
+		ctx.typesCulture[normaliserLibelle(t.Libelle)] = t.ID
+	}
+
+	vues := map[uuid.UUID]bool{}
+	for _, ligne := range lignes {
+		id, err := uuid.Parse(ligne["parcelle_id"])
+		if err != nil || vues[id] {
+			continue
+		}
+		vues[id] = true
+		parcelle, err := d.Parcelles.Trouver(id)
+		if errors.Is(err, depots.ErrIntrouvable) {
+			continue
+		}
+		if err != nil {
+			return ctx, err
+		}
+		ctx.parcelles[id] = parcelle
+	}
+	return ctx, nil
+}
+
+// construireAnnonceImport valide une ligne avec les règles des entrées JSON et renvoie
+// l'annonce à insérer ou la liste des erreurs
+func construireAnnonceImport(typeAnnonce string, ligne ligneCSV, ctx importContexte) (interface{}, []string) {
+	var messages []string
+
+	// Le type de culture est désigné par son libellé : un libellé inconnu est signalé ici,
+	// les autres champs par les règles binding
+	typeCultureID, ok := ctx.typesCulture[normaliserLibelle(ligne["type_culture"])]
+	if !ok {
+		messages = append(messages, fmt.Sprintf("type de culture inconnu : '%s'", ligne["type_culture"]))
+	}
+	quantite := nombreImport(ligne["quantite"])
+
+	var entree interface{}
+	switch typeAnnonce {
+	case "vente":
+		entree = &CreateAnnonceVenteInput{
+			Statut:        ligne["statut"],
+			Description:   ligne["description"],
+			TypeCultureID: typeCultureID.String(),
+			ParcelleID:    ligne["parcelle_id"],
+			Quantite:      quantite,
+			PrixKg:        nombreImport(ligne["prix_kg"]),
+			Photo:         ligne["photo"],
+		}
+	case "achat":
+		entree = &CreateAnnonceAchatInput{
+			Statut:        ligne["statut"],
+			Description:   ligne["description"],
+			TypeCultureID: typeCultureID.String(),
+			Quantite:      quantite,
+			Prix:          nombreImport(ligne["prix_kg"]),
+		}
+	default:
+		entree = &CreateAnnoncePrefInput{
+			Statut:        ligne["statut"],
+			Description:   ligne["description"],
+			TypeCultureID: typeCultureID.String(),
+			ParcelleID:    ligne["parcelle_id"],
+			Quantite:      quantite,
+			PrixKgPref:    nombreImport(ligne["prix_kg_pref"]),
+		}
+	}
+	if err := validerEntree(entree); err != nil {
+		for _, d := range erreurs.Depuis(err).Details {
+			messages = append(messages, d.Champ+" : "+d.Message(erreurs.LangueFR))
+		}
+	}
+
+	var parcelleID uuid.UUID
+	if typeAnnonce != "achat" {
+		if id, err := uuid.Parse(ligne["parcelle_id"]); err == nil {
+			parcelleID = id
+			parcelle, ok := ctx.parcelles[parcelleID]
+			switch {
+			case !ok:
+				messages = append(messages, fmt.Sprintf("parcelle introuvable : '%s'", ligne["parcelle_id"]))
+			case !parcelle.ExploiteePar(ctx.userID):
+				messages = append(messages, fmt.Sprintf("parcelle d'un autre exploitant : '%s'", ligne["parcelle_id"]))
+			}
+		}
+	}
+
+	if len(messages) > 0 {
+		return nil, messages
+	}
+
+	switch e := entree.(type) {
+	case *CreateAnnonceVenteInput:
+		return &models.AnnonceVente{
+			ID:            uuid.New(),
+			UserID:        ctx.userID,
+			TypeCultureID: typeCultureID,
+			ParcelleID:    parcelleID,
+			Photo:         e.Photo,
+			Statut:        e.Statut,
+			Description:   e.Description,
+			Quantite:      float64(e.Quantite),
+			PrixKg:        float64(e.PrixKg),
+		}, nil
+	case *CreateAnnonceAchatInput:
+		return &models.AnnonceAchat{
+			ID:            uuid.New(),
+			UserID:        ctx.userID,
+			TypeCultureID: typeCultureID,
+			Statut:        e.Statut,
+			Description:   e.Description,
+			Quantite:      float64(e.Quantite),
+			Prix:          float64(e.Prix),
+		}, nil
+	default:
+		pref := entree.(*CreateAnnoncePrefInput)
+		return &models.AnnoncePrefinancement{
+			ID:                    uuid.New(),
+			UserID:                ctx.userID,
+			TypeCultureID:         typeCultureID,
+			ParcelleID:            parcelleID,
+			Statut:                pref.Statut,
+			Description:           pref.Description,
+			Quantite:              float64(pref.Quantite),
+			Prix:                  float64(pref.PrixKgPref),
+			MontantPrefinancement: float64(pref.PrixKgPref * pref.Quantite),
+		}, nil
+	}
+}
+
+// nombreImport lit un nombre d'une cellule CSV ; une cellule vide vaut 0 (champ obligatoire)
+// et une cellule illisible est signalée par la règle "nombre"
+func nombreImport(valeur string) Nombre {
+	if strings.TrimSpace(valeur) == "" {
+		return 0
+	}
+	n, err := parseNombre(valeur)
+	if err != nil {
+		return Nombre(math.NaN())
+	}
+	return Nombre(n)
+}
+
+func normaliserLibelle(libelle string) string {
+	return strings.ToLower(strings.TrimSpace(libelle))
+}
+
+// alerterRecherchesImport évalue les recherches sauvegardées pour les annonces importées,
+// les diffuse sur le flux temps réel et les compte dans les métriques (relations déjà
+// chargées par creerAnnonceImport)
+func alerterRecherchesImport(db *gorm.DB, annonces []interface{}) {
+	for _, annonce := range annonces {
+		switch a := annonce.(type) {
+		case *models.AnnonceVente:
+			alerterRecherches(db, models.AnnonceTypeVente, a.ID, a.Description, a.Criteres(), nil)
+			publierAnnonce(models.AnnonceTypeVente, a.ID, a.TypeCultureID, a.Parcelle.Adresse, nil, a.Statut, toAnnonceDTO(*a))
+			compterAnnonce(models.AnnonceTypeVente, a.TypeCulture.Libelle, nil, a.Statut, 0)
+		case *models.AnnonceAchat:
+			alerterRecherches(db, models.AnnonceTypeAchat, a.ID, a.Description, a.Criteres(), nil)
+			publierAnnonce(models.AnnonceTypeAchat, a.ID, a.TypeCultureID, "", nil, a.Statut, toAnnonceAchatDTO(*a))
+			compterAnnonce(models.AnnonceTypeAchat, a.TypeCulture.Libelle, nil, a.Statut, 0)
+		case *models.AnnoncePrefinancement:
+			publierAnnonce(models.AnnonceTypePref, a.ID, a.TypeCultureID, a.Parcelle.Adresse, nil, a.Statut, toAnnoncePrefDTO(*a))
+			compterAnnonce(models.AnnonceTypePref, a.TypeCulture.Libelle, nil, a.Statut, a.MontantPrefinancement)
+		}
+	}
+}
+
+// creerAnnonceImport insère une annonce importée et émet son événement webhook de création
+// (relations chargées par le dépôt)
+func creerAnnonceImport(tx depots.Depots, annonce interface{}) error {
+	switch a := annonce.(type) {
+	case *models.AnnonceVente:
+		if err := tx.Ventes.Creer(a); err != nil {
+			return err
+		}
+		return tx.Evenements.Emettre(models.EvenementAnnonceCreee, models.AnnonceTypeVente, a.ID, toAnnonceDTO(*a))
+	case *models.AnnonceAchat:
+		if err := tx.Achats.Creer(a); err != nil {
+			return err
+		}
+		return tx.Evenements.Emettre(models.EvenementAnnonceCreee, models.AnnonceTypeAchat, a.ID, toAnnonceAchatDTO(*a))
+	case *models.AnnoncePrefinancement:
+		if err := tx.Prefs.Creer(a); err != nil {
+			return err
+		}
+		return tx.Evenements.Emettre(models.EvenementAnnonceCreee, models.AnnonceTypePref, a.ID, toAnnoncePrefDTO(*a))
+	}
+	return nil
+}
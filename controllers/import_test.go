@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+func TestParseCSVImport(t *testing.T) {
+	// Export Excel en français : BOM, séparateur « ; », en-tête en majuscules
+	entete, lignes, err := parseCSVImport([]byte("\xef\xbb\xbfStatut; Description ;Quantite\nactive;Cacao, grade 1; 1200\nen_pause;Anacarde\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(entete, ",") != "statut,description,quantite" {
+		t.Errorf("en-tête %v", entete)
+	}
+	if len(lignes) != 2 || lignes[0]["description"] != "Cacao, grade 1" || lignes[0]["quantite"] != "1200" {
+		t.Fatalf("lignes %v", lignes)
+	}
+	if _, ok := lignes[1]["quantite"]; ok {
+		t.Errorf("cellule absente lue : %v", lignes[1])
+	}
+
+	if _, _, err := parseCSVImport([]byte("statut,description\n")); err != erreurs.CSVSansDonnees {
+		t.Errorf("fichier sans données : %v", err)
+	}
+	if _, _, err := parseCSVImport([]byte("statut,description\n\"active,cacao\n")); erreurs.Depuis(err).Code != erreurs.CSVInvalide.Code {
+		t.Errorf("guillemet non fermé : %v", err)
+	}
+}
+
+// importer envoie un CSV brut à la route d'import
+func (e *environnement) importer(t *testing.T, mode string, utilisateur uuid.UUID, lignes ...string) (*httptest.ResponseRecorder, models.RapportImport) {
+	t.Helper()
+	contenu := "statut,description,type_culture,parcelle_id,quantite,prix_kg\n" + strings.Join(lignes, "\n")
+	req := httptest.NewRequest(http.MethodPost, "/import/annonces?type=vente&mode="+mode, strings.NewReader(contenu))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set(enteteUtilisateur, utilisateur.String())
+	w := httptest.NewRecorder()
+	e.routeur.ServeHTTP(w, req)
+
+	var rapport models.RapportImport
+	if w.Code < http.StatusBadRequest || w.Code == http.StatusUnprocessableEntity {
+		if err := json.Unmarshal(w.Body.Bytes(), &rapport); err != nil {
+			t.Fatalf("rapport illisible : %v (%s)", err, w.Body.String())
+		}
+	}
+	return w, rapport
+}
+
+func nouvelEnvironnementImport(t *testing.T) *environnement {
+	e := nouvelEnvironnement(t)
+	e.routeur.POST("/import/annonces", func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader(enteteUtilisateur))
+	}, ImportAnnonces)
+	return e
+}
+
+func TestImportAnnonces(t *testing.T) {
+	e := nouvelEnvironnementImport(t)
+	// Parcelle antérieure à la colonne user_id : acceptée comme pour les créations unitaires
+	sansExploitant := models.Parcelle{ID: uuid.New(), Adresse: "Gagnoa, Gôh", Surface: "3 ha"}
+	e.memoire.AjouterParcelle(sansExploitant)
+	valides := []string{
+		"active,Fèves de cacao,cacao," + e.daloa.ID.String() + ",1200,1500",
+		"en_pause,Noix de cajou,Anacarde," + e.daloa.ID.String() + ",800,450",
+		"active,Cacao grade 2,Cacao," + sansExploitant.ID.String() + ",300,1400",
+	}
+	invalides := []string{
+		"active,Café,café," + e.daloa.ID.String() + ",100,900",
+		"active,Cacao,Cacao," + e.korhogo.ID.String() + ",100,900",
+		"active,Cacao,Cacao," + uuid.NewString() + ",100,900",
+		"inconnu,Cacao,Cacao," + e.daloa.ID.String() + ",beaucoup,900",
+	}
+
+	// Simulation : le rapport détaille les lignes invalides, rien n'est créé
+	w, rapport := e.importer(t, importModeDryRun, e.vendeur.ID, append(valides, invalides...)...)
+	if w.Code != http.StatusOK || rapport.Total != 7 || rapport.Valides != 3 || rapport.Importees != 0 {
+		t.Fatalf("simulation : statut %d, rapport %+v", w.Code, rapport)
+	}
+	attendues := map[int]string{
+		5: "type de culture inconnu",
+		6: "parcelle d'un autre exploitant",
+		7: "parcelle introuvable",
+	}
+	if len(rapport.Erreurs) != len(invalides) {
+		t.Fatalf("erreurs %+v", rapport.Erreurs)
+	}
+	for _, erreur := range rapport.Erreurs {
+		if debut, ok := attendues[erreur.Ligne]; ok && !strings.HasPrefix(erreur.Erreurs[0], debut) {
+			t.Errorf("ligne %d : %v, attendu %q", erreur.Ligne, erreur.Erreurs, debut)
+		}
+	}
+	if ligne8 := rapport.Erreurs[3]; ligne8.Ligne != 8 || len(ligne8.Erreurs) != 2 {
+		t.Errorf("ligne 8 : %+v, attendu statut et quantité invalides", ligne8)
+	}
+
+	// Une seule ligne invalide suffit à tout refuser
+	w, rapport = e.importer(t, importModeCommit, e.vendeur.ID, append(valides, invalides[1])...)
+	if w.Code != http.StatusUnprocessableEntity || rapport.Importees != 0 {
+		t.Errorf("import avec une ligne invalide : statut %d, rapport %+v", w.Code, rapport)
+	}
+	if n := len(decoderListe(t, e.requete(http.MethodGet, "/v1/annonces_vente", uuid.Nil, nil))); n != 0 {
+		t.Fatalf("%d annonces créées par un import refusé", n)
+	}
+
+	w, rapport = e.importer(t, importModeCommit, e.vendeur.ID, valides...)
+	if w.Code != http.StatusCreated || rapport.Importees != 3 {
+		t.Fatalf("import : statut %d, rapport %+v", w.Code, rapport)
+	}
+	annonces := decoderListe(t, e.requete(http.MethodGet, "/v1/annonces_vente", uuid.Nil, nil))
+	if len(annonces) != 3 {
+		t.Fatalf("%d annonces après import, attendu 3", len(annonces))
+	}
+	for _, a := range annonces {
+		if noms := e.evenementsDe(a["id"].(string)); len(noms) != 1 || noms[0] != models.EvenementAnnonceCreee {
+			t.Errorf("annonce %v : événements %v", a["id"], noms)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"mime"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+)
+
+// Content-Type des requêtes PATCH (RFC 7396). application/json est également accepté.
+const contentTypeMergePatch = "application/merge-patch+json"
+
+// champsImmuables ne peuvent être ni remplacés (PUT) ni modifiés (PATCH)
+var champsImmuables = []string{"id", "user_id", "version", "montant_pref", "cree_at", "créé_a"}
+
+// setterPatch applique la valeur d'un champ d'un merge patch ; nul vaut true pour un null explicite.
+// Le détail renvoyé en cas d'erreur est rattaché au champ par appliquerMergePatch.
+type setterPatch func(valeur json.RawMessage, nul bool) *erreurs.Detail
+
+// lireMergePatch décode le corps d'une requête PATCH. Le document doit être un objet JSON :
+// un document d'un autre type remplacerait l'annonce entière, ce que PATCH n'autorise pas.
+func lireMergePatch(c *gin.Context) (map[string]json.RawMessage, bool) {
+	if contentType := c.GetHeader("Content-Type"); contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType != contentTypeMergePatch && mediaType != binding.MIMEJSON {
+			erreurs.Repondre(c, erreurs.TypeMediaNonSupporte.Avec(contentTypeMergePatch))
+			return nil, false
+		}
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(c.Request.Body).Decode(&patch); err != nil || patch == nil {
+		erreurs.Repondre(c, erreurs.CorpsInvalide)
+		return nil, false
+	}
+	return patch, true
+}
+
+// appliquerMergePatch applique un merge patch champ par champ. Les champs immuables et
+// inconnus sont refusés ; tous les champs en erreur sont détaillés, dans un ordre stable.
+func appliquerMergePatch(patch map[string]json.RawMessage, setters map[string]setterPatch) error {
+	champs := make([]string, 0, len(patch))
+	for champ := range patch {
+		champs = append(champs, champ)
+	}
+	sort.Strings(champs)
+
+	e := erreurs.DonneesInvalides
+	for _, champ := range champs {
+		if contient(champsImmuables, champ) {
+			e = e.Champ(champ, erreurs.DetailImmuable)
+			continue
+		}
+		setter, ok := setters[champ]
+		if !ok {
+			e = e.Champ(champ, erreurs.DetailInconnu)
+			continue
+		}
+		valeur := patch[champ]
+		if d := setter(valeur, bytes.Equal(bytes.TrimSpace(valeur), []byte("null"))); d != nil {
+			e = e.Champ(champ, d.Code, d.Param)
+		}
+	}
+	if len(e.Details) > 0 {
+		return e
+	}
+	return nil
+}
+
+var (
+	detailObligatoire = &erreurs.Detail{Code: erreurs.DetailObligatoire}
+	detailTexte       = &erreurs.Detail{Code: erreurs.DetailType, Param: "string"}
+	detailNombre      = &erreurs.Detail{Code: erreurs.DetailType, Param: "number"}
+	detailUUID        = &erreurs.Detail{Code: erreurs.DetailFormat, Param: "uuid"}
+)
+
+// patchTexte modifie un texte ; null le vide si le champ est facultatif
+func patchTexte(dest *string, facultatif bool) setterPatch {
+	return func(valeur json.RawMessage, nul bool) *erreurs.Detail {
+		if nul {
+			if !facultatif {
+				return detailObligatoire
+			}
+			*dest = ""
+			return nil
+		}
+		if err := json.Unmarshal(valeur, dest); err != nil {
+			return detailTexte
+		}
+		return nil
+	}
+}
+
+// patchNombre modifie un nombre, fourni en nombre JSON ou en texte (voir Nombre)
+func patchNombre(dest *float64) setterPatch {
+	return func(valeur json.RawMessage, nul bool) *erreurs.Detail {
+		if nul {
+			return detailObligatoire
+		}
+		var n Nombre
+		if err := json.Unmarshal(valeur, &n); err != nil || math.IsNaN(float64(n)) {
+			return detailNombre
+		}
+		*dest = float64(n)
+		return nil
+	}
+}
+
+// patchUUID modifie une référence (type de culture, parcelle)
+func patchUUID(dest *uuid.UUID) setterPatch {
+	return func(valeur json.RawMessage, nul bool) *erreurs.Detail {
+		if nul {
+			return detailObligatoire
+		}
+		var texte string
+		if err := json.Unmarshal(valeur, &texte); err != nil {
+			return detailUUID
+		}
+		id, err := uuid.Parse(texte)
+		if err != nil {
+			return detailUUID
+		}
+		*dest = id
+		return nil
+	}
+}
+
+// lireRemplacement décode le corps d'une requête PUT : tous les champs obligatoires doivent
+// être présents et les champs inconnus ou immuables sont refusés
+func lireRemplacement(c *gin.Context, input interface{}) error {
+	corps, err := c.GetRawData()
+	if err != nil {
+		return erreurs.CorpsInvalide
+	}
+
+	var champs map[string]json.RawMessage
+	if err := json.Unmarshal(corps, &champs); err != nil || champs == nil {
+		return erreurs.CorpsInvalide
+	}
+	e := erreurs.DonneesInvalides
+	for _, champ := range champsImmuables {
+		if _, ok := champs[champ]; ok {
+			e = e.Champ(champ, erreurs.DetailImmuable)
+		}
+	}
+	if len(e.Details) > 0 {
+		return e
+	}
+
+	decodeur := json.NewDecoder(bytes.NewReader(corps))
+	decodeur.DisallowUnknownFields()
+	if err := decodeur.Decode(input); err != nil {
+		// Le décodeur signale un champ inconnu par un message : json: unknown field "x"
+		if champ, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return erreurs.DonneesInvalides.Champ(strings.Trim(champ, `"`), erreurs.DetailInconnu)
+		}
+		return erreurs.Validation(err)
+	}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return erreurs.Validation(err)
+	}
+	return nil
+}
+
+// verifierReferencesAnnonce vérifie que le type de culture et, le cas échéant, la parcelle
+// d'une annonce créée ou modifiée existent, et que la parcelle est exploitée par l'auteur
+func verifierReferencesAnnonce(c *gin.Context, auteurID uuid.UUID, typeCultureID uuid.UUID, parcelleID *uuid.UUID) bool {
+	d := depotsDe(c)
+	if _, err := d.TypesCulture.Trouver(typeCultureID); err != nil {
+		erreurs.Repondre(c, erreurs.TypeCultureInconnu)
+		return false
+	}
+	if parcelleID != nil {
+		parcelle, err := d.Parcelles.Trouver(*parcelleID)
+		if err != nil {
+			erreurs.Repondre(c, erreurs.ParcelleInconnue)
+			return false
+		}
+		if !parcelle.ExploiteePar(auteurID) {
+			erreurs.Repondre(c, erreurs.ParcelleInterdite)
+			return false
+		}
+	}
+	return true
+}
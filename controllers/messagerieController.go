@@ -0,0 +1,563 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/services"
+)
+
+const (
+	// Les pièces jointes sont stockées avec les uploads, dans un sous-dossier
+	// non exposé par /static (voir routes.FichiersPublics)
+	sousDossierPiecesJointes = "messages"
+	pieceJointeTailleMax     = 10 << 20
+	messageTailleMax         = 5000
+	messagesLimiteDefaut     = 50
+	messagesLimiteMax        = 200
+)
+
+var extensionsPiecesJointes = []string{".jpg", ".jpeg", ".png", ".pdf"}
+
+// demarrerConversation ouvre (ou retrouve) la conversation de l'utilisateur connecté
+// avec le propriétaire d'une annonce
+func demarrerConversation(c *gin.Context, annonceType string, annonceID uuid.UUID, proprietaireID uuid.UUID) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if userID == proprietaireID {
+		erreurs.Repondre(c, erreurs.ConversationPropreAnnonce)
+		return
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	var conversation models.Conversation
+	err := db.Where("annonce_type = ? AND annonce_id = ? AND interlocuteur_id = ?", annonceType, annonceID, userID).
+		First(&conversation).Error
+	if err == nil {
+		c.JSON(http.StatusOK, conversation)
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	conversation = models.Conversation{
+		ID:              uuid.New(),
+		AnnonceType:     annonceType,
+		AnnonceID:       annonceID,
+		ProprietaireID:  proprietaireID,
+		InterlocuteurID: userID,
+	}
+	if err := db.Create(&conversation).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+	// Une conversation n'est ni une offre ni un engagement : seule son ouverture est comptée
+	conversationsOuvertes.Inc(annonceType)
+
+	c.JSON(http.StatusCreated, conversation)
+}
+
+// Ouvrir une conversation avec le producteur d'une annonce de vente
+func StartConversationAnnonceVente(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+
+	annonce, err := depotsDe(c).Ventes.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return
+	}
+
+	demarrerConversation(c, models.AnnonceTypeVente, annonce.ID, annonce.UserID)
+}
+
+// Ouvrir une conversation avec le producteur d'une annonce de préfinancement
+func StartConversationAnnoncePref(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+
+	annonce, err := depotsDe(c).Prefs.Trouver(id)
+	if err != nil {
+		erreurs.Repondre(c, erreurAnnonce(err))
+		return
+	}
+
+	demarrerConversation(c, models.AnnonceTypePref, annonce.ID, annonce.UserID)
+}
+
+// Lister les conversations de l'utilisateur connecté, avec le dernier message et le nombre de non lus
+func GetMesConversations(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	var conversations []models.Conversation
+	if err := db.Where("proprietaire_id = ? OR interlocuteur_id = ?", userID, userID).
+		Order("updated_at DESC").Find(&conversations).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	ids := make([]uuid.UUID, len(conversations))
+	for i, conv := range conversations {
+		ids[i] = conv.ID
+	}
+	derniers, err := derniersMessages(db, ids)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+	nonLus, err := messagesNonLus(db, ids, userID)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	result := make([]models.ResumeConversation, 0, len(conversations))
+	for _, conv := range conversations {
+		resume := models.ResumeConversation{Conversation: conv, NonLus: nonLus[conv.ID]}
+		if dernier, ok := derniers[conv.ID]; ok {
+			resume.DernierMessage = &dernier
+		}
+		result = append(result, resume)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// derniersMessages renvoie, en une requête, le message le plus récent de chaque conversation
+func derniersMessages(db *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]models.Message, error) {
+	derniers := map[uuid.UUID]models.Message{}
+	if len(ids) == 0 {
+		return derniers, nil
+	}
+
+	var messages []models.Message
+	if err := db.Where("conversation_id IN ?", ids).
+		Where("created_at = (SELECT MAX(m.created_at) FROM messages m WHERE m.conversation_id = messages.conversation_id)").
+		Order("id").Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range messages {
+		// Deux messages de même date : le premier suffit
+		if _, ok := derniers[m.ConversationID]; !ok {
+			derniers[m.ConversationID] = m
+		}
+	}
+	return derniers, nil
+}
+
+// messagesNonLus compte, en une requête, les messages reçus et non lus par userID dans
+// chaque conversation
+func messagesNonLus(db *gorm.DB, ids []uuid.UUID, userID uuid.UUID) (map[uuid.UUID]int64, error) {
+	nonLus := map[uuid.UUID]int64{}
+	if len(ids) == 0 {
+		return nonLus, nil
+	}
+
+	var lignes []struct {
+		ConversationID uuid.UUID
+		Total          int64
+	}
+	if err := db.Model(&models.Message{}).
+		Select("conversation_id, COUNT(*) AS total").
+		Where("conversation_id IN ? AND auteur_id <> ? AND lu_le IS NULL", ids, userID).
+		Group("conversation_id").
+		Scan(&lignes).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range lignes {
+		nonLus[l.ConversationID] = l.Total
+	}
+	return nonLus, nil
+}
+
+// chargerConversation renvoie la conversation si l'utilisateur en est participant
+func chargerConversation(db *gorm.DB, conversationID uuid.UUID, userID uuid.UUID) (models.Conversation, error) {
+	var conversation models.Conversation
+	if err := db.First(&conversation, "id = ?", conversationID).Error; err != nil {
+		return conversation, erreurs.ConversationIntrouvable
+	}
+	if !conversation.EstParticipant(userID) {
+		return conversation, erreurs.ConversationInterdite
+	}
+	return conversation, nil
+}
+
+// trouverMaConversation charge la conversation de l'URL et vérifie que l'utilisateur y
+// participe. Sans base, elle répond 503 : après elle, baseDe(c) n'est jamais nil.
+func trouverMaConversation(c *gin.Context) (models.Conversation, uuid.UUID, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return models.Conversation{}, uuid.Nil, false
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return models.Conversation{}, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return models.Conversation{}, uuid.Nil, false
+	}
+
+	conversation, err := chargerConversation(db, id, userID)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return conversation, userID, false
+	}
+	return conversation, userID, true
+}
+
+// Lister les messages d'une conversation, du plus récent au plus ancien.
+// Pagination : limit et avant (date RFC 3339 du plus ancien message déjà reçu).
+func GetConversationMessages(c *gin.Context) {
+	conversation, _, ok := trouverMaConversation(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(messagesLimiteDefaut)))
+	if err != nil || limit <= 0 || limit > messagesLimiteMax {
+		limit = messagesLimiteDefaut
+	}
+
+	query := baseDe(c).Where("conversation_id = ?", conversation.ID)
+	if avant := c.Query("avant"); avant != "" {
+		date, err := time.Parse(time.RFC3339Nano, avant)
+		if err != nil {
+			erreurs.Repondre(c, erreurs.ParametreInvalide.Champ("avant", erreurs.DetailFormat, "RFC 3339"))
+			return
+		}
+		query = query.Where("created_at < ?", date)
+	}
+
+	messages := []models.Message{}
+	if err := query.Order("created_at DESC").Limit(limit).Find(&messages).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+// enregistrerMessage crée le message, le diffuse en temps réel aux participants
+// et notifie le destinataire s'il n'est pas connecté
+func enregistrerMessage(db *gorm.DB, conversation models.Conversation, auteurID uuid.UUID, contenu string, pieceJointe string, pieceJointeNom string) (models.Message, error) {
+	message := models.Message{
+		ID:             uuid.New(),
+		ConversationID: conversation.ID,
+		AuteurID:       auteurID,
+		Contenu:        contenu,
+		PieceJointe:    pieceJointe,
+		PieceJointeNom: pieceJointeNom,
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+		return tx.Model(&conversation).Update("updated_at", message.CreatedAt).Error
+	})
+	if err != nil {
+		return message, err
+	}
+
+	destinataireID := conversation.Destinataire(auteurID)
+	services.Hub.Diffuser(models.EvenementMessagerie{
+		Type:           "message",
+		ConversationID: conversation.ID,
+		Message:        &message,
+	}, auteurID, destinataireID)
+
+	if !services.Hub.EstConnecte(destinataireID) {
+		id := conversation.AnnonceID
+		notification := models.Notification{
+			UserID:      destinataireID,
+			Type:        models.NotificationMessageRecu,
+			Titre:       "Nouveau message",
+			Message:     apercuMessage(message),
+			AnnonceType: conversation.AnnonceType,
+			AnnonceID:   &id,
+		}
+		if err := services.Notifier(db, notification); err != nil {
+			log.Printf("Erreur notification du message %s : %v\n", message.ID, err)
+		}
+	}
+
+	return message, nil
+}
+
+func apercuMessage(m models.Message) string {
+	if m.Contenu == "" {
+		return "Pièce jointe : " + m.PieceJointeNom
+	}
+	runes := []rune(m.Contenu)
+	if len(runes) > 100 {
+		return string(runes[:100]) + "…"
+	}
+	return m.Contenu
+}
+
+func validerContenuMessage(contenu string, avecPieceJointe bool) (string, error) {
+	contenu = strings.TrimSpace(contenu)
+	if contenu == "" && !avecPieceJointe {
+		return "", erreurs.MessageVide
+	}
+	if len([]rune(contenu)) > messageTailleMax {
+		return "", erreurs.MessageTropLong.Avec(messageTailleMax)
+	}
+	return contenu, nil
+}
+
+// enregistrerPieceJointe copie le fichier envoyé dans le dossier des pièces jointes
+func enregistrerPieceJointe(c *gin.Context) (string, string, error) {
+	fichier, err := c.FormFile("piece_jointe")
+	if err != nil {
+		return "", "", nil
+	}
+	if fichier.Size > pieceJointeTailleMax {
+		return "", "", erreurs.PieceJointeTropVolumineuse
+	}
+	extension := strings.ToLower(filepath.Ext(fichier.Filename))
+	if !contient(extensionsPiecesJointes, extension) {
+		return "", "", erreurs.PieceJointeTypeInterdit
+	}
+
+	dossier := filepath.Join(configDe(c).Serveur.DossierUploads, sousDossierPiecesJointes)
+	if err := os.MkdirAll(dossier, 0o755); err != nil {
+		return "", "", err
+	}
+	chemin := filepath.Join(dossier, uuid.New().String()+extension)
+	if err := c.SaveUploadedFile(fichier, chemin); err != nil {
+		return "", "", err
+	}
+	return chemin, filepath.Base(fichier.Filename), nil
+}
+
+// Envoyer un message (JSON {"contenu": ...} ou formulaire multipart avec piece_jointe)
+func PostConversationMessage(c *gin.Context) {
+	conversation, userID, ok := trouverMaConversation(c)
+	if !ok {
+		return
+	}
+
+	var contenu, pieceJointe, pieceJointeNom string
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		var err error
+		pieceJointe, pieceJointeNom, err = enregistrerPieceJointe(c)
+		if err != nil {
+			erreurs.Repondre(c, err)
+			return
+		}
+		contenu = c.PostForm("contenu")
+	} else {
+		var input struct {
+			Contenu string `json:"contenu"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			erreurs.Repondre(c, erreurs.Validation(err))
+			return
+		}
+		contenu = input.Contenu
+	}
+
+	contenu, err := validerContenuMessage(contenu, pieceJointe != "")
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	message, err := enregistrerMessage(baseDe(c), conversation, userID, contenu, pieceJointe, pieceJointeNom)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
+
+// marquerConversationLue renseigne l'accusé de lecture des messages reçus par le lecteur
+// et prévient l'autre participant
+func marquerConversationLue(db *gorm.DB, conversation models.Conversation, lecteurID uuid.UUID) (int64, error) {
+	maintenant := time.Now()
+	res := db.Model(&models.Message{}).
+		Where("conversation_id = ? AND auteur_id <> ? AND lu_le IS NULL", conversation.ID, lecteurID).
+		Update("lu_le", maintenant)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+
+	if res.RowsAffected > 0 {
+		services.Hub.Diffuser(models.EvenementMessagerie{
+			Type:           "lu",
+			ConversationID: conversation.ID,
+			LecteurID:      &lecteurID,
+			LuLe:           &maintenant,
+		}, conversation.Destinataire(lecteurID))
+	}
+	return res.RowsAffected, nil
+}
+
+// Marquer les messages reçus d'une conversation comme lus
+func MarquerConversationLue(c *gin.Context) {
+	conversation, userID, ok := trouverMaConversation(c)
+	if !ok {
+		return
+	}
+
+	total, err := marquerConversationLue(baseDe(c), conversation, userID)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Messages marqués comme lus", "total": total})
+}
+
+// Télécharger la pièce jointe d'un message (participants uniquement)
+func GetPieceJointeMessage(c *gin.Context) {
+	conversation, _, ok := trouverMaConversation(c)
+	if !ok {
+		return
+	}
+	messageID, err := uuid.Parse(c.Param("message_id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide.Champ("message_id", erreurs.DetailFormat, "uuid"))
+		return
+	}
+
+	var message models.Message
+	if err := baseDe(c).First(&message, "id = ? AND conversation_id = ?", messageID, conversation.ID).Error; err != nil || message.PieceJointe == "" {
+		erreurs.Repondre(c, erreurs.PieceJointeIntrouvable)
+		return
+	}
+
+	c.FileAttachment(message.PieceJointe, message.PieceJointeNom)
+}
+
+// clientWebSocket sérialise les écritures sur une connexion WebSocket
+type clientWebSocket struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (c *clientWebSocket) Envoyer(e models.EvenementMessagerie) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return websocket.JSON.Send(c.conn, e)
+}
+
+func (c *clientWebSocket) Fermer() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.Close()
+}
+
+// commandeWebSocket est un message envoyé par le client sur la WebSocket
+type commandeWebSocket struct {
+	Type           string    `json:"type"` // "message" ou "lu"
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Contenu        string    `json:"contenu"`
+}
+
+// Canal WebSocket de la messagerie : reçoit en temps réel les messages et accusés de lecture
+// de toutes les conversations de l'utilisateur, et permet d'envoyer des messages texte.
+// Les routes REST restent disponibles en repli.
+func ConversationsWebSocket(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	langue, requestID := erreurs.Langue(c), c.GetString(erreurs.CleRequestID)
+	serveur := websocket.Server{
+		// Les clients mobiles n'envoient pas d'en-tête Origin : l'authentification
+		// est assurée par le token vérifié avant la mise à niveau
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(conn *websocket.Conn) {
+			client := &clientWebSocket{conn: conn}
+			deconnecter := services.Hub.Connecter(userID, client)
+			defer deconnecter()
+
+			for {
+				var commande commandeWebSocket
+				if err := websocket.JSON.Receive(conn, &commande); err != nil {
+					return
+				}
+				if err := traiterCommandeWebSocket(db, userID, commande); err != nil {
+					e := erreurs.Depuis(err)
+					if e.Statut >= http.StatusInternalServerError {
+						log.Printf("[%s] WebSocket messagerie : %v\n", requestID, e)
+					}
+					corps := erreurs.Representer(e, langue, requestID)
+					client.Envoyer(models.EvenementMessagerie{
+						Type:           "erreur",
+						ConversationID: commande.ConversationID,
+						Erreur:         &corps,
+					})
+				}
+			}
+		},
+	}
+	serveur.ServeHTTP(c.Writer, c.Request)
+}
+
+func traiterCommandeWebSocket(db *gorm.DB, userID uuid.UUID, commande commandeWebSocket) error {
+	conversation, err := chargerConversation(db, commande.ConversationID, userID)
+	if err != nil {
+		return err
+	}
+
+	switch commande.Type {
+	case "message":
+		contenu, err := validerContenuMessage(commande.Contenu, false)
+		if err != nil {
+			return err
+		}
+		_, err = enregistrerMessage(db, conversation, userID, contenu, "", "")
+		return err
+	case "lu":
+		_, err := marquerConversationLue(db, conversation, userID)
+		return err
+	default:
+		return erreurs.CommandeInconnue.Avec(commande.Type)
+	}
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// La liste des conversations charge derniers messages et non lus en un nombre fixe de requêtes
+func TestMesConversations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Conversation{}, &models.Message{}); err != nil {
+		t.Fatal(err)
+	}
+
+	moi, vendeur, acheteur := uuid.New(), uuid.New(), uuid.New()
+	debut := time.Now().Add(-time.Hour).UTC()
+	conversations := []models.Conversation{
+		{ID: uuid.New(), AnnonceType: models.AnnonceTypeVente, AnnonceID: uuid.New(), ProprietaireID: vendeur, InterlocuteurID: moi},
+		{ID: uuid.New(), AnnonceType: models.AnnonceTypePref, AnnonceID: uuid.New(), ProprietaireID: moi, InterlocuteurID: acheteur},
+		{ID: uuid.New(), AnnonceType: models.AnnonceTypeVente, AnnonceID: uuid.New(), ProprietaireID: vendeur, InterlocuteurID: moi},
+	}
+	lu := debut
+	messages := []models.Message{
+		{ID: uuid.New(), ConversationID: conversations[0].ID, AuteurID: moi, Contenu: "Bonjour", CreatedAt: debut},
+		{ID: uuid.New(), ConversationID: conversations[0].ID, AuteurID: vendeur, Contenu: "Disponible", CreatedAt: debut.Add(time.Minute)},
+		{ID: uuid.New(), ConversationID: conversations[0].ID, AuteurID: vendeur, Contenu: "Livraison lundi", CreatedAt: debut.Add(2 * time.Minute)},
+		{ID: uuid.New(), ConversationID: conversations[1].ID, AuteurID: acheteur, Contenu: "Intéressé", CreatedAt: debut, LuLe: &lu},
+		{ID: uuid.New(), ConversationID: conversations[1].ID, AuteurID: moi, Contenu: "Parfait", CreatedAt: debut.Add(time.Minute)},
+	}
+	if err := db.Create(&conversations).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&messages).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	requetes := 0
+	compter := func(*gorm.DB) { requetes++ }
+	db.Callback().Query().After("gorm:query").Register("test:compter_query", compter)
+	db.Callback().Row().After("gorm:row").Register("test:compter_row", compter)
+
+	r := gin.New()
+	r.Use(Injecter(Dependances{DB: db}))
+	r.GET("/conversations", func(c *gin.Context) {
+		c.Set("user_id", moi.String())
+	}, GetMesConversations)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/conversations", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("statut %d (%s)", w.Code, w.Body.String())
+	}
+	if requetes != 3 {
+		t.Errorf("%d requêtes SQL, attendu 3", requetes)
+	}
+
+	var resumes []models.ResumeConversation
+	if err := json.Unmarshal(w.Body.Bytes(), &resumes); err != nil {
+		t.Fatal(err)
+	}
+	attendus := map[uuid.UUID]struct {
+		dernier string
+		nonLus  int64
+	}{
+		conversations[0].ID: {"Livraison lundi", 2},
+		conversations[1].ID: {"Parfait", 0},
+		conversations[2].ID: {"", 0},
+	}
+	if len(resumes) != len(attendus) {
+		t.Fatalf("%d conversations, attendu %d", len(resumes), len(attendus))
+	}
+	for _, resume := range resumes {
+		attendu := attendus[resume.ID]
+		dernier := ""
+		if resume.DernierMessage != nil {
+			dernier = resume.DernierMessage.Contenu
+		}
+		if dernier != attendu.dernier || resume.NonLus != attendu.nonLus {
+			t.Errorf("conversation %s : dernier %q, %d non lus ; attendu %q, %d", resume.ID, dernier, resume.NonLus, attendu.dernier, attendu.nonLus)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"github.com/Steph-business/annonce_de_vente/metriques"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// Compteurs métier exposés sous /metrics. Les montants sont en FCFA.
+var (
+	annoncesCreees = metriques.Defaut.Compteur("annonces_creees_total",
+		"Annonces créées (API et import CSV), par type et type de culture", "type", "type_culture")
+	conversationsOuvertes = metriques.Defaut.Compteur("conversations_ouvertes_total",
+		"Conversations ouvertes avec le producteur d'une annonce, par type d'annonce", "type")
+	transactionsConclues = metriques.Defaut.Compteur("transactions_conclues_total",
+		"Transactions conclues par le producteur (vente réalisée, préfinancement livré), par type d'annonce", "type")
+	montantPrefDemande = metriques.Defaut.Compteur("prefinancements_montant_demande_fcfa_total",
+		"Montant demandé par les annonces de préfinancement créées")
+	prefFinancees = metriques.Defaut.Compteur("prefinancements_finances_total",
+		"Annonces de préfinancement passées au statut financee")
+	montantPrefFinance = metriques.Defaut.Compteur("prefinancements_montant_finance_fcfa_total",
+		"Montant des annonces de préfinancement passées au statut financee")
+)
+
+// compterAnnonce met à jour les compteurs métier après la création (ancienStatut nil) ou
+// la modification d'une annonce. montant est celui du préfinancement (0 pour les autres types).
+func compterAnnonce(annonceType string, culture string, ancienStatut *string, nouveauStatut string, montant float64) {
+	if montant < 0 {
+		montant = 0
+	}
+	if ancienStatut == nil {
+		annoncesCreees.Inc(annonceType, culture)
+		if annonceType == models.AnnonceTypePref {
+			montantPrefDemande.Ajouter(montant)
+		}
+	}
+	if annonceType == models.AnnonceTypePref && nouveauStatut == models.StatutFinancee &&
+		(ancienStatut == nil || *ancienStatut != models.StatutFinancee) {
+		prefFinancees.Inc()
+		montantPrefFinance.Ajouter(montant)
+	}
+}
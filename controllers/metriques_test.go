@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Steph-business/annonce_de_vente/metriques"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// valeurMetrique lit la valeur d'une série dans la sortie du registre (0 si absente)
+func valeurMetrique(t *testing.T, serie string) float64 {
+	t.Helper()
+	var sortie strings.Builder
+	if err := metriques.Defaut.Ecrire(&sortie); err != nil {
+		t.Fatal(err)
+	}
+	for _, ligne := range strings.Split(sortie.String(), "\n") {
+		if valeur, ok := strings.CutPrefix(ligne, serie+" "); ok {
+			v, err := strconv.ParseFloat(valeur, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return v
+		}
+	}
+	return 0
+}
+
+func TestMetriquesAnnonces(t *testing.T) {
+	e := nouvelEnvironnement(t)
+	creees := `annonces_creees_total{type="pref",type_culture="Cacao"}`
+	avant := map[string]float64{}
+	for _, serie := range []string{creees, "prefinancements_montant_demande_fcfa_total", "prefinancements_finances_total", "prefinancements_montant_finance_fcfa_total"} {
+		avant[serie] = valeurMetrique(t, serie)
+	}
+
+	var pref typeTest
+	for _, tt := range typesTest {
+		if tt.annonceType == models.AnnonceTypePref {
+			pref = tt
+		}
+	}
+	e.creer(t, pref, e.vendeur.ID, nil)
+	e.creer(t, pref, e.vendeur.ID, map[string]interface{}{"statut": models.StatutFinancee})
+
+	attendues := map[string]float64{
+		creees: 2,
+		"prefinancements_montant_demande_fcfa_total": 2 * 800 * 1000,
+		"prefinancements_finances_total":             1,
+		"prefinancements_montant_finance_fcfa_total": 800 * 1000,
+	}
+	for serie, ecart := range attendues {
+		if v := valeurMetrique(t, serie) - avant[serie]; v != ecart {
+			t.Errorf("%s : +%v, attendu +%v", serie, v, ecart)
+		}
+	}
+}
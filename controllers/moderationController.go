@@ -0,0 +1,350 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/services"
+)
+
+type SignalementInput struct {
+	Motif       string `json:"motif" binding:"required"`
+	Commentaire string `json:"commentaire"`
+}
+
+type DecisionModerationInput struct {
+	Motif string `json:"motif"`
+	// DureeJours ne concerne que le bannissement : vide ou 0 pour un bannissement définitif
+	DureeJours int `json:"duree_jours"`
+}
+
+// tableAnnonce renvoie le modèle correspondant à un type d'annonce
+func tableAnnonce(annonceType string) (interface{}, bool) {
+	switch annonceType {
+	case models.AnnonceTypeVente:
+		return &models.AnnonceVente{}, true
+	case models.AnnonceTypeAchat:
+		return &models.AnnonceAchat{}, true
+	case models.AnnonceTypePref:
+		return &models.AnnoncePrefinancement{}, true
+	}
+	return nil, false
+}
+
+// proprietaireAnnonce renvoie l'auteur d'une annonce, lue dans les dépôts
+func proprietaireAnnonce(d depots.Depots, annonceType string, annonceID uuid.UUID) (uuid.UUID, error) {
+	switch annonceType {
+	case models.AnnonceTypeVente:
+		annonce, err := d.Ventes.Trouver(annonceID)
+		return annonce.UserID, erreurAnnonce(err)
+	case models.AnnonceTypeAchat:
+		annonce, err := d.Achats.Trouver(annonceID)
+		return annonce.UserID, erreurAnnonce(err)
+	case models.AnnonceTypePref:
+		annonce, err := d.Prefs.Trouver(annonceID)
+		return annonce.UserID, erreurAnnonce(err)
+	}
+	return uuid.Nil, erreurs.TypeAnnonceInconnu
+}
+
+// signalerAnnonce enregistre le signalement de l'utilisateur connecté et masque
+// l'annonce si le seuil de signalements en attente est atteint
+func signalerAnnonce(c *gin.Context, annonceType string) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	annonceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+
+	var input SignalementInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		erreurs.Repondre(c, erreurs.Validation(err))
+		return
+	}
+	if !contient(models.MotifsSignalement, input.Motif) {
+		erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("motif", erreurs.DetailValeurInconnue, models.MotifsSignalement...))
+		return
+	}
+	commentaire, err := validerCommentaire("commentaire", input.Commentaire)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	proprietaireID, err := proprietaireAnnonce(depotsDe(c), annonceType, annonceID)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+	if proprietaireID == userID {
+		erreurs.Repondre(c, erreurs.SignalementPropreAnnonce)
+		return
+	}
+
+	var existant int64
+	db.Model(&models.Signalement{}).
+		Where("annonce_type = ? AND annonce_id = ? AND auteur_id = ?", annonceType, annonceID, userID).
+		Count(&existant)
+	if existant > 0 {
+		erreurs.Repondre(c, erreurs.SignalementExistant)
+		return
+	}
+
+	signalement := models.Signalement{
+		ID:          uuid.New(),
+		AnnonceType: annonceType,
+		AnnonceID:   annonceID,
+		AuteurID:    userID,
+		Motif:       input.Motif,
+		Commentaire: commentaire,
+		Statut:      models.SignalementEnAttente,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&signalement).Error; err != nil {
+			return err
+		}
+
+		var moderation models.ModerationAnnonce
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("annonce_type = ? AND annonce_id = ?", annonceType, annonceID).
+			Limit(1).Find(&moderation).Error; err != nil {
+			return err
+		}
+		moderation.AnnonceType = annonceType
+		moderation.AnnonceID = annonceID
+
+		if err := tx.Model(&models.Signalement{}).
+			Where("annonce_type = ? AND annonce_id = ? AND statut = ?", annonceType, annonceID, models.SignalementEnAttente).
+			Count(&moderation.NbSignalements).Error; err != nil {
+			return err
+		}
+
+		// Une annonce masquée par un modérateur le reste ; sinon elle revient dans la file
+		if moderation.Statut != models.ModerationMasquee {
+			moderation.Statut = models.ModerationSignalee
+			if moderation.NbSignalements >= configDe(c).Moderation.SeuilSignalements {
+				moderation.Statut = models.ModerationMasqueeAuto
+			}
+		}
+		return tx.Save(&moderation).Error
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, signalement)
+}
+
+// Signaler une annonce de vente
+func SignalerAnnonceVente(c *gin.Context) {
+	signalerAnnonce(c, models.AnnonceTypeVente)
+}
+
+// Signaler une annonce d'achat
+func SignalerAnnonceAchat(c *gin.Context) {
+	signalerAnnonce(c, models.AnnonceTypeAchat)
+}
+
+// Signaler une annonce de préfinancement
+func SignalerAnnoncePref(c *gin.Context) {
+	signalerAnnonce(c, models.AnnonceTypePref)
+}
+
+// File de modération : annonces en attente de décision, les plus signalées d'abord
+// (filtres facultatifs : statut, annonce_type)
+func GetFileModeration(c *gin.Context) {
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	statuts := []string{models.ModerationSignalee, models.ModerationMasqueeAuto}
+	if statut := c.Query("statut"); statut != "" {
+		statuts = strings.Split(statut, ",")
+	}
+
+	query := db.Where("statut IN ?", statuts)
+	if annonceType := c.Query("annonce_type"); annonceType != "" {
+		query = query.Where("annonce_type = ?", annonceType)
+	}
+
+	var moderations []models.ModerationAnnonce
+	if err := query.Order("nb_signalements DESC, updated_at").Limit(100).Find(&moderations).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	result := make([]models.ElementModeration, 0, len(moderations))
+	for _, m := range moderations {
+		element := models.ElementModeration{ModerationAnnonce: m, Signalements: []models.Signalement{}}
+		db.Where("annonce_type = ? AND annonce_id = ? AND statut = ?", m.AnnonceType, m.AnnonceID, models.SignalementEnAttente).
+			Order("created_at").Find(&element.Signalements)
+		result = append(result, element)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// decisionModeration applique une décision de modérateur à une annonce : nouvel état,
+// clôture des signalements en attente et trace dans le journal d'audit. Pour un
+// bannissement, l'auteur de l'annonce est en plus suspendu.
+func decisionModeration(c *gin.Context, statut string, action string) {
+	moderateurID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	annonceType := c.Param("type")
+	annonceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+
+	var input DecisionModerationInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			erreurs.Repondre(c, erreurs.Validation(err))
+			return
+		}
+	}
+	if action != models.AuditApprouverAnnonce && strings.TrimSpace(input.Motif) == "" {
+		erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("motif", erreurs.DetailObligatoire))
+		return
+	}
+	if input.DureeJours < 0 {
+		erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("duree_jours", erreurs.DetailMinimum, "0"))
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	proprietaireID, err := proprietaireAnnonce(depotsDe(c), annonceType, annonceID)
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	maintenant := time.Now()
+	moderation := models.ModerationAnnonce{
+		AnnonceType:  annonceType,
+		AnnonceID:    annonceID,
+		Statut:       statut,
+		ModerateurID: &moderateurID,
+		ModereLe:     &maintenant,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Signalement{}).
+			Where("annonce_type = ? AND annonce_id = ? AND statut = ?", annonceType, annonceID, models.SignalementEnAttente).
+			Update("statut", models.SignalementTraite).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(&moderation).Error; err != nil {
+			return err
+		}
+		if err := services.Journaliser(tx, models.JournalAudit{
+			ActeurID:    moderateurID,
+			Action:      action,
+			CibleType:   models.CibleAnnonce,
+			CibleID:     annonceID,
+			AnnonceType: annonceType,
+			Motif:       input.Motif,
+		}); err != nil {
+			return err
+		}
+		if action != models.AuditBannirAuteur {
+			return nil
+		}
+
+		suspension := models.Suspension{
+			UserID:       proprietaireID,
+			Motif:        input.Motif,
+			ModerateurID: moderateurID,
+		}
+		if input.DureeJours > 0 {
+			fin := maintenant.AddDate(0, 0, input.DureeJours)
+			suspension.FinLe = &fin
+		}
+		if err := tx.Save(&suspension).Error; err != nil {
+			return err
+		}
+		return services.Journaliser(tx, models.JournalAudit{
+			ActeurID:  moderateurID,
+			Action:    action,
+			CibleType: models.CibleUtilisateur,
+			CibleID:   proprietaireID,
+			Motif:     input.Motif,
+		})
+	})
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, moderation)
+}
+
+// Approuver une annonce signalée : elle redevient visible et ses signalements sont clos
+func ApprouverAnnonce(c *gin.Context) {
+	decisionModeration(c, models.ModerationApprouvee, models.AuditApprouverAnnonce)
+}
+
+// Masquer une annonce (motif obligatoire)
+func MasquerAnnonce(c *gin.Context) {
+	decisionModeration(c, models.ModerationMasquee, models.AuditMasquerAnnonce)
+}
+
+// Masquer une annonce et bannir son auteur (motif obligatoire, duree_jours facultatif)
+func BannirAuteurAnnonce(c *gin.Context) {
+	decisionModeration(c, models.ModerationMasquee, models.AuditBannirAuteur)
+}
+
+// Consulter le journal d'audit (filtres facultatifs : action, acteur_id, cible_id)
+func GetJournalAudit(c *gin.Context) {
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	query := db.Model(&models.JournalAudit{})
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if acteurID := c.Query("acteur_id"); acteurID != "" {
+		query = query.Where("acteur_id = ?", acteurID)
+	}
+	if cibleID := c.Query("cible_id"); cibleID != "" {
+		query = query.Where("cible_id = ?", cibleID)
+	}
+
+	entrees := []models.JournalAudit{}
+	if err := query.Order("created_at DESC").Limit(200).Find(&entrees).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entrees)
+}
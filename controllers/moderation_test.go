@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// ajouterUtilisateur crée un utilisateur de plus dans la base de l'environnement
+func (e *environnement) ajouterUtilisateur(t *testing.T, nom string) models.User {
+	t.Helper()
+	utilisateur := models.User{ID: uuid.New(), Nom: nom}
+	if err := e.base.Create(&utilisateur).Error; err != nil {
+		t.Fatal(err)
+	}
+	return utilisateur
+}
+
+// admin renvoie le groupe /v1/admin, sans le contrôle du profil administrateur
+// (AdminMiddleware) : les tests visent les handlers
+func (e *environnement) admin() *gin.RouterGroup {
+	return e.groupe(models.VersionAPI1).Group("/admin")
+}
+
+func (e *environnement) monterModeration() {
+	g := e.groupe(models.VersionAPI1)
+	g.POST("/annonces_vente/:id/signaler", SignalerAnnonceVente)
+	g.POST("/annonces_achat/:id/signaler", SignalerAnnonceAchat)
+	g.POST("/annonces_pref/:id/signaler", SignalerAnnoncePref)
+
+	admin := e.admin()
+	admin.GET("/moderation", GetFileModeration)
+	admin.POST("/moderation/:type/:id/approuver", ApprouverAnnonce)
+	admin.POST("/moderation/:type/:id/masquer", MasquerAnnonce)
+	admin.POST("/moderation/:type/:id/bannir", BannirAuteurAnnonce)
+	admin.GET("/audit", GetJournalAudit)
+}
+
+// idsListe renvoie les identifiants d'une liste publique
+func (e *environnement) idsListe(t *testing.T, chemin string) map[string]bool {
+	t.Helper()
+	ids := map[string]bool{}
+	for _, a := range decoderListe(t, e.requete(http.MethodGet, chemin, uuid.Nil, nil)) {
+		ids[a["id"].(string)] = true
+	}
+	return ids
+}
+
+// Le seuil de signalements masque l'annonce jusqu'à la décision d'un modérateur
+func TestSignalementsEtModeration(t *testing.T) {
+	e := nouvelEnvironnementBase(t)
+	e.monterModeration()
+	moderateur := e.ajouterUtilisateur(t, "Modérateur")
+	vente := e.creer(t, typesTest[0], e.vendeur.ID, nil)["id"].(string)
+	signaler := "/v1/annonces_vente/" + vente + "/signaler"
+	signalement := map[string]interface{}{"motif": models.MotifPrixAbusif}
+
+	w := e.requete(http.MethodPost, signaler, e.vendeur.ID, signalement)
+	verifierErreur(t, w, http.StatusBadRequest, "signalement_propre_annonce")
+	w = e.requete(http.MethodPost, signaler, e.acheteur.ID, map[string]interface{}{"motif": "pas_un_motif"})
+	verifierErreur(t, w, http.StatusBadRequest, "donnees_invalides")
+	w = e.requete(http.MethodPost, "/v1/annonces_vente/"+uuid.NewString()+"/signaler", e.acheteur.ID, signalement)
+	verifierErreur(t, w, http.StatusNotFound, "annonce_introuvable")
+
+	// Seuil par défaut : 3 signalements en attente
+	rapporteurs := []uuid.UUID{e.acheteur.ID, e.ajouterUtilisateur(t, "Koffi").ID, e.ajouterUtilisateur(t, "Adjoua").ID}
+	for i, rapporteur := range rapporteurs {
+		if w := e.requete(http.MethodPost, signaler, rapporteur, signalement); w.Code != http.StatusCreated {
+			t.Fatalf("signalement %d : statut %d (%s)", i+1, w.Code, w.Body.String())
+		}
+		if visible := e.idsListe(t, "/v1/annonces_vente")[vente]; visible != (i < len(rapporteurs)-1) {
+			t.Errorf("après %d signalement(s) : annonce visible %v", i+1, visible)
+		}
+	}
+	w = e.requete(http.MethodPost, signaler, e.acheteur.ID, signalement)
+	verifierErreur(t, w, http.StatusConflict, "signalement_existant")
+
+	w = e.requete(http.MethodGet, "/v1/admin/moderation", moderateur.ID, nil)
+	file := decoderListe(t, w)
+	if len(file) != 1 || file[0]["statut"] != models.ModerationMasqueeAuto || len(file[0]["signalements"].([]interface{})) != 3 {
+		t.Fatalf("file de modération %v, attendu l'annonce masquée avec ses 3 signalements", file)
+	}
+
+	// L'approbation rend l'annonce visible et vide la file
+	decision := "/v1/admin/moderation/" + models.AnnonceTypeVente + "/" + vente
+	if w := e.requete(http.MethodPost, decision+"/approuver", moderateur.ID, nil); w.Code != http.StatusOK {
+		t.Fatalf("approbation : statut %d (%s)", w.Code, w.Body.String())
+	}
+	if !e.idsListe(t, "/v1/annonces_vente")[vente] {
+		t.Error("annonce approuvée absente de la liste")
+	}
+	if file := decoderListe(t, e.requete(http.MethodGet, "/v1/admin/moderation", moderateur.ID, nil)); len(file) != 0 {
+		t.Errorf("%d annonce(s) dans la file après approbation", len(file))
+	}
+
+	// Le masquage exige un motif
+	w = e.requete(http.MethodPost, decision+"/masquer", moderateur.ID, nil)
+	verifierErreur(t, w, http.StatusBadRequest, "donnees_invalides")
+	if w := e.requete(http.MethodPost, decision+"/masquer", moderateur.ID, map[string]interface{}{"motif": "Prix trompeur"}); w.Code != http.StatusOK {
+		t.Fatalf("masquage : statut %d (%s)", w.Code, w.Body.String())
+	}
+	if e.idsListe(t, "/v1/annonces_vente")[vente] {
+		t.Error("annonce masquée toujours listée")
+	}
+	// Un nouveau signalement ne fait pas revenir l'annonce masquée par un modérateur
+	if w := e.requete(http.MethodPost, signaler, e.ajouterUtilisateur(t, "Konan").ID, signalement); w.Code != http.StatusCreated {
+		t.Fatalf("signalement après masquage : statut %d", w.Code)
+	}
+	var moderation models.ModerationAnnonce
+	e.base.First(&moderation, "annonce_id = ?", vente)
+	if moderation.Statut != models.ModerationMasquee {
+		t.Errorf("statut %s après un signalement, attendu %s", moderation.Statut, models.ModerationMasquee)
+	}
+
+	for action, attendu := range map[string]int{models.AuditApprouverAnnonce: 1, models.AuditMasquerAnnonce: 1} {
+		if n := len(decoderListe(t, e.requete(http.MethodGet, "/v1/admin/audit?action="+action, moderateur.ID, nil))); n != attendu {
+			t.Errorf("journal d'audit : %d entrée(s) %s, attendu %d", n, action, attendu)
+		}
+	}
+}
+
+// Le bannissement masque l'annonce, suspend son auteur et retire ses autres annonces des listes
+func TestBannissement(t *testing.T) {
+	e := nouvelEnvironnementBase(t)
+	e.monterModeration()
+	moderateur := e.ajouterUtilisateur(t, "Modérateur")
+	vente := e.creer(t, typesTest[0], e.acheteur.ID, map[string]interface{}{"parcelle_id": e.korhogo.ID})["id"].(string)
+	achat := e.creer(t, typesTest[1], e.acheteur.ID, nil)["id"].(string)
+	autre := e.creer(t, typesTest[0], e.vendeur.ID, nil)["id"].(string)
+
+	w := e.requete(http.MethodPost, "/v1/admin/moderation/"+models.AnnonceTypeVente+"/"+vente+"/bannir", moderateur.ID,
+		map[string]interface{}{"motif": "Fraude répétée", "duree_jours": -1})
+	verifierErreur(t, w, http.StatusBadRequest, "donnees_invalides")
+	w = e.requete(http.MethodPost, "/v1/admin/moderation/"+models.AnnonceTypeVente+"/"+vente+"/bannir", moderateur.ID,
+		map[string]interface{}{"motif": "Fraude répétée", "duree_jours": 7})
+	if w.Code != http.StatusOK {
+		t.Fatalf("bannissement : statut %d (%s)", w.Code, w.Body.String())
+	}
+
+	if ventes := e.idsListe(t, "/v1/annonces_vente"); ventes[vente] || !ventes[autre] {
+		t.Errorf("ventes listées %v : attendu seulement celle de l'auteur non banni", ventes)
+	}
+	if achats := e.idsListe(t, "/v1/annonces_achat"); achats[achat] {
+		t.Error("annonce d'achat de l'auteur banni toujours listée")
+	}
+	w = e.requete(http.MethodPost, "/v1/annonces_achat", e.acheteur.ID, typesTest[1].corps(e))
+	verifierErreur(t, w, http.StatusForbidden, "compte_suspendu")
+
+	var suspension models.Suspension
+	if err := e.base.First(&suspension, "user_id = ?", e.acheteur.ID).Error; err != nil || suspension.FinLe == nil || suspension.ModerateurID != moderateur.ID {
+		t.Errorf("suspension %+v (%v), attendu 7 jours par le modérateur", suspension, err)
+	}
+	if n := len(decoderListe(t, e.requete(http.MethodGet, "/v1/admin/audit?cible_id="+e.acheteur.ID.String(), moderateur.ID, nil))); n != 1 {
+		t.Errorf("%d entrée(s) d'audit sur l'auteur banni, attendu 1", n)
+	}
+}
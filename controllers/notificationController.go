@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+const (
+	notificationsLimiteDefaut = 50
+	notificationsLimiteMax    = 100
+)
+
+// Lister les notifications de l'utilisateur connecté (les plus récentes d'abord).
+// Filtres facultatifs : lu=true|false, type ; pagination : limit, offset.
+func GetMesNotifications(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(notificationsLimiteDefaut)))
+	if err != nil || limit <= 0 || limit > notificationsLimiteMax {
+		limit = notificationsLimiteDefaut
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	query := db.Where("user_id = ?", userID)
+	if lu := c.Query("lu"); lu != "" {
+		valeur, err := strconv.ParseBool(lu)
+		if err != nil {
+			erreurs.Repondre(c, erreurs.ParametreInvalide.Champ("lu", erreurs.DetailType, "boolean"))
+			return
+		}
+		query = query.Where("lu = ?", valeur)
+	}
+	if typeNotification := c.Query("type"); typeNotification != "" {
+		query = query.Where("type = ?", typeNotification)
+	}
+
+	result := models.ListeNotifications{Notifications: []models.Notification{}}
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&result.Notifications).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+	if err := db.Model(&models.Notification{}).
+		Where("user_id = ? AND lu = ?", userID, false).
+		Count(&result.NonLues).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Marquer une notification comme lue
+func MarquerNotificationLue(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return
+	}
+
+	var notification models.Notification
+	if err := db.First(&notification, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		erreurs.Repondre(c, erreurs.NotificationIntrouvable)
+		return
+	}
+
+	if !notification.Lu {
+		maintenant := time.Now()
+		notification.Lu = true
+		notification.LuLe = &maintenant
+		if err := db.Save(&notification).Error; err != nil {
+			erreurs.Repondre(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// Marquer toutes les notifications de l'utilisateur comme lues
+func MarquerToutesNotificationsLues(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	res := db.Model(&models.Notification{}).
+		Where("user_id = ? AND lu = ?", userID, false).
+		Updates(map[string]interface{}{"lu": true, "lu_le": time.Now()})
+	if res.Error != nil {
+		erreurs.Repondre(c, res.Error)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notifications marquées comme lues", "total": res.RowsAffected})
+}
+
+// Récupérer les préférences de notification (tous les types et canaux, valeurs par défaut incluses)
+func GetPreferencesNotification(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	var configurees []models.PreferenceNotification
+	if err := db.Where("user_id = ?", userID).Find(&configurees).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+	actives := map[string]bool{}
+	for _, p := range configurees {
+		actives[p.Type+"/"+p.Canal] = p.Active
+	}
+
+	var result []models.PreferenceNotification
+	for _, typeNotification := range models.TypesNotification {
+		for _, canal := range models.CanauxNotification {
+			active, ok := actives[typeNotification+"/"+canal]
+			if !ok {
+				active = models.CanalActifParDefaut(canal)
+			}
+			result = append(result, models.PreferenceNotification{
+				UserID: userID,
+				Type:   typeNotification,
+				Canal:  canal,
+				Active: active,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+type PreferenceNotificationInput struct {
+	Type   string `json:"type" binding:"required"`
+	Canal  string `json:"canal" binding:"required"`
+	Active *bool  `json:"active" binding:"required"`
+}
+
+// Modifier les préférences de notification (seules les lignes envoyées sont modifiées)
+func UpdatePreferencesNotification(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	var input []PreferenceNotificationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		erreurs.Repondre(c, erreurs.Validation(err))
+		return
+	}
+
+	preferences := make([]models.PreferenceNotification, 0, len(input))
+	for _, p := range input {
+		if !contient(models.TypesNotification, p.Type) {
+			erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("type", erreurs.DetailValeurInconnue, models.TypesNotification...))
+			return
+		}
+		if !contient(models.CanauxNotification, p.Canal) {
+			erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("canal", erreurs.DetailValeurInconnue, models.CanauxNotification...))
+			return
+		}
+		preferences = append(preferences, models.PreferenceNotification{
+			UserID: userID,
+			Type:   p.Type,
+			Canal:  p.Canal,
+			Active: *p.Active,
+		})
+	}
+
+	if len(preferences) > 0 {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "canal"}},
+				DoUpdates: clause.AssignmentColumns([]string{"active"}),
+			}).Create(&preferences).Error
+		})
+		if err != nil {
+			erreurs.Repondre(c, err)
+			return
+		}
+	}
+
+	GetPreferencesNotification(c)
+}
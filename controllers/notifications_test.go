@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/services"
+)
+
+func (e *environnement) monterNotifications() {
+	g := e.groupe(models.VersionAPI1)
+	g.GET("/notifications", GetMesNotifications)
+	g.PUT("/notifications/lu", MarquerToutesNotificationsLues)
+	g.PUT("/notifications/:id/lu", MarquerNotificationLue)
+	g.GET("/notifications/preferences", GetPreferencesNotification)
+	g.PUT("/notifications/preferences", UpdatePreferencesNotification)
+}
+
+func (e *environnement) boiteDeReception(t *testing.T, utilisateur uuid.UUID, filtre string) models.ListeNotifications {
+	t.Helper()
+	w := e.requete(http.MethodGet, "/v1/notifications"+filtre, utilisateur, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /notifications%s : statut %d (%s)", filtre, w.Code, w.Body.String())
+	}
+	var liste models.ListeNotifications
+	if err := json.Unmarshal(w.Body.Bytes(), &liste); err != nil {
+		t.Fatal(err)
+	}
+	return liste
+}
+
+func TestPreferencesNotification(t *testing.T) {
+	e := nouvelEnvironnementBase(t)
+	e.monterNotifications()
+
+	modifs := []map[string]interface{}{
+		{"type": models.NotificationMessageRecu, "canal": models.CanalInApp, "active": false},
+		{"type": models.NotificationOffreRecue, "canal": models.CanalEmail, "active": true},
+	}
+	w := e.requete(http.MethodPut, "/v1/notifications/preferences", e.acheteur.ID, modifs)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT : statut %d (%s)", w.Code, w.Body.String())
+	}
+	var preferences []models.PreferenceNotification
+	if err := json.Unmarshal(w.Body.Bytes(), &preferences); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(models.TypesNotification) * len(models.CanauxNotification); len(preferences) != n {
+		t.Fatalf("%d préférences, attendu %d (tous les types et canaux)", len(preferences), n)
+	}
+	for _, p := range preferences {
+		attendue := models.CanalActifParDefaut(p.Canal)
+		switch p.Type + "/" + p.Canal {
+		case models.NotificationMessageRecu + "/" + models.CanalInApp:
+			attendue = false
+		case models.NotificationOffreRecue + "/" + models.CanalEmail:
+			attendue = true
+		}
+		if p.Active != attendue {
+			t.Errorf("%s/%s active %v, attendu %v", p.Type, p.Canal, p.Active, attendue)
+		}
+	}
+
+	w = e.requete(http.MethodPut, "/v1/notifications/preferences", e.acheteur.ID,
+		[]map[string]interface{}{{"type": "inconnu", "canal": models.CanalInApp, "active": true}})
+	verifierErreur(t, w, http.StatusBadRequest, "donnees_invalides")
+
+	// Les préférences de l'acheteur ne touchent pas celles du vendeur
+	w = e.requete(http.MethodGet, "/v1/notifications/preferences", e.vendeur.ID, nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &preferences); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range preferences {
+		if p.Active != models.CanalActifParDefaut(p.Canal) {
+			t.Errorf("vendeur : %s/%s active %v, attendu la valeur par défaut", p.Type, p.Canal, p.Active)
+		}
+	}
+}
+
+func TestBoiteDeReception(t *testing.T) {
+	e := nouvelEnvironnementBase(t)
+	e.monterNotifications()
+
+	// message_recu est désactivé in-app : la notification n'arrive pas dans la boîte
+	w := e.requete(http.MethodPut, "/v1/notifications/preferences", e.acheteur.ID,
+		[]map[string]interface{}{{"type": models.NotificationMessageRecu, "canal": models.CanalInApp, "active": false}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT préférences : statut %d", w.Code)
+	}
+	envois := []models.Notification{
+		{UserID: e.acheteur.ID, Type: models.NotificationRechercheCorrespondante, Titre: "Cacao à Daloa"},
+		{UserID: e.acheteur.ID, Type: models.NotificationMessageRecu, Titre: "Nouveau message"},
+		{UserID: e.acheteur.ID, Type: models.NotificationOffreRecue, Titre: "Offre sur votre annonce"},
+		{UserID: e.vendeur.ID, Type: models.NotificationOffreRecue, Titre: "Offre du vendeur"},
+	}
+	for _, n := range envois {
+		if err := services.Notifier(e.base, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	liste := e.boiteDeReception(t, e.acheteur.ID, "")
+	if len(liste.Notifications) != 2 || liste.NonLues != 2 {
+		t.Fatalf("%d notifications dont %d non lues, attendu 2 et 2", len(liste.Notifications), liste.NonLues)
+	}
+	recente, ancienne := liste.Notifications[0], liste.Notifications[1]
+	if recente.Type != models.NotificationOffreRecue || ancienne.Type != models.NotificationRechercheCorrespondante {
+		t.Errorf("ordre %s, %s ; attendu la plus récente d'abord", recente.Type, ancienne.Type)
+	}
+	if liste := e.boiteDeReception(t, e.acheteur.ID, "?type="+models.NotificationOffreRecue); len(liste.Notifications) != 1 {
+		t.Errorf("%d notifications de type %s, attendu 1", len(liste.Notifications), models.NotificationOffreRecue)
+	}
+
+	w = e.requete(http.MethodPut, "/v1/notifications/"+ancienne.ID.String()+"/lu", e.vendeur.ID, nil)
+	verifierErreur(t, w, http.StatusNotFound, "notification_introuvable")
+	w = e.requete(http.MethodPut, "/v1/notifications/"+ancienne.ID.String()+"/lu", e.acheteur.ID, nil)
+	if lue := decoder(t, w); lue["lu"] != true || lue["lu_le"] == nil {
+		t.Errorf("notification %v non marquée lue", lue)
+	}
+
+	if liste := e.boiteDeReception(t, e.acheteur.ID, "?lu=false"); len(liste.Notifications) != 1 || liste.Notifications[0].ID != recente.ID || liste.NonLues != 1 {
+		t.Errorf("non lues : %d notifications, compteur %d ; attendu la plus récente seule", len(liste.Notifications), liste.NonLues)
+	}
+	w = e.requete(http.MethodGet, "/v1/notifications?lu=peut-etre", e.acheteur.ID, nil)
+	verifierErreur(t, w, http.StatusBadRequest, "parametre_invalide")
+
+	w = e.requete(http.MethodPut, "/v1/notifications/lu", e.acheteur.ID, nil)
+	if total := decoder(t, w)["total"]; total != float64(1) {
+		t.Errorf("%v notification(s) marquée(s) lue(s), attendu 1", total)
+	}
+	if liste := e.boiteDeReception(t, e.acheteur.ID, ""); liste.NonLues != 0 {
+		t.Errorf("%d non lues après tout marquer lu", liste.NonLues)
+	}
+	if liste := e.boiteDeReception(t, e.vendeur.ID, ""); len(liste.Notifications) != 1 || liste.NonLues != 1 {
+		t.Errorf("vendeur : %d notifications dont %d non lues, attendu 1 et 1", len(liste.Notifications), liste.NonLues)
+	}
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/services"
+)
+
+type RechercheInput struct {
+	Nom         string               `json:"nom" binding:"required"`
+	AnnonceType string               `json:"annonce_type" binding:"required,oneof=vente achat"`
+	Filtre      models.FiltreAnnonce `json:"filtre"`
+	Active      *bool                `json:"active"`
+}
+
+// Lister les recherches sauvegardées de l'utilisateur connecté
+func GetMesRecherches(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	recherches := []models.RechercheSauvegardee{}
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&recherches).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, recherches)
+}
+
+// Créer une recherche sauvegardée
+func CreateRecherche(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input RechercheInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		erreurs.Repondre(c, erreurs.Validation(err))
+		return
+	}
+	if err := validerFiltre(input.Filtre, input.AnnonceType); err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	recherche := models.RechercheSauvegardee{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Nom:         input.Nom,
+		AnnonceType: input.AnnonceType,
+		Filtre:      input.Filtre,
+		Active:      input.Active == nil || *input.Active,
+	}
+
+	if err := db.Create(&recherche).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, recherche)
+}
+
+// trouverMaRecherche charge une recherche sauvegardée appartenant à l'utilisateur connecté.
+// Sans base, elle répond 503 : après elle, baseDe(c) n'est jamais nil.
+func trouverMaRecherche(c *gin.Context) (models.RechercheSauvegardee, bool) {
+	var recherche models.RechercheSauvegardee
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return recherche, false
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return recherche, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return recherche, false
+	}
+	if err := db.First(&recherche, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		erreurs.Repondre(c, erreurs.RechercheIntrouvable)
+		return recherche, false
+	}
+	return recherche, true
+}
+
+// Récupérer une recherche sauvegardée par ID
+func GetRechercheByID(c *gin.Context) {
+	recherche, ok := trouverMaRecherche(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, recherche)
+}
+
+// Modifier une recherche sauvegardée
+func UpdateRecherche(c *gin.Context) {
+	recherche, ok := trouverMaRecherche(c)
+	if !ok {
+		return
+	}
+
+	var input RechercheInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		erreurs.Repondre(c, erreurs.Validation(err))
+		return
+	}
+	if err := validerFiltre(input.Filtre, input.AnnonceType); err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	recherche.Nom = input.Nom
+	recherche.AnnonceType = input.AnnonceType
+	recherche.Filtre = input.Filtre
+	if input.Active != nil {
+		recherche.Active = *input.Active
+	}
+
+	if err := baseDe(c).Save(&recherche).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, recherche)
+}
+
+// Supprimer une recherche sauvegardée
+func DeleteRecherche(c *gin.Context) {
+	recherche, ok := trouverMaRecherche(c)
+	if !ok {
+		return
+	}
+
+	if err := baseDe(c).Delete(&recherche).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recherche supprimée avec succès"})
+}
+
+// alerterRecherches notifie les propriétaires des recherches sauvegardées actives auxquelles
+// l'annonce correspond. Pour une modification, avant contient l'état précédent : les
+// recherches qui correspondaient déjà ne sont pas notifiées une seconde fois.
+func alerterRecherches(db *gorm.DB, annonceType string, annonceID uuid.UUID, description string, apres models.CriteresAnnonce, avant *models.CriteresAnnonce) {
+	if db == nil {
+		return
+	}
+	var recherches []models.RechercheSauvegardee
+	if err := db.Where("annonce_type = ? AND active = ? AND user_id <> ?", annonceType, true, apres.UserID).
+		Find(&recherches).Error; err != nil {
+		log.Printf("Erreur chargement des recherches sauvegardées : %v\n", err)
+		return
+	}
+
+	for _, r := range recherches {
+		if !r.Filtre.Correspond(apres) {
+			continue
+		}
+		if avant != nil && r.Filtre.Correspond(*avant) {
+			continue
+		}
+
+		id := annonceID
+		notification := models.Notification{
+			UserID:      r.UserID,
+			Type:        models.NotificationRechercheCorrespondante,
+			Titre:       fmt.Sprintf("Nouvelle annonce pour « %s »", r.Nom),
+			Message:     description,
+			AnnonceType: annonceType,
+			AnnonceID:   &id,
+		}
+		if err := services.Notifier(db, notification); err != nil {
+			log.Printf("Erreur création de la notification pour la recherche %s : %v\n", r.ID, err)
+		}
+	}
+}
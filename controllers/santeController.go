@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Steph-business/annonce_de_vente/database"
+)
+
+// delaiSondeBase borne les vérifications de la base faites par la sonde de disponibilité
+const delaiSondeBase = 2 * time.Second
+
+// Sonde de vivacité (liveness) : le processus répond. Elle ne dépend pas de la base, pour
+// qu'une panne de celle-ci ne fasse pas redémarrer toutes les instances.
+func Vivacite(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"statut": "ok"})
+}
+
+// Sonde de disponibilité (readiness) : l'instance peut recevoir du trafic si elle n'est pas
+// en cours d'arrêt, si la base répond et si aucune migration de ce binaire n'est en attente.
+// Répond 503 sinon ; le détail des erreurs est journalisé, pas renvoyé.
+func Disponibilite(c *gin.Context) {
+	verifications := gin.H{}
+	pret := true
+
+	select {
+	case <-dependancesDe(c).Arret:
+		verifications["arret"] = "en cours"
+		pret = false
+	default:
+	}
+
+	if db := baseDe(c); db != nil {
+		ctx, annuler := context.WithTimeout(c.Request.Context(), delaiSondeBase)
+		defer annuler()
+
+		verifications["base"] = "ok"
+		verifications["migrations"] = "ok"
+		if sqlDB, err := db.DB(); err != nil {
+			log.Printf("Sonde de disponibilité : base : %v\n", err)
+			verifications["base"] = "indisponible"
+			pret = false
+		} else if err := sqlDB.PingContext(ctx); err != nil {
+			log.Printf("Sonde de disponibilité : base : %v\n", err)
+			verifications["base"] = "indisponible"
+			pret = false
+		} else if enAttente, err := database.MigrationsEnAttente(db.WithContext(ctx)); err != nil {
+			log.Printf("Sonde de disponibilité : migrations : %v\n", err)
+			verifications["migrations"] = "indisponible"
+			pret = false
+		} else if len(enAttente) > 0 {
+			verifications["migrations"] = fmt.Sprintf("%d en attente", len(enAttente))
+			pret = false
+		}
+	}
+
+	statut, code := "ok", http.StatusOK
+	if !pret {
+		statut, code = "indisponible", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"statut": statut, "verifications": verifications})
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Steph-business/annonce_de_vente/config"
+	"github.com/Steph-business/annonce_de_vente/database"
+	"github.com/Steph-business/annonce_de_vente/depots"
+)
+
+func sonder(t *testing.T, d Dependances, chemin string) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Injecter(d))
+	r.GET("/healthz", Vivacite)
+	r.GET("/readyz", Disponibilite)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, chemin, nil))
+	return w.Code
+}
+
+func TestSondes(t *testing.T) {
+	arret := make(chan struct{})
+	d := Dependances{Depots: depots.NouvelleMemoire().Depots(), Arret: arret}
+	if code := sonder(t, d, "/readyz"); code != http.StatusOK {
+		t.Errorf("readyz sans base : %d", code)
+	}
+	close(arret)
+	if code := sonder(t, d, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz pendant l'arrêt : %d", code)
+	}
+	if code := sonder(t, d, "/healthz"); code != http.StatusOK {
+		t.Errorf("healthz pendant l'arrêt : %d", code)
+	}
+}
+
+// La sonde de disponibilité échoue tant que des migrations sont en attente
+func TestSondeMigrations(t *testing.T) {
+	db := database.Connecter(config.Base{Pilote: config.PiloteSQLite, FichierSQLite: filepath.Join(t.TempDir(), "test.db")})
+	d := Dependances{Depots: depots.Gorm(db), DB: db}
+
+	if _, err := database.MigrerHaut(db, 1); err != nil {
+		t.Fatal(err)
+	}
+	if code := sonder(t, d, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz avec migrations en attente : %d", code)
+	}
+	if _, err := database.MigrerHaut(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	if code := sonder(t, d, "/readyz"); code != http.StatusOK {
+		t.Errorf("readyz après migrations : %d", code)
+	}
+
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	if code := sonder(t, d, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz base fermée : %d", code)
+	}
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/services"
+)
+
+// Intervalle des commentaires keep-alive envoyés quand aucun événement n'est publié
+const streamHeartbeat = 15 * time.Second
+
+// publierAnnonce diffuse sur le bus interne la création (ancienStatut nil) ou la
+// modification d'une annonce, plus un événement de changement de statut le cas échéant.
+// donnees est le DTO v1 de l'annonce : StreamAnnonces le rend dans la version de chaque abonné.
+func publierAnnonce(annonceType string, annonceID uuid.UUID, typeCultureID uuid.UUID, adresse string, ancienStatut *string, nouveauStatut string, donnees interface{}) {
+	evenement := models.EvenementAnnonce{
+		AnnonceType:   annonceType,
+		AnnonceID:     annonceID.String(),
+		TypeCultureID: typeCultureID.String(),
+		Adresse:       adresse,
+		Donnees:       donnees,
+	}
+
+	if ancienStatut == nil {
+		evenement.Evenement = models.EvenementAnnonceCreee
+		services.Bus.Publier(evenement)
+		return
+	}
+
+	evenement.Evenement = models.EvenementAnnonceModifiee
+	services.Bus.Publier(evenement)
+	if *ancienStatut != nouveauStatut {
+		evenement.Evenement = models.EvenementAnnonceStatutModifie
+		services.Bus.Publier(evenement)
+	}
+}
+
+// filtreStream sélectionne les événements demandés par le client
+type filtreStream struct {
+	types         []string
+	typeCultureID string
+	region        string
+}
+
+func (f filtreStream) correspond(e models.EvenementAnnonce) bool {
+	if len(f.types) > 0 && !contient(f.types, e.AnnonceType) {
+		return false
+	}
+	if f.typeCultureID != "" && f.typeCultureID != e.TypeCultureID {
+		return false
+	}
+	if f.region != "" && !strings.Contains(strings.ToLower(e.Adresse), f.region) {
+		return false
+	}
+	return true
+}
+
+// Flux Server-Sent Events des annonces créées et modifiées.
+// Filtres facultatifs : type=vente,achat,pref ; type_culture_id ; region (adresse de la parcelle).
+// Reprise : en-tête Last-Event-ID (ou paramètre last_event_id) avec le dernier ID reçu.
+func StreamAnnonces(c *gin.Context) {
+	filtre := filtreStream{
+		typeCultureID: c.Query("type_culture_id"),
+		region:        strings.ToLower(c.Query("region")),
+	}
+	if types := c.Query("type"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			t = strings.TrimSpace(t)
+			if t != models.AnnonceTypeVente && t != models.AnnonceTypeAchat && t != models.AnnonceTypePref {
+				erreurs.Repondre(c, erreurs.TypeAnnonceInconnu.Champ("type", erreurs.DetailValeurInconnue, models.AnnonceTypeVente, models.AnnonceTypeAchat, models.AnnonceTypePref))
+				return
+			}
+			filtre.types = append(filtre.types, t)
+		}
+	}
+
+	var dernierID uint64
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			erreurs.Repondre(c, erreurs.ParametreInvalide.Champ("Last-Event-ID", erreurs.DetailType, "number"))
+			return
+		}
+		dernierID = id
+		// ID venant d'une instance précédente du serveur (les IDs repartent de zéro au redémarrage)
+		if dernierID > services.Bus.DernierID() {
+			dernierID = services.Bus.DernierID()
+		}
+	}
+
+	// S'abonner avant de relire le tampon pour ne manquer aucun événement
+	evenements, desabonner := services.Bus.Abonner()
+	defer desabonner()
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	// Le flux dure plus longtemps que le délai d'écriture du serveur (delai_ecriture)
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	envoyer := func(e models.EvenementAnnonce) {
+		if e.ID <= dernierID {
+			return
+		}
+		dernierID = e.ID
+		if !filtre.correspond(e) {
+			return
+		}
+		// e est une copie : l'événement du bus garde le DTO v1 pour les autres abonnés
+		e.Donnees = renduAnnonces(c, e.Donnees)
+		c.Render(-1, sse.Event{
+			Id:    strconv.FormatUint(e.ID, 10),
+			Event: e.Evenement,
+			Data:  e,
+		})
+	}
+
+	if lastEventID != "" {
+		for _, e := range services.Bus.Depuis(dernierID) {
+			envoyer(e)
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	arret := dependancesDe(c).Arret
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-arret:
+			// Arrêt du serveur : le client se reconnectera à une autre instance avec son Last-Event-ID
+			return
+		case e, ok := <-evenements:
+			if !ok {
+				// Client trop lent : il se reconnectera avec son Last-Event-ID
+				return
+			}
+			envoyer(e)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			c.Writer.WriteString(": keep-alive\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Steph-business/annonce_de_vente/middleware"
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/services"
+)
+
+// Le flux rend chaque événement au contrat de la version de l'abonné
+func TestStreamVersion(t *testing.T) {
+	e := nouvelEnvironnement(t)
+	for version, prefixe := range map[int]string{models.VersionAPI1: "/v1", models.VersionAPI2: "/v2"} {
+		e.routeur.GET(prefixe+"/annonces/stream", middleware.VersionAPIMiddleware(version), StreamAnnonces)
+	}
+
+	depuis := services.Bus.DernierID()
+	vente := e.creer(t, typesTest[0], e.vendeur.ID, nil)
+
+	for prefixe, champ := range map[string]string{"/v1": "user_id", "/v2": "auteur"} {
+		t.Run(prefixe, func(t *testing.T) {
+			// Le flux ne se termine qu'à la déconnexion du client
+			ctx, annuler := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer annuler()
+			chemin := prefixe + "/annonces/stream?type=vente&last_event_id=" + strconv.FormatUint(depuis, 10)
+			req := httptest.NewRequest(http.MethodGet, chemin, nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			e.routeur.ServeHTTP(w, req)
+
+			var evenement models.EvenementAnnonce
+			var donnees map[string]interface{}
+			for _, ligne := range strings.Split(w.Body.String(), "\n") {
+				if !strings.HasPrefix(ligne, "data:") {
+					continue
+				}
+				evenement.Donnees = &donnees
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(ligne, "data:")), &evenement); err != nil {
+					t.Fatalf("événement illisible : %v (%s)", err, ligne)
+				}
+				if evenement.AnnonceID == vente["id"] {
+					break
+				}
+			}
+			if evenement.AnnonceID != vente["id"] {
+				t.Fatalf("création absente du flux : %s", w.Body.String())
+			}
+			if _, ok := donnees[champ]; !ok {
+				t.Errorf("donnees sans %q : %v", champ, donnees)
+			}
+		})
+	}
+}
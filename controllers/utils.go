@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+)
+
+// currentUserID récupère l'ID de l'utilisateur authentifié (mis par le middleware)
+// et répond directement au client en cas d'échec
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		erreurs.Repondre(c, erreurs.NonAuthentifie)
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(fmt.Sprint(value))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide.Champ("user_id", erreurs.DetailFormat, "uuid"))
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// optionalUserID renvoie l'ID de l'utilisateur s'il est authentifié (routes publiques)
+func optionalUserID(c *gin.Context) (uuid.UUID, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(fmt.Sprint(value))
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func contient(valeurs []string, valeur string) bool {
+	for _, v := range valeurs {
+		if v == valeur {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+)
+
+// Règles communes des entrées d'annonce (balises binding) :
+//   - quantite : nombre, 0 < quantite <= 100 000 000 kg
+//   - prix au kg : nombre, 0 < prix <= 10 000 000
+//   - description : 2000 caractères maximum ; photo : 500 caractères maximum
+//   - identifiants : UUID
+//   - statut : active, en_pause, expiree, plus vendue (vente, achat) ou financee (préfinancement),
+//     voir models.StatutsAnnonce
+
+// Nombre est un nombre d'une entrée JSON, accepté en nombre JSON ou en texte ("12.5", "12,5",
+// "1 200,50"). Une valeur illisible ne fait pas échouer le décodage : elle est signalée par la
+// règle binding "nombre", avec les autres erreurs de validation.
+type Nombre float64
+
+func (n *Nombre) UnmarshalJSON(donnees []byte) error {
+	donnees = bytes.TrimSpace(donnees)
+	if bytes.Equal(donnees, []byte("null")) {
+		*n = 0
+		return nil
+	}
+
+	var valeur float64
+	if err := json.Unmarshal(donnees, &valeur); err == nil {
+		*n = Nombre(valeur)
+		return nil
+	}
+	var texte string
+	if err := json.Unmarshal(donnees, &texte); err == nil {
+		if valeur, err := parseNombre(texte); err == nil {
+			*n = Nombre(valeur)
+			return nil
+		}
+	}
+	*n = Nombre(math.NaN())
+	return nil
+}
+
+// parseNombre accepte la virgule décimale et les espaces de milliers ("1 200,50")
+func parseNombre(valeur string) (float64, error) {
+	valeur = strings.ReplaceAll(valeur, " ", "")
+	valeur = strings.ReplaceAll(valeur, " ", "")
+	valeur = strings.Replace(valeur, ",", ".", 1)
+	n, err := strconv.ParseFloat(valeur, 64)
+	if err == nil && (math.IsNaN(n) || math.IsInf(n, 0)) {
+		err = strconv.ErrSyntax
+	}
+	return n, err
+}
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterValidation(erreurs.RegleNombre, func(fl validator.FieldLevel) bool {
+			if fl.Field().Kind() != reflect.Float64 {
+				return true
+			}
+			return !math.IsNaN(fl.Field().Float())
+		})
+	}
+}
+
+// validerEntree applique les règles binding d'une entrée déjà remplie (PATCH, import CSV)
+func validerEntree(entree interface{}) error {
+	if err := binding.Validator.ValidateStruct(entree); err != nil {
+		return erreurs.Validation(err)
+	}
+	return nil
+}
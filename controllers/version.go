@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+)
+
+// etagVersion renvoie la valeur de l'en-tête ETag d'une annonce
+func etagVersion(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// versionAttendue lit l'en-tête If-Match, obligatoire sur PUT et DELETE. If-Match: *
+// accepte la version courante. Répond 428 si l'en-tête manque ou est invalide.
+func versionAttendue(c *gin.Context, courante int64) (int64, bool) {
+	valeur := strings.TrimSpace(c.GetHeader("If-Match"))
+	if valeur == "" {
+		erreurs.Repondre(c, erreurs.IfMatchRequis)
+		return 0, false
+	}
+	if valeur == "*" {
+		return courante, true
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(valeur, "W/"), `"`), 10, 64)
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IfMatchInvalide)
+		return 0, false
+	}
+	return version, true
+}
+
+// preconditionEchouee répond 412 avec la représentation courante de l'annonce et son ETag
+func preconditionEchouee(c *gin.Context, version int64, courante interface{}) {
+	c.Header("ETag", etagVersion(version))
+	erreurs.RepondreAvec(c, erreurs.VersionPerimee, gin.H{"annonce": renduAnnonces(c, courante)})
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// Les handlers sont communs aux versions de l'API : ils construisent les DTO v1, enrichis
+// des favoris et des notes, que renduAnnonces convertit au contrat de la version demandée.
+// Les webhooks gardent le contrat v1 ; le flux temps réel rend chaque événement dans la
+// version de l'abonné.
+
+// versionAPI renvoie la version de l'API demandée (v1 par défaut)
+func versionAPI(c *gin.Context) int {
+	if version := c.GetInt(models.CleVersionAPI); version != 0 {
+		return version
+	}
+	return models.VersionAPI1
+}
+
+// renduAnnonces convertit une réponse d'annonces v1 (DTO seul, liste ou favoris) au contrat
+// de la version demandée. Les autres valeurs sont renvoyées telles quelles.
+func renduAnnonces(c *gin.Context, v1 interface{}) interface{} {
+	if versionAPI(c) != models.VersionAPI2 {
+		return v1
+	}
+
+	switch dto := v1.(type) {
+	case models.ListeAnnonceVente:
+		return annonceVenteV2(dto)
+	case models.ListeAnnonceAchat:
+		return annonceAchatV2(dto)
+	case models.LiteAnnoncePrefinancement:
+		return annoncePrefV2(dto)
+	case []models.ListeAnnonceVente:
+		return annoncesVenteV2(dto)
+	case []models.ListeAnnonceAchat:
+		result := []models.AnnonceAchatV2{}
+		for _, a := range dto {
+			result = append(result, annonceAchatV2(a))
+		}
+		return result
+	case []models.LiteAnnoncePrefinancement:
+		return annoncesPrefV2(dto)
+	case models.ListeFavoris:
+		return models.ListeFavorisV2{
+			Ventes:          annoncesVenteV2(dto.Ventes),
+			Prefinancements: annoncesPrefV2(dto.Prefinancements),
+		}
+	}
+	return v1
+}
+
+func annonceVenteV2(a models.ListeAnnonceVente) models.AnnonceVenteV2 {
+	return models.AnnonceVenteV2{
+		ID:          a.ID,
+		Type:        models.AnnonceTypeVente,
+		Version:     a.Version,
+		Statut:      a.Statut,
+		Description: a.Description,
+		Quantite:    a.Quantite,
+		PrixKg:      a.PrixKg,
+		Photo:       a.Photo,
+		CreatedAt:   dateCreation(a.CreatedAt),
+		Auteur:      models.AuteurV2{ID: a.UserID, Nom: a.UserNom, Note: a.NoteUtilisateur, NbAvis: a.NbAvisUtilisateur},
+		TypeCulture: models.TypeCultureV2{ID: a.TypeCultureID, Libelle: a.TypeCultureLibelle},
+		Parcelle:    models.ParcelleV2{ID: a.ParcelleID, Adresse: a.ParcelleAdresse, Surface: a.ParcelleSurface},
+		Favoris:     models.FavorisV2{Nombre: a.NbFavoris, EstFavori: a.EstFavori, Modifie: a.FavoriModifie},
+	}
+}
+
+func annonceAchatV2(a models.ListeAnnonceAchat) models.AnnonceAchatV2 {
+	return models.AnnonceAchatV2{
+		ID:          a.ID,
+		Type:        models.AnnonceTypeAchat,
+		Version:     a.Version,
+		Statut:      a.Statut,
+		Description: a.Description,
+		Quantite:    a.Quantite,
+		PrixKg:      a.Prix,
+		CreatedAt:   dateCreation(a.CreatedAt),
+		Auteur:      models.AuteurV2{ID: a.UserID, Nom: a.UserNom, Note: a.NoteUtilisateur, NbAvis: a.NbAvisUtilisateur},
+		TypeCulture: models.TypeCultureV2{ID: a.TypeCultureID, Libelle: a.TypeCultureLibelle},
+	}
+}
+
+func annoncePrefV2(a models.LiteAnnoncePrefinancement) models.AnnoncePrefV2 {
+	return models.AnnoncePrefV2{
+		ID:          a.ID,
+		Type:        models.AnnonceTypePref,
+		Version:     a.Version,
+		Statut:      a.Statut,
+		Description: a.Description,
+		Quantite:    a.Quantite,
+		PrixKgPref:  a.PrixKgPref,
+		MontantPref: a.MontantPref,
+		CreatedAt:   dateCreation(a.CreatedAt),
+		Auteur:      models.AuteurV2{ID: a.UserID, Nom: a.UserNom, Note: a.NoteUtilisateur, NbAvis: a.NbAvisUtilisateur},
+		TypeCulture: models.TypeCultureV2{ID: a.TypeCultureID, Libelle: a.TypeCultureLibelle},
+		Parcelle:    models.ParcelleV2{ID: a.ParcelleID, Adresse: a.ParcelleAdresse, Surface: a.ParcelleSuf},
+		Favoris:     models.FavorisV2{Nombre: a.NbFavoris, EstFavori: a.EstFavori, Modifie: a.FavoriModifie},
+	}
+}
+
+// Les listes v2 sont toujours des tableaux, jamais null
+func annoncesVenteV2(annonces []models.ListeAnnonceVente) []models.AnnonceVenteV2 {
+	result := []models.AnnonceVenteV2{}
+	for _, a := range annonces {
+		result = append(result, annonceVenteV2(a))
+	}
+	return result
+}
+
+func annoncesPrefV2(annonces []models.LiteAnnoncePrefinancement) []models.AnnoncePrefV2 {
+	result := []models.AnnoncePrefV2{}
+	for _, a := range annonces {
+		result = append(result, annoncePrefV2(a))
+	}
+	return result
+}
+
+// dateCreation renvoie nil pour une date inconnue (annonces antérieures à la colonne created_at)
+func dateCreation(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
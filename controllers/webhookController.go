@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/services"
+)
+
+type WebhookInput struct {
+	URL          string   `json:"url" binding:"required"`
+	Evenements   []string `json:"evenements"`
+	AnnonceTypes []string `json:"annonce_types"`
+	Active       *bool    `json:"active"`
+}
+
+// validerWebhookInput vérifie l'URL et les filtres d'un endpoint. Une URL vers une adresse
+// interne est refusée ici si son hôte est une adresse IP ; un nom d'hôte l'est à la livraison.
+func validerWebhookInput(c *gin.Context, input WebhookInput) bool {
+	u, err := url.Parse(input.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("url", erreurs.DetailFormat, "http, https"))
+		return false
+	}
+	if services.HoteInterdit(u.Hostname()) {
+		erreurs.Repondre(c, erreurs.AdresseWebhookInterdite)
+		return false
+	}
+	for _, e := range input.Evenements {
+		if !contient(models.EvenementsWebhook, e) {
+			erreurs.Repondre(c, erreurs.DonneesInvalides.Champ("evenements", erreurs.DetailValeurInconnue, models.EvenementsWebhook...))
+			return false
+		}
+	}
+	for _, t := range input.AnnonceTypes {
+		if t != models.AnnonceTypeVente && t != models.AnnonceTypeAchat && t != models.AnnonceTypePref {
+			erreurs.Repondre(c, erreurs.TypeAnnonceInconnu.Champ("annonce_types", erreurs.DetailValeurInconnue, models.AnnonceTypeVente, models.AnnonceTypeAchat, models.AnnonceTypePref))
+			return false
+		}
+	}
+	return true
+}
+
+func genererSecretWebhook() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Enregistrer un endpoint de webhook. Le secret de signature n'est renvoyé qu'à la création.
+func CreateWebhook(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		erreurs.Repondre(c, erreurs.Validation(err))
+		return
+	}
+	if !validerWebhookInput(c, input) {
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	secret, err := genererSecretWebhook()
+	if err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	endpoint := models.WebhookEndpoint{
+		ID:           uuid.New(),
+		UserID:       userID,
+		URL:          input.URL,
+		Secret:       secret,
+		Evenements:   input.Evenements,
+		AnnonceTypes: input.AnnonceTypes,
+		Active:       input.Active == nil || *input.Active,
+	}
+
+	if err := db.Create(&endpoint).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"webhook": endpoint, "secret": secret})
+}
+
+// Lister les endpoints de webhook de l'utilisateur connecté
+func GetMesWebhooks(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	db, ok := baseRequise(c)
+	if !ok {
+		return
+	}
+
+	endpoints := []models.WebhookEndpoint{}
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&endpoints).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoints)
+}
+
+// trouverMonWebhook charge un endpoint appartenant à l'utilisateur connecté. Sans base,
+// elle répond 503 : après elle, baseDe(c) n'est jamais nil.
+func trouverMonWebhook(c *gin.Context) (models.WebhookEndpoint, bool) {
+	var endpoint models.WebhookEndpoint
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return endpoint, false
+	}
+	db, ok := baseRequise(c)
+	if !ok {
+		return endpoint, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide)
+		return endpoint, false
+	}
+	if err := db.First(&endpoint, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		erreurs.Repondre(c, erreurs.WebhookIntrouvable)
+		return endpoint, false
+	}
+	return endpoint, true
+}
+
+// Récupérer un endpoint de webhook par ID
+func GetWebhookByID(c *gin.Context) {
+	endpoint, ok := trouverMonWebhook(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// Modifier un endpoint de webhook
+func UpdateWebhook(c *gin.Context) {
+	endpoint, ok := trouverMonWebhook(c)
+	if !ok {
+		return
+	}
+
+	var input WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		erreurs.Repondre(c, erreurs.Validation(err))
+		return
+	}
+	if !validerWebhookInput(c, input) {
+		return
+	}
+
+	endpoint.URL = input.URL
+	endpoint.Evenements = input.Evenements
+	endpoint.AnnonceTypes = input.AnnonceTypes
+	if input.Active != nil {
+		endpoint.Active = *input.Active
+	}
+
+	if err := baseDe(c).Save(&endpoint).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// Supprimer un endpoint de webhook et ses livraisons
+func DeleteWebhook(c *gin.Context) {
+	endpoint, ok := trouverMonWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := baseDe(c).Where("endpoint_id = ?", endpoint.ID).Delete(&models.WebhookLivraison{}).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+	if err := baseDe(c).Delete(&endpoint).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook supprimé avec succès"})
+}
+
+// Lister les livraisons d'un endpoint (filtre facultatif : statut)
+func GetWebhookLivraisons(c *gin.Context) {
+	endpoint, ok := trouverMonWebhook(c)
+	if !ok {
+		return
+	}
+
+	query := baseDe(c).Where("endpoint_id = ?", endpoint.ID)
+	if statut := c.Query("statut"); statut != "" {
+		query = query.Where("statut = ?", statut)
+	}
+
+	livraisons := []models.WebhookLivraison{}
+	if err := query.Order("created_at DESC").Limit(100).Find(&livraisons).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, livraisons)
+}
+
+// Rejouer une livraison (dead letter ou déjà livrée) : elle est remise en file immédiatement
+func RejouerWebhookLivraison(c *gin.Context) {
+	endpoint, ok := trouverMonWebhook(c)
+	if !ok {
+		return
+	}
+	livraisonID, err := uuid.Parse(c.Param("livraison_id"))
+	if err != nil {
+		erreurs.Repondre(c, erreurs.IDInvalide.Champ("livraison_id", erreurs.DetailFormat, "uuid"))
+		return
+	}
+
+	var livraison models.WebhookLivraison
+	if err := baseDe(c).First(&livraison, "id = ? AND endpoint_id = ?", livraisonID, endpoint.ID).Error; err != nil {
+		erreurs.Repondre(c, erreurs.LivraisonIntrouvable)
+		return
+	}
+
+	livraison.Statut = models.LivraisonEnAttente
+	livraison.Tentatives = 0
+	livraison.ProchaineTentative = time.Now()
+	livraison.DerniereErreur = ""
+
+	if err := baseDe(c).Save(&livraison).Error; err != nil {
+		erreurs.Repondre(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, livraison)
+}
@@ -3,45 +3,82 @@ package database
 import (
 	"fmt"
 	"log"
-	"os"
 
-	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+
+	"github.com/Steph-business/annonce_de_vente/config"
 )
 
-var DB *gorm.DB
+// Pilotes de base de données (paramètre db_driver), qui sont aussi les noms des
+// dialectes GORM
+const (
+	PilotePostgres = config.PilotePostgres
+	PiloteSQLite   = config.PiloteSQLite
+)
 
-func InitDB() {
-	// Charger les variables d'environnement depuis le fichier .env
-	err := godotenv.Load(".env")
-	if err != nil {
-		log.Fatal("Erreur de chargement du fichier .env")
+// InitDB ouvre la connexion à la base et la renvoie, transmise aux handlers par main.go.
+// Le schéma n'est décrit que par les migrations versionnées : avec Postgres, InitDB n'y
+// touche pas (migrate up ou -migrer) et signale les migrations en attente, que /readyz
+// rapporte aussi. Avec SQLite, la base locale appartient au service : les migrations en
+// attente sont appliquées.
+func InitDB(cfg config.Base) *gorm.DB {
+	db := Connecter(cfg)
+	if db.Dialector.Name() == PiloteSQLite {
+		if _, err := MigrerHaut(db, 0); err != nil {
+			log.Fatal("Erreur lors des migrations :", err)
+		}
+		return db
 	}
 
-	// Récupération des valeurs d’environnement
-	DB_HOST := os.Getenv("host")
-	DB_PORT := os.Getenv("port")
-	DB_USER := os.Getenv("user")
-	DB_PASSWORD := os.Getenv("password")
-	DB_NAME := os.Getenv("dbname")
-
-	// Chaîne de connexion PostgreSQL
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME)
+	enAttente, err := MigrationsEnAttente(db)
+	if err != nil {
+		log.Fatal("Erreur de lecture des migrations :", err)
+	}
+	if len(enAttente) > 0 {
+		log.Printf("%d migration(s) en attente : lancer `migrate up` ou démarrer avec -migrer", len(enAttente))
+	}
+	return db
+}
 
-	// Connexion avec désactivation du protocole préparé
-	DB, err = gorm.Open(postgres.New(postgres.Config{
-		DSN:                  dsn,
-		PreferSimpleProtocol: true, //  IMPORTANT : désactive les requêtes préparées
-	}), &gorm.Config{
+// Connecter ouvre la connexion à la base sans toucher au schéma : Postgres (Supabase, par
+// défaut) ou un fichier SQLite local, selon cfg.Pilote
+func Connecter(cfg config.Base) *gorm.DB {
+	gormConfig := &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
-	})
+	}
 
+	var db *gorm.DB
+	var err error
+	switch cfg.Pilote {
+	case PilotePostgres:
+		db, err = ouvrirPostgres(cfg, gormConfig)
+	case PiloteSQLite:
+		db, err = ouvrirSQLite(cfg.FichierSQLite, gormConfig)
+	default:
+		log.Fatalf("Pilote de base de données inconnu : %q (attendu %s ou %s)", cfg.Pilote, PilotePostgres, PiloteSQLite)
+	}
 	if err != nil {
 		log.Fatal("Erreur de connexion à la base de données :", err)
 	}
+	if err := instrumenter(db); err != nil {
+		log.Fatal("Erreur d'instrumentation de la base de données :", err)
+	}
+
+	fmt.Printf("Connexion à la base de données réussie (%s)\n", cfg.Pilote)
+	return db
+}
 
-	fmt.Println("Connexion à la base de données réussie")
+// ouvrirPostgres se connecte à la base Postgres (Supabase)
+func ouvrirPostgres(cfg config.Base, gormConfig *gorm.Config) (*gorm.DB, error) {
+	// Chaîne de connexion PostgreSQL
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Hote, cfg.Port, cfg.Utilisateur, cfg.MotDePasse, cfg.Nom, cfg.SSLMode)
+
+	// Connexion avec désactivation du protocole préparé
+	return gorm.Open(postgres.New(postgres.Config{
+		DSN:                  dsn,
+		PreferSimpleProtocol: true, //  IMPORTANT : désactive les requêtes préparées
+	}), gormConfig)
 }
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/config"
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// Démarrage par défaut (sans -migrer) sur SQLite : InitDB crée le schéma par les migrations,
+// et la recherche par distance fonctionne sur ce schéma
+func TestInitDBDistance(t *testing.T) {
+	db := InitDB(config.Base{Pilote: PiloteSQLite, FichierSQLite: filepath.Join(t.TempDir(), "test.db")})
+	if enAttente, err := MigrationsEnAttente(db); err != nil || len(enAttente) != 0 {
+		t.Fatalf("%d migration(s) en attente après InitDB, %v", len(enAttente), err)
+	}
+
+	latitude, longitude := 6.877, -6.450
+	auteur := models.User{ID: uuid.New(), Nom: "Awa Koné"}
+	culture := models.TypeCulture{ID: uuid.New(), Libelle: "Cacao"}
+	parcelle := models.Parcelle{ID: uuid.New(), Adresse: "Daloa, Haut-Sassandra", Latitude: &latitude, Longitude: &longitude}
+	vente := models.AnnonceVente{ID: uuid.New(), UserID: auteur.ID, TypeCultureID: culture.ID, ParcelleID: parcelle.ID, Statut: models.StatutActive}
+	for _, ligne := range []interface{}{&auteur, &culture, &parcelle, &vente} {
+		if err := db.Create(ligne).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := depots.Gorm(db)
+	for rayon, attendu := range map[float64]int{50: 1, 5: 0} {
+		// Point à ~15 km au nord de Daloa
+		filtre := models.FiltreAnnonce{Lat: ptr(7.01), Lng: ptr(-6.45), RayonKm: ptr(rayon)}
+		annonces, err := d.Ventes.Lister(filtre)
+		if err != nil {
+			t.Fatalf("rayon %v km : %v", rayon, err)
+		}
+		if len(annonces) != attendu {
+			t.Errorf("rayon %v km : %d annonces, attendu %d", rayon, len(annonces), attendu)
+		}
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
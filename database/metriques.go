@@ -0,0 +1,84 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Steph-business/annonce_de_vente/metriques"
+)
+
+// Bornes de l'histogramme des requêtes SQL, plus courtes que celles des requêtes HTTP
+var bornesRequetesBase = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+var dureeRequetesBase = metriques.Defaut.Histogramme("db_query_duration_seconds",
+	"Durée des requêtes SQL, par opération GORM (create, query, update, delete, row, raw) et table",
+	bornesRequetesBase, "operation", "table")
+
+// cleDebutRequete conserve dans l'instruction GORM l'heure de début de la requête
+const cleDebutRequete = "metriques:debut"
+
+// instrumenter mesure la durée des requêtes SQL par des callbacks GORM placés autour de
+// l'exécution de chaque opération, et expose les statistiques du pool de connexions
+func instrumenter(db *gorm.DB) error {
+	avant := func(tx *gorm.DB) {
+		tx.InstanceSet(cleDebutRequete, time.Now())
+	}
+	apres := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			if debut, ok := tx.InstanceGet(cleDebutRequete); ok {
+				dureeRequetesBase.Observer(time.Since(debut.(time.Time)).Seconds(), operation, tx.Statement.Table)
+			}
+		}
+	}
+
+	cb := db.Callback()
+	if err := errors.Join(
+		cb.Create().Before("gorm:create").Register("metriques:avant_create", avant),
+		cb.Create().After("gorm:create").Register("metriques:apres_create", apres("create")),
+		cb.Query().Before("gorm:query").Register("metriques:avant_query", avant),
+		cb.Query().After("gorm:query").Register("metriques:apres_query", apres("query")),
+		cb.Update().Before("gorm:update").Register("metriques:avant_update", avant),
+		cb.Update().After("gorm:update").Register("metriques:apres_update", apres("update")),
+		cb.Delete().Before("gorm:delete").Register("metriques:avant_delete", avant),
+		cb.Delete().After("gorm:delete").Register("metriques:apres_delete", apres("delete")),
+		cb.Row().Before("gorm:row").Register("metriques:avant_row", avant),
+		cb.Row().After("gorm:row").Register("metriques:apres_row", apres("row")),
+		cb.Raw().Before("gorm:raw").Register("metriques:avant_raw", avant),
+		cb.Raw().After("gorm:raw").Register("metriques:apres_raw", apres("raw")),
+	); err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	exposerPool(sqlDB)
+	return nil
+}
+
+// exposerPool expose les statistiques du pool de connexions, lues à chaque collecte
+func exposerPool(sqlDB *sql.DB) {
+	lire := func(valeur func(s sql.DBStats) float64) func() float64 {
+		return func() float64 { return valeur(sqlDB.Stats()) }
+	}
+	metriques.Defaut.JaugeLue("db_connections_max_open", "Nombre maximal de connexions ouvertes (0 : illimité)",
+		lire(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	metriques.Defaut.JaugeLue("db_connections_open", "Connexions ouvertes, utilisées ou inactives",
+		lire(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	metriques.Defaut.JaugeLue("db_connections_in_use", "Connexions en cours d'utilisation",
+		lire(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	metriques.Defaut.JaugeLue("db_connections_idle", "Connexions inactives",
+		lire(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	metriques.Defaut.CompteurLu("db_connections_wait_total", "Attentes d'une connexion libre",
+		lire(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	metriques.Defaut.CompteurLu("db_connections_wait_seconds_total", "Temps total passé à attendre une connexion libre",
+		lire(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	metriques.Defaut.CompteurLu("db_connections_closed_total", "Connexions fermées (inactives en excès ou expirées)",
+		lire(func(s sql.DBStats) float64 {
+			return float64(s.MaxIdleClosed + s.MaxIdleTimeClosed + s.MaxLifetimeClosed)
+		}))
+}
@@ -0,0 +1,301 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migrations SQL versionnées, un dossier par pilote. Chaque migration est une paire de
+// fichiers <version>_<nom>.up.sql / <version>_<nom>.down.sql.
+//
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var fichiersMigrations embed.FS
+
+// Table des migrations appliquées
+const tableVersions = "versions_schema"
+
+// Clé du verrou consultatif Postgres pris pendant chaque étape de migration ("annonces")
+const cleVerrouMigrations int64 = 0x616e6e6f6e636573
+
+// Migration est une évolution du schéma et son retour arrière
+type Migration struct {
+	Version int64
+	Nom     string
+	Haut    string
+	Bas     string
+}
+
+// EtatMigration indique si une migration est appliquée et depuis quand
+type EtatMigration struct {
+	Version     int64
+	Nom         string
+	AppliqueeLe *time.Time
+	// Connue est faux pour une version appliquée par un binaire plus récent
+	Connue bool
+}
+
+// versionSchema est une ligne de la table des versions
+type versionSchema struct {
+	Version     int64     `gorm:"primaryKey;autoIncrement:false"`
+	Nom         string    `gorm:"not null"`
+	AppliqueeLe time.Time `gorm:"not null"`
+}
+
+func (versionSchema) TableName() string {
+	return tableVersions
+}
+
+// Migrations renvoie les migrations du pilote, triées par version
+func Migrations(pilote string) ([]Migration, error) {
+	dossier := path.Join("migrations", pilote)
+	fichiers, err := fs.ReadDir(fichiersMigrations, dossier)
+	if err != nil {
+		return nil, fmt.Errorf("aucune migration pour le pilote %q", pilote)
+	}
+
+	parVersion := map[int64]*Migration{}
+	for _, f := range fichiers {
+		base, sens, ok := decouperNomMigration(f.Name())
+		if !ok {
+			return nil, fmt.Errorf("nom de migration invalide : %s", f.Name())
+		}
+		versionTexte, nom, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(versionTexte, 10, 64)
+		if err != nil || version <= 0 || nom == "" {
+			return nil, fmt.Errorf("nom de migration invalide : %s", f.Name())
+		}
+		contenu, err := fs.ReadFile(fichiersMigrations, path.Join(dossier, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, existe := parVersion[version]
+		if !existe {
+			m = &Migration{Version: version, Nom: nom}
+			parVersion[version] = m
+		} else if m.Nom != nom {
+			return nil, fmt.Errorf("version %04d utilisée par deux migrations (%s et %s)", version, m.Nom, nom)
+		}
+		if sens == "up" {
+			m.Haut = string(contenu)
+		} else {
+			m.Bas = string(contenu)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(parVersion))
+	for _, m := range parVersion {
+		if m.Haut == "" || m.Bas == "" {
+			return nil, fmt.Errorf("migration %04d_%s : fichiers up et down requis", m.Version, m.Nom)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// decouperNomMigration sépare "0001_nom.up.sql" en "0001_nom" et "up"
+func decouperNomMigration(fichier string) (string, string, bool) {
+	for _, sens := range []string{"up", "down"} {
+		if base, ok := strings.CutSuffix(fichier, "."+sens+".sql"); ok {
+			return base, sens, true
+		}
+	}
+	return "", "", false
+}
+
+// MigrerHaut applique dans l'ordre les n premières migrations en attente (toutes si n <= 0)
+// et renvoie le nombre de migrations appliquées. Chaque migration s'exécute dans sa propre
+// transaction, sous un verrou qui sérialise les exécutions concurrentes (plusieurs
+// instances démarrant en même temps) : une migration appliquée entre-temps est ignorée.
+func MigrerHaut(db *gorm.DB, n int) (int, error) {
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return 0, err
+	}
+
+	appliquees := 0
+	for n <= 0 || appliquees < n {
+		var suivante *Migration
+		err := etapeMigration(db, func(tx *gorm.DB, versions map[int64]versionSchema) error {
+			for i := range migrations {
+				if _, ok := versions[migrations[i].Version]; !ok {
+					suivante = &migrations[i]
+					break
+				}
+			}
+			if suivante == nil {
+				return nil
+			}
+			if err := tx.Exec(suivante.Haut).Error; err != nil {
+				return err
+			}
+			return tx.Create(&versionSchema{
+				Version:     suivante.Version,
+				Nom:         suivante.Nom,
+				AppliqueeLe: time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return appliquees, erreurMigration(suivante, err)
+		}
+		if suivante == nil {
+			break
+		}
+		appliquees++
+	}
+	return appliquees, nil
+}
+
+// MigrerBas annule les n dernières migrations appliquées (toutes si n <= 0), de la plus
+// récente à la plus ancienne, et renvoie le nombre de migrations annulées
+func MigrerBas(db *gorm.DB, n int) (int, error) {
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return 0, err
+	}
+	parVersion := map[int64]*Migration{}
+	for i := range migrations {
+		parVersion[migrations[i].Version] = &migrations[i]
+	}
+
+	annulees := 0
+	for n <= 0 || annulees < n {
+		var derniere *Migration
+		err := etapeMigration(db, func(tx *gorm.DB, versions map[int64]versionSchema) error {
+			var version int64
+			for v := range versions {
+				if v > version {
+					version = v
+				}
+			}
+			if version == 0 {
+				return nil
+			}
+			derniere = parVersion[version]
+			if derniere == nil {
+				derniere = &Migration{Version: version, Nom: versions[version].Nom}
+				return fmt.Errorf("absente de ce binaire, retour arrière impossible")
+			}
+			if err := tx.Exec(derniere.Bas).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&versionSchema{Version: version}).Error
+		})
+		if err != nil {
+			return annulees, erreurMigration(derniere, err)
+		}
+		if derniere == nil {
+			break
+		}
+		annulees++
+	}
+	return annulees, nil
+}
+
+// EtatMigrations liste les migrations connues et celles présentes en base, par version,
+// sans verrou ni écriture : la table des versions n'est pas créée si elle manque
+func EtatMigrations(db *gorm.DB) ([]EtatMigration, error) {
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	versions, err := versionsAppliquees(db)
+	if err != nil {
+		return nil, err
+	}
+
+	etats := make([]EtatMigration, 0, len(migrations))
+	for _, m := range migrations {
+		etat := EtatMigration{Version: m.Version, Nom: m.Nom, Connue: true}
+		if v, ok := versions[m.Version]; ok {
+			appliqueeLe := v.AppliqueeLe
+			etat.AppliqueeLe = &appliqueeLe
+			delete(versions, m.Version)
+		}
+		etats = append(etats, etat)
+	}
+	for _, v := range versions {
+		appliqueeLe := v.AppliqueeLe
+		etats = append(etats, EtatMigration{Version: v.Version, Nom: v.Nom, AppliqueeLe: &appliqueeLe})
+	}
+	sort.Slice(etats, func(i, j int) bool { return etats[i].Version < etats[j].Version })
+	return etats, nil
+}
+
+// MigrationsEnAttente renvoie les migrations de ce binaire pas encore appliquées, sans
+// verrou ni écriture (sonde de disponibilité). Sans table des versions, aucune migration
+// n'a été appliquée : elles sont toutes en attente.
+func MigrationsEnAttente(db *gorm.DB) ([]Migration, error) {
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	versions, err := versionsAppliquees(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var enAttente []Migration
+	for _, m := range migrations {
+		if _, ok := versions[m.Version]; !ok {
+			enAttente = append(enAttente, m)
+		}
+	}
+	return enAttente, nil
+}
+
+// versionsAppliquees lit la table des versions sans la créer ; une table absente équivaut
+// à aucune migration appliquée
+func versionsAppliquees(db *gorm.DB) (map[int64]versionSchema, error) {
+	versions := map[int64]versionSchema{}
+	if !db.Migrator().HasTable(tableVersions) {
+		return versions, nil
+	}
+
+	var lignes []versionSchema
+	if err := db.Find(&lignes).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range lignes {
+		versions[l.Version] = l
+	}
+	return versions, nil
+}
+
+// etapeMigration ouvre une transaction, prend le verrou des migrations, crée la table des
+// versions si besoin et passe à fn les versions appliquées. Avec SQLite, la transaction
+// verrouille déjà la base en écriture dès son début (_txlock=immediate).
+func etapeMigration(db *gorm.DB, fn func(tx *gorm.DB, versions map[int64]versionSchema) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == PilotePostgres {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", cleVerrouMigrations).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Migrator().AutoMigrate(&versionSchema{}); err != nil {
+			return err
+		}
+
+		versions, err := versionsAppliquees(tx)
+		if err != nil {
+			return err
+		}
+		return fn(tx, versions)
+	})
+}
+
+func erreurMigration(m *Migration, err error) error {
+	if m == nil {
+		return err
+	}
+	return fmt.Errorf("migration %04d_%s : %w", m.Version, m.Nom, err)
+}
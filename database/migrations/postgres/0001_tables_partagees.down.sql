@@ -0,0 +1,6 @@
+-- Les tables partagées appartiennent à Supabase et ne sont jamais supprimées : le retour
+-- arrière retire seulement les colonnes ajoutées par ce service.
+ALTER TABLE annonces_prefinancement DROP COLUMN IF EXISTS created_at;
+ALTER TABLE annonces_prefinancement DROP COLUMN IF EXISTS version;
+ALTER TABLE annonces_achat DROP COLUMN IF EXISTS version;
+ALTER TABLE annonces_vente DROP COLUMN IF EXISTS version;
@@ -0,0 +1,68 @@
+-- Tables partagées avec les autres services (gérées côté Supabase en production).
+-- IF NOT EXISTS : la migration s'applique aussi sur une base où elles existent déjà.
+CREATE TABLE IF NOT EXISTS users (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    nom text
+);
+
+CREATE TABLE IF NOT EXISTS type_culture (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    libelle text
+);
+
+CREATE TABLE IF NOT EXISTS parcelle (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    adresse text,
+    surface text
+);
+
+CREATE TABLE IF NOT EXISTS annonces_vente (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid,
+    type_culture_id uuid,
+    parcelle_id uuid,
+    photo text,
+    statut text,
+    quantite numeric,
+    prix_kg numeric,
+    description text,
+    created_at timestamptz DEFAULT now(),
+    CONSTRAINT fk_annonces_vente_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_annonces_vente_type_culture FOREIGN KEY (type_culture_id) REFERENCES type_culture (id),
+    CONSTRAINT fk_annonces_vente_parcelle FOREIGN KEY (parcelle_id) REFERENCES parcelle (id)
+);
+
+CREATE TABLE IF NOT EXISTS annonces_achat (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid,
+    type_culture_id uuid,
+    statut text,
+    prix_kg numeric,
+    description text,
+    quantite numeric,
+    created_at timestamptz DEFAULT now(),
+    CONSTRAINT fk_annonces_achat_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_annonces_achat_type_culture FOREIGN KEY (type_culture_id) REFERENCES type_culture (id)
+);
+
+CREATE TABLE IF NOT EXISTS annonces_prefinancement (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid,
+    type_culture_id uuid,
+    parcelle_id uuid,
+    statut text,
+    description text,
+    montant_pref numeric,
+    prix_kg_pref numeric,
+    quantite numeric,
+    CONSTRAINT fk_annonces_prefinancement_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_annonces_prefinancement_type_culture FOREIGN KEY (type_culture_id) REFERENCES type_culture (id),
+    CONSTRAINT fk_annonces_prefinancement_parcelle FOREIGN KEY (parcelle_id) REFERENCES parcelle (id)
+);
+
+-- Colonnes propres à ce service : version (verrouillage optimiste) et date de création
+-- des annonces de préfinancement (contrat v2)
+ALTER TABLE annonces_vente ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE annonces_achat ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE annonces_prefinancement ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE annonces_prefinancement ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT now();
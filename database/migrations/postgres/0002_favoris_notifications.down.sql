@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS preferences_notification;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS recherches_sauvegardees;
+DROP TABLE IF EXISTS favoris;
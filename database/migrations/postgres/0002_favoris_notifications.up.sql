@@ -0,0 +1,53 @@
+CREATE TABLE IF NOT EXISTS favoris (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid,
+    annonce_type text,
+    annonce_id uuid,
+    prix_initial numeric,
+    statut_initial text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_favoris_annonce_id ON favoris (annonce_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_favoris_user_annonce ON favoris (user_id, annonce_type, annonce_id);
+
+CREATE TABLE IF NOT EXISTS recherches_sauvegardees (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid,
+    nom text,
+    annonce_type text,
+    filtre_user_id text,
+    filtre_statut text,
+    filtre_type_culture_id text,
+    filtre_prix_min numeric,
+    filtre_prix_max numeric,
+    filtre_quantite_min numeric,
+    filtre_region text,
+    active boolean,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_recherches_sauvegardees_annonce_type ON recherches_sauvegardees (annonce_type);
+CREATE INDEX IF NOT EXISTS idx_recherches_sauvegardees_user_id ON recherches_sauvegardees (user_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid,
+    type text,
+    titre text,
+    message text,
+    annonce_type text,
+    annonce_id uuid,
+    lu boolean,
+    lu_le timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_lu ON notifications (lu);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id);
+
+CREATE TABLE IF NOT EXISTS preferences_notification (
+    user_id uuid,
+    type text,
+    canal text,
+    active boolean,
+    PRIMARY KEY (user_id, type, canal)
+);
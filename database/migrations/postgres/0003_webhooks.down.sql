@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS webhook_livraisons;
+DROP TABLE IF EXISTS webhook_endpoints;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid,
+    url text,
+    secret text,
+    evenements text,
+    annonce_types text,
+    active boolean,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_user_id ON webhook_endpoints (user_id);
+
+-- Outbox des livraisons
+CREATE TABLE IF NOT EXISTS webhook_livraisons (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    endpoint_id uuid,
+    evenement text,
+    payload text,
+    statut text,
+    tentatives bigint,
+    prochaine_tentative timestamptz,
+    dernier_code_http bigint,
+    derniere_erreur text,
+    livree_le timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_livraisons_a_envoyer ON webhook_livraisons (statut, prochaine_tentative);
+CREATE INDEX IF NOT EXISTS idx_webhook_livraisons_endpoint_id ON webhook_livraisons (endpoint_id);
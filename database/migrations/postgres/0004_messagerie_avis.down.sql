@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS avis;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS conversations;
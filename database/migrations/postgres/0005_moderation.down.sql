@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS journal_audit;
+DROP TABLE IF EXISTS suspensions;
+DROP TABLE IF EXISTS moderation_annonces;
+DROP TABLE IF EXISTS signalements;
@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS signalements (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    annonce_type text,
+    annonce_id uuid,
+    auteur_id uuid,
+    motif text,
+    commentaire text,
+    statut text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_signalements_statut ON signalements (statut);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_signalements_annonce_auteur ON signalements (annonce_type, annonce_id, auteur_id);
+
+CREATE TABLE IF NOT EXISTS moderation_annonces (
+    annonce_type text,
+    annonce_id uuid,
+    statut text,
+    nb_signalements bigint,
+    moderateur_id uuid,
+    modere_le timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (annonce_type, annonce_id)
+);
+CREATE INDEX IF NOT EXISTS idx_moderation_annonces_statut ON moderation_annonces (statut);
+
+CREATE TABLE IF NOT EXISTS suspensions (
+    user_id uuid PRIMARY KEY,
+    motif text,
+    moderateur_id uuid,
+    fin_le timestamptz,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS journal_audit (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    acteur_id uuid,
+    action text,
+    cible_type text,
+    cible_id uuid,
+    annonce_type text,
+    motif text,
+    details text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_journal_audit_created_at ON journal_audit (created_at);
+CREATE INDEX IF NOT EXISTS idx_journal_audit_cible_id ON journal_audit (cible_id);
+CREATE INDEX IF NOT EXISTS idx_journal_audit_action ON journal_audit (action);
+CREATE INDEX IF NOT EXISTS idx_journal_audit_acteur_id ON journal_audit (acteur_id);
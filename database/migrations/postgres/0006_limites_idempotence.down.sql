@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cles_idempotence;
+DROP TABLE IF EXISTS limites_debit;
@@ -0,0 +1,22 @@
+-- Compteurs du limiteur de débit (rate_limit_stockage=base)
+CREATE TABLE IF NOT EXISTS limites_debit (
+    cle text PRIMARY KEY,
+    jetons numeric NOT NULL,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_limites_debit_updated_at ON limites_debit (updated_at);
+
+-- Réponses mémorisées des requêtes Idempotency-Key
+CREATE TABLE IF NOT EXISTS cles_idempotence (
+    user_id text,
+    cle text,
+    empreinte text NOT NULL,
+    statut text NOT NULL,
+    code_http bigint,
+    content_type text,
+    reponse text,
+    created_at timestamptz,
+    expire_le timestamptz,
+    PRIMARY KEY (user_id, cle)
+);
+CREATE INDEX IF NOT EXISTS idx_cles_idempotence_expire_le ON cles_idempotence (expire_le);
@@ -0,0 +1,4 @@
+-- Exploitant de la parcelle : l'import CSV n'accepte que les parcelles du compte importateur.
+-- Nullable, la table étant partagée : les parcelles existantes restent à attribuer.
+ALTER TABLE parcelle ADD COLUMN IF NOT EXISTS user_id uuid;
+CREATE INDEX IF NOT EXISTS idx_parcelle_user_id ON parcelle (user_id);
@@ -0,0 +1,2 @@
+-- Conclusion en deux temps : déclarée par le producteur, confirmée par l'interlocuteur
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS conclusion_demandee_le timestamptz;
@@ -0,0 +1,2 @@
+-- En-têtes de la réponse mémorisée (ETag, Location...), rejoués avec elle
+ALTER TABLE cles_idempotence ADD COLUMN IF NOT EXISTS entetes text;
@@ -0,0 +1,6 @@
+ALTER TABLE recherches_sauvegardees DROP COLUMN IF EXISTS filtre_rayon_km;
+ALTER TABLE recherches_sauvegardees DROP COLUMN IF EXISTS filtre_lng;
+ALTER TABLE recherches_sauvegardees DROP COLUMN IF EXISTS filtre_lat;
+DROP INDEX IF EXISTS idx_parcelle_coordonnees;
+ALTER TABLE parcelle DROP COLUMN IF EXISTS longitude;
+ALTER TABLE parcelle DROP COLUMN IF EXISTS latitude;
@@ -0,0 +1,10 @@
+-- Coordonnées WGS 84 de la parcelle, pour la recherche d'annonces par distance.
+-- Nullable : les parcelles non géolocalisées n'apparaissent pas dans ces recherches.
+ALTER TABLE parcelle ADD COLUMN IF NOT EXISTS latitude double precision;
+ALTER TABLE parcelle ADD COLUMN IF NOT EXISTS longitude double precision;
+CREATE INDEX IF NOT EXISTS idx_parcelle_coordonnees ON parcelle (latitude, longitude);
+
+-- Les recherches sauvegardées reprennent le filtre par distance
+ALTER TABLE recherches_sauvegardees ADD COLUMN IF NOT EXISTS filtre_lat numeric;
+ALTER TABLE recherches_sauvegardees ADD COLUMN IF NOT EXISTS filtre_lng numeric;
+ALTER TABLE recherches_sauvegardees ADD COLUMN IF NOT EXISTS filtre_rayon_km numeric;
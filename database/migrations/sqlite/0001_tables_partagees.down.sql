@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS annonces_prefinancement;
+DROP TABLE IF EXISTS annonces_achat;
+DROP TABLE IF EXISTS annonces_vente;
+DROP TABLE IF EXISTS parcelle;
+DROP TABLE IF EXISTS type_culture;
+DROP TABLE IF EXISTS users;
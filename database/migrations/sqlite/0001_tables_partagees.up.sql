@@ -0,0 +1,64 @@
+-- Tables gérées côté Supabase en production, créées ici pour la base SQLite locale
+CREATE TABLE IF NOT EXISTS users (
+    id uuid PRIMARY KEY,
+    nom text
+);
+
+CREATE TABLE IF NOT EXISTS type_culture (
+    id uuid PRIMARY KEY,
+    libelle text
+);
+
+CREATE TABLE IF NOT EXISTS parcelle (
+    id uuid PRIMARY KEY,
+    adresse text,
+    surface text
+);
+
+CREATE TABLE IF NOT EXISTS annonces_vente (
+    id uuid PRIMARY KEY,
+    user_id uuid,
+    type_culture_id uuid,
+    parcelle_id uuid,
+    photo text,
+    statut text,
+    quantite real,
+    prix_kg real,
+    description text,
+    created_at datetime,
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT fk_annonces_vente_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_annonces_vente_type_culture FOREIGN KEY (type_culture_id) REFERENCES type_culture (id),
+    CONSTRAINT fk_annonces_vente_parcelle FOREIGN KEY (parcelle_id) REFERENCES parcelle (id)
+);
+
+CREATE TABLE IF NOT EXISTS annonces_achat (
+    id uuid PRIMARY KEY,
+    user_id uuid,
+    type_culture_id uuid,
+    statut text,
+    prix_kg real,
+    description text,
+    quantite real,
+    created_at datetime,
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT fk_annonces_achat_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_annonces_achat_type_culture FOREIGN KEY (type_culture_id) REFERENCES type_culture (id)
+);
+
+CREATE TABLE IF NOT EXISTS annonces_prefinancement (
+    id uuid PRIMARY KEY,
+    user_id uuid,
+    type_culture_id uuid,
+    parcelle_id uuid,
+    statut text,
+    description text,
+    montant_pref real,
+    prix_kg_pref real,
+    quantite real,
+    version integer NOT NULL DEFAULT 1,
+    created_at datetime,
+    CONSTRAINT fk_annonces_prefinancement_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_annonces_prefinancement_type_culture FOREIGN KEY (type_culture_id) REFERENCES type_culture (id),
+    CONSTRAINT fk_annonces_prefinancement_parcelle FOREIGN KEY (parcelle_id) REFERENCES parcelle (id)
+);
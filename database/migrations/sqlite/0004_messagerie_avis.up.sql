@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS conversations (
+    id uuid PRIMARY KEY,
+    annonce_type text,
+    annonce_id uuid,
+    proprietaire_id uuid,
+    interlocuteur_id uuid,
+    conclue_le datetime,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_conversations_interlocuteur_id ON conversations (interlocuteur_id);
+CREATE INDEX IF NOT EXISTS idx_conversations_proprietaire_id ON conversations (proprietaire_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_conversations_annonce_interlocuteur ON conversations (annonce_type, annonce_id, interlocuteur_id);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id uuid PRIMARY KEY,
+    conversation_id uuid,
+    auteur_id uuid,
+    contenu text,
+    piece_jointe text,
+    piece_jointe_nom text,
+    lu_le datetime,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages (conversation_id);
+
+CREATE TABLE IF NOT EXISTS avis (
+    id uuid PRIMARY KEY,
+    conversation_id uuid,
+    annonce_type text,
+    annonce_id uuid,
+    auteur_id uuid,
+    cible_id uuid,
+    role_cible text,
+    note_qualite integer,
+    note_ponctualite integer,
+    note_communication integer,
+    commentaire text,
+    reponse text,
+    repondu_le datetime,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_avis_cible_id ON avis (cible_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_avis_conversation_auteur ON avis (conversation_id, auteur_id);
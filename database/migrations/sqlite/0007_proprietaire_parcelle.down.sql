@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_parcelle_user_id;
+ALTER TABLE parcelle DROP COLUMN user_id;
@@ -0,0 +1,3 @@
+-- Exploitant de la parcelle : l'import CSV n'accepte que les parcelles du compte importateur
+ALTER TABLE parcelle ADD COLUMN user_id uuid;
+CREATE INDEX IF NOT EXISTS idx_parcelle_user_id ON parcelle (user_id);
@@ -0,0 +1 @@
+ALTER TABLE conversations DROP COLUMN conclusion_demandee_le;
@@ -0,0 +1,2 @@
+-- Conclusion en deux temps : déclarée par le producteur, confirmée par l'interlocuteur
+ALTER TABLE conversations ADD COLUMN conclusion_demandee_le datetime;
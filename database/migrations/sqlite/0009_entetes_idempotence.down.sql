@@ -0,0 +1 @@
+ALTER TABLE cles_idempotence DROP COLUMN entetes;
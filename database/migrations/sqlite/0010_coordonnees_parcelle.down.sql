@@ -0,0 +1,6 @@
+ALTER TABLE recherches_sauvegardees DROP COLUMN filtre_rayon_km;
+ALTER TABLE recherches_sauvegardees DROP COLUMN filtre_lng;
+ALTER TABLE recherches_sauvegardees DROP COLUMN filtre_lat;
+DROP INDEX IF EXISTS idx_parcelle_coordonnees;
+ALTER TABLE parcelle DROP COLUMN longitude;
+ALTER TABLE parcelle DROP COLUMN latitude;
@@ -0,0 +1,9 @@
+-- Coordonnées WGS 84 de la parcelle, pour la recherche d'annonces par distance
+ALTER TABLE parcelle ADD COLUMN latitude real;
+ALTER TABLE parcelle ADD COLUMN longitude real;
+CREATE INDEX IF NOT EXISTS idx_parcelle_coordonnees ON parcelle (latitude, longitude);
+
+-- Les recherches sauvegardées reprennent le filtre par distance
+ALTER TABLE recherches_sauvegardees ADD COLUMN filtre_lat real;
+ALTER TABLE recherches_sauvegardees ADD COLUMN filtre_lng real;
+ALTER TABLE recherches_sauvegardees ADD COLUMN filtre_rayon_km real;
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// Les migrations sont vérifiées sur une base SQLite temporaire ; les migrations Postgres
+// doivent suivre les mêmes versions.
+
+func baseTest(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := ouvrirSQLite(filepath.Join(t.TempDir(), "test.db"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrationsPilotes(t *testing.T) {
+	postgres, err := Migrations(PilotePostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := Migrations(PiloteSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("%d migrations Postgres, %d SQLite", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].Version != sqlite[i].Version || postgres[i].Nom != sqlite[i].Nom {
+			t.Errorf("migration %d : %04d_%s (Postgres) / %04d_%s (SQLite)", i,
+				postgres[i].Version, postgres[i].Nom, sqlite[i].Version, sqlite[i].Nom)
+		}
+	}
+}
+
+// Modèles dont les tables sont créées par les migrations : tables partagées avec les autres
+// services (gérées côté Supabase en production) et tables propres au service
+var modelesSchema = []interface{}{
+	&models.User{},
+	&models.TypeCulture{},
+	&models.Parcelle{},
+	&models.AnnonceVente{},
+	&models.AnnonceAchat{},
+	&models.AnnoncePrefinancement{},
+	&models.Favori{},
+	&models.RechercheSauvegardee{},
+	&models.Notification{},
+	&models.PreferenceNotification{},
+	&models.WebhookEndpoint{},
+	&models.WebhookLivraison{},
+	&models.Conversation{},
+	&models.Message{},
+	&models.Avis{},
+	&models.Signalement{},
+	&models.ModerationAnnonce{},
+	&models.Suspension{},
+	&models.JournalAudit{},
+	&models.LimiteDebit{},
+	&models.CleIdempotence{},
+}
+
+// Le schéma créé par les migrations contient toutes les colonnes des modèles
+func TestMigrationsSchemaModeles(t *testing.T) {
+	db := baseTest(t)
+	if _, err := MigrerHaut(db, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, modele := range modelesSchema {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(modele); err != nil {
+			t.Fatal(err)
+		}
+		if !db.Migrator().HasTable(stmt.Table) {
+			t.Errorf("table %s absente", stmt.Table)
+			continue
+		}
+		for _, champ := range stmt.Schema.Fields {
+			if champ.DBName != "" && !db.Migrator().HasColumn(modele, champ.DBName) {
+				t.Errorf("colonne %s.%s absente", stmt.Table, champ.DBName)
+			}
+		}
+	}
+}
+
+func TestMigrationsHautBas(t *testing.T) {
+	db := baseTest(t)
+	migrations, err := Migrations(PiloteSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := len(migrations)
+
+	// Base jamais migrée : tout est en attente, et la lecture de l'état ne crée rien
+	if enAttente, err := MigrationsEnAttente(db); err != nil || len(enAttente) != total {
+		t.Fatalf("MigrationsEnAttente sans table des versions = %d, %v ; attendu %d", len(enAttente), err, total)
+	}
+	if etats, err := EtatMigrations(db); err != nil || len(etats) != total || etats[0].AppliqueeLe != nil {
+		t.Fatalf("EtatMigrations sans table des versions = %d, %v", len(etats), err)
+	}
+	if db.Migrator().HasTable(tableVersions) {
+		t.Fatal("table des versions créée par une lecture de l'état")
+	}
+	if n, err := MigrerHaut(db, 2); err != nil || n != 2 {
+		t.Fatalf("MigrerHaut(2) = %d, %v", n, err)
+	}
+	if enAttente, err := MigrationsEnAttente(db); err != nil || len(enAttente) != total-2 {
+		t.Fatalf("MigrationsEnAttente = %d, %v ; attendu %d", len(enAttente), err, total-2)
+	}
+	if n, err := MigrerHaut(db, 0); err != nil || n != total-2 {
+		t.Fatalf("MigrerHaut = %d, %v ; attendu %d", n, err, total-2)
+	}
+	if n, err := MigrerHaut(db, 0); err != nil || n != 0 {
+		t.Fatalf("MigrerHaut sans migration en attente = %d, %v", n, err)
+	}
+
+	etats, err := EtatMigrations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range etats {
+		if e.AppliqueeLe == nil || !e.Connue {
+			t.Errorf("migration %04d_%s : %+v", e.Version, e.Nom, e)
+		}
+	}
+
+	if n, err := MigrerBas(db, 0); err != nil || n != total {
+		t.Fatalf("MigrerBas = %d, %v ; attendu %d", n, err, total)
+	}
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0] != tableVersions {
+		t.Errorf("tables restantes après retour arrière : %v", tables)
+	}
+
+	if n, err := MigrerHaut(db, 0); err != nil || n != total {
+		t.Fatalf("MigrerHaut après retour arrière = %d, %v", n, err)
+	}
+}
+
+// Plusieurs instances lancées en même temps appliquent chaque migration une seule fois
+func TestMigrationsConcurrentes(t *testing.T) {
+	fichier := filepath.Join(t.TempDir(), "test.db")
+	migrations, err := Migrations(PiloteSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	total := 0
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := ouvrirSQLite(fichier, &gorm.Config{Logger: logger.Discard})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n, err := MigrerHaut(db, 0)
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			total += n
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if total != len(migrations) {
+		t.Errorf("%d migrations appliquées, attendu %d", total, len(migrations))
+	}
+}
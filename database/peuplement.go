@@ -0,0 +1,382 @@
+package database
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// Profils du jeu de données (commande seed)
+const (
+	// ProfilBase : données de référence et quelques annonces de chaque type
+	ProfilBase = "base"
+	// ProfilDemo : des centaines d'annonces, pour les tests de charge
+	ProfilDemo = "demo"
+)
+
+// RapportPeuplement compte les lignes ajoutées par Peupler (les lignes déjà présentes ne
+// sont pas comptées)
+type RapportPeuplement struct {
+	TypesCulture    int64
+	Utilisateurs    int64
+	Parcelles       int64
+	Ventes          int64
+	Achats          int64
+	Prefinancements int64
+}
+
+// volumesPeuplement : nombre d'enregistrements générés par profil
+type volumesPeuplement struct {
+	utilisateurs, parcelles, ventes, achats, prefinancements int
+}
+
+var volumesProfils = map[string]volumesPeuplement{
+	ProfilBase: {utilisateurs: len(nomsUtilisateurs), parcelles: len(localites), ventes: 24, achats: 12, prefinancements: 8},
+	ProfilDemo: {utilisateurs: 150, parcelles: 300, ventes: 600, achats: 300, prefinancements: 150},
+}
+
+// Espace de noms des identifiants générés : un même enregistrement garde le même UUID
+// d'une exécution à l'autre, ce qui rend le peuplement idempotent
+var espacePeuplement = uuid.NewSHA1(uuid.NameSpaceURL, []byte("github.com/Steph-business/annonce_de_vente/seed"))
+
+// culturePeuplement : culture de référence, prix bord champ (FCFA/kg), quantités
+// habituelles (kg) et photos de uploads/
+type culturePeuplement struct {
+	libelle            string
+	detail             string
+	prixMin, prixMax   float64
+	quantMin, quantMax float64
+	photos             []string
+}
+
+var cultures = []culturePeuplement{
+	{"Cacao", "fèves fermentées et bien séchées", 1800, 2200, 500, 20000, []string{"cacao.jpg", "cacao2.jpg"}},
+	{"Café", "robusta décortiqué", 1300, 1600, 300, 10000, []string{"cafe.jpg"}},
+	{"Anacarde", "noix brutes de cajou", 300, 450, 1000, 30000, []string{"img1.jpg"}},
+	{"Hévéa", "fonds de tasse", 350, 450, 1000, 25000, []string{"img1.jpg"}},
+	{"Palmier à huile", "régimes frais", 80, 120, 2000, 40000, []string{"graine.jpg"}},
+	{"Manioc", "tubercules frais", 100, 200, 500, 15000, []string{"manioc.jpg"}},
+	{"Banane plantain", "régimes mûrs", 150, 300, 300, 8000, []string{"banane.jpg"}},
+	{"Igname", "kponan de première récolte", 200, 400, 300, 10000, []string{"img1.jpg"}},
+	{"Piment", "frais, variété locale", 800, 1500, 50, 2000, []string{"piment.jpg"}},
+	{"Tomate", "fraîche de saison", 300, 700, 100, 5000, []string{"tomate.jpg", "tomate1.jpg"}},
+	{"Maïs", "grain séché", 150, 250, 1000, 20000, []string{"img1.jpg"}},
+	{"Riz paddy", "récolte de bas-fond", 200, 300, 1000, 15000, []string{"img1.jpg"}},
+	{"Gombo", "frais", 500, 900, 50, 1500, []string{"img1.jpg"}},
+}
+
+// localitePeuplement : ville, région, coordonnées approximatives du centre-ville et
+// cultures pratiquées dans la zone
+type localitePeuplement struct {
+	ville, region       string
+	latitude, longitude float64
+	cultures            []string
+}
+
+var localites = []localitePeuplement{
+	{"Daloa", "Haut-Sassandra", 6.877, -6.450, []string{"Cacao", "Café", "Banane plantain", "Riz paddy"}},
+	{"Soubré", "Nawa", 5.785, -6.606, []string{"Cacao", "Hévéa", "Banane plantain"}},
+	{"San-Pédro", "San-Pédro", 4.748, -6.636, []string{"Cacao", "Hévéa", "Palmier à huile"}},
+	{"Gagnoa", "Gôh", 6.131, -5.951, []string{"Cacao", "Café", "Hévéa", "Manioc"}},
+	{"Divo", "Lôh-Djiboua", 5.837, -5.357, []string{"Cacao", "Banane plantain", "Manioc"}},
+	{"Abengourou", "Indénié-Djuablin", 6.730, -3.496, []string{"Cacao", "Café", "Banane plantain"}},
+	{"Man", "Tonkpi", 7.412, -7.554, []string{"Café", "Cacao", "Riz paddy", "Piment"}},
+	{"Duékoué", "Guémon", 6.741, -7.349, []string{"Cacao", "Café", "Riz paddy"}},
+	{"Korhogo", "Poro", 9.458, -5.630, []string{"Anacarde", "Maïs", "Riz paddy", "Piment"}},
+	{"Ferkessédougou", "Tchologo", 9.594, -5.197, []string{"Anacarde", "Maïs", "Riz paddy"}},
+	{"Bouaké", "Gbêkê", 7.690, -5.030, []string{"Anacarde", "Igname", "Maïs", "Tomate"}},
+	{"Katiola", "Hambol", 8.138, -5.101, []string{"Anacarde", "Igname", "Maïs"}},
+	{"Bondoukou", "Gontougo", 8.040, -2.800, []string{"Anacarde", "Igname", "Cacao"}},
+	{"Odienné", "Kabadougou", 9.510, -7.564, []string{"Anacarde", "Riz paddy", "Maïs"}},
+	{"Séguéla", "Worodougou", 7.961, -6.673, []string{"Anacarde", "Igname", "Maïs"}},
+	{"Toumodi", "Bélier", 6.557, -5.019, []string{"Igname", "Manioc", "Tomate", "Gombo"}},
+	{"Sinfra", "Marahoué", 6.621, -5.911, []string{"Cacao", "Banane plantain", "Igname"}},
+	{"Agboville", "Agnéby-Tiassa", 5.928, -4.213, []string{"Cacao", "Hévéa", "Banane plantain", "Manioc"}},
+	{"Adzopé", "La Mé", 6.107, -3.860, []string{"Cacao", "Hévéa", "Palmier à huile"}},
+	{"Aboisso", "Sud-Comoé", 5.467, -3.207, []string{"Palmier à huile", "Hévéa", "Cacao"}},
+	{"Dabou", "Grands-Ponts", 5.325, -4.377, []string{"Palmier à huile", "Hévéa", "Manioc", "Piment"}},
+	{"Daoukro", "Iffou", 7.059, -3.963, []string{"Cacao", "Igname", "Anacarde"}},
+	{"Guiglo", "Cavally", 6.543, -7.493, []string{"Cacao", "Café", "Riz paddy"}},
+	{"Yamoussoukro", "Yamoussoukro", 6.827, -5.289, []string{"Manioc", "Tomate", "Gombo", "Piment"}},
+}
+
+// Utilisateurs du profil base ; le profil demo en génère d'autres à partir des prénoms et
+// noms ci-dessous
+var nomsUtilisateurs = []string{
+	"Awa Koné", "Yao Kouassi", "Adjoua N'Guessan", "Moussa Traoré", "Aya Kouamé", "Koffi Konan",
+	"Mariam Ouattara", "Sékou Coulibaly", "Affoué Brou", "Jean-Baptiste Gnagne", "Fatoumata Diabaté",
+	"Serge Zadi",
+}
+
+var (
+	prenoms = []string{
+		"Amani", "Adama", "Akissi", "Bakary", "Brice", "Clarisse", "Drissa", "Éric", "Estelle", "Gisèle",
+		"Hervé", "Ibrahim", "Kadiatou", "Kouadio", "Lacina", "Marcelline", "N'Dri", "Odile", "Rokia", "Salif",
+	}
+	noms = []string{
+		"Bamba", "Diomandé", "Dosso", "Ehui", "Fofana", "Gbagbo", "Kacou", "Koffi", "Koua", "Méité",
+		"N'Goran", "Sangaré", "Soro", "Tanoh", "Touré", "Yéo", "Zoro",
+	}
+)
+
+// Peupler insère le jeu de données du profil : types de culture, utilisateurs,
+// parcelles et annonces des trois types. Les enregistrements ont des identifiants
+// stables et les lignes déjà présentes sont laissées telles quelles, si bien que la
+// commande peut être relancée sans créer de doublon. Un type de culture déjà présent
+// avec le même libellé est réutilisé.
+func Peupler(db *gorm.DB, profil string) (RapportPeuplement, error) {
+	volumes, ok := volumesProfils[profil]
+	if !ok {
+		return RapportPeuplement{}, fmt.Errorf("profil inconnu : %q (attendu %s ou %s)", profil, ProfilBase, ProfilDemo)
+	}
+
+	var rapport RapportPeuplement
+	err := db.Transaction(func(tx *gorm.DB) error {
+		idsCultures, n, err := peuplerCultures(tx)
+		if err != nil {
+			return err
+		}
+		rapport.TypesCulture = n
+
+		utilisateurs := genererUtilisateurs(volumes.utilisateurs)
+		if rapport.Utilisateurs, err = inserer(tx, &utilisateurs); err != nil {
+			return err
+		}
+		parcelles, zones := genererParcelles(volumes.parcelles)
+		if rapport.Parcelles, err = inserer(tx, &parcelles); err != nil {
+			return err
+		}
+
+		g := generateurAnnonces{utilisateurs: utilisateurs, parcelles: parcelles, zones: zones, cultures: idsCultures}
+		ventes := g.ventes(volumes.ventes)
+		if rapport.Ventes, err = inserer(tx, &ventes); err != nil {
+			return err
+		}
+		achats := g.achats(volumes.achats)
+		if rapport.Achats, err = inserer(tx, &achats); err != nil {
+			return err
+		}
+		prefinancements := g.prefinancements(volumes.prefinancements)
+		rapport.Prefinancements, err = inserer(tx, &prefinancements)
+		return err
+	})
+	return rapport, err
+}
+
+// inserer ajoute les lignes absentes (par identifiant) et renvoie le nombre de lignes ajoutées
+func inserer(tx *gorm.DB, lignes interface{}) (int64, error) {
+	res := tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(lignes, 100)
+	return res.RowsAffected, res.Error
+}
+
+// peuplerCultures insère les types de culture absents et renvoie l'identifiant de chaque
+// culture par libellé
+func peuplerCultures(tx *gorm.DB) (map[string]uuid.UUID, int64, error) {
+	var existants []models.TypeCulture
+	if err := tx.Find(&existants).Error; err != nil {
+		return nil, 0, err
+	}
+	parLibelle := map[string]uuid.UUID{}
+	for _, t := range existants {
+		parLibelle[strings.ToLower(strings.TrimSpace(t.Libelle))] = t.ID
+	}
+
+	ids := map[string]uuid.UUID{}
+	var nouveaux []models.TypeCulture
+	for _, c := range cultures {
+		if id, ok := parLibelle[strings.ToLower(c.libelle)]; ok {
+			ids[c.libelle] = id
+			continue
+		}
+		t := models.TypeCulture{ID: idPeuplement("culture", c.libelle), Libelle: c.libelle}
+		ids[c.libelle] = t.ID
+		nouveaux = append(nouveaux, t)
+	}
+	if len(nouveaux) == 0 {
+		return ids, 0, nil
+	}
+	n, err := inserer(tx, &nouveaux)
+	return ids, n, err
+}
+
+func genererUtilisateurs(n int) []models.User {
+	utilisateurs := make([]models.User, n)
+	for i := range utilisateurs {
+		nom := ""
+		if i < len(nomsUtilisateurs) {
+			nom = nomsUtilisateurs[i]
+		} else {
+			r := aleatoire("utilisateur", i)
+			nom = prenoms[r.Intn(len(prenoms))] + " " + noms[r.Intn(len(noms))]
+		}
+		utilisateurs[i] = models.User{ID: idPeuplement("utilisateur", i), Nom: nom}
+	}
+	return utilisateurs
+}
+
+// genererParcelles répartit les parcelles entre les localités et entre les utilisateurs
+// nommés (présents dans les deux profils), et renvoie, pour chacune, sa localité
+func genererParcelles(n int) ([]models.Parcelle, []localitePeuplement) {
+	parcelles := make([]models.Parcelle, n)
+	zones := make([]localitePeuplement, n)
+	for i := range parcelles {
+		l := localites[i%len(localites)]
+		adresse := l.ville + ", " + l.region
+		if i >= len(localites) {
+			adresse = fmt.Sprintf("Lot %d, %s", i/len(localites), adresse)
+		}
+		r := aleatoire("parcelle", i)
+		exploitant := idPeuplement("utilisateur", i%len(nomsUtilisateurs))
+		// Les parcelles sont dispersées à une dizaine de kilomètres autour de la ville
+		latitude := l.latitude + (r.Float64()-0.5)*0.2
+		longitude := l.longitude + (r.Float64()-0.5)*0.2
+		parcelles[i] = models.Parcelle{
+			ID:        idPeuplement("parcelle", i),
+			Adresse:   adresse,
+			Surface:   fmt.Sprintf("%d ha", 1+r.Intn(25)),
+			UserID:    &exploitant,
+			Latitude:  &latitude,
+			Longitude: &longitude,
+		}
+		zones[i] = l
+	}
+	return parcelles, zones
+}
+
+type generateurAnnonces struct {
+	utilisateurs []models.User
+	parcelles    []models.Parcelle
+	zones        []localitePeuplement
+	cultures     map[string]uuid.UUID
+}
+
+// tirage choisit la parcelle, son exploitant comme auteur et une culture de sa zone
+func (g generateurAnnonces) tirage(r *rand.Rand) (models.User, models.Parcelle, localitePeuplement, culturePeuplement) {
+	p := r.Intn(len(g.parcelles))
+	zone := g.zones[p]
+	libelle := zone.cultures[r.Intn(len(zone.cultures))]
+	var culture culturePeuplement
+	for _, c := range cultures {
+		if c.libelle == libelle {
+			culture = c
+		}
+	}
+	return g.utilisateurs[p%len(nomsUtilisateurs)], g.parcelles[p], zone, culture
+}
+
+func (g generateurAnnonces) ventes(n int) []models.AnnonceVente {
+	ventes := make([]models.AnnonceVente, n)
+	for i := range ventes {
+		r := aleatoire("vente", i)
+		auteur, parcelle, zone, culture := g.tirage(r)
+		ventes[i] = models.AnnonceVente{
+			ID:            idPeuplement("vente", i),
+			UserID:        auteur.ID,
+			TypeCultureID: g.cultures[culture.libelle],
+			ParcelleID:    parcelle.ID,
+			Photo:         "/static/" + culture.photos[r.Intn(len(culture.photos))],
+			Statut:        statutAleatoire(r, models.StatutVendue),
+			Quantite:      quantiteAleatoire(r, culture),
+			PrixKg:        prixAleatoire(r, culture),
+			Description:   fmt.Sprintf("%s, %s. Disponible à %s.", culture.libelle, culture.detail, zone.ville),
+			CreatedAt:     dateAleatoire(r),
+		}
+	}
+	return ventes
+}
+
+func (g generateurAnnonces) achats(n int) []models.AnnonceAchat {
+	achats := make([]models.AnnonceAchat, n)
+	for i := range achats {
+		r := aleatoire("achat", i)
+		auteur, _, zone, culture := g.tirage(r)
+		achats[i] = models.AnnonceAchat{
+			ID:            idPeuplement("achat", i),
+			UserID:        auteur.ID,
+			TypeCultureID: g.cultures[culture.libelle],
+			Statut:        statutAleatoire(r, models.StatutVendue),
+			Prix:          prixAleatoire(r, culture),
+			Description:   fmt.Sprintf("Achète %s (%s), enlèvement dans la région de %s, paiement comptant.", strings.ToLower(culture.libelle), culture.detail, zone.ville),
+			Quantite:      quantiteAleatoire(r, culture),
+			CreatedAt:     dateAleatoire(r),
+		}
+	}
+	return achats
+}
+
+func (g generateurAnnonces) prefinancements(n int) []models.AnnoncePrefinancement {
+	prefinancements := make([]models.AnnoncePrefinancement, n)
+	for i := range prefinancements {
+		r := aleatoire("prefinancement", i)
+		auteur, parcelle, zone, culture := g.tirage(r)
+		quantite := quantiteAleatoire(r, culture)
+		prix := arrondir(prixAleatoire(r, culture)*(0.85+0.1*r.Float64()), 5)
+		prefinancements[i] = models.AnnoncePrefinancement{
+			ID:                    idPeuplement("prefinancement", i),
+			UserID:                auteur.ID,
+			TypeCultureID:         g.cultures[culture.libelle],
+			ParcelleID:            parcelle.ID,
+			Statut:                statutAleatoire(r, models.StatutFinancee),
+			Description:           fmt.Sprintf("Préfinancement de la prochaine récolte de %s à %s (%s).", strings.ToLower(culture.libelle), zone.ville, parcelle.Surface),
+			MontantPrefinancement: prix * quantite, // même calcul que les contrôleurs
+			Prix:                  prix,
+			Quantite:              quantite,
+			CreatedAt:             dateAleatoire(r),
+		}
+	}
+	return prefinancements
+}
+
+// statutAleatoire : surtout des annonces actives, quelques-unes en pause, conclues
+// (vendue ou financee) ou expirées
+func statutAleatoire(r *rand.Rand, conclue string) string {
+	switch x := r.Intn(100); {
+	case x < 75:
+		return models.StatutActive
+	case x < 85:
+		return models.StatutEnPause
+	case x < 95:
+		return conclue
+	default:
+		return models.StatutExpiree
+	}
+}
+
+func prixAleatoire(r *rand.Rand, c culturePeuplement) float64 {
+	return arrondir(c.prixMin+(c.prixMax-c.prixMin)*r.Float64(), 5)
+}
+
+func quantiteAleatoire(r *rand.Rand, c culturePeuplement) float64 {
+	return math.Max(arrondir(c.quantMin+(c.quantMax-c.quantMin)*r.Float64(), 50), 50)
+}
+
+// dateAleatoire : date de publication dans les 120 derniers jours
+func dateAleatoire(r *rand.Rand) time.Time {
+	return time.Now().Add(-time.Duration(r.Int63n(int64(120 * 24 * time.Hour)))).Truncate(time.Minute)
+}
+
+func arrondir(v, pas float64) float64 {
+	return math.Round(v/pas) * pas
+}
+
+// idPeuplement renvoie l'identifiant stable d'un enregistrement généré
+func idPeuplement(genre string, cle interface{}) uuid.UUID {
+	return uuid.NewSHA1(espacePeuplement, []byte(fmt.Sprintf("%s:%v", genre, cle)))
+}
+
+// aleatoire renvoie un générateur propre à un enregistrement : ses valeurs ne dépendent
+// pas des enregistrements générés avant lui
+func aleatoire(genre string, i int) *rand.Rand {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s:%d", genre, i)
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+func TestPeuplementIdempotent(t *testing.T) {
+	db := baseTest(t)
+	if _, err := MigrerHaut(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Culture saisie à la main avant le peuplement : elle est réutilisée
+	cacao := models.TypeCulture{ID: uuid.New(), Libelle: "cacao"}
+	if err := db.Create(&cacao).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	base, err := Peupler(db, ProfilBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base.TypesCulture != int64(len(cultures)-1) || base.Ventes == 0 || base.Achats == 0 || base.Prefinancements == 0 {
+		t.Errorf("profil base : %+v", base)
+	}
+	if encore, err := Peupler(db, ProfilBase); err != nil || encore != (RapportPeuplement{}) {
+		t.Errorf("second peuplement : %+v, %v", encore, err)
+	}
+	// Le montant suit le calcul des contrôleurs : prix × quantité
+	var incoherents int64
+	err = db.Model(&models.AnnoncePrefinancement{}).Where("abs(montant_pref - prix_kg_pref * quantite) > 0.01").Count(&incoherents).Error
+	if err != nil || incoherents != 0 {
+		t.Errorf("%d préfinancement(s) dont le montant n'est pas prix × quantité (%v)", incoherents, err)
+	}
+
+	demo, err := Peupler(db, ProfilDemo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ventes int64
+	db.Model(&models.AnnonceVente{}).Count(&ventes)
+	if ventes != int64(volumesProfils[ProfilDemo].ventes) || demo.Ventes != ventes-base.Ventes {
+		t.Errorf("profil demo : %d annonces de vente (%+v)", ventes, demo)
+	}
+	if encore, err := Peupler(db, ProfilDemo); err != nil || encore != (RapportPeuplement{}) {
+		t.Errorf("second peuplement demo : %+v, %v", encore, err)
+	}
+
+	var cacaos int64
+	db.Model(&models.TypeCulture{}).Where("lower(libelle) = ?", "cacao").Count(&cacaos)
+	var annoncesCacao int64
+	db.Model(&models.AnnonceVente{}).Where("type_culture_id = ?", cacao.ID).Count(&annoncesCacao)
+	if cacaos != 1 || annoncesCacao == 0 {
+		t.Errorf("cacao : %d types de culture, %d annonces", cacaos, annoncesCacao)
+	}
+}
+
+// Les photos des annonces générées existent dans uploads/
+func TestPeuplementPhotos(t *testing.T) {
+	for _, c := range cultures {
+		for _, photo := range c.photos {
+			if _, err := os.Stat(filepath.Join("..", "uploads", photo)); err != nil {
+				t.Errorf("%s : %v", c.libelle, err)
+			}
+		}
+	}
+	for _, l := range localites {
+		for _, libelle := range l.cultures {
+			connue := false
+			for _, c := range cultures {
+				connue = connue || strings.EqualFold(c.libelle, libelle)
+			}
+			if !connue {
+				t.Errorf("%s : culture %q inconnue", l.ville, libelle)
+			}
+		}
+	}
+}
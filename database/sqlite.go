@@ -0,0 +1,72 @@
+package database
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Pilote SQLite enregistré avec les fonctions de remplacement (voir init)
+const piloteSQLite = "sqlite3_annonces"
+
+// Les fonctions LOWER et UPPER de SQLite ne convertissent que les caractères ASCII : elles
+// sont remplacées par celles de Go, pour que les recherches (région, description, nom)
+// ignorent la casse des lettres accentuées comme sous Postgres.
+func init() {
+	sql.Register(piloteSQLite, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("lower", strings.ToLower, true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("upper", strings.ToUpper, true)
+		},
+	})
+}
+
+// ouvrirSQLite ouvre (ou crée) la base SQLite du fichier donné. Les transactions prennent
+// le verrou d'écriture dès leur début et attendent qu'il se libère : SQLite n'a pas de
+// verrou par ligne (les clauses FOR UPDATE sont ignorées par le pilote).
+func ouvrirSQLite(fichier string, config *gorm.Config) (*gorm.DB, error) {
+	dsn := "file:" + fichier + "?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate"
+
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: piloteSQLite, DSN: dsn}), config)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Callback().Create().Before("gorm:create").Register("annonces:uuid", genererUUID)
+	return db, err
+}
+
+// genererUUID attribue un UUID aux enregistrements créés sans identifiant, comme le fait
+// la valeur par défaut gen_random_uuid() des tables Postgres
+func genererUUID(db *gorm.DB) {
+	if db.Statement.Schema == nil {
+		return
+	}
+	champ := db.Statement.Schema.PrioritizedPrimaryField
+	if champ == nil || champ.FieldType != reflect.TypeOf(uuid.UUID{}) {
+		return
+	}
+
+	attribuer := func(valeur reflect.Value) {
+		valeur = reflect.Indirect(valeur)
+		if _, vide := champ.ValueOf(db.Statement.Context, valeur); vide {
+			if err := champ.Set(db.Statement.Context, valeur, uuid.New()); err != nil {
+				db.AddError(err)
+			}
+		}
+	}
+	switch db.Statement.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < db.Statement.ReflectValue.Len(); i++ {
+			attribuer(db.Statement.ReflectValue.Index(i))
+		}
+	case reflect.Struct:
+		attribuer(db.Statement.ReflectValue)
+	}
+}
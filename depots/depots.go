@@ -0,0 +1,90 @@
+package depots
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// ErrIntrouvable est renvoyée par les méthodes Trouver quand l'enregistrement n'existe pas
+var ErrIntrouvable = errors.New("enregistrement introuvable")
+
+// Les méthodes des dépôts d'annonces suivent les mêmes règles pour les trois types :
+//   - Lister et Trouver renvoient les annonces avec leurs relations (auteur, type de
+//     culture, parcelle) ; Lister exclut les annonces masquées par la modération et
+//     celles des utilisateurs suspendus.
+//   - Creer insère l'annonce (version 1) et recharge ses relations.
+//   - Enregistrer sauvegarde l'annonce seulement si sa version en base vaut toujours
+//     attendue (erreurs.VersionPerimee sinon), passe sa version à attendue + 1 et recharge
+//     ses relations.
+//   - Supprimer supprime l'annonce seulement si sa version vaut toujours attendue.
+
+type AnnoncesVente interface {
+	Lister(filtre models.FiltreAnnonce) ([]models.AnnonceVente, error)
+	Trouver(id uuid.UUID) (models.AnnonceVente, error)
+	Creer(annonce *models.AnnonceVente) error
+	Enregistrer(annonce *models.AnnonceVente, attendue int64) error
+	Supprimer(id uuid.UUID, attendue int64) error
+}
+
+type AnnoncesAchat interface {
+	Lister(filtre models.FiltreAnnonce) ([]models.AnnonceAchat, error)
+	Trouver(id uuid.UUID) (models.AnnonceAchat, error)
+	Creer(annonce *models.AnnonceAchat) error
+	Enregistrer(annonce *models.AnnonceAchat, attendue int64) error
+	Supprimer(id uuid.UUID, attendue int64) error
+}
+
+type AnnoncesPref interface {
+	Lister(filtre models.FiltreAnnonce) ([]models.AnnoncePrefinancement, error)
+	Trouver(id uuid.UUID) (models.AnnoncePrefinancement, error)
+	Creer(annonce *models.AnnoncePrefinancement) error
+	Enregistrer(annonce *models.AnnoncePrefinancement, attendue int64) error
+	Supprimer(id uuid.UUID, attendue int64) error
+}
+
+// Données de référence, partagées avec les autres services (lecture seule ici)
+
+type Utilisateurs interface {
+	Trouver(id uuid.UUID) (models.User, error)
+	// SuspensionActive renvoie la suspension en cours de l'utilisateur, nil s'il n'est pas suspendu
+	SuspensionActive(id uuid.UUID, maintenant time.Time) (*models.Suspension, error)
+}
+
+type TypesCulture interface {
+	Trouver(id uuid.UUID) (models.TypeCulture, error)
+	Lister() ([]models.TypeCulture, error)
+}
+
+type Parcelles interface {
+	Trouver(id uuid.UUID) (models.Parcelle, error)
+}
+
+// Evenements émet les événements webhook des annonces (voir services.EmettreEvenement).
+// Dans une transaction, ils ne sont enregistrés que si la transaction est validée.
+type Evenements interface {
+	Emettre(evenement string, annonceType string, annonceID uuid.UUID, donnees interface{}) error
+	EmettreModification(annonceType string, annonceID uuid.UUID, ancienStatut string, statut string, donnees interface{}) error
+}
+
+// Depots regroupe les dépôts d'une même source de données
+type Depots struct {
+	Ventes       AnnoncesVente
+	Achats       AnnoncesAchat
+	Prefs        AnnoncesPref
+	Utilisateurs Utilisateurs
+	TypesCulture TypesCulture
+	Parcelles    Parcelles
+	Evenements   Evenements
+
+	transaction func(fn func(tx Depots) error) error
+}
+
+// Transaction exécute fn avec des dépôts liés à une même transaction : les écritures et
+// les événements émis par fn sont validés ensemble, ou annulés si fn renvoie une erreur.
+func (d Depots) Transaction(fn func(tx Depots) error) error {
+	return d.transaction(fn)
+}
@@ -0,0 +1,287 @@
+package depots
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/services"
+)
+
+// Relations chargées avec les annonces
+var (
+	relationsAvecParcelle = []string{"User", "TypeCulture", "Parcelle"}
+	relationsSansParcelle = []string{"User", "TypeCulture"}
+)
+
+// Gorm renvoie les dépôts adossés à la base de données
+func Gorm(db *gorm.DB) Depots {
+	d := Depots{
+		Ventes:       ventesGorm{db},
+		Achats:       achatsGorm{db},
+		Prefs:        prefsGorm{db},
+		Utilisateurs: utilisateursGorm{db},
+		TypesCulture: typesCultureGorm{db},
+		Parcelles:    parcellesGorm{db},
+		Evenements:   evenementsGorm{db},
+	}
+	d.transaction = func(fn func(tx Depots) error) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			return fn(Gorm(tx))
+		})
+	}
+	return d
+}
+
+// AppliquerFiltre ajoute les conditions du filtre à une requête sur une table d'annonces.
+// colonnePrix diffère selon le type d'annonce ; la région et la distance ne sont appliquées
+// que si la table référence une parcelle. Pour la distance, seul le rectangle englobant
+// est sélectionné ici : le résultat est à passer à dansLeRayon.
+func AppliquerFiltre(query *gorm.DB, f models.FiltreAnnonce, colonnePrix string, avecParcelle bool) *gorm.DB {
+	if f.UserID != "" {
+		query = query.Where("user_id = ?", f.UserID)
+	}
+	if f.Statut != "" {
+		query = query.Where("statut = ?", f.Statut)
+	}
+	if f.TypeCultureID != "" {
+		query = query.Where("type_culture_id = ?", f.TypeCultureID)
+	}
+	if f.PrixMin != nil {
+		query = query.Where(colonnePrix+" >= ?", *f.PrixMin)
+	}
+	if f.PrixMax != nil {
+		query = query.Where(colonnePrix+" <= ?", *f.PrixMax)
+	}
+	if f.QuantiteMin != nil {
+		query = query.Where("quantite >= ?", *f.QuantiteMin)
+	}
+	if f.Region != "" && avecParcelle {
+		query = query.Where("parcelle_id IN (?)", query.Session(&gorm.Session{NewDB: true}).
+			Model(&models.Parcelle{}).Select("id").
+			Where("LOWER(adresse) LIKE ?", "%"+strings.ToLower(f.Region)+"%"))
+	}
+	if f.AvecDistance() && avecParcelle {
+		latMin, latMax, lngMin, lngMax := f.Zone()
+		query = query.Where("parcelle_id IN (?)", query.Session(&gorm.Session{NewDB: true}).
+			Model(&models.Parcelle{}).Select("id").
+			Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?", latMin, latMax, lngMin, lngMax))
+	}
+	return query
+}
+
+// dansLeRayon retire les annonces sélectionnées par le rectangle englobant mais situées
+// hors du cercle de recherche
+func dansLeRayon[A interface{ Criteres() models.CriteresAnnonce }](annonces []A, f models.FiltreAnnonce) []A {
+	if !f.AvecDistance() {
+		return annonces
+	}
+	retenues := annonces[:0]
+	for _, a := range annonces {
+		if f.Correspond(a.Criteres()) {
+			retenues = append(retenues, a)
+		}
+	}
+	return retenues
+}
+
+// ExclureAnnoncesMasquees retire d'une requête publique (listes, favoris) les annonces masquées
+// par la modération et celles des utilisateurs suspendus
+func ExclureAnnoncesMasquees(query *gorm.DB, annonceType string) *gorm.DB {
+	nouvelle := query.Session(&gorm.Session{NewDB: true})
+	return query.
+		Where("id NOT IN (?)", nouvelle.Model(&models.ModerationAnnonce{}).Select("annonce_id").
+			Where("annonce_type = ? AND statut IN ?", annonceType, models.ModerationsMasquees)).
+		Where("user_id NOT IN (?)", nouvelle.Model(&models.Suspension{}).Select("user_id").
+			Where("fin_le IS NULL OR fin_le > ?", time.Now()))
+}
+
+func avecRelations(db *gorm.DB, relations []string) *gorm.DB {
+	for _, r := range relations {
+		db = db.Preload(r)
+	}
+	return db
+}
+
+// trouver charge un enregistrement par son ID ; ErrIntrouvable s'il n'existe pas
+func trouver(db *gorm.DB, cible interface{}, id uuid.UUID) error {
+	err := db.First(cible, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrIntrouvable
+	}
+	return err
+}
+
+// creer insère une annonce et recharge ses relations
+func creer(db *gorm.DB, annonce interface{}, relations []string) error {
+	if err := db.Create(annonce).Error; err != nil {
+		return err
+	}
+	return avecRelations(db, relations).First(annonce).Error
+}
+
+// enregistrer sauvegarde une annonce dont le champ Version vaut déjà attendue + 1,
+// seulement si sa version en base vaut toujours attendue, puis recharge ses relations
+func enregistrer(db *gorm.DB, annonce interface{}, attendue int64, relations []string) error {
+	res := db.Model(annonce).Where("version = ?", attendue).Select("*").Omit(clause.Associations).Updates(annonce)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return erreurs.VersionPerimee
+	}
+	return avecRelations(db, relations).First(annonce).Error
+}
+
+// supprimer supprime une annonce seulement si sa version vaut toujours attendue
+func supprimer(db *gorm.DB, modele interface{}, id uuid.UUID, attendue int64) error {
+	res := db.Where("id = ? AND version = ?", id, attendue).Delete(modele)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return erreurs.VersionPerimee
+	}
+	return nil
+}
+
+type ventesGorm struct{ db *gorm.DB }
+
+func (d ventesGorm) Lister(filtre models.FiltreAnnonce) ([]models.AnnonceVente, error) {
+	var annonces []models.AnnonceVente
+	query := AppliquerFiltre(avecRelations(d.db, relationsAvecParcelle), filtre, "prix_kg", true)
+	err := ExclureAnnoncesMasquees(query, models.AnnonceTypeVente).Find(&annonces).Error
+	return dansLeRayon(annonces, filtre), err
+}
+
+func (d ventesGorm) Trouver(id uuid.UUID) (models.AnnonceVente, error) {
+	var annonce models.AnnonceVente
+	err := trouver(avecRelations(d.db, relationsAvecParcelle), &annonce, id)
+	return annonce, err
+}
+
+func (d ventesGorm) Creer(annonce *models.AnnonceVente) error {
+	return creer(d.db, annonce, relationsAvecParcelle)
+}
+
+func (d ventesGorm) Enregistrer(annonce *models.AnnonceVente, attendue int64) error {
+	annonce.Version = attendue + 1
+	return enregistrer(d.db, annonce, attendue, relationsAvecParcelle)
+}
+
+func (d ventesGorm) Supprimer(id uuid.UUID, attendue int64) error {
+	return supprimer(d.db, &models.AnnonceVente{}, id, attendue)
+}
+
+type achatsGorm struct{ db *gorm.DB }
+
+func (d achatsGorm) Lister(filtre models.FiltreAnnonce) ([]models.AnnonceAchat, error) {
+	var annonces []models.AnnonceAchat
+	query := AppliquerFiltre(avecRelations(d.db, relationsSansParcelle), filtre, "prix_kg", false)
+	err := ExclureAnnoncesMasquees(query, models.AnnonceTypeAchat).Find(&annonces).Error
+	return annonces, err
+}
+
+func (d achatsGorm) Trouver(id uuid.UUID) (models.AnnonceAchat, error) {
+	var annonce models.AnnonceAchat
+	err := trouver(avecRelations(d.db, relationsSansParcelle), &annonce, id)
+	return annonce, err
+}
+
+func (d achatsGorm) Creer(annonce *models.AnnonceAchat) error {
+	return creer(d.db, annonce, relationsSansParcelle)
+}
+
+func (d achatsGorm) Enregistrer(annonce *models.AnnonceAchat, attendue int64) error {
+	annonce.Version = attendue + 1
+	return enregistrer(d.db, annonce, attendue, relationsSansParcelle)
+}
+
+func (d achatsGorm) Supprimer(id uuid.UUID, attendue int64) error {
+	return supprimer(d.db, &models.AnnonceAchat{}, id, attendue)
+}
+
+type prefsGorm struct{ db *gorm.DB }
+
+func (d prefsGorm) Lister(filtre models.FiltreAnnonce) ([]models.AnnoncePrefinancement, error) {
+	var annonces []models.AnnoncePrefinancement
+	query := AppliquerFiltre(avecRelations(d.db, relationsAvecParcelle), filtre, "prix_kg_pref", true)
+	err := ExclureAnnoncesMasquees(query, models.AnnonceTypePref).Find(&annonces).Error
+	return dansLeRayon(annonces, filtre), err
+}
+
+func (d prefsGorm) Trouver(id uuid.UUID) (models.AnnoncePrefinancement, error) {
+	var annonce models.AnnoncePrefinancement
+	err := trouver(avecRelations(d.db, relationsAvecParcelle), &annonce, id)
+	return annonce, err
+}
+
+func (d prefsGorm) Creer(annonce *models.AnnoncePrefinancement) error {
+	return creer(d.db, annonce, relationsAvecParcelle)
+}
+
+func (d prefsGorm) Enregistrer(annonce *models.AnnoncePrefinancement, attendue int64) error {
+	annonce.Version = attendue + 1
+	return enregistrer(d.db, annonce, attendue, relationsAvecParcelle)
+}
+
+func (d prefsGorm) Supprimer(id uuid.UUID, attendue int64) error {
+	return supprimer(d.db, &models.AnnoncePrefinancement{}, id, attendue)
+}
+
+type utilisateursGorm struct{ db *gorm.DB }
+
+func (d utilisateursGorm) Trouver(id uuid.UUID) (models.User, error) {
+	var user models.User
+	err := trouver(d.db, &user, id)
+	return user, err
+}
+
+func (d utilisateursGorm) SuspensionActive(id uuid.UUID, maintenant time.Time) (*models.Suspension, error) {
+	var suspension models.Suspension
+	if err := d.db.Where("user_id = ?", id).Limit(1).Find(&suspension).Error; err != nil {
+		return nil, err
+	}
+	if suspension.CreatedAt.IsZero() || !suspension.Active(maintenant) {
+		return nil, nil
+	}
+	return &suspension, nil
+}
+
+type typesCultureGorm struct{ db *gorm.DB }
+
+func (d typesCultureGorm) Trouver(id uuid.UUID) (models.TypeCulture, error) {
+	var tc models.TypeCulture
+	err := trouver(d.db, &tc, id)
+	return tc, err
+}
+
+func (d typesCultureGorm) Lister() ([]models.TypeCulture, error) {
+	types := []models.TypeCulture{}
+	err := d.db.Order("libelle").Find(&types).Error
+	return types, err
+}
+
+type parcellesGorm struct{ db *gorm.DB }
+
+func (d parcellesGorm) Trouver(id uuid.UUID) (models.Parcelle, error) {
+	var parcelle models.Parcelle
+	err := trouver(d.db, &parcelle, id)
+	return parcelle, err
+}
+
+type evenementsGorm struct{ db *gorm.DB }
+
+func (d evenementsGorm) Emettre(evenement string, annonceType string, annonceID uuid.UUID, donnees interface{}) error {
+	return services.EmettreEvenement(d.db, evenement, annonceType, annonceID, donnees)
+}
+
+func (d evenementsGorm) EmettreModification(annonceType string, annonceID uuid.UUID, ancienStatut string, statut string, donnees interface{}) error {
+	return services.EmettreModification(d.db, annonceType, annonceID, ancienStatut, statut, donnees)
+}
@@ -0,0 +1,480 @@
+package depots
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/services"
+)
+
+// EvenementEmis est un événement webhook enregistré par les dépôts en mémoire
+type EvenementEmis struct {
+	Evenement   string
+	AnnonceType string
+	AnnonceID   uuid.UUID
+	Donnees     interface{}
+}
+
+// Memoire est une source de données en mémoire, pour les tests et le développement sans
+// base. La modération n'y existe pas : seules les annonces des utilisateurs suspendus sont
+// exclues des listes. Les transactions sont exécutées l'une après l'autre, et annulées
+// si elles échouent.
+type Memoire struct {
+	mu           sync.Mutex
+	transactions sync.Mutex
+	donnees      donneesMemoire
+}
+
+type donneesMemoire struct {
+	ventes       map[uuid.UUID]models.AnnonceVente
+	achats       map[uuid.UUID]models.AnnonceAchat
+	prefs        map[uuid.UUID]models.AnnoncePrefinancement
+	utilisateurs map[uuid.UUID]models.User
+	typesCulture map[uuid.UUID]models.TypeCulture
+	parcelles    map[uuid.UUID]models.Parcelle
+	suspensions  map[uuid.UUID]models.Suspension
+	evenements   []EvenementEmis
+}
+
+// copie renvoie une copie indépendante des données (les annonces sont des valeurs)
+func (d donneesMemoire) copie() donneesMemoire {
+	return donneesMemoire{
+		ventes:       copieMap(d.ventes),
+		achats:       copieMap(d.achats),
+		prefs:        copieMap(d.prefs),
+		utilisateurs: copieMap(d.utilisateurs),
+		typesCulture: copieMap(d.typesCulture),
+		parcelles:    copieMap(d.parcelles),
+		suspensions:  copieMap(d.suspensions),
+		evenements:   append([]EvenementEmis(nil), d.evenements...),
+	}
+}
+
+func copieMap[T any](m map[uuid.UUID]T) map[uuid.UUID]T {
+	c := make(map[uuid.UUID]T, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// NouvelleMemoire crée une source de données en mémoire vide
+func NouvelleMemoire() *Memoire {
+	return &Memoire{donnees: donneesMemoire{}.copie()}
+}
+
+// Depots renvoie les dépôts adossés à la mémoire
+func (m *Memoire) Depots() Depots {
+	return Depots{
+		Ventes:       ventesMemoire{m},
+		Achats:       achatsMemoire{m},
+		Prefs:        prefsMemoire{m},
+		Utilisateurs: utilisateursMemoire{m},
+		TypesCulture: typesCultureMemoire{m},
+		Parcelles:    parcellesMemoire{m},
+		Evenements:   evenementsMemoire{m},
+		transaction:  m.transaction,
+	}
+}
+
+func (m *Memoire) transaction(fn func(tx Depots) error) error {
+	m.transactions.Lock()
+	defer m.transactions.Unlock()
+
+	m.mu.Lock()
+	avant := m.donnees.copie()
+	m.mu.Unlock()
+
+	err := fn(m.Depots())
+	if err != nil {
+		m.mu.Lock()
+		m.donnees = avant
+		m.mu.Unlock()
+	}
+	return err
+}
+
+// AjouterUtilisateur, AjouterTypeCulture et AjouterParcelle enregistrent les données de
+// référence, gérées hors de ce service
+
+func (m *Memoire) AjouterUtilisateur(u models.User) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.donnees.utilisateurs[u.ID] = u
+}
+
+func (m *Memoire) AjouterTypeCulture(tc models.TypeCulture) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.donnees.typesCulture[tc.ID] = tc
+}
+
+func (m *Memoire) AjouterParcelle(p models.Parcelle) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.donnees.parcelles[p.ID] = p
+}
+
+// Suspendre enregistre la suspension d'un utilisateur
+func (m *Memoire) Suspendre(s models.Suspension) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now()
+	}
+	m.donnees.suspensions[s.UserID] = s
+}
+
+// Evenements renvoie les événements webhook émis, dans l'ordre
+func (m *Memoire) Evenements() []EvenementEmis {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]EvenementEmis(nil), m.donnees.evenements...)
+}
+
+// suspendu indique si l'auteur d'une annonce est suspendu (m.mu verrouillé)
+func (m *Memoire) suspendu(userID uuid.UUID, maintenant time.Time) bool {
+	s, ok := m.donnees.suspensions[userID]
+	return ok && s.Active(maintenant)
+}
+
+// Les relations sont résolues à la lecture, comme un Preload (m.mu verrouillé)
+
+func (m *Memoire) vente(a models.AnnonceVente) models.AnnonceVente {
+	a.User = m.donnees.utilisateurs[a.UserID]
+	a.TypeCulture = m.donnees.typesCulture[a.TypeCultureID]
+	a.Parcelle = m.donnees.parcelles[a.ParcelleID]
+	return a
+}
+
+func (m *Memoire) achat(a models.AnnonceAchat) models.AnnonceAchat {
+	a.User = m.donnees.utilisateurs[a.UserID]
+	a.TypeCulture = m.donnees.typesCulture[a.TypeCultureID]
+	return a
+}
+
+func (m *Memoire) pref(a models.AnnoncePrefinancement) models.AnnoncePrefinancement {
+	a.User = m.donnees.utilisateurs[a.UserID]
+	a.TypeCulture = m.donnees.typesCulture[a.TypeCultureID]
+	a.Parcelle = m.donnees.parcelles[a.ParcelleID]
+	return a
+}
+
+// parDate trie des annonces par date de création puis par ID, pour un ordre stable
+func parDate(n int, date func(i int) time.Time, id func(i int) uuid.UUID) func(i, j int) bool {
+	return func(i, j int) bool {
+		if !date(i).Equal(date(j)) {
+			return date(i).Before(date(j))
+		}
+		return id(i).String() < id(j).String()
+	}
+}
+
+// Les annonces sont stockées sans relations
+
+type ventesMemoire struct{ m *Memoire }
+
+func (d ventesMemoire) Lister(filtre models.FiltreAnnonce) ([]models.AnnonceVente, error) {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	maintenant := time.Now()
+	annonces := []models.AnnonceVente{}
+	for _, a := range d.m.donnees.ventes {
+		a = d.m.vente(a)
+		if filtre.Correspond(a.Criteres()) && !d.m.suspendu(a.UserID, maintenant) {
+			annonces = append(annonces, a)
+		}
+	}
+	sort.Slice(annonces, parDate(len(annonces),
+		func(i int) time.Time { return annonces[i].CreatedAt },
+		func(i int) uuid.UUID { return annonces[i].ID }))
+	return annonces, nil
+}
+
+func (d ventesMemoire) Trouver(id uuid.UUID) (models.AnnonceVente, error) {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	a, ok := d.m.donnees.ventes[id]
+	if !ok {
+		return a, ErrIntrouvable
+	}
+	return d.m.vente(a), nil
+}
+
+func (d ventesMemoire) Creer(annonce *models.AnnonceVente) error {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	if annonce.ID == uuid.Nil {
+		annonce.ID = uuid.New()
+	}
+	if annonce.Version == 0 {
+		annonce.Version = 1
+	}
+	if annonce.CreatedAt.IsZero() {
+		annonce.CreatedAt = time.Now()
+	}
+	stockee := *annonce
+	stockee.User, stockee.TypeCulture, stockee.Parcelle = models.User{}, models.TypeCulture{}, models.Parcelle{}
+	d.m.donnees.ventes[annonce.ID] = stockee
+	*annonce = d.m.vente(stockee)
+	return nil
+}
+
+func (d ventesMemoire) Enregistrer(annonce *models.AnnonceVente, attendue int64) error {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	existante, ok := d.m.donnees.ventes[annonce.ID]
+	if !ok || existante.Version != attendue {
+		return erreurs.VersionPerimee
+	}
+	annonce.Version = attendue + 1
+	stockee := *annonce
+	stockee.User, stockee.TypeCulture, stockee.Parcelle = models.User{}, models.TypeCulture{}, models.Parcelle{}
+	d.m.donnees.ventes[annonce.ID] = stockee
+	*annonce = d.m.vente(stockee)
+	return nil
+}
+
+func (d ventesMemoire) Supprimer(id uuid.UUID, attendue int64) error {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	existante, ok := d.m.donnees.ventes[id]
+	if !ok || existante.Version != attendue {
+		return erreurs.VersionPerimee
+	}
+	delete(d.m.donnees.ventes, id)
+	return nil
+}
+
+type achatsMemoire struct{ m *Memoire }
+
+func (d achatsMemoire) Lister(filtre models.FiltreAnnonce) ([]models.AnnonceAchat, error) {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	maintenant := time.Now()
+	annonces := []models.AnnonceAchat{}
+	for _, a := range d.m.donnees.achats {
+		a = d.m.achat(a)
+		if filtre.Correspond(a.Criteres()) && !d.m.suspendu(a.UserID, maintenant) {
+			annonces = append(annonces, a)
+		}
+	}
+	sort.Slice(annonces, parDate(len(annonces),
+		func(i int) time.Time { return annonces[i].CreatedAt },
+		func(i int) uuid.UUID { return annonces[i].ID }))
+	return annonces, nil
+}
+
+func (d achatsMemoire) Trouver(id uuid.UUID) (models.AnnonceAchat, error) {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	a, ok := d.m.donnees.achats[id]
+	if !ok {
+		return a, ErrIntrouvable
+	}
+	return d.m.achat(a), nil
+}
+
+func (d achatsMemoire) Creer(annonce *models.AnnonceAchat) error {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	if annonce.ID == uuid.Nil {
+		annonce.ID = uuid.New()
+	}
+	if annonce.Version == 0 {
+		annonce.Version = 1
+	}
+	if annonce.CreatedAt.IsZero() {
+		annonce.CreatedAt = time.Now()
+	}
+	stockee := *annonce
+	stockee.User, stockee.TypeCulture = models.User{}, models.TypeCulture{}
+	d.m.donnees.achats[annonce.ID] = stockee
+	*annonce = d.m.achat(stockee)
+	return nil
+}
+
+func (d achatsMemoire) Enregistrer(annonce *models.AnnonceAchat, attendue int64) error {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	existante, ok := d.m.donnees.achats[annonce.ID]
+	if !ok || existante.Version != attendue {
+		return erreurs.VersionPerimee
+	}
+	annonce.Version = attendue + 1
+	stockee := *annonce
+	stockee.User, stockee.TypeCulture = models.User{}, models.TypeCulture{}
+	d.m.donnees.achats[annonce.ID] = stockee
+	*annonce = d.m.achat(stockee)
+	return nil
+}
+
+func (d achatsMemoire) Supprimer(id uuid.UUID, attendue int64) error {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	existante, ok := d.m.donnees.achats[id]
+	if !ok || existante.Version != attendue {
+		return erreurs.VersionPerimee
+	}
+	delete(d.m.donnees.achats, id)
+	return nil
+}
+
+type prefsMemoire struct{ m *Memoire }
+
+func (d prefsMemoire) Lister(filtre models.FiltreAnnonce) ([]models.AnnoncePrefinancement, error) {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	maintenant := time.Now()
+	annonces := []models.AnnoncePrefinancement{}
+	for _, a := range d.m.donnees.prefs {
+		a = d.m.pref(a)
+		if filtre.Correspond(a.Criteres()) && !d.m.suspendu(a.UserID, maintenant) {
+			annonces = append(annonces, a)
+		}
+	}
+	sort.Slice(annonces, parDate(len(annonces),
+		func(i int) time.Time { return annonces[i].CreatedAt },
+		func(i int) uuid.UUID { return annonces[i].ID }))
+	return annonces, nil
+}
+
+func (d prefsMemoire) Trouver(id uuid.UUID) (models.AnnoncePrefinancement, error) {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	a, ok := d.m.donnees.prefs[id]
+	if !ok {
+		return a, ErrIntrouvable
+	}
+	return d.m.pref(a), nil
+}
+
+func (d prefsMemoire) Creer(annonce *models.AnnoncePrefinancement) error {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	if annonce.ID == uuid.Nil {
+		annonce.ID = uuid.New()
+	}
+	if annonce.Version == 0 {
+		annonce.Version = 1
+	}
+	if annonce.CreatedAt.IsZero() {
+		annonce.CreatedAt = time.Now()
+	}
+	stockee := *annonce
+	stockee.User, stockee.TypeCulture, stockee.Parcelle = models.User{}, models.TypeCulture{}, models.Parcelle{}
+	d.m.donnees.prefs[annonce.ID] = stockee
+	*annonce = d.m.pref(stockee)
+	return nil
+}
+
+func (d prefsMemoire) Enregistrer(annonce *models.AnnoncePrefinancement, attendue int64) error {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	existante, ok := d.m.donnees.prefs[annonce.ID]
+	if !ok || existante.Version != attendue {
+		return erreurs.VersionPerimee
+	}
+	annonce.Version = attendue + 1
+	stockee := *annonce
+	stockee.User, stockee.TypeCulture, stockee.Parcelle = models.User{}, models.TypeCulture{}, models.Parcelle{}
+	d.m.donnees.prefs[annonce.ID] = stockee
+	*annonce = d.m.pref(stockee)
+	return nil
+}
+
+func (d prefsMemoire) Supprimer(id uuid.UUID, attendue int64) error {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	existante, ok := d.m.donnees.prefs[id]
+	if !ok || existante.Version != attendue {
+		return erreurs.VersionPerimee
+	}
+	delete(d.m.donnees.prefs, id)
+	return nil
+}
+
+type utilisateursMemoire struct{ m *Memoire }
+
+func (d utilisateursMemoire) Trouver(id uuid.UUID) (models.User, error) {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	u, ok := d.m.donnees.utilisateurs[id]
+	if !ok {
+		return u, ErrIntrouvable
+	}
+	return u, nil
+}
+
+func (d utilisateursMemoire) SuspensionActive(id uuid.UUID, maintenant time.Time) (*models.Suspension, error) {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	s, ok := d.m.donnees.suspensions[id]
+	if !ok || !s.Active(maintenant) {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+type typesCultureMemoire struct{ m *Memoire }
+
+func (d typesCultureMemoire) Trouver(id uuid.UUID) (models.TypeCulture, error) {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	tc, ok := d.m.donnees.typesCulture[id]
+	if !ok {
+		return tc, ErrIntrouvable
+	}
+	return tc, nil
+}
+
+func (d typesCultureMemoire) Lister() ([]models.TypeCulture, error) {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	types := []models.TypeCulture{}
+	for _, tc := range d.m.donnees.typesCulture {
+		types = append(types, tc)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Libelle < types[j].Libelle })
+	return types, nil
+}
+
+type parcellesMemoire struct{ m *Memoire }
+
+func (d parcellesMemoire) Trouver(id uuid.UUID) (models.Parcelle, error) {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	p, ok := d.m.donnees.parcelles[id]
+	if !ok {
+		return p, ErrIntrouvable
+	}
+	return p, nil
+}
+
+type evenementsMemoire struct{ m *Memoire }
+
+func (d evenementsMemoire) Emettre(evenement string, annonceType string, annonceID uuid.UUID, donnees interface{}) error {
+	d.m.mu.Lock()
+	defer d.m.mu.Unlock()
+	d.m.donnees.evenements = append(d.m.donnees.evenements, EvenementEmis{
+		Evenement:   evenement,
+		AnnonceType: annonceType,
+		AnnonceID:   annonceID,
+		Donnees:     donnees,
+	})
+	return nil
+}
+
+func (d evenementsMemoire) EmettreModification(annonceType string, annonceID uuid.UUID, ancienStatut string, statut string, donnees interface{}) error {
+	for _, evenement := range services.EvenementsModification(annonceType, ancienStatut, statut) {
+		if err := d.Emettre(evenement, annonceType, annonceID, donnees); err != nil {
+			return err
+		}
+	}
+	return nil
+}
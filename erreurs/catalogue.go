@@ -0,0 +1,102 @@
+package erreurs
+
+import "net/http"
+
+// Catalogue des erreurs de l'API. Les codes sont stables : les clients peuvent s'y fier,
+// contrairement aux messages qui peuvent évoluer.
+
+// Requête
+var (
+	CorpsInvalide        = Definir(http.StatusBadRequest, "corps_invalide", "Corps de requête illisible ou JSON mal formé", "Unreadable request body or malformed JSON")
+	DonneesInvalides     = Definir(http.StatusBadRequest, "donnees_invalides", "Données invalides", "Invalid data")
+	ParametreInvalide    = Definir(http.StatusBadRequest, "parametre_invalide", "Paramètre de requête invalide", "Invalid query parameter")
+	FiltreInvalide       = Definir(http.StatusBadRequest, "filtre_invalide", "Paramètres de filtre invalides", "Invalid filter parameters")
+	RouteIntrouvable     = Definir(http.StatusNotFound, "route_introuvable", "Route introuvable", "Route not found")
+	IDInvalide           = Definir(http.StatusBadRequest, "id_invalide", "Identifiant invalide", "Invalid identifier")
+	TypeAnnonceInconnu   = Definir(http.StatusBadRequest, "type_annonce_inconnu", "Type d'annonce inconnu (vente, achat ou pref attendu)", "Unknown listing type (vente, achat or pref expected)")
+	TypeMediaNonSupporte = Definir(http.StatusUnsupportedMediaType, "type_media_non_supporte", "Content-Type attendu : %s", "Expected Content-Type: %s")
+	TropDeRequetes       = Definir(http.StatusTooManyRequests, "trop_de_requetes", "Trop de requêtes, réessayez plus tard", "Too many requests, try again later")
+	ErreurInterne        = Definir(http.StatusInternalServerError, "erreur_interne", "Erreur interne du serveur", "Internal server error")
+	BaseIndisponible     = Definir(http.StatusServiceUnavailable, "base_indisponible", "Fonctionnalité indisponible sans base de données", "Feature unavailable without a database")
+)
+
+// Authentification et autorisations
+var (
+	TokenRequis         = Definir(http.StatusUnauthorized, "token_requis", "Token requis", "Token required")
+	TokenFormatInvalide = Definir(http.StatusUnauthorized, "token_format_invalide", "Format de token invalide", "Invalid token format")
+	TokenInvalide       = Definir(http.StatusForbidden, "token_invalide", "Token invalide", "Invalid token")
+	NonAuthentifie      = Definir(http.StatusUnauthorized, "non_authentifie", "Utilisateur non authentifié", "User not authenticated")
+	AccesAdministrateur = Definir(http.StatusForbidden, "acces_administrateur", "Accès réservé aux administrateurs", "Administrators only")
+	AccesPartenaire     = Definir(http.StatusForbidden, "acces_partenaire", "Accès réservé aux partenaires", "Partners only")
+	CompteSuspendu      = Definir(http.StatusForbidden, "compte_suspendu", "Compte suspendu : %s", "Account suspended: %s")
+)
+
+// Idempotence et concurrence
+var (
+	CleIdempotenceTropLongue = Definir(http.StatusBadRequest, "cle_idempotence_trop_longue", "%s trop long (%d caractères maximum)", "%s too long (%d characters maximum)")
+	CleIdempotenceReutilisee = Definir(http.StatusConflict, "cle_idempotence_reutilisee", "Clé d'idempotence déjà utilisée pour une requête différente", "Idempotency key already used for a different request")
+	IdempotenceEnCours       = Definir(http.StatusConflict, "idempotence_en_cours", "Requête avec cette clé d'idempotence en cours de traitement", "A request with this idempotency key is being processed")
+	IfMatchRequis            = Definir(http.StatusPreconditionRequired, "if_match_requis", "En-tête If-Match requis (ETag de l'annonce)", "If-Match header required (listing ETag)")
+	IfMatchInvalide          = Definir(http.StatusPreconditionRequired, "if_match_invalide", "En-tête If-Match invalide", "Invalid If-Match header")
+	VersionPerimee           = Definir(http.StatusPreconditionFailed, "version_perimee", "L'annonce a été modifiée entre-temps", "The listing has been modified in the meantime")
+)
+
+// Ressources introuvables
+var (
+	AnnonceIntrouvable      = Definir(http.StatusNotFound, "annonce_introuvable", "Annonce non trouvée", "Listing not found")
+	UtilisateurIntrouvable  = Definir(http.StatusNotFound, "utilisateur_introuvable", "Utilisateur introuvable", "User not found")
+	TypeCultureIntrouvable  = Definir(http.StatusNotFound, "type_culture_introuvable", "Type de culture introuvable", "Crop type not found")
+	ConversationIntrouvable = Definir(http.StatusNotFound, "conversation_introuvable", "Conversation non trouvée", "Conversation not found")
+	PieceJointeIntrouvable  = Definir(http.StatusNotFound, "piece_jointe_introuvable", "Pièce jointe non trouvée", "Attachment not found")
+	AvisIntrouvable         = Definir(http.StatusNotFound, "avis_introuvable", "Avis non trouvé", "Review not found")
+	FavoriIntrouvable       = Definir(http.StatusNotFound, "favori_introuvable", "Favori non trouvé", "Favorite not found")
+	NotificationIntrouvable = Definir(http.StatusNotFound, "notification_introuvable", "Notification non trouvée", "Notification not found")
+	RechercheIntrouvable    = Definir(http.StatusNotFound, "recherche_introuvable", "Recherche non trouvée", "Saved search not found")
+	WebhookIntrouvable      = Definir(http.StatusNotFound, "webhook_introuvable", "Webhook non trouvé", "Webhook not found")
+	LivraisonIntrouvable    = Definir(http.StatusNotFound, "livraison_introuvable", "Livraison non trouvée", "Delivery not found")
+	SuspensionIntrouvable   = Definir(http.StatusNotFound, "suspension_introuvable", "Aucune suspension pour cet utilisateur", "No suspension for this user")
+)
+
+// Références fournies dans le corps d'une requête
+var (
+	TypeCultureInconnu = Definir(http.StatusBadRequest, "type_culture_inconnu", "Type de culture introuvable", "Crop type not found")
+	ParcelleInconnue   = Definir(http.StatusBadRequest, "parcelle_inconnue", "Parcelle introuvable", "Plot not found")
+	ParcelleInterdite  = Definir(http.StatusForbidden, "parcelle_interdite", "Parcelle d'un autre exploitant", "Plot belongs to another farmer")
+	UtilisateurInconnu = Definir(http.StatusBadRequest, "utilisateur_inconnu", "Utilisateur introuvable", "User not found")
+)
+
+// Règles métier
+var (
+	ConversationInterdite      = Definir(http.StatusForbidden, "conversation_interdite", "Accès réservé aux participants de la conversation", "Only conversation participants have access")
+	ConversationPropreAnnonce  = Definir(http.StatusBadRequest, "conversation_propre_annonce", "Impossible d'ouvrir une conversation sur sa propre annonce", "Cannot open a conversation on your own listing")
+	MessageVide                = Definir(http.StatusBadRequest, "message_vide", "Message vide", "Empty message")
+	MessageTropLong            = Definir(http.StatusBadRequest, "message_trop_long", "Message trop long (%d caractères maximum)", "Message too long (%d characters maximum)")
+	CommandeInconnue           = Definir(http.StatusBadRequest, "commande_inconnue", "Type de commande inconnu : %s", "Unknown command type: %s")
+	PieceJointeTropVolumineuse = Definir(http.StatusBadRequest, "piece_jointe_trop_volumineuse", "Pièce jointe trop volumineuse (10 Mo maximum)", "Attachment too large (10 MB maximum)")
+	PieceJointeTypeInterdit    = Definir(http.StatusBadRequest, "piece_jointe_type_interdit", "Type de pièce jointe non autorisé (jpg, png ou pdf)", "Attachment type not allowed (jpg, png or pdf)")
+
+	ConclusionNonDemandee     = Definir(http.StatusConflict, "conclusion_non_demandee", "Le producteur n'a pas encore déclaré la transaction conclue", "The producer has not declared the transaction concluded yet")
+	TransactionNonConclue     = Definir(http.StatusConflict, "transaction_non_conclue", "La transaction n'est pas encore conclue", "The transaction has not been concluded yet")
+	AvisExistant              = Definir(http.StatusConflict, "avis_existant", "Vous avez déjà laissé un avis pour cette transaction", "You have already reviewed this transaction")
+	ModificationAvisInterdite = Definir(http.StatusForbidden, "modification_avis_interdite", "Seul l'auteur peut modifier cet avis", "Only the author can edit this review")
+	DelaiAvisDepasse          = Definir(http.StatusConflict, "delai_avis_depasse", "Le délai de modification de cet avis est dépassé", "The edit window for this review has expired")
+	ReponseAvisInterdite      = Definir(http.StatusForbidden, "reponse_avis_interdite", "Seul l'utilisateur évalué peut répondre à cet avis", "Only the reviewed user can reply to this review")
+
+	SignalementPropreAnnonce = Definir(http.StatusBadRequest, "signalement_propre_annonce", "Impossible de signaler sa propre annonce", "Cannot report your own listing")
+	SignalementExistant      = Definir(http.StatusConflict, "signalement_existant", "Vous avez déjà signalé cette annonce", "You have already reported this listing")
+	TypeCultureExistant      = Definir(http.StatusConflict, "type_culture_existant", "Un type de culture porte déjà ce libellé", "A crop type with this label already exists")
+	TypeCultureUtilise       = Definir(http.StatusConflict, "type_culture_utilise", "Type de culture utilisé par des annonces", "Crop type is used by listings")
+	AdresseWebhookInterdite  = Definir(http.StatusBadRequest, "adresse_webhook_interdite", "L'URL du webhook doit désigner une adresse publique", "The webhook URL must point to a public address")
+)
+
+// Import CSV
+var (
+	CSVManquant           = Definir(http.StatusBadRequest, "csv_manquant", "Fichier CSV manquant (champ \"fichier\")", "Missing CSV file (\"fichier\" field)")
+	CSVIllisible          = Definir(http.StatusBadRequest, "csv_illisible", "Impossible de lire le fichier CSV", "Unable to read the CSV file")
+	CSVTropVolumineux     = Definir(http.StatusBadRequest, "csv_trop_volumineux", "Fichier CSV trop volumineux", "CSV file too large")
+	CSVVide               = Definir(http.StatusBadRequest, "csv_vide", "Fichier CSV vide", "Empty CSV file")
+	CSVInvalide           = Definir(http.StatusBadRequest, "csv_invalide", "CSV invalide : %v", "Invalid CSV: %v")
+	CSVSansDonnees        = Definir(http.StatusBadRequest, "csv_sans_donnees", "Le fichier CSV ne contient aucune ligne de données", "The CSV file has no data rows")
+	CSVTropDeLignes       = Definir(http.StatusBadRequest, "csv_trop_de_lignes", "Le fichier CSV dépasse %d lignes", "The CSV file exceeds %d rows")
+	CSVColonnesManquantes = Definir(http.StatusBadRequest, "csv_colonnes_manquantes", "Colonnes manquantes : %s", "Missing columns: %s")
+)
@@ -0,0 +1,193 @@
+package erreurs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CleRequestID est la clé du contexte Gin (et l'en-tête HTTP) portant l'identifiant de requête
+const CleRequestID = "X-Request-ID"
+
+// Langues des messages d'erreur. Le français est la langue par défaut.
+const (
+	LangueFR = "fr"
+	LangueEN = "en"
+)
+
+// Erreur est une erreur renvoyée aux clients de l'API : un code stable, lisible par un
+// programme, un statut HTTP et un message en français et en anglais. Les messages peuvent
+// contenir des verbes de format, remplis par Avec.
+type Erreur struct {
+	Statut  int
+	Code    string
+	fr      string
+	en      string
+	args    []interface{}
+	Details []Detail
+	// cause est l'erreur interne d'origine : journalisée, jamais renvoyée au client
+	cause error
+}
+
+// Definir déclare une erreur du catalogue
+func Definir(statut int, code string, fr string, en string) *Erreur {
+	return &Erreur{Statut: statut, Code: code, fr: fr, en: en}
+}
+
+func (e *Erreur) copie() *Erreur {
+	c := *e
+	c.args = append([]interface{}(nil), e.args...)
+	c.Details = append([]Detail(nil), e.Details...)
+	return &c
+}
+
+// Avec renvoie l'erreur avec les paramètres de son message
+func (e *Erreur) Avec(args ...interface{}) *Erreur {
+	c := e.copie()
+	c.args = args
+	return c
+}
+
+// Champ renvoie l'erreur complétée d'un détail sur un champ de la requête
+func (e *Erreur) Champ(champ string, code string, param ...string) *Erreur {
+	c := e.copie()
+	c.Details = append(c.Details, Detail{Champ: champ, Code: code, Param: strings.Join(param, ", ")})
+	return c
+}
+
+// Message renvoie le message de l'erreur dans la langue demandée
+func (e *Erreur) Message(langue string) string {
+	format := e.fr
+	if langue == LangueEN {
+		format = e.en
+	}
+	if len(e.args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, e.args...)
+}
+
+func (e *Erreur) Error() string {
+	message := e.Message(LangueFR)
+	if e.cause != nil {
+		return message + " : " + e.cause.Error()
+	}
+	return message
+}
+
+func (e *Erreur) Unwrap() error {
+	return e.cause
+}
+
+// Is compare les erreurs par code : errors.Is(err, erreurs.AnnonceIntrouvable) reste vrai
+// après Avec ou Champ
+func (e *Erreur) Is(cible error) bool {
+	var autre *Erreur
+	return errors.As(cible, &autre) && autre.Code == e.Code
+}
+
+// Interne enveloppe une erreur inattendue (base de données, système de fichiers...) :
+// elle est journalisée et le client ne reçoit qu'un message générique
+func Interne(err error) *Erreur {
+	c := ErreurInterne.copie()
+	c.cause = err
+	return c
+}
+
+// Depuis convertit une erreur quelconque en *Erreur ; les erreurs hors catalogue deviennent
+// des erreurs internes
+func Depuis(err error) *Erreur {
+	var e *Erreur
+	if errors.As(err, &e) {
+		return e
+	}
+	return Interne(err)
+}
+
+// Corps est la représentation JSON d'une erreur, sous la clé "error" de la réponse
+type Corps struct {
+	Code      string        `json:"code"`
+	Message   string        `json:"message"`
+	Details   []CorpsDetail `json:"details,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+}
+
+// CorpsDetail est la représentation JSON d'un détail de validation
+type CorpsDetail struct {
+	Champ   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Representer construit le corps d'une erreur pour la langue et la requête données
+func Representer(e *Erreur, langue string, requestID string) Corps {
+	corps := Corps{Code: e.Code, Message: e.Message(langue), RequestID: requestID}
+	for _, d := range e.Details {
+		corps.Details = append(corps.Details, CorpsDetail{Champ: d.Champ, Code: d.Code, Message: d.Message(langue)})
+	}
+	return corps
+}
+
+// Repondre envoie l'erreur au client dans l'enveloppe commune et interrompt la chaîne
+// des handlers. Les erreurs internes sont journalisées avec l'identifiant de requête.
+func Repondre(c *gin.Context, err error) {
+	RepondreAvec(c, err, nil)
+}
+
+// RepondreAvec envoie l'erreur avec des champs supplémentaires au même niveau que "error"
+// (ex. l'état courant d'une annonce sur un 412)
+func RepondreAvec(c *gin.Context, err error, extra gin.H) {
+	e := Depuis(err)
+	requestID := c.GetString(CleRequestID)
+	if e.cause != nil {
+		log.Printf("[%s] %s %s : %v\n", requestID, c.Request.Method, c.Request.URL.Path, e.cause)
+	}
+
+	reponse := gin.H{"error": Representer(e, Langue(c), requestID)}
+	for cle, valeur := range extra {
+		reponse[cle] = valeur
+	}
+	c.AbortWithStatusJSON(e.Statut, reponse)
+}
+
+// Langue choisit la langue des messages d'après l'en-tête Accept-Language (fr par défaut)
+func Langue(c *gin.Context) string {
+	return LangueAcceptee(c.GetHeader("Accept-Language"))
+}
+
+// LangueAcceptee renvoie la langue prise en charge préférée par l'en-tête Accept-Language
+func LangueAcceptee(entete string) string {
+	type choix struct {
+		langue string
+		poids  float64
+	}
+	var candidats []choix
+	for _, partie := range strings.Split(entete, ",") {
+		morceaux := strings.Split(strings.TrimSpace(partie), ";")
+		langue := strings.ToLower(strings.SplitN(strings.TrimSpace(morceaux[0]), "-", 2)[0])
+		if langue != LangueFR && langue != LangueEN {
+			continue
+		}
+		poids := 1.0
+		for _, m := range morceaux[1:] {
+			if q, ok := strings.CutPrefix(strings.TrimSpace(m), "q="); ok {
+				if v, err := strconv.ParseFloat(q, 64); err == nil {
+					poids = v
+				}
+			}
+		}
+		if poids > 0 {
+			candidats = append(candidats, choix{langue, poids})
+		}
+	}
+	if len(candidats) == 0 {
+		return LangueFR
+	}
+	sort.SliceStable(candidats, func(i, j int) bool { return candidats[i].poids > candidats[j].poids })
+	return candidats[0].langue
+}
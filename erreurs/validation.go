@@ -0,0 +1,178 @@
+package erreurs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Codes des détails de validation, rattachés à un champ de la requête
+const (
+	DetailObligatoire    = "obligatoire"
+	DetailInconnu        = "inconnu"
+	DetailImmuable       = "immuable"
+	DetailType           = "type"
+	DetailFormat         = "format"
+	DetailSuperieurA     = "superieur_a"
+	DetailMinimum        = "minimum"
+	DetailMaximum        = "maximum"
+	DetailValeurInconnue = "valeur_inconnue"
+	DetailTropLong       = "trop_long"
+	DetailInvalide       = "invalide"
+)
+
+// RegleNombre est la règle binding des nombres acceptés en nombre JSON ou en texte :
+// elle échoue sur une valeur illisible
+const RegleNombre = "nombre"
+
+// Detail décrit le problème d'un champ ; Param complète le message (valeur minimale,
+// valeurs acceptées...)
+type Detail struct {
+	Champ string
+	Code  string
+	Param string
+}
+
+// messageDetail : message d'un détail en français et en anglais, et sa variante avec Param
+type messageDetail struct {
+	fr, en           string
+	frParam, enParam string
+}
+
+var messagesDetails = map[string]messageDetail{
+	DetailObligatoire:    {fr: "champ obligatoire", en: "field is required"},
+	DetailInconnu:        {fr: "champ inconnu", en: "unknown field"},
+	DetailImmuable:       {fr: "champ non modifiable", en: "field cannot be modified"},
+	DetailType:           {"type invalide", "invalid type", "type invalide (%s attendu)", "invalid type (%s expected)"},
+	DetailFormat:         {"format invalide", "invalid format", "format invalide (%s attendu)", "invalid format (%s expected)"},
+	DetailSuperieurA:     {"valeur trop petite", "value too small", "doit être supérieur à %s", "must be greater than %s"},
+	DetailMinimum:        {"valeur trop petite", "value too small", "doit être au moins %s", "must be at least %s"},
+	DetailMaximum:        {"valeur trop grande", "value too large", "doit être au plus %s", "must be at most %s"},
+	DetailValeurInconnue: {"valeur inconnue", "unknown value", "valeur inconnue (valeurs acceptées : %s)", "unknown value (accepted values: %s)"},
+	DetailTropLong:       {"trop long", "too long", "trop long (%s caractères maximum)", "too long (%s characters maximum)"},
+	DetailInvalide:       {fr: "valeur invalide", en: "invalid value"},
+}
+
+// Message renvoie le message du détail dans la langue demandée
+func (d Detail) Message(langue string) string {
+	m, ok := messagesDetails[d.Code]
+	if !ok {
+		m = messagesDetails[DetailInvalide]
+	}
+	if d.Param != "" && m.frParam != "" {
+		if langue == LangueEN {
+			return fmt.Sprintf(m.enParam, d.Param)
+		}
+		return fmt.Sprintf(m.frParam, d.Param)
+	}
+	if langue == LangueEN {
+		return m.en
+	}
+	return m.fr
+}
+
+// Les erreurs du validateur désignent les champs par leur nom JSON, comme les clients
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			nom := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if nom == "-" {
+				return ""
+			}
+			if nom == "" {
+				return f.Name
+			}
+			return nom
+		})
+	}
+}
+
+// Validation convertit l'erreur d'un binding Gin (JSON mal formé, type incorrect, règle
+// binding non respectée) en erreur donnees_invalides détaillée champ par champ
+func Validation(err error) *Erreur {
+	var e *Erreur
+	if errors.As(err, &e) {
+		return e
+	}
+
+	resultat := DonneesInvalides
+	var validation validator.ValidationErrors
+	var typeJSON *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validation):
+		for _, fe := range validation {
+			resultat = resultat.Champ(cheminChamp(fe), codeRegle(fe), paramRegle(fe))
+		}
+	case errors.As(err, &typeJSON):
+		resultat = resultat.Champ(typeJSON.Field, DetailType, typeJSONAttendu(typeJSON.Type))
+	default:
+		resultat = CorpsInvalide
+	}
+	return resultat
+}
+
+// cheminChamp renvoie le chemin JSON d'un champ, sans le nom de la structure racine
+func cheminChamp(fe validator.FieldError) string {
+	chemin := fe.Namespace()
+	if i := strings.Index(chemin, "."); i >= 0 {
+		return chemin[i+1:]
+	}
+	return fe.Field()
+}
+
+func codeRegle(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case RegleNombre:
+		return DetailType
+	case "required":
+		return DetailObligatoire
+	case "gt":
+		return DetailSuperieurA
+	case "gte", "min":
+		return DetailMinimum
+	case "lte", "max":
+		if fe.Kind() == reflect.String {
+			return DetailTropLong
+		}
+		return DetailMaximum
+	case "oneof":
+		return DetailValeurInconnue
+	case "uuid", "email", "url", "datetime":
+		return DetailFormat
+	}
+	return DetailInvalide
+}
+
+func paramRegle(fe validator.FieldError) string {
+	switch {
+	case fe.Tag() == "oneof":
+		return strings.Join(strings.Fields(fe.Param()), ", ")
+	case fe.Tag() == RegleNombre:
+		return "number"
+	case codeRegle(fe) == DetailFormat:
+		return fe.Tag()
+	}
+	return fe.Param()
+}
+
+// typeJSONAttendu renvoie le nom du type JSON correspondant à un type Go
+func typeJSONAttendu(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
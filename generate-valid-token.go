@@ -1,7 +1,11 @@
+//go:build ignore
+
+// Génère un token JWT de test : jwt_secret=... go run generate-valid-token.go
 package main
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -15,8 +19,15 @@ func generateToken() {
 		"exp":       time.Now().Add(time.Hour * 24 * 30).Unix(), // 30 jours
 	}
 
-	// Secret (doit correspondre exactement à votre middleware)
-	secret := "mon_projet_agrobloc_jwt"
+	// Secret (doit correspondre exactement au paramètre jwt_secret de l'API)
+	secret := os.Getenv("jwt_secret")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	if secret == "" {
+		fmt.Println("Variable d'environnement jwt_secret (ou JWT_SECRET) requise")
+		return
+	}
 
 	// Créer le token
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
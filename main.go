@@ -1,13 +1,132 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Steph-business/annonce_de_vente/config"
+	"github.com/Steph-business/annonce_de_vente/controllers"
 	"github.com/Steph-business/annonce_de_vente/database"
+	"github.com/Steph-business/annonce_de_vente/depots"
 	"github.com/Steph-business/annonce_de_vente/routes"
+	"github.com/Steph-business/annonce_de_vente/services"
 )
 
+// delaiLectureEntetes borne la lecture des en-têtes d'une requête (connexions lentes)
+const delaiLectureEntetes = 10 * time.Second
+
 func main() {
-	database.InitDB()
-	r := routes.SetupRoutes()
-	r.Static("/static", "./uploads")
-	r.Run("0.0.0.0:8080")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			commandeMigrate(os.Args[2:])
+			return
+		case "seed":
+			commandeSeed(os.Args[2:])
+			return
+		}
+	}
+
+	options := flag.NewFlagSet("annonces", flag.ExitOnError)
+	migrer := options.Bool("migrer", false, "applique les migrations SQL en attente au démarrage")
+	afficher := options.Bool("afficher_config", false, "affiche la configuration effective (secrets masqués) et quitte")
+	cfg, err := config.Charger(options, os.Args[1:])
+	if *afficher {
+		cfg.Afficher(os.Stdout)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *afficher {
+		return
+	}
+	if err := cfg.ValiderServeur(); err != nil {
+		log.Fatal(err)
+	}
+
+	var db *gorm.DB
+	if *migrer {
+		db = database.Connecter(cfg.Base)
+		appliquees, err := database.MigrerHaut(db, 0)
+		if err != nil {
+			log.Fatal("Erreur lors des migrations :", err)
+		}
+		log.Printf("%d migration(s) appliquée(s)", appliquees)
+	} else {
+		db = database.InitDB(cfg.Base)
+	}
+
+	// Fermé à la réception de SIGTERM : la sonde de disponibilité échoue et les flux
+	// temps réel se terminent
+	arret := make(chan struct{})
+	r := routes.SetupRoutes(controllers.Dependances{
+		Depots: depots.Gorm(db),
+		DB:     db,
+		Config: &cfg,
+		Arret:  arret,
+	})
+	r.StaticFS("/static", routes.FichiersPublics(cfg.Serveur.DossierUploads))
+
+	serveur := &http.Server{
+		Addr:              cfg.Serveur.Adresse,
+		Handler:           r,
+		ReadHeaderTimeout: delaiLectureEntetes,
+		ReadTimeout:       cfg.Serveur.DelaiLecture,
+		WriteTimeout:      cfg.Serveur.DelaiEcriture,
+		IdleTimeout:       cfg.Serveur.DelaiInactivite,
+	}
+	// Les WebSockets de la messagerie ne sont plus suivies par le serveur HTTP : elles sont
+	// fermées explicitement
+	serveur.RegisterOnShutdown(services.Hub.Fermer)
+
+	// Livraison des webhooks en arrière-plan (outbox)
+	contexteWorkers, arreterWorkers := context.WithCancel(context.Background())
+	workerTermine := make(chan struct{})
+	go func() {
+		defer close(workerTermine)
+		services.NewWorkerWebhooks(db).Run(contexteWorkers)
+	}()
+
+	signaux, ignorerSignaux := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer ignorerSignaux()
+	erreurServeur := make(chan error, 1)
+	go func() {
+		erreurServeur <- serveur.ListenAndServe()
+	}()
+	log.Printf("Serveur à l'écoute sur %s", cfg.Serveur.Adresse)
+
+	select {
+	case err := <-erreurServeur:
+		log.Fatal("Erreur du serveur HTTP :", err)
+	case <-signaux.Done():
+	}
+	// Un second signal interrompt le processus sans attendre
+	ignorerSignaux()
+
+	log.Printf("Arrêt demandé : fin des requêtes en cours et des workers (%s maximum)", cfg.Serveur.DelaiArret)
+	close(arret)
+	arreterWorkers()
+	contexteArret, annuler := context.WithTimeout(context.Background(), cfg.Serveur.DelaiArret)
+	defer annuler()
+
+	if err := serveur.Shutdown(contexteArret); err != nil {
+		log.Printf("Arrêt du serveur HTTP : %v", err)
+	}
+	select {
+	case <-workerTermine:
+	case <-contexteArret.Done():
+		log.Println("Arrêt : livraison de webhooks interrompue, elle sera reprise au prochain démarrage")
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	log.Println("Serveur arrêté")
 }
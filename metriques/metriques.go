@@ -0,0 +1,316 @@
+// Package metriques expose des métriques au format texte de Prometheus (version 0.0.4) :
+// compteurs, jauges et histogrammes à étiquettes, et valeurs lues au moment de la collecte.
+// Chaque couche déclare ses métriques dans le registre Defaut, servi sous /metrics.
+package metriques
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TypeContenu est le type MIME du format d'exposition texte
+const TypeContenu = "text/plain; version=0.0.4; charset=utf-8"
+
+// Bornes des histogrammes de durée, en secondes (valeurs par défaut de Prometheus)
+var BornesDuree = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Types de métriques du format d'exposition
+const (
+	typeCompteur    = "counter"
+	typeJauge       = "gauge"
+	typeHistogramme = "histogram"
+)
+
+// famille est une métrique du registre, avec toutes ses séries
+type famille interface {
+	ecrire(w *bufio.Writer)
+}
+
+// Registre regroupe les métriques exposées ensemble
+type Registre struct {
+	mu       sync.Mutex
+	familles map[string]famille
+}
+
+// Defaut est le registre servi sous /metrics
+var Defaut = NouveauRegistre()
+
+func NouveauRegistre() *Registre {
+	return &Registre{familles: map[string]famille{}}
+}
+
+// enregistrer ajoute une famille ; une famille du même nom est remplacée (valeurs lues
+// sur une nouvelle connexion à la base, par exemple)
+func (r *Registre) enregistrer(nom string, f famille) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.familles[nom] = f
+}
+
+// Compteur déclare un compteur, dont chaque série est identifiée par les valeurs des étiquettes
+func (r *Registre) Compteur(nom, aide string, etiquettes ...string) *Compteur {
+	c := &Compteur{}
+	c.initialiser(nom, aide, typeCompteur, etiquettes)
+	r.enregistrer(nom, c)
+	return c
+}
+
+// Jauge déclare une jauge, valeur qui peut augmenter ou diminuer
+func (r *Registre) Jauge(nom, aide string, etiquettes ...string) *Jauge {
+	j := &Jauge{}
+	j.initialiser(nom, aide, typeJauge, etiquettes)
+	r.enregistrer(nom, j)
+	return j
+}
+
+// Histogramme déclare un histogramme aux bornes (croissantes) données
+func (r *Registre) Histogramme(nom, aide string, bornes []float64, etiquettes ...string) *Histogramme {
+	h := &Histogramme{bornes: bornes}
+	h.initialiser(nom, aide, typeHistogramme, etiquettes)
+	for _, s := range h.series {
+		s.parBorne = make([]uint64, len(bornes))
+	}
+	r.enregistrer(nom, h)
+	return h
+}
+
+// JaugeLue déclare une jauge sans étiquette dont la valeur est lue à chaque collecte
+func (r *Registre) JaugeLue(nom, aide string, lire func() float64) {
+	r.enregistrer(nom, &lue{nom: nom, aide: aide, typ: typeJauge, lire: lire})
+}
+
+// CompteurLu déclare un compteur sans étiquette dont la valeur est lue à chaque collecte
+func (r *Registre) CompteurLu(nom, aide string, lire func() float64) {
+	r.enregistrer(nom, &lue{nom: nom, aide: aide, typ: typeCompteur, lire: lire})
+}
+
+// Ecrire écrit toutes les métriques, triées par nom, au format d'exposition texte
+func (r *Registre) Ecrire(w io.Writer) error {
+	r.mu.Lock()
+	noms := make([]string, 0, len(r.familles))
+	for nom := range r.familles {
+		noms = append(noms, nom)
+	}
+	sort.Strings(noms)
+	familles := make([]famille, len(noms))
+	for i, nom := range noms {
+		familles[i] = r.familles[nom]
+	}
+	r.mu.Unlock()
+
+	tampon := bufio.NewWriter(w)
+	for _, f := range familles {
+		f.ecrire(tampon)
+	}
+	return tampon.Flush()
+}
+
+// Handler sert les métriques du registre
+func Handler(r *Registre) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", TypeContenu)
+		c.Status(http.StatusOK)
+		r.Ecrire(c.Writer)
+	}
+}
+
+// vecteur contient les séries d'une métrique à étiquettes
+type vecteur struct {
+	nom, aide, typ string
+	etiquettes     []string
+
+	mu     sync.Mutex
+	series map[string]*serie
+}
+
+type serie struct {
+	valeurs []string
+	// valeur d'un compteur ou d'une jauge
+	valeur float64
+	// histogramme : nombre d'observations par borne (non cumulé), somme et nombre total
+	parBorne []uint64
+	somme    float64
+	nombre   uint64
+}
+
+// initialiser prépare une métrique déclarée ; sans étiquette, son unique série existe dès
+// la déclaration et vaut 0 avant la première mesure
+func (v *vecteur) initialiser(nom, aide, typ string, etiquettes []string) {
+	v.nom, v.aide, v.typ, v.etiquettes = nom, aide, typ, etiquettes
+	v.series = map[string]*serie{}
+	if len(etiquettes) == 0 {
+		v.series[""] = &serie{}
+	}
+}
+
+// serie renvoie la série des valeurs d'étiquettes, créée au premier usage ; à appeler
+// sous v.mu
+func (v *vecteur) serie(valeurs []string) *serie {
+	if len(valeurs) != len(v.etiquettes) {
+		panic(fmt.Sprintf("metriques : %s attend %d étiquette(s), %d reçue(s)", v.nom, len(v.etiquettes), len(valeurs)))
+	}
+	cle := strings.Join(valeurs, "\xff")
+	s, ok := v.series[cle]
+	if !ok {
+		s = &serie{valeurs: append([]string(nil), valeurs...)}
+		v.series[cle] = s
+	}
+	return s
+}
+
+// triees renvoie une copie des séries, triées par valeurs d'étiquettes
+func (v *vecteur) triees() []serie {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	cles := make([]string, 0, len(v.series))
+	for cle := range v.series {
+		cles = append(cles, cle)
+	}
+	sort.Strings(cles)
+	series := make([]serie, len(cles))
+	for i, cle := range cles {
+		s := *v.series[cle]
+		s.parBorne = append([]uint64(nil), s.parBorne...)
+		series[i] = s
+	}
+	return series
+}
+
+func (v *vecteur) ecrireEntete(w *bufio.Writer) {
+	ecrireEntete(w, v.nom, v.aide, v.typ)
+}
+
+func (v *vecteur) ecrire(w *bufio.Writer) {
+	v.ecrireEntete(w)
+	for _, s := range v.triees() {
+		ecrireLigne(w, v.nom, v.etiquettes, s.valeurs, s.valeur)
+	}
+}
+
+// Compteur est une valeur qui ne fait qu'augmenter
+type Compteur struct{ vecteur }
+
+// Inc ajoute 1 à la série des valeurs d'étiquettes
+func (c *Compteur) Inc(valeurs ...string) {
+	c.Ajouter(1, valeurs...)
+}
+
+// Ajouter ajoute v (positif) à la série des valeurs d'étiquettes
+func (c *Compteur) Ajouter(v float64, valeurs ...string) {
+	if v < 0 {
+		panic("metriques : un compteur ne peut pas diminuer (" + c.nom + ")")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.serie(valeurs).valeur += v
+}
+
+// Jauge est une valeur qui peut augmenter ou diminuer
+type Jauge struct{ vecteur }
+
+// Ajouter ajoute v (positif ou négatif) à la série des valeurs d'étiquettes
+func (j *Jauge) Ajouter(v float64, valeurs ...string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.serie(valeurs).valeur += v
+}
+
+// Histogramme répartit des observations (durées, tailles) entre des bornes
+type Histogramme struct {
+	vecteur
+	bornes []float64
+}
+
+// Observer enregistre une observation dans la série des valeurs d'étiquettes
+func (h *Histogramme) Observer(v float64, valeurs ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.serie(valeurs)
+	if s.parBorne == nil {
+		s.parBorne = make([]uint64, len(h.bornes))
+	}
+	if i := sort.SearchFloat64s(h.bornes, v); i < len(h.bornes) {
+		s.parBorne[i]++
+	}
+	s.somme += v
+	s.nombre++
+}
+
+func (h *Histogramme) ecrire(w *bufio.Writer) {
+	h.ecrireEntete(w)
+	etiquettes := append(append([]string(nil), h.etiquettes...), "le")
+	for _, s := range h.triees() {
+		// Valeurs des étiquettes suivies de celle de "le", la borne
+		valeurs := append(append(make([]string, 0, len(s.valeurs)+1), s.valeurs...), "")
+		var cumul uint64
+		for i, borne := range h.bornes {
+			cumul += s.parBorne[i]
+			valeurs[len(s.valeurs)] = formaterNombre(borne)
+			ecrireLigne(w, h.nom+"_bucket", etiquettes, valeurs, float64(cumul))
+		}
+		valeurs[len(s.valeurs)] = "+Inf"
+		ecrireLigne(w, h.nom+"_bucket", etiquettes, valeurs, float64(s.nombre))
+		ecrireLigne(w, h.nom+"_sum", h.etiquettes, s.valeurs, s.somme)
+		ecrireLigne(w, h.nom+"_count", h.etiquettes, s.valeurs, float64(s.nombre))
+	}
+}
+
+// lue est une métrique sans étiquette lue à chaque collecte
+type lue struct {
+	nom, aide, typ string
+	lire           func() float64
+}
+
+func (l *lue) ecrire(w *bufio.Writer) {
+	ecrireEntete(w, l.nom, l.aide, l.typ)
+	ecrireLigne(w, l.nom, nil, nil, l.lire())
+}
+
+func ecrireEntete(w *bufio.Writer, nom, aide, typ string) {
+	aide = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(aide)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", nom, aide, nom, typ)
+}
+
+// echappementEtiquette échappe une valeur d'étiquette entre guillemets
+var echappementEtiquette = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func ecrireLigne(w *bufio.Writer, nom string, etiquettes, valeurs []string, v float64) {
+	w.WriteString(nom)
+	if len(etiquettes) > 0 {
+		w.WriteByte('{')
+		for i, e := range etiquettes {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(e)
+			w.WriteString(`="`)
+			w.WriteString(echappementEtiquette.Replace(valeurs[i]))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formaterNombre(v))
+	w.WriteByte('\n')
+}
+
+func formaterNombre(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
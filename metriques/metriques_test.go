@@ -0,0 +1,65 @@
+package metriques
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEcrire(t *testing.T) {
+	r := NouveauRegistre()
+	requetes := r.Compteur("requetes_total", "Requêtes traitées", "methode", "route")
+	requetes.Inc("GET", "/annonces_vente")
+	requetes.Ajouter(2, "GET", "/annonces_vente")
+	requetes.Inc("POST", `/a"b\c`)
+
+	duree := r.Histogramme("duree_secondes", "Durée\ndes requêtes", []float64{0.1, 1}, "route")
+	duree.Observer(0.05, "/x")
+	duree.Observer(0.1, "/x")
+	duree.Observer(3, "/x")
+
+	enCours := r.Jauge("en_cours", "Requêtes en cours")
+	enCours.Ajouter(2)
+	enCours.Ajouter(-1)
+
+	r.JaugeLue("connexions", "Connexions ouvertes", func() float64 { return 4 })
+	r.Compteur("abandons_total", "Requêtes abandonnées")
+
+	var sortie strings.Builder
+	if err := r.Ecrire(&sortie); err != nil {
+		t.Fatal(err)
+	}
+
+	attendue := `# HELP abandons_total Requêtes abandonnées
+# TYPE abandons_total counter
+abandons_total 0
+# HELP connexions Connexions ouvertes
+# TYPE connexions gauge
+connexions 4
+# HELP duree_secondes Durée\ndes requêtes
+# TYPE duree_secondes histogram
+duree_secondes_bucket{route="/x",le="0.1"} 2
+duree_secondes_bucket{route="/x",le="1"} 2
+duree_secondes_bucket{route="/x",le="+Inf"} 3
+duree_secondes_sum{route="/x"} 3.15
+duree_secondes_count{route="/x"} 3
+# HELP en_cours Requêtes en cours
+# TYPE en_cours gauge
+en_cours 1
+# HELP requetes_total Requêtes traitées
+# TYPE requetes_total counter
+requetes_total{methode="GET",route="/annonces_vente"} 3
+requetes_total{methode="POST",route="/a\"b\\c"} 1
+`
+	if sortie.String() != attendue {
+		t.Errorf("sortie :\n%s\nattendue :\n%s", sortie.String(), attendue)
+	}
+}
+
+func TestEtiquettesManquantes(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("série acceptée sans ses étiquettes")
+		}
+	}()
+	NouveauRegistre().Compteur("requetes_total", "Requêtes traitées", "route").Inc()
+}
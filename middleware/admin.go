@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+)
+
+// AdminMiddleware réserve une route aux administrateurs. Il doit être placé après
+// AuthMiddleware : le profil_id du token doit figurer dans profilIDs (paramètre
+// admin_profil_ids).
+func AdminMiddleware(profilIDs []int) gin.HandlerFunc {
+	return profilsMiddleware(profilIDs, erreurs.AccesAdministrateur)
+}
+
+// PartenaireMiddleware réserve une route aux partenaires (webhooks). Il doit être placé
+// après AuthMiddleware : le profil_id du token doit figurer dans profilIDs (paramètres
+// partenaire_profil_ids et admin_profil_ids).
+func PartenaireMiddleware(profilIDs []int) gin.HandlerFunc {
+	return profilsMiddleware(profilIDs, erreurs.AccesPartenaire)
+}
+
+// profilsMiddleware n'admet que les profils de profilIDs et répond refus aux autres
+func profilsMiddleware(profilIDs []int, refus *erreurs.Erreur) gin.HandlerFunc {
+	profils := map[int]bool{}
+	for _, id := range profilIDs {
+		profils[id] = true
+	}
+
+	return func(c *gin.Context) {
+		profilID, ok := c.Get("profil_id")
+		if !ok {
+			erreurs.Repondre(c, erreurs.TokenRequis)
+			return
+		}
+		if id, _ := profilID.(int); !profils[id] {
+			erreurs.Repondre(c, refus)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,11 +1,12 @@
 package middleware
 
 import (
-	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
 )
 
 // Claims structure qui correspond au payload de votre token Node.js
@@ -15,44 +16,69 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// AuthMiddleware vérifie le token JWT généré par votre API Node.js
-func AuthMiddleware() gin.HandlerFunc {
+// parseToken extrait et vérifie le token d'un en-tête "Bearer <token>". Sans secret
+// configuré, aucun token n'est accepté.
+func parseToken(authHeader string, secret []byte) (*Claims, error) {
+	// Format attendu: "Bearer <token>"
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, erreurs.TokenFormatInvalide
+	}
+
+	tokenString := parts[1]
+	claims := &Claims{}
+
+	if len(secret) == 0 {
+		return nil, erreurs.TokenInvalide
+	}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return secret, nil
+	})
+
+	if err != nil || !token.Valid {
+		return nil, erreurs.TokenInvalide
+	}
+	return claims, nil
+}
+
+// setUser stocke les informations de l'utilisateur dans le contexte Gin
+func setUser(c *gin.Context, claims *Claims) {
+	c.Set("user_id", int(claims.UserID))
+	c.Set("profil_id", int(claims.ProfilID))
+}
+
+// AuthMiddleware vérifie le token JWT généré par votre API Node.js, signé avec le même
+// secret (paramètre jwt_secret)
+func AuthMiddleware(secret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Token requis"})
-			c.Abort()
+			erreurs.Repondre(c, erreurs.TokenRequis)
 			return
 		}
 
-		// Format attendu: "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Format de token invalide"})
-			c.Abort()
+		claims, err := parseToken(authHeader, []byte(secret))
+		if err != nil {
+			erreurs.Repondre(c, err)
 			return
 		}
 
-		tokenString := parts[1]
-		claims := &Claims{}
-
-		// Utiliser le même secret que votre API Node.js
-		jwtSecret := []byte("mon_projet_agrobloc_jwt")
+		setUser(c, claims)
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
+		c.Next()
+	}
+}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusForbidden, gin.H{"message": "Token invalide"})
-			c.Abort()
-			return
+// OptionalAuthMiddleware identifie l'utilisateur si un token valide est fourni,
+// sans bloquer les requêtes anonymes (routes publiques)
+func OptionalAuthMiddleware(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+			if claims, err := parseToken(authHeader, []byte(secret)); err == nil {
+				setUser(c, claims)
+			}
 		}
 
-		// Stocker les informations de l'utilisateur dans le contexte Gin
-		c.Set("user_id", int(claims.UserID))
-		c.Set("profil_id", int(claims.ProfilID))
-
 		c.Next()
 	}
 }
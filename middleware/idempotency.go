@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+const (
+	EnteteIdempotence       = "Idempotency-Key"
+	EnteteIdempotenceRejeu  = "Idempotent-Replayed"
+	cleIdempotenceTailleMax = 255
+
+	// Au-delà de ce délai, une clé encore en cours est celle d'une instance arrêtée en
+	// plein traitement : elle est libérée
+	idempotenceEnCoursMax = 5 * time.Minute
+)
+
+// enregistreurReponse copie le corps de la réponse pour pouvoir le rejouer
+type enregistreurReponse struct {
+	gin.ResponseWriter
+	corps bytes.Buffer
+}
+
+func (w *enregistreurReponse) Write(b []byte) (int, error) {
+	w.corps.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *enregistreurReponse) WriteString(s string) (int, error) {
+	w.corps.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware rend une route de création idempotente pour les requêtes portant
+// l'en-tête Idempotency-Key. Il doit être placé après AuthMiddleware : les clés sont
+// propres à chaque utilisateur.
+//   - même clé, même requête : la réponse enregistrée est renvoyée (en-tête Idempotent-Replayed)
+//   - même clé, corps ou chemin différent (autre version de l'API, autre annonce) : 409
+//   - même clé pendant le traitement de la première requête : 409
+//
+// Les réponses 5xx ne sont pas conservées, ni les clés d'un handler qui panique, pour que
+// le client puisse réessayer. La réponse est rejouée avec les en-têtes posés par le
+// handler (ETag, Location...). Les clés expirent après duree.
+func IdempotencyMiddleware(db *gorm.DB, duree time.Duration) gin.HandlerFunc {
+	var appels atomic.Int64
+
+	return func(c *gin.Context) {
+		cle := c.GetHeader(EnteteIdempotence)
+		if cle == "" {
+			c.Next()
+			return
+		}
+		if len(cle) > cleIdempotenceTailleMax {
+			erreurs.Repondre(c, erreurs.CleIdempotenceTropLongue.Avec(EnteteIdempotence, cleIdempotenceTailleMax))
+			return
+		}
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		corps, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			erreurs.Repondre(c, erreurs.CorpsInvalide)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(corps))
+
+		maintenant := time.Now()
+		if appels.Add(1)%1000 == 0 {
+			db.Where("expire_le < ?", maintenant).Delete(&models.CleIdempotence{})
+		}
+
+		empreinte := sha256.New()
+		// Le chemin réel, et non le modèle de route, porte la version de l'API et l'annonce visée
+		fmt.Fprintf(empreinte, "%s %s\n", c.Request.Method, c.Request.URL.Path)
+		empreinte.Write(corps)
+
+		ligne := models.CleIdempotence{
+			UserID:    fmt.Sprint(userID),
+			Cle:       cle,
+			Empreinte: hex.EncodeToString(empreinte.Sum(nil)),
+			Statut:    models.IdempotenceEnCours,
+			ExpireLe:  maintenant.Add(duree),
+		}
+
+		reservee, err := reserverCleIdempotence(db, ligne, maintenant)
+		if err != nil {
+			log.Printf("Idempotence indisponible : %v\n", err)
+			c.Next()
+			return
+		}
+		if reservee != nil {
+			rejouerIdempotence(c, *reservee, ligne.Empreinte)
+			return
+		}
+
+		cible := db.Model(&models.CleIdempotence{}).Where("user_id = ? AND cle = ?", ligne.UserID, ligne.Cle)
+		termine := false
+		defer func() {
+			// Handler interrompu (panique) : la clé est libérée avant que la panique ne
+			// remonte, sans quoi le client recevrait 409 jusqu'à l'expiration
+			if !termine {
+				if err := cible.Delete(&models.CleIdempotence{}).Error; err != nil {
+					log.Printf("Erreur libération de la clé d'idempotence : %v\n", err)
+				}
+			}
+		}()
+
+		avant := c.Writer.Header().Clone()
+		w := &enregistreurReponse{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		termine = true
+
+		if w.Status() >= http.StatusInternalServerError {
+			err = cible.Delete(&models.CleIdempotence{}).Error
+		} else {
+			err = cible.Updates(map[string]interface{}{
+				"statut":       models.IdempotenceTerminee,
+				"code_http":    w.Status(),
+				"content_type": w.Header().Get("Content-Type"),
+				"entetes":      entetesHandler(avant, w.Header()),
+				"reponse":      w.corps.String(),
+			}).Error
+		}
+		if err != nil {
+			log.Printf("Erreur enregistrement de la clé d'idempotence : %v\n", err)
+		}
+	}
+}
+
+// entetesHandler renvoie les en-têtes ajoutés ou modifiés pendant le traitement, sans ceux
+// des middlewares placés avant (identifiant de requête, limites de débit), propres à
+// chaque requête
+func entetesHandler(avant, apres http.Header) models.EntetesHTTP {
+	entetes := models.EntetesHTTP{}
+	for nom, valeurs := range apres {
+		if nom != "Content-Length" && !slices.Equal(avant[nom], valeurs) {
+			entetes[nom] = valeurs
+		}
+	}
+	return entetes
+}
+
+// reserverCleIdempotence enregistre la clé si elle est libre (expirée, ou abandonnée en
+// cours de traitement) et renvoie nil ; sinon elle renvoie l'enregistrement existant
+func reserverCleIdempotence(db *gorm.DB, ligne models.CleIdempotence, maintenant time.Time) (*models.CleIdempotence, error) {
+	var existante *models.CleIdempotence
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND cle = ?", ligne.UserID, ligne.Cle).
+			Where("expire_le < ? OR (statut = ? AND created_at < ?)", maintenant, models.IdempotenceEnCours, maintenant.Add(-idempotenceEnCoursMax)).
+			Delete(&models.CleIdempotence{}).Error; err != nil {
+			return err
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ligne)
+		if res.Error != nil || res.RowsAffected > 0 {
+			return res.Error
+		}
+		existante = &models.CleIdempotence{}
+		return tx.First(existante, "user_id = ? AND cle = ?", ligne.UserID, ligne.Cle).Error
+	})
+	return existante, err
+}
+
+// rejouerIdempotence répond à une requête dont la clé a déjà été utilisée
+func rejouerIdempotence(c *gin.Context, existante models.CleIdempotence, empreinte string) {
+	if existante.Empreinte != empreinte {
+		erreurs.Repondre(c, erreurs.CleIdempotenceReutilisee)
+		return
+	}
+	if existante.Statut != models.IdempotenceTerminee {
+		erreurs.Repondre(c, erreurs.IdempotenceEnCours)
+		return
+	}
+
+	for nom, valeurs := range existante.Entetes {
+		c.Writer.Header()[nom] = valeurs
+	}
+	c.Header(EnteteIdempotenceRejeu, "true")
+	c.Data(existante.CodeHTTP, existante.ContentType, []byte(existante.Reponse))
+	c.Abort()
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// routeurIdempotent sert POST /v1/annonces et /v2/annonces derrière le middleware ; le
+// handler panique tant que *panique est vrai
+func routeurIdempotent(t *testing.T, panique *bool) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.CleIdempotence{}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ interface{}) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	idempotence := IdempotencyMiddleware(db, time.Hour)
+	for _, prefixe := range []string{"/v1", "/v2"} {
+		r.POST(prefixe+"/annonces", func(c *gin.Context) {
+			c.Set("user_id", "vendeur")
+			c.Header("X-Request-ID", c.GetHeader("X-Request-ID"))
+		}, idempotence, func(c *gin.Context) {
+			if *panique {
+				panic("base indisponible")
+			}
+			c.Header("ETag", `"1"`)
+			c.Header("Location", prefixe+"/annonces/42")
+			c.JSON(http.StatusCreated, gin.H{"id": 42})
+		})
+	}
+	return r
+}
+
+func envoyer(r *gin.Engine, chemin string, requestID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, chemin, strings.NewReader(`{"quantite": 3}`))
+	req.Header.Set(EnteteIdempotence, "cle-1")
+	req.Header.Set("X-Request-ID", requestID)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotenceRejeu(t *testing.T) {
+	panique := false
+	r := routeurIdempotent(t, &panique)
+
+	premiere := envoyer(r, "/v1/annonces", "a")
+	rejeu := envoyer(r, "/v1/annonces", "b")
+	if rejeu.Code != http.StatusCreated || rejeu.Body.String() != premiere.Body.String() {
+		t.Fatalf("rejeu : %d %s", rejeu.Code, rejeu.Body.String())
+	}
+	if rejeu.Header().Get(EnteteIdempotenceRejeu) != "true" {
+		t.Error("en-tête Idempotent-Replayed absent")
+	}
+	for _, nom := range []string{"ETag", "Location", "Content-Type"} {
+		if rejeu.Header().Get(nom) != premiere.Header().Get(nom) {
+			t.Errorf("%s rejoué : %q, attendu %q", nom, rejeu.Header().Get(nom), premiere.Header().Get(nom))
+		}
+	}
+	// Les en-têtes des middlewares précédents restent ceux de la nouvelle requête
+	if id := rejeu.Header().Get("X-Request-ID"); id != "b" {
+		t.Errorf("X-Request-ID rejoué : %q", id)
+	}
+}
+
+// Un handler qui panique libère la clé : le client peut réessayer sans attendre l'expiration
+func TestIdempotencePanique(t *testing.T) {
+	panique := true
+	r := routeurIdempotent(t, &panique)
+
+	if w := envoyer(r, "/v1/annonces", "a"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("panique : statut %d", w.Code)
+	}
+	panique = false
+	if w := envoyer(r, "/v1/annonces", "b"); w.Code != http.StatusCreated || w.Header().Get(EnteteIdempotenceRejeu) != "" {
+		t.Errorf("nouvel essai : statut %d, rejeu %q (%s)", w.Code, w.Header().Get(EnteteIdempotenceRejeu), w.Body.String())
+	}
+}
+
+// Une clé n'est rejouée que sur le chemin de sa première requête : une réponse v1 n'est
+// jamais renvoyée à un client v2
+func TestIdempotenceVersion(t *testing.T) {
+	panique := false
+	r := routeurIdempotent(t, &panique)
+
+	if w := envoyer(r, "/v1/annonces", "a"); w.Code != http.StatusCreated {
+		t.Fatalf("v1 : statut %d", w.Code)
+	}
+	w := envoyer(r, "/v2/annonces", "b")
+	if w.Code != http.StatusConflict || w.Header().Get(EnteteIdempotenceRejeu) != "" {
+		t.Errorf("même clé en v2 : statut %d, rejeu %q ; attendu 409 (%s)", w.Code, w.Header().Get(EnteteIdempotenceRejeu), w.Body.String())
+	}
+}
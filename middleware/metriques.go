@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/metriques"
+)
+
+// routeInconnue étiquette les requêtes qui ne correspondent à aucune route, pour ne pas
+// créer une série par chemin demandé
+const routeInconnue = "inconnue"
+
+var (
+	dureeRequetes = metriques.Defaut.Histogramme("http_request_duration_seconds",
+		"Durée des requêtes HTTP, par méthode, route et statut", metriques.BornesDuree, "method", "route", "status")
+	requetesEnCours = metriques.Defaut.Jauge("http_requests_in_flight",
+		"Requêtes HTTP en cours de traitement (flux temps réel compris)")
+)
+
+// MetriquesMiddleware mesure la durée de chaque requête. La route est le modèle
+// enregistré (/v1/annonces_vente/:id), pas le chemin demandé.
+func MetriquesMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		debut := time.Now()
+		requetesEnCours.Ajouter(1)
+		defer requetesEnCours.Ajouter(-1)
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = routeInconnue
+		}
+		dureeRequetes.Observer(time.Since(debut).Seconds(), c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// JetonMiddleware réserve une route aux requêtes portant l'en-tête "Bearer <jeton>" ;
+// sans jeton configuré, la route est ouverte
+func JetonMiddleware(jeton string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if jeton == "" {
+			c.Next()
+			return
+		}
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			erreurs.Repondre(c, erreurs.TokenRequis)
+			return
+		}
+		fourni, ok := strings.CutPrefix(authHeader, "Bearer ")
+		if !ok {
+			erreurs.Repondre(c, erreurs.TokenFormatInvalide)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(fourni), []byte(jeton)) != 1 {
+			erreurs.Repondre(c, erreurs.TokenInvalide)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Steph-business/annonce_de_vente/config"
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+)
+
+// debit renvoie le nombre de jetons rechargés par seconde
+func debit(r config.RegleLimite) float64 {
+	return float64(r.Capacite) / r.Periode.Seconds()
+}
+
+// Decision est le résultat de la consommation d'un jeton
+type Decision struct {
+	Autorise bool
+	Restant  int
+	// Reinitialisation : délai avant que le seau soit de nouveau plein
+	Reinitialisation time.Duration
+	// Attente : délai avant le prochain jeton disponible (requête refusée)
+	Attente time.Duration
+}
+
+// StockageLimites conserve l'état des seaux. L'implémentation en mémoire convient à
+// une instance unique ; StockageLimitesSQL partage les seaux entre instances.
+type StockageLimites interface {
+	Prendre(cle string, regle config.RegleLimite, maintenant time.Time) (Decision, error)
+}
+
+// consommer applique l'algorithme du seau à jetons à partir de l'état précédent
+func consommer(jetons float64, derniere time.Time, regle config.RegleLimite, maintenant time.Time) (float64, Decision) {
+	capacite := float64(regle.Capacite)
+	if ecoule := maintenant.Sub(derniere).Seconds(); ecoule > 0 {
+		jetons = math.Min(capacite, jetons+ecoule*debit(regle))
+	}
+
+	var d Decision
+	if jetons >= 1 {
+		jetons--
+		d.Autorise = true
+	} else {
+		d.Attente = time.Duration((1 - jetons) / debit(regle) * float64(time.Second))
+	}
+	d.Restant = int(math.Floor(jetons))
+	d.Reinitialisation = time.Duration((capacite - jetons) / debit(regle) * float64(time.Second))
+	return jetons, d
+}
+
+type seau struct {
+	jetons   float64
+	derniere time.Time
+}
+
+// StockageLimitesMemoire conserve les seaux en mémoire (stockage par défaut)
+type StockageLimitesMemoire struct {
+	mu     sync.Mutex
+	seaux  map[string]*seau
+	appels int
+}
+
+func NewStockageLimitesMemoire() *StockageLimitesMemoire {
+	return &StockageLimitesMemoire{seaux: map[string]*seau{}}
+}
+
+func (s *StockageLimitesMemoire) Prendre(cle string, regle config.RegleLimite, maintenant time.Time) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.appels++
+	if s.appels%10000 == 0 {
+		s.nettoyer(maintenant)
+	}
+
+	b, ok := s.seaux[cle]
+	if !ok {
+		b = &seau{jetons: float64(regle.Capacite), derniere: maintenant}
+		s.seaux[cle] = b
+	}
+
+	jetons, d := consommer(b.jetons, b.derniere, regle, maintenant)
+	b.jetons, b.derniere = jetons, maintenant
+	return d, nil
+}
+
+// nettoyer retire les seaux inutilisés depuis une heure (ils seraient pleins de toute façon
+// pour les périodes usuelles)
+func (s *StockageLimitesMemoire) nettoyer(maintenant time.Time) {
+	for cle, b := range s.seaux {
+		if maintenant.Sub(b.derniere) > time.Hour {
+			delete(s.seaux, cle)
+		}
+	}
+}
+
+// BudgetRoute est le budget d'un groupe de routes : une règle pour les requêtes anonymes
+// (clé : adresse IP) et une pour les utilisateurs authentifiés (clé : user_id)
+type BudgetRoute struct {
+	Nom         string
+	Anonyme     config.RegleLimite
+	Authentifie config.RegleLimite
+}
+
+// ChargerBudget applique au budget les règles configurées sous son nom : <nom> pour les
+// utilisateurs authentifiés et <nom>_ip pour les anonymes (paramètres rate_limit_<nom> et
+// rate_limit_<nom>_ip). Sans règle anonyme, celle des utilisateurs authentifiés s'applique.
+func ChargerBudget(b BudgetRoute, regles map[string]config.RegleLimite) BudgetRoute {
+	if regle, ok := regles[b.Nom]; ok {
+		b.Authentifie = regle
+	}
+	if regle, ok := regles[b.Nom+"_ip"]; ok {
+		b.Anonyme = regle
+	}
+	if b.Anonyme.Capacite <= 0 || b.Anonyme.Periode <= 0 {
+		b.Anonyme = b.Authentifie
+	}
+	return b
+}
+
+// RateLimitMiddleware limite le débit des requêtes selon le budget. Il doit être placé
+// après AuthMiddleware ou OptionalAuthMiddleware pour identifier l'utilisateur. Les
+// réponses portent les en-têtes RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset
+// et RateLimit-Policy ; une requête refusée reçoit 429 avec Retry-After.
+func RateLimitMiddleware(stockage StockageLimites, budget BudgetRoute) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		regle := budget.Anonyme
+		cle := budget.Nom + ":ip:" + c.ClientIP()
+		if userID, ok := c.Get("user_id"); ok {
+			regle = budget.Authentifie
+			cle = budget.Nom + ":user:" + fmt.Sprint(userID)
+		}
+
+		d, err := stockage.Prendre(cle, regle, time.Now())
+		if err != nil {
+			// Stockage indisponible : la requête est laissée passer plutôt que de bloquer le service
+			log.Printf("Limiteur de débit indisponible : %v\n", err)
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(regle.Capacite))
+		h.Set("RateLimit-Remaining", strconv.Itoa(d.Restant))
+		h.Set("RateLimit-Reset", strconv.Itoa(secondesArrondies(d.Reinitialisation)))
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", regle.Capacite, secondesArrondies(regle.Periode)))
+
+		if !d.Autorise {
+			h.Set("Retry-After", strconv.Itoa(secondesArrondies(d.Attente)))
+			erreurs.Repondre(c, erreurs.TropDeRequetes)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func secondesArrondies(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
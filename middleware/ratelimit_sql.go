@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Steph-business/annonce_de_vente/config"
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// StockageLimitesSQL partage les seaux entre instances via la table limites_debit.
+// Chaque prise de jeton verrouille la ligne de la clé le temps d'une courte transaction.
+type StockageLimitesSQL struct {
+	DB     *gorm.DB
+	appels atomic.Int64
+}
+
+func NewStockageLimitesSQL(db *gorm.DB) *StockageLimitesSQL {
+	return &StockageLimitesSQL{DB: db}
+}
+
+func (s *StockageLimitesSQL) Prendre(cle string, regle config.RegleLimite, maintenant time.Time) (Decision, error) {
+	if s.appels.Add(1)%10000 == 0 {
+		s.DB.Where("updated_at < ?", maintenant.Add(-time.Hour)).Delete(&models.LimiteDebit{})
+	}
+
+	var d Decision
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		ligne := models.LimiteDebit{Cle: cle, Jetons: float64(regle.Capacite), UpdatedAt: maintenant}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ligne).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ligne, "cle = ?", cle).Error; err != nil {
+			return err
+		}
+
+		var jetons float64
+		jetons, d = consommer(ligne.Jetons, ligne.UpdatedAt, regle, maintenant)
+		return tx.Model(&models.LimiteDebit{}).Where("cle = ?", cle).
+			Updates(map[string]interface{}{"jetons": jetons, "updated_at": maintenant}).Error
+	})
+	return d, err
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+)
+
+// requestIDTailleMax limite la taille d'un identifiant de requête fourni par le client
+const requestIDTailleMax = 64
+
+// RequestIDMiddleware attribue un identifiant à chaque requête : celui de l'en-tête
+// X-Request-ID s'il est fourni et valide (proxy, client), sinon un UUID. Il est renvoyé
+// dans l'en-tête de la réponse et dans le corps des erreurs, et préfixe les journaux.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(erreurs.CleRequestID)
+		if !requestIDValide(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set(erreurs.CleRequestID, id)
+		c.Header(erreurs.CleRequestID, id)
+		c.Next()
+	}
+}
+
+func requestIDValide(id string) bool {
+	if id == "" || len(id) > requestIDTailleMax {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// LoggerMiddleware journalise chaque requête avec son identifiant, pour relier le journal
+// d'accès aux erreurs internes et aux rapports des clients
+func LoggerMiddleware() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %s | %3d | %13v | %15s | %-7s %#v | %s\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			p.StatusCode,
+			p.Latency,
+			p.ClientIP,
+			p.Method,
+			p.Path,
+			p.Keys[erreurs.CleRequestID],
+			p.ErrorMessage,
+		)
+	})
+}
+
+// RecoveryMiddleware transforme une panique en erreur interne dans l'enveloppe commune ;
+// le détail de la panique n'est que journalisé
+func RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, err interface{}) {
+		erreurs.Repondre(c, erreurs.Interne(fmt.Errorf("panique : %v", err)))
+	})
+}
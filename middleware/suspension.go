@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+)
+
+// SuspensionMiddleware refuse les requêtes des utilisateurs suspendus ou bannis.
+// Il doit être placé après AuthMiddleware.
+func SuspensionMiddleware(utilisateurs depots.Utilisateurs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		valeur, ok := c.Get("user_id")
+		if !ok {
+			c.Next()
+			return
+		}
+		userID, err := uuid.Parse(fmt.Sprint(valeur))
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		suspension, err := utilisateurs.SuspensionActive(userID, time.Now())
+		if err == nil && suspension != nil {
+			erreurs.Repondre(c, erreurs.CompteSuspendu.Avec(suspension.Motif))
+			return
+		}
+
+		c.Next()
+	}
+}
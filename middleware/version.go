@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// VersionAPIMiddleware indique aux handlers la version du contrat de réponse
+// (groupes /v1 et /v2)
+func VersionAPIMiddleware(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(models.CleVersionAPI, version)
+		c.Next()
+	}
+}
+
+// DepreciationMiddleware signale une route dépréciée (en-tête Deprecation) et
+// indique dans l'en-tête Link la même route sous le préfixe qui la remplace
+func DepreciationMiddleware(successeur string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successeur+c.Request.URL.Path+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"gorm.io/gorm/logger"
+
+	"github.com/Steph-business/annonce_de_vente/config"
+	"github.com/Steph-business/annonce_de_vente/database"
+)
+
+const usageMigrate = `Usage : annonces migrate [options] <commande>
+
+Commandes :
+  up [n]     applique les n prochaines migrations (toutes par défaut)
+  down [n]   annule les n dernières migrations (1 par défaut)
+  status     liste les migrations et leur état
+
+Options :`
+
+// commandeMigrate exécute la sous-commande migrate sur la base configurée (db_driver)
+func commandeMigrate(args []string) {
+	options := flag.NewFlagSet("migrate", flag.ExitOnError)
+	options.Usage = func() {
+		fmt.Fprintln(options.Output(), usageMigrate)
+		options.PrintDefaults()
+	}
+	cfg, err := config.Charger(options, args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	args = options.Args()
+	if len(args) == 0 || len(args) > 2 {
+		options.Usage()
+		os.Exit(2)
+	}
+	n := 0
+	if len(args) == 2 {
+		if n, err = strconv.Atoi(args[1]); err != nil || n <= 0 {
+			fmt.Fprintln(os.Stderr, "Nombre de migrations invalide :", args[1])
+			os.Exit(2)
+		}
+	}
+
+	db := database.Connecter(cfg.Base)
+	// Les requêtes SQL des migrations ne sont pas journalisées
+	db.Logger = logger.Default.LogMode(logger.Warn)
+
+	switch args[0] {
+	case "up":
+		appliquees, err := database.MigrerHaut(db, n)
+		if err != nil {
+			log.Fatalf("Erreur après %d migration(s) appliquée(s) : %v", appliquees, err)
+		}
+		fmt.Printf("%d migration(s) appliquée(s)\n", appliquees)
+	case "down":
+		if n == 0 {
+			n = 1
+		}
+		annulees, err := database.MigrerBas(db, n)
+		if err != nil {
+			log.Fatalf("Erreur après %d migration(s) annulée(s) : %v", annulees, err)
+		}
+		fmt.Printf("%d migration(s) annulée(s)\n", annulees)
+	case "status":
+		etats, err := database.EtatMigrations(db)
+		if err != nil {
+			log.Fatal("Erreur de lecture des migrations :", err)
+		}
+		for _, e := range etats {
+			etat := "en attente"
+			if e.AppliqueeLe != nil {
+				etat = "appliquée le " + e.AppliqueeLe.Local().Format("2006-01-02 15:04:05")
+			}
+			if !e.Connue {
+				etat += " (inconnue de ce binaire)"
+			}
+			fmt.Printf("%04d  %-28s %s\n", e.Version, e.Nom, etat)
+		}
+	default:
+		options.Usage()
+		os.Exit(2)
+	}
+}
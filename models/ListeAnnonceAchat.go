@@ -1,12 +1,21 @@
 package models
 
+import "time"
+
 type ListeAnnonceAchat struct {
 	ID                 string  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID             string  `json:"user_id"`
+	Version            int64   `json:"version"`
 	Statut             string  `json:"statut"`
 	Prix               float64 `json:"prix_kg"`
 	Description        string  `json:"description"`
 	Quantite           float64 `json:"quantite"`
 	UserNom            string  `json:"nom"`
 	TypeCultureLibelle string  `json:"libelle"`
+	// Réputation de l'acheteur
+	NoteUtilisateur   float64 `json:"note_utilisateur"`
+	NbAvisUtilisateur int64   `json:"nb_avis_utilisateur"`
+	// Absents du contrat v1, repris par le contrat v2
+	TypeCultureID string    `json:"-"`
+	CreatedAt     time.Time `json:"-"`
 }
- 
\ No newline at end of file
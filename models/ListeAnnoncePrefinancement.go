@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type LiteAnnoncePrefinancement struct {
+	ID                 string  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID             string  `json:"user_id"`
+	Version            int64   `json:"version"`
+	Statut             string  `json:"statut"`
+	Description        string  `json:"description"`
+	MontantPref        float64 `json:"montant_pref"`
+	PrixKgPref         float64 `json:"prix_kg_pref"`
+	Quantite           float64 `json:"quantite"`
+	UserNom            string  `json:"nom"`
+	TypeCultureLibelle string  `json:"libelle"`
+	ParcelleAdresse    string  `json:"adresse"`
+	ParcelleSuf        string  `json:"surface"`
+	NbFavoris          int64   `json:"nb_favoris"`
+	EstFavori          bool    `json:"est_favori"`
+	FavoriModifie      bool    `json:"favori_modifie,omitempty"`
+	// Réputation du producteur
+	NoteUtilisateur   float64 `json:"note_utilisateur"`
+	NbAvisUtilisateur int64   `json:"nb_avis_utilisateur"`
+	// Absents du contrat v1, repris par le contrat v2
+	TypeCultureID string    `json:"-"`
+	ParcelleID    string    `json:"-"`
+	CreatedAt     time.Time `json:"-"`
+}
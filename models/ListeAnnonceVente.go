@@ -1,7 +1,11 @@
 package models
 
+import "time"
+
 type ListeAnnonceVente struct {
 	ID                 string  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID             string  `json:"user_id"`
+	Version            int64   `json:"version"`
 	Photo              string  `json:"photo"`
 	Statut             string  `json:"statut"`
 	Description        string  `json:"description"`
@@ -11,4 +15,15 @@ type ListeAnnonceVente struct {
 	TypeCultureLibelle string  `json:"libelle"`
 	ParcelleAdresse    string  `json:"adresse"`
 	// ParcelleSuf     string  `json:"surface"`
+	NbFavoris     int64 `json:"nb_favoris"`
+	EstFavori     bool  `json:"est_favori"`
+	FavoriModifie bool  `json:"favori_modifie,omitempty"`
+	// Réputation du vendeur
+	NoteUtilisateur   float64 `json:"note_utilisateur"`
+	NbAvisUtilisateur int64   `json:"nb_avis_utilisateur"`
+	// Absents du contrat v1, repris par le contrat v2
+	TypeCultureID   string    `json:"-"`
+	ParcelleID      string    `json:"-"`
+	ParcelleSurface string    `json:"-"`
+	CreatedAt       time.Time `json:"-"`
 }
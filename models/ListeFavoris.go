@@ -0,0 +1,7 @@
+package models
+
+// ListeFavoris est la réponse de "mes favoris", avec les mêmes DTO que les listes publiques
+type ListeFavoris struct {
+	Ventes          []ListeAnnonceVente         `json:"ventes"`
+	Prefinancements []LiteAnnoncePrefinancement `json:"prefinancements"`
+}
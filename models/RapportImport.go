@@ -0,0 +1,17 @@
+package models
+
+// ErreurLigneImport regroupe les erreurs de validation d'une ligne du CSV
+type ErreurLigneImport struct {
+	Ligne   int      `json:"ligne"`
+	Erreurs []string `json:"erreurs"`
+}
+
+// RapportImport est la réponse renvoyée par l'import en masse d'annonces
+type RapportImport struct {
+	Type      string              `json:"type"`
+	Mode      string              `json:"mode"`
+	Total     int                 `json:"total"`
+	Valides   int                 `json:"valides"`
+	Importees int                 `json:"importees"`
+	Erreurs   []ErreurLigneImport `json:"erreurs"`
+}
@@ -27,8 +27,19 @@ type Parcelle struct {
 	ID      uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
 	Adresse string    `json:"adresse"`
 	Surface string    `json:"surface"`
+	// UserID est l'exploitant de la parcelle, nil pour une parcelle pas encore attribuée
+	UserID *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid"`
+	// Coordonnées en degrés décimaux (WGS 84), nil pour une parcelle non géolocalisée
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
 }
 
 func (Parcelle) TableName() string {
 	return "parcelle"
 }
+
+// ExploiteePar indique si l'utilisateur peut publier une annonce sur la parcelle. Une
+// parcelle sans exploitant renseigné (antérieure à la colonne user_id) est acceptée.
+func (p Parcelle) ExploiteePar(userID uuid.UUID) bool {
+	return p.UserID == nil || *p.UserID == userID
+}
@@ -0,0 +1,31 @@
+package models
+
+// ActiviteUtilisateur est la fiche d'un utilisateur dans l'API d'administration
+type ActiviteUtilisateur struct {
+	User
+	NbAnnoncesVente     int64           `json:"nb_annonces_vente"`
+	NbAnnoncesAchat     int64           `json:"nb_annonces_achat"`
+	NbAnnoncesPref      int64           `json:"nb_annonces_pref"`
+	NbConversations     int64           `json:"nb_conversations"`
+	NbMessages          int64           `json:"nb_messages"`
+	NbSignalementsEmis  int64           `json:"nb_signalements_emis"`
+	NbSignalementsRecus int64           `json:"nb_signalements_recus"`
+	Note                NoteUtilisateur `json:"note"`
+	Suspension          *Suspension     `json:"suspension,omitempty"`
+	Audit               []JournalAudit  `json:"audit"`
+}
+
+// StatistiquesPlateforme regroupe les compteurs globaux de la plateforme
+type StatistiquesPlateforme struct {
+	Utilisateurs int64 `json:"utilisateurs"`
+	// AnnoncesParStatut : nombre d'annonces par type puis par statut
+	AnnoncesParStatut     map[string]map[string]int64 `json:"annonces_par_statut"`
+	Conversations         int64                       `json:"conversations"`
+	Messages              int64                       `json:"messages"`
+	Avis                  int64                       `json:"avis"`
+	SignalementsEnAttente int64                       `json:"signalements_en_attente"`
+	AnnoncesMasquees      int64                       `json:"annonces_masquees"`
+	SuspensionsActives    int64                       `json:"suspensions_actives"`
+	LivraisonsEnAttente   int64                       `json:"livraisons_webhook_en_attente"`
+	LivraisonsAbandonnees int64                       `json:"livraisons_webhook_abandonnees"`
+}
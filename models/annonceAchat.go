@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type AnnonceAchat struct {
@@ -14,7 +15,9 @@ type AnnonceAchat struct {
 	Prix          float64   `json:"prix_kg" gorm:"column:prix_kg"`
 	Description   string    `json:"description"`
 	Quantite      float64   `json:"quantite"`
-	CreatedAt     time.Time `json:"cree_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	// Version est incrémentée à chaque modification (verrouillage optimiste, ETag)
+	Version int64 `json:"version" gorm:"not null;default:1"`
 	// UpdatedAt time.Time `json:"updated_at"`
 
 	// Relations
@@ -25,3 +28,21 @@ type AnnonceAchat struct {
 func (AnnonceAchat) TableName() string {
 	return "annonces_achat"
 }
+
+// Criteres renvoie les valeurs de l'annonce comparées aux filtres
+func (a AnnonceAchat) Criteres() CriteresAnnonce {
+	return CriteresAnnonce{
+		UserID:        a.UserID,
+		Statut:        a.Statut,
+		TypeCultureID: a.TypeCultureID,
+		Prix:          a.Prix,
+		Quantite:      a.Quantite,
+	}
+}
+
+func (a *AnnonceAchat) BeforeCreate(tx *gorm.DB) error {
+	if a.Version == 0 {
+		a.Version = 1
+	}
+	return nil
+}
@@ -1,24 +1,28 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type AnnoncePrefinancement struct {
 	ID                    uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
 	UserID                uuid.UUID `json:"user_id" gorm:"type:uuid"`
-	TypeCultureID         uuid.UUID `json:"type_culture_id " gorm:"type:uuid"`
+	TypeCultureID         uuid.UUID `json:"type_culture_id" gorm:"type:uuid"`
 	ParcelleID            uuid.UUID `json:"parcelle_id" gorm:"type:uuid"`
 	Statut                string    `json:"statut" gorm:"column:statut"`
 	Description           string    `json:"description"`
 	MontantPrefinancement float64   `json:"montant_pref"  gorm:"column:montant_pref"`
 	Prix                  float64   `json:"prix_kg_pref"  gorm:"column:prix_kg_pref"`
 	Quantite              float64   `json:"quantite"  gorm:"column:quantite"`
+	// Version est incrémentée à chaque modification (verrouillage optimiste, ETag)
+	Version int64 `json:"version" gorm:"not null;default:1"`
 
+	CreatedAt time.Time `json:"created_at"`
 	// UpdatedAt time.Time `json:"updated_at"`
 
-	// CreatedAt omitted for now
-
 	// Relations
 	User        User        `json:"users" gorm:"foreignKey:UserID"`
 	TypeCulture TypeCulture `json:"type_culture" gorm:"foreignKey:TypeCultureID"`
@@ -29,3 +33,24 @@ func (AnnoncePrefinancement) TableName() string {
 	return "annonces_prefinancement"
 
 }
+
+// Criteres renvoie les valeurs de l'annonce comparées aux filtres (Parcelle doit être chargée)
+func (a AnnoncePrefinancement) Criteres() CriteresAnnonce {
+	return CriteresAnnonce{
+		UserID:        a.UserID,
+		Statut:        a.Statut,
+		TypeCultureID: a.TypeCultureID,
+		Prix:          a.Prix,
+		Quantite:      a.Quantite,
+		Adresse:       a.Parcelle.Adresse,
+		Latitude:      a.Parcelle.Latitude,
+		Longitude:     a.Parcelle.Longitude,
+	}
+}
+
+func (a *AnnoncePrefinancement) BeforeCreate(tx *gorm.DB) error {
+	if a.Version == 0 {
+		a.Version = 1
+	}
+	return nil
+}
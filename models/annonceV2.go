@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// Contrat de réponse v2 des annonces : mêmes noms de champs pour les trois types,
+// relations imbriquées (auteur, type de culture, parcelle) plutôt qu'aplaties.
+// Les noms des champs modifiables sont ceux des corps de création.
+
+type AuteurV2 struct {
+	ID  string `json:"id"`
+	Nom string `json:"nom"`
+	// Réputation : moyenne et nombre des avis reçus
+	Note   float64 `json:"note"`
+	NbAvis int64   `json:"nb_avis"`
+}
+
+type TypeCultureV2 struct {
+	ID      string `json:"id"`
+	Libelle string `json:"libelle"`
+}
+
+type ParcelleV2 struct {
+	ID      string `json:"id"`
+	Adresse string `json:"adresse"`
+	Surface string `json:"surface"`
+}
+
+type FavorisV2 struct {
+	Nombre    int64 `json:"nombre"`
+	EstFavori bool  `json:"est_favori"`
+	// Modifie : prix ou statut changé depuis l'ajout en favori (liste "mes favoris")
+	Modifie bool `json:"modifie,omitempty"`
+}
+
+type AnnonceVenteV2 struct {
+	ID          string        `json:"id"`
+	Type        string        `json:"type"`
+	Version     int64         `json:"version"`
+	Statut      string        `json:"statut"`
+	Description string        `json:"description"`
+	Quantite    float64       `json:"quantite"`
+	PrixKg      float64       `json:"prix_kg"`
+	Photo       string        `json:"photo"`
+	CreatedAt   *time.Time    `json:"created_at"`
+	Auteur      AuteurV2      `json:"auteur"`
+	TypeCulture TypeCultureV2 `json:"type_culture"`
+	Parcelle    ParcelleV2    `json:"parcelle"`
+	Favoris     FavorisV2     `json:"favoris"`
+}
+
+type AnnonceAchatV2 struct {
+	ID          string        `json:"id"`
+	Type        string        `json:"type"`
+	Version     int64         `json:"version"`
+	Statut      string        `json:"statut"`
+	Description string        `json:"description"`
+	Quantite    float64       `json:"quantite"`
+	PrixKg      float64       `json:"prix_kg"`
+	CreatedAt   *time.Time    `json:"created_at"`
+	Auteur      AuteurV2      `json:"auteur"`
+	TypeCulture TypeCultureV2 `json:"type_culture"`
+}
+
+type AnnoncePrefV2 struct {
+	ID          string        `json:"id"`
+	Type        string        `json:"type"`
+	Version     int64         `json:"version"`
+	Statut      string        `json:"statut"`
+	Description string        `json:"description"`
+	Quantite    float64       `json:"quantite"`
+	PrixKgPref  float64       `json:"prix_kg_pref"`
+	MontantPref float64       `json:"montant_pref"`
+	CreatedAt   *time.Time    `json:"created_at"`
+	Auteur      AuteurV2      `json:"auteur"`
+	TypeCulture TypeCultureV2 `json:"type_culture"`
+	Parcelle    ParcelleV2    `json:"parcelle"`
+	Favoris     FavorisV2     `json:"favoris"`
+}
+
+// ListeFavorisV2 est la réponse v2 de "mes favoris"
+type ListeFavorisV2 struct {
+	Ventes          []AnnonceVenteV2 `json:"ventes"`
+	Prefinancements []AnnoncePrefV2  `json:"prefinancements"`
+}
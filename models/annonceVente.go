@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type AnnonceVente struct {
@@ -16,11 +17,11 @@ type AnnonceVente struct {
 	Quantite      float64   `json:"quantite"`
 	PrixKg        float64   `json:"prix_kg"`
 	Description   string    `json:"description"`
-	CreatedAt     time.Time `json:"créé_a"`
+	CreatedAt     time.Time `json:"created_at"`
+	// Version est incrémentée à chaque modification (verrouillage optimiste, ETag)
+	Version int64 `json:"version" gorm:"not null;default:1"`
 	// UpdatedAt time.Time `json:"updated_at"`
 
-	// CreatedAt omitted for now
-
 	// Relations
 	User        User        `json:"users" gorm:"foreignKey:UserID"`
 	TypeCulture TypeCulture `json:"type_culture" gorm:"foreignKey:TypeCultureID"`
@@ -30,3 +31,24 @@ type AnnonceVente struct {
 func (AnnonceVente) TableName() string {
 	return "annonces_vente"
 }
+
+// Criteres renvoie les valeurs de l'annonce comparées aux filtres (Parcelle doit être chargée)
+func (a AnnonceVente) Criteres() CriteresAnnonce {
+	return CriteresAnnonce{
+		UserID:        a.UserID,
+		Statut:        a.Statut,
+		TypeCultureID: a.TypeCultureID,
+		Prix:          a.PrixKg,
+		Quantite:      a.Quantite,
+		Adresse:       a.Parcelle.Adresse,
+		Latitude:      a.Parcelle.Latitude,
+		Longitude:     a.Parcelle.Longitude,
+	}
+}
+
+func (a *AnnonceVente) BeforeCreate(tx *gorm.DB) error {
+	if a.Version == 0 {
+		a.Version = 1
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Rôle de l'utilisateur évalué dans la transaction
+const (
+	RoleProducteur = "producteur"
+	RoleAcheteur   = "acheteur"
+	RoleFinanceur  = "financeur"
+)
+
+// DelaiModificationAvis est la durée pendant laquelle l'auteur peut modifier son avis
+const DelaiModificationAvis = 14 * 24 * time.Hour
+
+// Avis est l'évaluation d'un participant d'une transaction conclue (conversation
+// déclarée conclue par le producteur et confirmée par l'interlocuteur) par l'autre
+// participant.
+// Un seul avis par auteur et par transaction.
+type Avis struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	ConversationID    uuid.UUID  `json:"conversation_id" gorm:"type:uuid;uniqueIndex:idx_avis_conversation_auteur"`
+	AnnonceType       string     `json:"annonce_type"`
+	AnnonceID         uuid.UUID  `json:"annonce_id" gorm:"type:uuid"`
+	AuteurID          uuid.UUID  `json:"auteur_id" gorm:"type:uuid;uniqueIndex:idx_avis_conversation_auteur"`
+	CibleID           uuid.UUID  `json:"cible_id" gorm:"type:uuid;index"`
+	RoleCible         string     `json:"role_cible"`
+	NoteQualite       int        `json:"note_qualite"`
+	NotePonctualite   int        `json:"note_ponctualite"`
+	NoteCommunication int        `json:"note_communication"`
+	Commentaire       string     `json:"commentaire" gorm:"type:text"`
+	Reponse           string     `json:"reponse,omitempty" gorm:"type:text"`
+	ReponduLe         *time.Time `json:"repondu_le,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+func (Avis) TableName() string {
+	return "avis"
+}
+
+// Modifiable indique si l'avis peut encore être modifié par son auteur
+func (a Avis) Modifiable(maintenant time.Time) bool {
+	return maintenant.Before(a.CreatedAt.Add(DelaiModificationAvis))
+}
+
+// NoteUtilisateur est la note agrégée d'un utilisateur (moyenne des trois critères)
+type NoteUtilisateur struct {
+	UserID        string  `json:"user_id"`
+	Moyenne       float64 `json:"moyenne"`
+	Qualite       float64 `json:"qualite"`
+	Ponctualite   float64 `json:"ponctualite"`
+	Communication float64 `json:"communication"`
+	NbAvis        int64   `json:"nb_avis"`
+}
+
+// ListeAvis est la réponse des avis reçus par un utilisateur
+type ListeAvis struct {
+	Note NoteUtilisateur `json:"note"`
+	Avis []Avis          `json:"avis"`
+}
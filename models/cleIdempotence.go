@@ -0,0 +1,61 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// États d'une clé d'idempotence
+const (
+	IdempotenceEnCours  = "en_cours"
+	IdempotenceTerminee = "terminee"
+)
+
+// CleIdempotence mémorise la réponse d'une requête envoyée avec un en-tête Idempotency-Key,
+// pour la rejouer si le client renvoie la même requête avant ExpireLe
+type CleIdempotence struct {
+	UserID      string `gorm:"primaryKey"`
+	Cle         string `gorm:"primaryKey"`
+	Empreinte   string `gorm:"not null"`
+	Statut      string `gorm:"not null"`
+	CodeHTTP    int
+	ContentType string
+	// Entetes contient les en-têtes posés par le handler (ETag, Location...), rejoués avec
+	// la réponse
+	Entetes   EntetesHTTP `gorm:"type:text"`
+	Reponse   string      `gorm:"type:text"`
+	CreatedAt time.Time
+	ExpireLe  time.Time `gorm:"index"`
+}
+
+func (CleIdempotence) TableName() string {
+	return "cles_idempotence"
+}
+
+// EntetesHTTP est un ensemble d'en-têtes HTTP stocké en JSON dans une colonne texte
+type EntetesHTTP http.Header
+
+func (e EntetesHTTP) Value() (driver.Value, error) {
+	if e == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string][]string(e))
+	return string(b), err
+}
+
+func (e *EntetesHTTP) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	case []byte:
+		return json.Unmarshal(v, e)
+	default:
+		return errors.New("EntetesHTTP : type de colonne non supporté")
+	}
+}
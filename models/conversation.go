@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+)
+
+// Conversation est un fil de discussion rattaché à une annonce, entre le producteur
+// (propriétaire de l'annonce) et un acheteur ou investisseur (interlocuteur)
+type Conversation struct {
+	ID              uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	AnnonceType     string    `json:"annonce_type" gorm:"uniqueIndex:idx_conversations_annonce_interlocuteur"`
+	AnnonceID       uuid.UUID `json:"annonce_id" gorm:"type:uuid;uniqueIndex:idx_conversations_annonce_interlocuteur"`
+	ProprietaireID  uuid.UUID `json:"proprietaire_id" gorm:"type:uuid;index"`
+	InterlocuteurID uuid.UUID `json:"interlocuteur_id" gorm:"type:uuid;uniqueIndex:idx_conversations_annonce_interlocuteur;index"`
+	// ConclusionDemandeeLe est renseigné quand le producteur déclare la vente ou la
+	// livraison du préfinancement effectuée ; ConclueLe quand l'interlocuteur le confirme.
+	// Les deux participants peuvent alors s'évaluer.
+	ConclusionDemandeeLe *time.Time `json:"conclusion_demandee_le,omitempty"`
+	ConclueLe            *time.Time `json:"conclue_le,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+func (Conversation) TableName() string {
+	return "conversations"
+}
+
+// EstParticipant indique si l'utilisateur peut lire et écrire dans la conversation
+func (c Conversation) EstParticipant(userID uuid.UUID) bool {
+	return userID == c.ProprietaireID || userID == c.InterlocuteurID
+}
+
+// Destinataire renvoie l'autre participant de la conversation
+func (c Conversation) Destinataire(auteurID uuid.UUID) uuid.UUID {
+	if auteurID == c.ProprietaireID {
+		return c.InterlocuteurID
+	}
+	return c.ProprietaireID
+}
+
+// Message est un message d'une conversation. LuLe sert d'accusé de lecture :
+// il est renseigné quand le destinataire lit la conversation.
+type Message struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	ConversationID uuid.UUID  `json:"conversation_id" gorm:"type:uuid;index"`
+	AuteurID       uuid.UUID  `json:"auteur_id" gorm:"type:uuid"`
+	Contenu        string     `json:"contenu" gorm:"type:text"`
+	PieceJointe    string     `json:"-"`
+	PieceJointeNom string     `json:"piece_jointe_nom,omitempty"`
+	LuLe           *time.Time `json:"lu_le,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (Message) TableName() string {
+	return "messages"
+}
+
+// ResumeConversation est l'élément de la liste "mes conversations"
+type ResumeConversation struct {
+	Conversation
+	DernierMessage *Message `json:"dernier_message,omitempty"`
+	NonLus         int64    `json:"non_lus"`
+}
+
+// EvenementMessagerie est envoyé aux participants connectés en WebSocket
+type EvenementMessagerie struct {
+	Type           string         `json:"type"` // "message", "lu" ou "erreur"
+	ConversationID uuid.UUID      `json:"conversation_id"`
+	Message        *Message       `json:"message,omitempty"`
+	LecteurID      *uuid.UUID     `json:"lecteur_id,omitempty"`
+	LuLe           *time.Time     `json:"lu_le,omitempty"`
+	Erreur         *erreurs.Corps `json:"error,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Événement propre au flux temps réel (en plus de ceux des webhooks)
+const EvenementAnnonceStatutModifie = "annonce.statut_modifie"
+
+// EvenementAnnonce est diffusé sur le bus interne à chaque création ou modification
+// d'annonce (flux SSE). L'ID est croissant et sert à la reprise via Last-Event-ID.
+// Donnees porte le DTO v1 de l'annonce, converti à l'envoi au contrat de la version du flux.
+type EvenementAnnonce struct {
+	ID            uint64      `json:"id"`
+	Evenement     string      `json:"evenement"`
+	AnnonceType   string      `json:"annonce_type"`
+	AnnonceID     string      `json:"annonce_id"`
+	TypeCultureID string      `json:"type_culture_id"`
+	Adresse       string      `json:"adresse,omitempty"`
+	CreeLe        time.Time   `json:"cree_le"`
+	Donnees       interface{} `json:"donnees"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Favori est une annonce (vente ou préfinancement) suivie par un utilisateur. Le prix et le statut au moment
+// de l'ajout sont conservés pour signaler les annonces modifiées depuis.
+type Favori struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID        uuid.UUID `json:"user_id" gorm:"type:uuid;uniqueIndex:idx_favoris_user_annonce"`
+	AnnonceType   string    `json:"annonce_type" gorm:"uniqueIndex:idx_favoris_user_annonce"`
+	AnnonceID     uuid.UUID `json:"annonce_id" gorm:"type:uuid;uniqueIndex:idx_favoris_user_annonce;index"`
+	PrixInitial   float64   `json:"prix_initial"`
+	StatutInitial string    `json:"statut_initial"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (Favori) TableName() string {
+	return "favoris"
+}
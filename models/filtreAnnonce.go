@@ -0,0 +1,103 @@
+package models
+
+import (
+	"math"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// FiltreAnnonce regroupe les critères de recherche des listes d'annonces
+// (paramètres de requête de GET /annonces_vente et GET /annonces_achat).
+// Il est aussi enregistré tel quel dans les recherches sauvegardées.
+type FiltreAnnonce struct {
+	UserID        string   `json:"user_id,omitempty" form:"user_id"`
+	Statut        string   `json:"statut,omitempty" form:"statut"`
+	TypeCultureID string   `json:"type_culture_id,omitempty" form:"type_culture_id"`
+	PrixMin       *float64 `json:"prix_min,omitempty" form:"prix_min"`
+	PrixMax       *float64 `json:"prix_max,omitempty" form:"prix_max"`
+	QuantiteMin   *float64 `json:"quantite_min,omitempty" form:"quantite_min"`
+	// Region est recherchée dans l'adresse de la parcelle (annonces de vente et de
+	// préfinancement)
+	Region string `json:"region,omitempty" form:"region"`
+	// Lat, Lng et RayonKm, donnés ensemble, retiennent les parcelles géolocalisées à moins
+	// de RayonKm du point (annonces de vente et de préfinancement)
+	Lat     *float64 `json:"lat,omitempty" form:"lat"`
+	Lng     *float64 `json:"lng,omitempty" form:"lng"`
+	RayonKm *float64 `json:"rayon_km,omitempty" form:"rayon_km"`
+}
+
+// rayonTerreKm est le rayon moyen de la Terre
+const rayonTerreKm = 6371.0
+
+// DistanceKm renvoie la distance à vol d'oiseau entre deux points (formule de haversine)
+func DistanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * rayonTerreKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// AvecDistance indique si le filtre comporte une recherche par distance
+func (f FiltreAnnonce) AvecDistance() bool {
+	return f.Lat != nil && f.Lng != nil && f.RayonKm != nil
+}
+
+// Zone renvoie le rectangle qui englobe le cercle de recherche, pour une première
+// sélection en SQL ; la distance exacte est vérifiée par Correspond
+func (f FiltreAnnonce) Zone() (latMin, latMax, lngMin, lngMax float64) {
+	ecartLat := *f.RayonKm / (rayonTerreKm * math.Pi / 180)
+	latMin, latMax = math.Max(-90, *f.Lat-ecartLat), math.Min(90, *f.Lat+ecartLat)
+	cos := math.Cos(*f.Lat * math.Pi / 180)
+	ecartLng := 180.0
+	if cos > 0.01 {
+		ecartLng = math.Min(180, ecartLat/cos)
+	}
+	return latMin, latMax, math.Max(-180, *f.Lng-ecartLng), math.Min(180, *f.Lng+ecartLng)
+}
+
+// CriteresAnnonce contient les valeurs d'une annonce comparées aux filtres
+// (recherches sauvegardées, dépôts en mémoire)
+type CriteresAnnonce struct {
+	UserID        uuid.UUID
+	Statut        string
+	TypeCultureID uuid.UUID
+	Prix          float64
+	Quantite      float64
+	Adresse       string
+	Latitude      *float64
+	Longitude     *float64
+}
+
+// Correspond indique si une annonce satisfait tous les critères du filtre
+func (f FiltreAnnonce) Correspond(a CriteresAnnonce) bool {
+	if f.UserID != "" && f.UserID != a.UserID.String() {
+		return false
+	}
+	if f.Statut != "" && f.Statut != a.Statut {
+		return false
+	}
+	if f.TypeCultureID != "" && f.TypeCultureID != a.TypeCultureID.String() {
+		return false
+	}
+	if f.PrixMin != nil && a.Prix < *f.PrixMin {
+		return false
+	}
+	if f.PrixMax != nil && a.Prix > *f.PrixMax {
+		return false
+	}
+	if f.QuantiteMin != nil && a.Quantite < *f.QuantiteMin {
+		return false
+	}
+	if f.Region != "" && !strings.Contains(strings.ToLower(a.Adresse), strings.ToLower(f.Region)) {
+		return false
+	}
+	if f.AvecDistance() {
+		if a.Latitude == nil || a.Longitude == nil || DistanceKm(*f.Lat, *f.Lng, *a.Latitude, *a.Longitude) > *f.RayonKm {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// LimiteDebit est l'état d'un seau à jetons du limiteur de débit, partagé entre
+// les instances quand le stockage en base est utilisé
+type LimiteDebit struct {
+	Cle       string    `gorm:"primaryKey"`
+	Jetons    float64   `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"index"`
+}
+
+func (LimiteDebit) TableName() string {
+	return "limites_debit"
+}
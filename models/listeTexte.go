@@ -0,0 +1,45 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// ListeTexte est une liste de chaînes stockée en JSON dans une colonne texte
+type ListeTexte []string
+
+func (l ListeTexte) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	return string(b), err
+}
+
+func (l *ListeTexte) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	case []byte:
+		return json.Unmarshal(v, l)
+	default:
+		return errors.New("ListeTexte : type de colonne non supporté")
+	}
+}
+
+// Contient indique si la liste est vide (aucun filtre) ou contient la valeur
+func (l ListeTexte) Contient(valeur string) bool {
+	if len(l) == 0 {
+		return true
+	}
+	for _, v := range l {
+		if v == valeur {
+			return true
+		}
+	}
+	return false
+}
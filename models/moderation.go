@@ -0,0 +1,147 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Motifs de signalement d'une annonce
+const (
+	MotifFraude               = "fraude"
+	MotifDoublon              = "doublon"
+	MotifContenuInapproprie   = "contenu_inapproprie"
+	MotifPrixAbusif           = "prix_abusif"
+	MotifInformationsErronees = "informations_erronees"
+	MotifAutre                = "autre"
+)
+
+// MotifsSignalement liste les motifs acceptés
+var MotifsSignalement = []string{
+	MotifFraude,
+	MotifDoublon,
+	MotifContenuInapproprie,
+	MotifPrixAbusif,
+	MotifInformationsErronees,
+	MotifAutre,
+}
+
+// États d'un signalement
+const (
+	SignalementEnAttente = "en_attente"
+	SignalementTraite    = "traite"
+)
+
+// Signalement est le signalement d'une annonce par un utilisateur (un seul par annonce et par utilisateur)
+type Signalement struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	AnnonceType string    `json:"annonce_type" gorm:"uniqueIndex:idx_signalements_annonce_auteur"`
+	AnnonceID   uuid.UUID `json:"annonce_id" gorm:"type:uuid;uniqueIndex:idx_signalements_annonce_auteur"`
+	AuteurID    uuid.UUID `json:"auteur_id" gorm:"type:uuid;uniqueIndex:idx_signalements_annonce_auteur"`
+	Motif       string    `json:"motif"`
+	Commentaire string    `json:"commentaire,omitempty" gorm:"type:text"`
+	Statut      string    `json:"statut" gorm:"index"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (Signalement) TableName() string {
+	return "signalements"
+}
+
+// États de modération d'une annonce
+const (
+	// ModerationSignalee : signalements en attente, annonce toujours visible
+	ModerationSignalee = "signalee"
+	// ModerationMasqueeAuto : seuil de signalements atteint, annonce masquée en attendant un modérateur
+	ModerationMasqueeAuto = "masquee_auto"
+	ModerationApprouvee   = "approuvee"
+	ModerationMasquee     = "masquee"
+)
+
+// ModerationsMasquees liste les états dans lesquels une annonce est exclue des listes publiques
+var ModerationsMasquees = []string{ModerationMasqueeAuto, ModerationMasquee}
+
+// ModerationAnnonce est l'état de modération d'une annonce. Les tables d'annonces étant
+// partagées, il est conservé à part ; une annonce sans ligne est visible.
+type ModerationAnnonce struct {
+	AnnonceType    string     `json:"annonce_type" gorm:"primaryKey"`
+	AnnonceID      uuid.UUID  `json:"annonce_id" gorm:"type:uuid;primaryKey"`
+	Statut         string     `json:"statut" gorm:"index"`
+	NbSignalements int64      `json:"nb_signalements"`
+	ModerateurID   *uuid.UUID `json:"moderateur_id,omitempty" gorm:"type:uuid"`
+	ModereLe       *time.Time `json:"modere_le,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (ModerationAnnonce) TableName() string {
+	return "moderation_annonces"
+}
+
+// EstMasquee indique si l'annonce est exclue des listes publiques
+func (m ModerationAnnonce) EstMasquee() bool {
+	return m.Statut == ModerationMasqueeAuto || m.Statut == ModerationMasquee
+}
+
+// ElementModeration est un élément de la file de modération
+type ElementModeration struct {
+	ModerationAnnonce
+	Signalements []Signalement `json:"signalements"`
+}
+
+// Suspension interdit à un utilisateur de publier et masque ses annonces.
+// FinLe vide : suspension définitive (bannissement).
+type Suspension struct {
+	UserID       uuid.UUID  `json:"user_id" gorm:"type:uuid;primaryKey"`
+	Motif        string     `json:"motif" gorm:"type:text"`
+	ModerateurID uuid.UUID  `json:"moderateur_id" gorm:"type:uuid"`
+	FinLe        *time.Time `json:"fin_le,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (Suspension) TableName() string {
+	return "suspensions"
+}
+
+// Active indique si la suspension est en cours
+func (s Suspension) Active(maintenant time.Time) bool {
+	return s.FinLe == nil || maintenant.Before(*s.FinLe)
+}
+
+// Actions enregistrées dans le journal d'audit
+const (
+	AuditApprouverAnnonce = "annonce.approuver"
+	AuditMasquerAnnonce   = "annonce.masquer"
+	AuditBannirAuteur     = "utilisateur.bannir"
+	AuditModifierAnnonce  = "annonce.modifier"
+	AuditSupprimerAnnonce = "annonce.supprimer"
+	AuditSuspendre        = "utilisateur.suspendre"
+	AuditReactiver        = "utilisateur.reactiver"
+	AuditCreerCulture     = "type_culture.creer"
+	AuditModifierCulture  = "type_culture.modifier"
+	AuditSupprimerCulture = "type_culture.supprimer"
+)
+
+// Types de cible du journal d'audit
+const (
+	CibleAnnonce     = "annonce"
+	CibleUtilisateur = "utilisateur"
+	CibleTypeCulture = "type_culture"
+)
+
+// JournalAudit trace une action d'administration : qui, quoi, sur quelle cible et pourquoi
+type JournalAudit struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	ActeurID    uuid.UUID `json:"acteur_id" gorm:"type:uuid;index"`
+	Action      string    `json:"action" gorm:"index"`
+	CibleType   string    `json:"cible_type"`
+	CibleID     uuid.UUID `json:"cible_id" gorm:"type:uuid;index"`
+	AnnonceType string    `json:"annonce_type,omitempty"`
+	Motif       string    `json:"motif,omitempty" gorm:"type:text"`
+	// Details : valeurs modifiées par l'action, en JSON
+	Details   string    `json:"details,omitempty" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+func (JournalAudit) TableName() string {
+	return "journal_audit"
+}
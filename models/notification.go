@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types de notifications
+const (
+	NotificationRechercheCorrespondante = "recherche_correspondante"
+	NotificationOffreRecue              = "offre_recue"
+	NotificationEngagementPref          = "engagement_prefinancement"
+	NotificationAnnonceExpiree          = "annonce_expiree"
+	NotificationMessageRecu             = "message_recu"
+)
+
+// TypesNotification liste les types connus, pour la validation et les préférences
+var TypesNotification = []string{
+	NotificationRechercheCorrespondante,
+	NotificationOffreRecue,
+	NotificationEngagementPref,
+	NotificationAnnonceExpiree,
+	NotificationMessageRecu,
+}
+
+// Canaux de diffusion des notifications
+const (
+	CanalInApp = "in_app"
+	CanalEmail = "email"
+	CanalSMS   = "sms"
+)
+
+// CanauxNotification liste les canaux connus. Seul le canal in_app est actif par défaut.
+var CanauxNotification = []string{CanalInApp, CanalEmail, CanalSMS}
+
+// Notification est un message de la boîte de réception in-app d'un utilisateur,
+// éventuellement rattaché à une annonce
+type Notification struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;index"`
+	Type        string     `json:"type"`
+	Titre       string     `json:"titre"`
+	Message     string     `json:"message"`
+	AnnonceType string     `json:"annonce_type,omitempty"`
+	AnnonceID   *uuid.UUID `json:"annonce_id,omitempty" gorm:"type:uuid"`
+	Lu          bool       `json:"lu" gorm:"index"`
+	LuLe        *time.Time `json:"lu_le,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// PreferenceNotification active ou désactive un type de notification sur un canal.
+// En l'absence de ligne, la valeur par défaut s'applique (voir CanalActifParDefaut).
+type PreferenceNotification struct {
+	UserID uuid.UUID `json:"-" gorm:"type:uuid;primaryKey"`
+	Type   string    `json:"type" gorm:"primaryKey"`
+	Canal  string    `json:"canal" gorm:"primaryKey"`
+	Active bool      `json:"active"`
+}
+
+func (PreferenceNotification) TableName() string {
+	return "preferences_notification"
+}
+
+// CanalActifParDefaut indique si un canal est actif quand l'utilisateur n'a rien configuré
+func CanalActifParDefaut(canal string) bool {
+	return canal == CanalInApp
+}
+
+// ListeNotifications est la réponse de la boîte de réception
+type ListeNotifications struct {
+	Notifications []Notification `json:"notifications"`
+	NonLues       int64          `json:"non_lues"`
+}
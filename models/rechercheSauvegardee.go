@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RechercheSauvegardee est un ensemble de filtres enregistré par un acheteur.
+// Chaque nouvelle annonce (ou annonce modifiée) qui y correspond génère une notification.
+type RechercheSauvegardee struct {
+	ID          uuid.UUID     `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID      uuid.UUID     `json:"user_id" gorm:"type:uuid;index"`
+	Nom         string        `json:"nom"`
+	AnnonceType string        `json:"annonce_type" gorm:"index"`
+	Filtre      FiltreAnnonce `json:"filtre" gorm:"embedded;embeddedPrefix:filtre_"`
+	Active      bool          `json:"active"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+func (RechercheSauvegardee) TableName() string {
+	return "recherches_sauvegardees"
+}
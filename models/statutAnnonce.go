@@ -0,0 +1,19 @@
+package models
+
+// Statuts d'annonce. vendue (vente, achat) et financee (préfinancement) déclenchent les
+// événements webhook annonce.vendue et annonce.financee.
+const (
+	StatutActive   = "active"
+	StatutEnPause  = "en_pause"
+	StatutVendue   = "vendue"
+	StatutFinancee = "financee"
+	StatutExpiree  = "expiree"
+)
+
+// StatutsAnnonce renvoie les statuts acceptés pour un type d'annonce
+func StatutsAnnonce(annonceType string) []string {
+	if annonceType == AnnonceTypePref {
+		return []string{StatutActive, StatutEnPause, StatutFinancee, StatutExpiree}
+	}
+	return []string{StatutActive, StatutEnPause, StatutVendue, StatutExpiree}
+}
@@ -0,0 +1,9 @@
+package models
+
+// Types d'annonces, utilisés par les entités qui référencent indifféremment
+// une annonce de vente, d'achat ou de préfinancement
+const (
+	AnnonceTypeVente = "vente"
+	AnnonceTypeAchat = "achat"
+	AnnonceTypePref  = "pref"
+)
@@ -0,0 +1,11 @@
+package models
+
+// Versions du contrat de réponse de l'API, montées sous /v1 et /v2. Les routes sans
+// préfixe sont un alias déprécié de /v1.
+const (
+	VersionAPI1 = 1
+	VersionAPI2 = 2
+)
+
+// CleVersionAPI est la clé du contexte Gin portant la version de l'API demandée
+const CleVersionAPI = "version_api"
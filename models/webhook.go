@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Événements émis vers les webhooks des partenaires
+const (
+	EvenementAnnonceCreee    = "annonce.creee"
+	EvenementAnnonceModifiee = "annonce.modifiee"
+	EvenementAnnonceVendue   = "annonce.vendue"
+	EvenementAnnonceFinancee = "annonce.financee"
+)
+
+// EvenementsWebhook liste les événements auxquels un partenaire peut s'abonner
+var EvenementsWebhook = []string{
+	EvenementAnnonceCreee,
+	EvenementAnnonceModifiee,
+	EvenementAnnonceVendue,
+	EvenementAnnonceFinancee,
+}
+
+// Statuts d'une livraison de webhook
+const (
+	LivraisonEnAttente = "en_attente"
+	LivraisonLivree    = "livree"
+	LivraisonAbandonne = "abandonnee" // dead letter : nombre maximal de tentatives atteint
+)
+
+// WebhookEndpoint est une URL de partenaire appelée lors des événements sur les annonces.
+// Les listes vides d'événements ou de types d'annonces signifient « tous ».
+type WebhookEndpoint struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID       uuid.UUID  `json:"user_id" gorm:"type:uuid;index"`
+	URL          string     `json:"url"`
+	Secret       string     `json:"-"`
+	Evenements   ListeTexte `json:"evenements" gorm:"type:text"`
+	AnnonceTypes ListeTexte `json:"annonce_types" gorm:"type:text"`
+	Active       bool       `json:"active"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+// WebhookLivraison est une ligne de l'outbox : un événement à livrer à un endpoint.
+// Elle est créée dans la même transaction que la modification de l'annonce.
+type WebhookLivraison struct {
+	ID                 uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	EndpointID         uuid.UUID  `json:"endpoint_id" gorm:"type:uuid;index"`
+	Evenement          string     `json:"evenement"`
+	Payload            string     `json:"payload" gorm:"type:text"`
+	Statut             string     `json:"statut" gorm:"index:idx_webhook_livraisons_a_envoyer"`
+	Tentatives         int        `json:"tentatives"`
+	ProchaineTentative time.Time  `json:"prochaine_tentative" gorm:"index:idx_webhook_livraisons_a_envoyer"`
+	DernierCodeHTTP    int        `json:"dernier_code_http,omitempty"`
+	DerniereErreur     string     `json:"derniere_erreur,omitempty"`
+	LivreeLe           *time.Time `json:"livree_le,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+func (WebhookLivraison) TableName() string {
+	return "webhook_livraisons"
+}
+
+// EvenementWebhook est le corps JSON envoyé aux partenaires
+type EvenementWebhook struct {
+	ID          string      `json:"id"`
+	Evenement   string      `json:"evenement"`
+	AnnonceType string      `json:"annonce_type"`
+	AnnonceID   string      `json:"annonce_id"`
+	CreeLe      time.Time   `json:"cree_le"`
+	Donnees     interface{} `json:"donnees"`
+}
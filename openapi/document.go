@@ -0,0 +1,315 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version de la spécification OpenAPI produite
+const VersionOpenAPI = "3.0.3"
+
+// Types de contenu des requêtes et des réponses
+const (
+	JSON       = "application/json"
+	MergePatch = "application/merge-patch+json"
+	Multipart  = "multipart/form-data"
+	CSV        = "text/csv"
+	SSE        = "text/event-stream"
+)
+
+// Authentification d'une opération
+const (
+	// AuthAucune : route publique
+	AuthAucune = iota
+	// AuthOptionnelle : route publique, l'utilisateur est identifié si un token est fourni
+	AuthOptionnelle
+	// AuthRequise : token Bearer obligatoire
+	AuthRequise
+)
+
+// schemaSecurite est le nom du schéma de sécurité Bearer (JWT)
+const schemaSecurite = "bearerAuth"
+
+// Document est un document OpenAPI 3 (sous-ensemble utilisé par l'API)
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+	schemas    *generateurSchemas
+	erreur     reflect.Type
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server est une adresse de base de l'API : les chemins du document lui sont relatifs
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem associe une méthode HTTP (en minuscules) à son opération
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Route décrit une route de l'API. Les valeurs Corps, Query et Reponse sont des valeurs
+// (zéro) des types Go concernés : leurs schémas sont déduits des balises json, form et binding.
+type Route struct {
+	Methode string
+	// Chemin au format Gin (/annonces_vente/:id)
+	Chemin      string
+	Tag         string
+	Resume      string
+	Description string
+	Auth        int
+	// Corps de la requête ; ContentType vaut application/json par défaut. Avec
+	// application/merge-patch+json, aucun champ n'est obligatoire.
+	Corps       interface{}
+	ContentType string
+	// Query est une structure dont les champs (balise form) sont des paramètres de requête
+	Query      interface{}
+	Parametres []Parameter
+	// Statut de la réponse en cas de succès (200 par défaut)
+	Statut  int
+	Reponse interface{}
+	// TypeReponse remplace application/json (flux SSE, fichier...)
+	TypeReponse string
+	// Erreurs : statuts d'erreur documentés en plus de ceux de l'authentification
+	Erreurs []int
+}
+
+// Nouveau crée un document vide. erreur est la valeur du corps des réponses d'erreur.
+func Nouveau(titre string, version string, description string, erreur interface{}) *Document {
+	d := &Document{
+		OpenAPI: VersionOpenAPI,
+		Info:    Info{Title: titre, Description: description, Version: version},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]SecurityScheme{
+				schemaSecurite: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	d.schemas = &generateurSchemas{composants: d.Components.Schemas, noms: map[reflect.Type]string{}}
+	d.erreur = reflect.TypeOf(erreur)
+	return d
+}
+
+// AjouterServeur déclare une adresse de base de l'API (préfixe de version)
+func (d *Document) AjouterServeur(url string, description string) {
+	d.Servers = append(d.Servers, Server{URL: url, Description: description})
+}
+
+// AjouterTag déclare un tag (groupe d'opérations) et sa description
+func (d *Document) AjouterTag(nom string, description string) {
+	d.Tags = append(d.Tags, Tag{Name: nom, Description: description})
+}
+
+// parametreChemin reconnaît les paramètres de chemin Gin (:id)
+var parametreChemin = regexp.MustCompile(`:(\w+)`)
+
+// CheminOpenAPI convertit un chemin Gin en chemin OpenAPI : /annonces/:id devient /annonces/{id}
+func CheminOpenAPI(chemin string) string {
+	return parametreChemin.ReplaceAllString(chemin, "{$1}")
+}
+
+// Ajouter ajoute des routes au document
+func (d *Document) Ajouter(routes ...Route) {
+	for _, r := range routes {
+		chemin := CheminOpenAPI(r.Chemin)
+		item, ok := d.Paths[chemin]
+		if !ok {
+			item = PathItem{}
+			d.Paths[chemin] = item
+		}
+		item[strings.ToLower(r.Methode)] = d.operation(r)
+	}
+}
+
+// Contient indique si le document décrit la méthode et le chemin (au format Gin)
+func (d *Document) Contient(methode string, chemin string) bool {
+	item, ok := d.Paths[CheminOpenAPI(chemin)]
+	if !ok {
+		return false
+	}
+	_, ok = item[strings.ToLower(methode)]
+	return ok
+}
+
+// Routes renvoie la liste triée des opérations du document ("GET /annonces_vente/{id}")
+func (d *Document) Routes() []string {
+	var routes []string
+	for chemin, item := range d.Paths {
+		for methode := range item {
+			routes = append(routes, strings.ToUpper(methode)+" "+chemin)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+func (d *Document) operation(r Route) *Operation {
+	op := &Operation{
+		Summary:     r.Resume,
+		Description: r.Description,
+		OperationID: idOperation(r.Methode, r.Chemin),
+		Responses:   map[string]Response{},
+	}
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+	}
+
+	// Paramètres de chemin, remplacés par ceux de Parametres s'ils sont décrits
+	decrits := map[string]bool{}
+	for _, p := range r.Parametres {
+		decrits[p.In+":"+p.Name] = true
+	}
+	for _, m := range parametreChemin.FindAllStringSubmatch(r.Chemin, -1) {
+		if decrits["path:"+m[1]] {
+			continue
+		}
+		schema := &Schema{Type: "string"}
+		if m[1] == "id" || strings.HasSuffix(m[1], "_id") {
+			schema.Format = "uuid"
+		}
+		op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: schema})
+	}
+	for _, p := range r.Parametres {
+		if p.In == "path" {
+			p.Required = true
+		}
+		op.Parameters = append(op.Parameters, p)
+	}
+	if r.Query != nil {
+		op.Parameters = append(op.Parameters, d.schemas.parametresQuery(reflect.TypeOf(r.Query))...)
+	}
+
+	if r.Corps != nil {
+		contentType := r.ContentType
+		if contentType == "" {
+			contentType = JSON
+		}
+		schema := d.schemas.schema(reflect.TypeOf(r.Corps))
+		if contentType == MergePatch {
+			schema = d.schemas.partiel(schema)
+		}
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{contentType: {Schema: schema}}}
+	}
+
+	statut := r.Statut
+	if statut == 0 {
+		statut = http.StatusOK
+	}
+	succes := Response{Description: http.StatusText(statut)}
+	if r.Reponse != nil || r.TypeReponse != "" {
+		typeReponse := r.TypeReponse
+		if typeReponse == "" {
+			typeReponse = JSON
+		}
+		media := MediaType{}
+		if r.Reponse != nil {
+			media.Schema = d.schemas.schema(reflect.TypeOf(r.Reponse))
+		}
+		succes.Content = map[string]MediaType{typeReponse: media}
+	}
+	op.Responses[strconv.Itoa(statut)] = succes
+
+	erreurs := append([]int(nil), r.Erreurs...)
+	switch r.Auth {
+	case AuthRequise:
+		op.Security = []map[string][]string{{schemaSecurite: {}}}
+		erreurs = append(erreurs, http.StatusUnauthorized, http.StatusForbidden)
+	case AuthOptionnelle:
+		op.Security = []map[string][]string{{}, {schemaSecurite: {}}}
+	}
+	for _, code := range erreurs {
+		op.Responses[strconv.Itoa(code)] = d.reponseErreur(http.StatusText(code))
+	}
+	op.Responses["default"] = d.reponseErreur("Erreur (enveloppe commune, voir le code)")
+	return op
+}
+
+func (d *Document) reponseErreur(description string) Response {
+	reponse := Response{Description: description}
+	if d.erreur != nil {
+		reponse.Content = map[string]MediaType{JSON: {Schema: d.schemas.schema(d.erreur)}}
+	}
+	return reponse
+}
+
+// idOperation construit un identifiant d'opération lisible : PATCH /annonces_vente/:id
+// donne patch_annonces_vente_id
+func idOperation(methode string, chemin string) string {
+	morceaux := []string{strings.ToLower(methode)}
+	for _, m := range strings.Split(chemin, "/") {
+		if m = strings.TrimPrefix(m, ":"); m != "" {
+			morceaux = append(morceaux, strings.ReplaceAll(m, ".", "_"))
+		}
+	}
+	return strings.Join(morceaux, "_")
+}
@@ -0,0 +1,34 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed explorateur.html
+var pageExplorateur string
+
+// Handler sert le document en JSON. Il est sérialisé une seule fois : le document ne doit
+// plus être modifié ensuite.
+func Handler(d *Document) gin.HandlerFunc {
+	corps, err := json.Marshal(d)
+	if err != nil {
+		panic("openapi : document non sérialisable : " + err.Error())
+	}
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", corps)
+	}
+}
+
+// Explorateur sert l'explorateur interactif de l'API, autonome (sans ressource externe),
+// qui lit la spécification à l'adresse donnée
+func Explorateur(urlSpecification string) gin.HandlerFunc {
+	page := strings.Replace(pageExplorateur, "{{SPECIFICATION}}", urlSpecification, 1)
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	}
+}
@@ -0,0 +1,271 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema est un schéma OpenAPI 3.0 (sous-ensemble de JSON Schema)
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// prefixeComposant est le préfixe des références vers les schémas nommés
+const prefixeComposant = "#/components/schemas/"
+
+var (
+	typeTime        = reflect.TypeOf(time.Time{})
+	typeRawMessage  = reflect.TypeOf(json.RawMessage{})
+	typeUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	typeTexte       = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// generateurSchemas déduit les schémas des types Go. Les structures nommées deviennent des
+// composants référencés par $ref (ce qui permet les types récursifs), les autres sont décrites
+// en ligne.
+type generateurSchemas struct {
+	composants map[string]*Schema
+	noms       map[reflect.Type]string
+}
+
+func (g *generateurSchemas) schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		s := g.schema(t.Elem())
+		if s.Ref != "" {
+			// nullable n'est pas autorisé à côté de $ref en 3.0 : la référence est enveloppée
+			return &Schema{OneOf: []*Schema{s}, Nullable: true}
+		}
+		s.Nullable = true
+		return s
+	}
+
+	switch {
+	case t == typeTime:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == typeRawMessage || t.Kind() == reflect.Interface:
+		return &Schema{}
+	case t.Implements(typeTexte):
+		// uuid.UUID... : représentés par un texte
+		return &Schema{Type: "string", Format: formatTexte(t)}
+	case estNombreSouple(t):
+		return &Schema{
+			OneOf:       []*Schema{{Type: "number"}, {Type: "string"}},
+			Description: "nombre JSON ou texte (\"12.5\", \"12,5\", \"1 200,50\")",
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structure(t)
+		}
+		return g.reference(t)
+	}
+	return &Schema{}
+}
+
+// reference renvoie la référence au composant d'une structure nommée, créé au premier usage
+func (g *generateurSchemas) reference(t reflect.Type) *Schema {
+	nom, ok := g.noms[t]
+	if !ok {
+		// Les types non exportés (réponses propres à la documentation) prennent une majuscule
+		nom = strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, pris := g.composants[nom]; pris {
+			// Homonyme dans un autre paquet : préfixé par le nom du paquet
+			paquet := t.PkgPath()
+			nom = paquet[strings.LastIndex(paquet, "/")+1:] + "." + nom
+		}
+		g.noms[t] = nom
+		g.composants[nom] = &Schema{}
+		*g.composants[nom] = *g.structure(t)
+	}
+	return &Schema{Ref: prefixeComposant + nom}
+}
+
+// structure décrit les champs exportés d'une structure, y compris ceux des structures
+// anonymes incluses, comme encoding/json
+func (g *generateurSchemas) structure(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.champs(t, s)
+	return s
+}
+
+func (g *generateurSchemas) champs(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		nom, ok := nomJSON(f)
+		if !ok {
+			continue
+		}
+		if f.Anonymous && nom == "" {
+			inclus := f.Type
+			if inclus.Kind() == reflect.Ptr {
+				inclus = inclus.Elem()
+			}
+			if inclus.Kind() == reflect.Struct {
+				g.champs(inclus, s)
+				continue
+			}
+		}
+		if nom == "" {
+			nom = f.Name
+		}
+
+		champ := g.schema(f.Type)
+		if regles(f, champ) {
+			s.Required = append(s.Required, nom)
+		}
+		s.Properties[nom] = champ
+	}
+}
+
+// partiel renvoie le schéma d'un corps JSON Merge Patch : les mêmes champs, tous facultatifs
+func (g *generateurSchemas) partiel(s *Schema) *Schema {
+	if s.Ref != "" {
+		s = g.composants[strings.TrimPrefix(s.Ref, prefixeComposant)]
+	}
+	copie := *s
+	copie.Required = nil
+	copie.Description = "JSON Merge Patch (RFC 7396) : seuls les champs présents sont modifiés"
+	return &copie
+}
+
+// parametresQuery décrit les champs d'une structure de paramètres de requête (balise form)
+func (g *generateurSchemas) parametresQuery(t reflect.Type) []Parameter {
+	var parametres []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		nom := strings.SplitN(f.Tag.Get("form"), ",", 2)[0]
+		if nom == "" || nom == "-" || !f.IsExported() {
+			continue
+		}
+		schema := g.schema(f.Type)
+		schema.Nullable = false
+		requis := regles(f, schema)
+		parametres = append(parametres, Parameter{Name: nom, In: "query", Required: requis, Schema: schema})
+	}
+	return parametres
+}
+
+// nomJSON renvoie le nom JSON d'un champ ("" pour un champ anonyme sans nom), ou false
+// si le champ n'est pas sérialisé
+func nomJSON(f reflect.StructField) (string, bool) {
+	if !f.IsExported() && !f.Anonymous {
+		return "", false
+	}
+	nom := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	if nom == "-" {
+		return "", false
+	}
+	return nom, true
+}
+
+// regles applique au schéma les règles binding du champ et indique s'il est obligatoire
+func regles(f reflect.StructField, s *Schema) bool {
+	cible := s
+	if len(s.OneOf) == 2 && s.OneOf[0].Type == "number" {
+		// Nombre souple : les bornes s'appliquent à la variante numérique
+		cible = s.OneOf[0]
+	}
+	texte := cible.Type == "string"
+
+	requis := false
+	for _, regle := range strings.Split(f.Tag.Get("binding"), ",") {
+		nom, param, _ := strings.Cut(regle, "=")
+		switch nom {
+		case "required":
+			requis = true
+		case "gt":
+			cible.Minimum, cible.ExclusiveMinimum = nombre(param), true
+		case "gte", "min":
+			if texte {
+				cible.MinLength = entier(param)
+			} else {
+				cible.Minimum = nombre(param)
+			}
+		case "lte", "max":
+			if texte {
+				cible.MaxLength = entier(param)
+			} else {
+				cible.Maximum = nombre(param)
+			}
+		case "oneof":
+			cible.Enum = strings.Fields(param)
+		case "uuid":
+			cible.Format = "uuid"
+		case "url":
+			cible.Format = "uri"
+		case "email":
+			cible.Format = "email"
+		}
+	}
+	return requis
+}
+
+// estNombreSouple reconnaît les nombres décodés par un UnmarshalJSON personnalisé, qui
+// acceptent aussi le texte (voir controllers.Nombre)
+func estNombreSouple(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return reflect.PointerTo(t).Implements(typeUnmarshaler)
+	}
+	return false
+}
+
+func formatTexte(t reflect.Type) string {
+	if t.Name() == "UUID" {
+		return "uuid"
+	}
+	return ""
+}
+
+func nombre(param string) *float64 {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+func entier(param string) *int {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
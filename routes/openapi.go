@@ -0,0 +1,404 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Steph-business/annonce_de_vente/controllers"
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/middleware"
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/openapi"
+)
+
+// Adresses de la spécification OpenAPI et de l'explorateur interactif
+const (
+	cheminSpecification = "/openapi.json"
+	cheminExplorateur   = "/docs"
+)
+
+// Corps de réponse sans type nommé dans les contrôleurs (gin.H), décrits pour la documentation
+type (
+	reponseErreur struct {
+		Error erreurs.Corps `json:"error"`
+	}
+	reponseMessage struct {
+		Message string `json:"message"`
+	}
+	reponseMessageTotal struct {
+		Message string `json:"message"`
+		Total   int64  `json:"total"`
+	}
+	reponseWebhookCree struct {
+		Webhook models.WebhookEndpoint `json:"webhook"`
+		// Secret de signature, renvoyé uniquement à la création
+		Secret string `json:"secret"`
+	}
+	reponseAdminAnnonces struct {
+		Total int64 `json:"total"`
+		// Annonces du type demandé, au contrat de la version
+		Annonces []interface{} `json:"annonces"`
+	}
+	reponseAdminUtilisateurs struct {
+		Total        int64         `json:"total"`
+		Utilisateurs []models.User `json:"utilisateurs"`
+	}
+	messageTexte struct {
+		Contenu string `json:"contenu"`
+	}
+)
+
+func texte(valeurs ...string) *openapi.Schema {
+	return &openapi.Schema{Type: "string", Enum: valeurs}
+}
+
+func entier() *openapi.Schema {
+	return &openapi.Schema{Type: "integer"}
+}
+
+func query(nom string, description string, schema *openapi.Schema) openapi.Parameter {
+	return openapi.Parameter{Name: nom, In: "query", Description: description, Schema: schema}
+}
+
+var (
+	typesAnnonce = []string{models.AnnonceTypeVente, models.AnnonceTypeAchat, models.AnnonceTypePref}
+
+	paramTypeAnnonce = openapi.Parameter{Name: "type", In: "path", Description: "type d'annonce", Schema: texte(typesAnnonce...)}
+	paramIfMatch     = openapi.Parameter{
+		Name: "If-Match", In: "header", Required: true,
+		Description: "ETag de l'annonce lu par le client (\"*\" accepte la version courante)",
+		Schema:      texte(),
+	}
+	paramIdempotence = openapi.Parameter{
+		Name: middleware.EnteteIdempotence, In: "header",
+		Description: "clé d'idempotence : une requête rejouée avec la même clé renvoie la réponse d'origine",
+		Schema:      texte(),
+	}
+	paramsPagination = []openapi.Parameter{
+		query("limit", "nombre maximal d'éléments", entier()),
+		query("offset", "éléments à sauter", entier()),
+	}
+)
+
+// Statuts d'erreur documentés par famille de routes
+var (
+	erreursLecture      = []int{http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests}
+	erreursCreation     = []int{http.StatusBadRequest, http.StatusConflict, http.StatusTooManyRequests}
+	erreursModification = []int{http.StatusBadRequest, http.StatusNotFound, http.StatusPreconditionFailed, http.StatusPreconditionRequired}
+	erreursRessource    = []int{http.StatusBadRequest, http.StatusNotFound}
+)
+
+// contratAnnonces regroupe les types de réponse des annonces d'une version de l'API
+type contratAnnonces struct {
+	vente, achat, pref                interface{}
+	listeVente, listeAchat, listePref interface{}
+	favoris                           interface{}
+}
+
+func contratVersion(version int) contratAnnonces {
+	if version == models.VersionAPI2 {
+		return contratAnnonces{
+			models.AnnonceVenteV2{}, models.AnnonceAchatV2{}, models.AnnoncePrefV2{},
+			[]models.AnnonceVenteV2{}, []models.AnnonceAchatV2{}, []models.AnnoncePrefV2{},
+			models.ListeFavorisV2{},
+		}
+	}
+	return contratAnnonces{
+		models.ListeAnnonceVente{}, models.ListeAnnonceAchat{}, models.LiteAnnoncePrefinancement{},
+		[]models.ListeAnnonceVente{}, []models.ListeAnnonceAchat{}, []models.LiteAnnoncePrefinancement{},
+		models.ListeFavoris{},
+	}
+}
+
+// documentAPI décrit toutes les routes d'une version de l'API (enregistrerRoutes). Un test
+// vérifie que chaque route enregistrée y figure : toute nouvelle route doit être décrite ici.
+func documentAPI(version int) *openapi.Document {
+	description := "Annonces de vente, d'achat et de préfinancement de produits agricoles. " +
+		"Les routes protégées exigent un token JWT (en-tête Authorization: Bearer). " +
+		"Les erreurs utilisent une enveloppe commune {\"error\": {code, message, details, request_id}}."
+	if version == models.VersionAPI1 {
+		description += " Contrat v1 : les routes sans préfixe /v1 en sont un alias déprécié."
+	} else {
+		description += " Contrat v2 : relations imbriquées (auteur, type_culture, parcelle, favoris) et champs homogènes entre les types d'annonces."
+	}
+	d := openapi.Nouveau("API Annonces de vente", strconv.Itoa(version)+".0.0", description, reponseErreur{})
+	d.AjouterServeur("/v"+strconv.Itoa(version), "")
+
+	d.AjouterTag("Annonces de vente", "")
+	d.AjouterTag("Annonces d'achat", "")
+	d.AjouterTag("Annonces de préfinancement", "")
+	d.AjouterTag("Annonces", "Import CSV et flux temps réel, communs aux trois types")
+	d.AjouterTag("Favoris", "")
+	d.AjouterTag("Recherches sauvegardées", "Alertes sur les nouvelles annonces correspondant à un filtre")
+	d.AjouterTag("Notifications", "")
+	d.AjouterTag("Webhooks", "Événements envoyés aux partenaires ; profil partenaire ou administrateur requis")
+	d.AjouterTag("Messagerie", "")
+	d.AjouterTag("Avis", "")
+	d.AjouterTag("Signalements", "")
+	d.AjouterTag("Administration", "Profil administrateur requis")
+	d.AjouterTag("Documentation", "")
+
+	contrat := contratVersion(version)
+	ajouterRoutesAnnonces(d, contrat)
+	ajouterRoutesUtilisateur(d, contrat)
+	ajouterRoutesMessagerie(d)
+	ajouterRoutesAdministration(d)
+
+	d.Ajouter(
+		openapi.Route{Methode: http.MethodGet, Chemin: cheminSpecification, Tag: "Documentation", Resume: "Spécification OpenAPI 3 de l'API", TypeReponse: openapi.JSON},
+		openapi.Route{Methode: http.MethodGet, Chemin: cheminExplorateur, Tag: "Documentation", Resume: "Explorateur interactif de l'API", TypeReponse: "text/html"},
+	)
+	return d
+}
+
+// ajouterRoutesAnnonces décrit les routes des trois types d'annonces, l'import et le flux
+func ajouterRoutesAnnonces(d *openapi.Document, contrat contratAnnonces) {
+	types := []struct {
+		chemin, tag string
+		corps       interface{}
+		reponse     interface{}
+		liste       interface{}
+		filtre      interface{}
+		params      []openapi.Parameter
+	}{
+		{"/annonces_vente", "Annonces de vente", controllers.CreateAnnonceVenteInput{}, contrat.vente, contrat.listeVente, models.FiltreAnnonce{}, nil},
+		{"/annonces_achat", "Annonces d'achat", controllers.CreateAnnonceAchatInput{}, contrat.achat, contrat.listeAchat, models.FiltreAnnonce{}, nil},
+		{"/annonces_pref", "Annonces de préfinancement", controllers.CreateAnnoncePrefInput{}, contrat.pref, contrat.listePref, nil, []openapi.Parameter{
+			query("user_id", "auteur", &openapi.Schema{Type: "string", Format: "uuid"}),
+			query("statut", "", texte(models.StatutsAnnonce(models.AnnonceTypePref)...)),
+			query("type_culture_id", "", &openapi.Schema{Type: "string", Format: "uuid"}),
+		}},
+	}
+
+	for _, t := range types {
+		d.Ajouter(
+			openapi.Route{
+				Methode: http.MethodGet, Chemin: t.chemin, Tag: t.tag, Auth: openapi.AuthOptionnelle,
+				Resume: "Lister les annonces", Query: t.filtre, Parametres: t.params,
+				Reponse: t.liste, Erreurs: []int{http.StatusBadRequest, http.StatusTooManyRequests},
+			},
+			openapi.Route{
+				Methode: http.MethodGet, Chemin: t.chemin + "/:id", Tag: t.tag, Auth: openapi.AuthOptionnelle,
+				Resume: "Lire une annonce", Description: "L'en-tête ETag porte la version de l'annonce.",
+				Reponse: t.reponse, Erreurs: erreursLecture,
+			},
+			openapi.Route{
+				Methode: http.MethodPost, Chemin: t.chemin, Tag: t.tag, Auth: openapi.AuthRequise,
+				Resume: "Créer une annonce", Corps: t.corps, Parametres: []openapi.Parameter{paramIdempotence},
+				Statut: http.StatusCreated, Reponse: t.reponse, Erreurs: erreursCreation,
+			},
+			openapi.Route{
+				Methode: http.MethodPut, Chemin: t.chemin + "/:id", Tag: t.tag, Auth: openapi.AuthRequise,
+				Resume: "Remplacer une annonce", Description: "Tous les champs obligatoires doivent être fournis.",
+				Corps: t.corps, Parametres: []openapi.Parameter{paramIfMatch},
+				Reponse: t.reponse, Erreurs: erreursModification,
+			},
+			openapi.Route{
+				Methode: http.MethodPatch, Chemin: t.chemin + "/:id", Tag: t.tag, Auth: openapi.AuthRequise,
+				Resume: "Modifier partiellement une annonce", Description: "JSON Merge Patch (RFC 7396) ; null vide un champ facultatif.",
+				Corps: t.corps, ContentType: openapi.MergePatch, Parametres: []openapi.Parameter{paramIfMatch},
+				Reponse: t.reponse, Erreurs: append(erreursModification, http.StatusUnsupportedMediaType),
+			},
+			openapi.Route{
+				Methode: http.MethodDelete, Chemin: t.chemin + "/:id", Tag: t.tag, Auth: openapi.AuthRequise,
+				Resume: "Supprimer une annonce", Parametres: []openapi.Parameter{paramIfMatch},
+				Reponse: reponseMessage{}, Erreurs: erreursModification,
+			},
+			openapi.Route{
+				Methode: http.MethodPost, Chemin: t.chemin + "/:id/signaler", Tag: "Signalements", Auth: openapi.AuthRequise,
+				Resume: "Signaler une annonce", Corps: controllers.SignalementInput{},
+				Statut: http.StatusCreated, Reponse: models.Signalement{},
+				Erreurs: append(erreursRessource, http.StatusConflict, http.StatusTooManyRequests),
+			},
+		)
+	}
+
+	// Favoris et conversations : annonces de vente et de préfinancement uniquement
+	for _, t := range []struct{ chemin, libelle string }{{"/annonces_vente", "de vente"}, {"/annonces_pref", "de préfinancement"}} {
+		d.Ajouter(
+			openapi.Route{
+				Methode: http.MethodPost, Chemin: t.chemin + "/:id/favori", Tag: "Favoris", Auth: openapi.AuthRequise,
+				Resume: "Ajouter une annonce " + t.libelle + " aux favoris", Description: "200 si l'annonce était déjà en favori.",
+				Statut: http.StatusCreated, Reponse: models.Favori{}, Erreurs: erreursRessource,
+			},
+			openapi.Route{
+				Methode: http.MethodDelete, Chemin: t.chemin + "/:id/favori", Tag: "Favoris", Auth: openapi.AuthRequise,
+				Resume: "Retirer une annonce " + t.libelle + " des favoris", Reponse: reponseMessage{}, Erreurs: erreursRessource,
+			},
+			openapi.Route{
+				Methode: http.MethodPost, Chemin: t.chemin + "/:id/conversations", Tag: "Messagerie", Auth: openapi.AuthRequise,
+				Resume: "Ouvrir une conversation sur une annonce " + t.libelle, Description: "200 si la conversation existe déjà.",
+				Statut: http.StatusCreated, Reponse: models.Conversation{}, Erreurs: erreursRessource,
+			},
+		)
+	}
+
+	d.Ajouter(
+		openapi.Route{
+			Methode: http.MethodPost, Chemin: "/annonces/import", Tag: "Annonces", Auth: openapi.AuthRequise,
+			Resume: "Importer des annonces depuis un fichier CSV",
+			Description: "Fichier en multipart (champ fichier) ou corps text/csv. En dry_run rien n'est inséré ; " +
+				"en commit, 201 si tout est inséré, 422 avec le rapport sinon.",
+			ContentType: openapi.CSV, Corps: "",
+			Parametres: []openapi.Parameter{
+				query("type", "type des annonces importées", texte(typesAnnonce...)),
+				query("mode", "dry_run par défaut", texte("dry_run", "commit")),
+			},
+			Reponse: models.RapportImport{}, Erreurs: []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusTooManyRequests},
+		},
+		openapi.Route{
+			Methode: http.MethodGet, Chemin: "/annonces/stream", Tag: "Annonces", Auth: openapi.AuthOptionnelle,
+			Resume: "Flux temps réel des annonces (Server-Sent Events)",
+			Parametres: []openapi.Parameter{
+				query("type", "types d'annonces, séparés par des virgules", texte()),
+				query("type_culture_id", "", &openapi.Schema{Type: "string", Format: "uuid"}),
+				query("region", "recherchée dans l'adresse de la parcelle", texte()),
+				query("last_event_id", "reprise après ce numéro d'événement (ou en-tête Last-Event-ID)", entier()),
+				{Name: "Last-Event-ID", In: "header", Description: "dernier numéro d'événement reçu", Schema: entier()},
+			},
+			TypeReponse: openapi.SSE, Erreurs: []int{http.StatusBadRequest, http.StatusTooManyRequests},
+		},
+	)
+}
+
+// ajouterRoutesUtilisateur décrit les favoris, recherches, notifications, webhooks et avis
+func ajouterRoutesUtilisateur(d *openapi.Document, contrat contratAnnonces) {
+	d.Ajouter(
+		openapi.Route{Methode: http.MethodGet, Chemin: "/favoris", Tag: "Favoris", Auth: openapi.AuthRequise, Resume: "Lister mes favoris", Reponse: contrat.favoris},
+
+		openapi.Route{Methode: http.MethodGet, Chemin: "/recherches", Tag: "Recherches sauvegardées", Auth: openapi.AuthRequise, Resume: "Lister mes recherches", Reponse: []models.RechercheSauvegardee{}},
+		openapi.Route{Methode: http.MethodPost, Chemin: "/recherches", Tag: "Recherches sauvegardées", Auth: openapi.AuthRequise, Resume: "Créer une recherche", Corps: controllers.RechercheInput{}, Statut: http.StatusCreated, Reponse: models.RechercheSauvegardee{}, Erreurs: []int{http.StatusBadRequest}},
+		openapi.Route{Methode: http.MethodGet, Chemin: "/recherches/:id", Tag: "Recherches sauvegardées", Auth: openapi.AuthRequise, Resume: "Lire une recherche", Reponse: models.RechercheSauvegardee{}, Erreurs: erreursRessource},
+		openapi.Route{Methode: http.MethodPut, Chemin: "/recherches/:id", Tag: "Recherches sauvegardées", Auth: openapi.AuthRequise, Resume: "Modifier une recherche", Corps: controllers.RechercheInput{}, Reponse: models.RechercheSauvegardee{}, Erreurs: erreursRessource},
+		openapi.Route{Methode: http.MethodDelete, Chemin: "/recherches/:id", Tag: "Recherches sauvegardées", Auth: openapi.AuthRequise, Resume: "Supprimer une recherche", Reponse: reponseMessage{}, Erreurs: erreursRessource},
+
+		openapi.Route{
+			Methode: http.MethodGet, Chemin: "/notifications", Tag: "Notifications", Auth: openapi.AuthRequise, Resume: "Lister mes notifications",
+			Parametres: append([]openapi.Parameter{
+				query("lu", "", &openapi.Schema{Type: "boolean"}),
+				query("type", "", texte(models.TypesNotification...)),
+			}, paramsPagination...),
+			Reponse: models.ListeNotifications{},
+		},
+		openapi.Route{Methode: http.MethodPut, Chemin: "/notifications/lu", Tag: "Notifications", Auth: openapi.AuthRequise, Resume: "Marquer toutes mes notifications comme lues", Reponse: reponseMessageTotal{}},
+		openapi.Route{Methode: http.MethodPut, Chemin: "/notifications/:id/lu", Tag: "Notifications", Auth: openapi.AuthRequise, Resume: "Marquer une notification comme lue", Reponse: models.Notification{}, Erreurs: erreursRessource},
+		openapi.Route{Methode: http.MethodGet, Chemin: "/notifications/preferences", Tag: "Notifications", Auth: openapi.AuthRequise, Resume: "Lire mes préférences de notification", Reponse: []models.PreferenceNotification{}},
+		openapi.Route{Methode: http.MethodPut, Chemin: "/notifications/preferences", Tag: "Notifications", Auth: openapi.AuthRequise, Resume: "Modifier mes préférences (seules les lignes envoyées)", Corps: []controllers.PreferenceNotificationInput{}, Reponse: []models.PreferenceNotification{}, Erreurs: []int{http.StatusBadRequest}},
+
+		openapi.Route{Methode: http.MethodGet, Chemin: "/webhooks", Tag: "Webhooks", Auth: openapi.AuthRequise, Resume: "Lister mes webhooks", Reponse: []models.WebhookEndpoint{}},
+		openapi.Route{Methode: http.MethodPost, Chemin: "/webhooks", Tag: "Webhooks", Auth: openapi.AuthRequise, Resume: "Créer un webhook", Corps: controllers.WebhookInput{}, Statut: http.StatusCreated, Reponse: reponseWebhookCree{}, Erreurs: []int{http.StatusBadRequest}},
+		openapi.Route{Methode: http.MethodGet, Chemin: "/webhooks/:id", Tag: "Webhooks", Auth: openapi.AuthRequise, Resume: "Lire un webhook", Reponse: models.WebhookEndpoint{}, Erreurs: erreursRessource},
+		openapi.Route{Methode: http.MethodPut, Chemin: "/webhooks/:id", Tag: "Webhooks", Auth: openapi.AuthRequise, Resume: "Modifier un webhook", Corps: controllers.WebhookInput{}, Reponse: models.WebhookEndpoint{}, Erreurs: erreursRessource},
+		openapi.Route{Methode: http.MethodDelete, Chemin: "/webhooks/:id", Tag: "Webhooks", Auth: openapi.AuthRequise, Resume: "Supprimer un webhook", Reponse: reponseMessage{}, Erreurs: erreursRessource},
+		openapi.Route{
+			Methode: http.MethodGet, Chemin: "/webhooks/:id/livraisons", Tag: "Webhooks", Auth: openapi.AuthRequise, Resume: "Lister les livraisons d'un webhook (100 dernières)",
+			Parametres: []openapi.Parameter{query("statut", "", texte(models.LivraisonEnAttente, models.LivraisonLivree, models.LivraisonAbandonne))},
+			Reponse:    []models.WebhookLivraison{}, Erreurs: erreursRessource,
+		},
+		openapi.Route{Methode: http.MethodPost, Chemin: "/webhooks/:id/livraisons/:livraison_id/rejouer", Tag: "Webhooks", Auth: openapi.AuthRequise, Resume: "Rejouer une livraison", Statut: http.StatusAccepted, Reponse: models.WebhookLivraison{}, Erreurs: erreursRessource},
+
+		openapi.Route{
+			Methode: http.MethodGet, Chemin: "/utilisateurs/:id/avis", Tag: "Avis", Auth: openapi.AuthOptionnelle, Resume: "Avis reçus par un utilisateur",
+			Parametres: []openapi.Parameter{query("role", "rôle de l'utilisateur évalué", texte(models.RoleProducteur, models.RoleAcheteur, models.RoleFinanceur))},
+			Reponse:    models.ListeAvis{}, Erreurs: []int{http.StatusBadRequest, http.StatusTooManyRequests},
+		},
+		openapi.Route{Methode: http.MethodPost, Chemin: "/conversations/:id/conclure", Tag: "Avis", Auth: openapi.AuthRequise, Resume: "Déclarer la transaction conclue (producteur), puis la confirmer (interlocuteur)", Reponse: models.Conversation{}, Erreurs: erreursRessource},
+		openapi.Route{Methode: http.MethodPost, Chemin: "/conversations/:id/avis", Tag: "Avis", Auth: openapi.AuthRequise, Resume: "Laisser un avis", Corps: controllers.AvisInput{}, Statut: http.StatusCreated, Reponse: models.Avis{}, Erreurs: append(erreursRessource, http.StatusConflict)},
+		openapi.Route{Methode: http.MethodPut, Chemin: "/avis/:id", Tag: "Avis", Auth: openapi.AuthRequise, Resume: "Modifier mon avis", Corps: controllers.AvisInput{}, Reponse: models.Avis{}, Erreurs: append(erreursRessource, http.StatusConflict)},
+		openapi.Route{Methode: http.MethodPost, Chemin: "/avis/:id/reponse", Tag: "Avis", Auth: openapi.AuthRequise, Resume: "Répondre à un avis reçu", Corps: controllers.ReponseAvisInput{}, Reponse: models.Avis{}, Erreurs: erreursRessource},
+	)
+}
+
+// ajouterRoutesMessagerie décrit les conversations et la WebSocket
+func ajouterRoutesMessagerie(d *openapi.Document) {
+	d.Ajouter(
+		openapi.Route{Methode: http.MethodGet, Chemin: "/conversations", Tag: "Messagerie", Auth: openapi.AuthRequise, Resume: "Lister mes conversations", Reponse: []models.ResumeConversation{}},
+		openapi.Route{
+			Methode: http.MethodGet, Chemin: "/conversations/ws", Tag: "Messagerie", Auth: openapi.AuthRequise,
+			Resume:      "Canal WebSocket de la messagerie",
+			Description: "Reçoit les messages et accusés de lecture en temps réel (EvenementMessagerie) et accepte les commandes message et lu.",
+			Statut:      http.StatusSwitchingProtocols,
+		},
+		openapi.Route{
+			Methode: http.MethodGet, Chemin: "/conversations/:id/messages", Tag: "Messagerie", Auth: openapi.AuthRequise, Resume: "Lister les messages (plus récents d'abord)",
+			Parametres: []openapi.Parameter{
+				query("limit", "", entier()),
+				query("avant", "messages antérieurs à cette date (RFC 3339)", &openapi.Schema{Type: "string", Format: "date-time"}),
+			},
+			Reponse: []models.Message{}, Erreurs: erreursRessource,
+		},
+		openapi.Route{
+			Methode: http.MethodPost, Chemin: "/conversations/:id/messages", Tag: "Messagerie", Auth: openapi.AuthRequise, Resume: "Envoyer un message",
+			Description: "Corps JSON {\"contenu\"} ou multipart (champs contenu et piece_jointe : jpg, png ou pdf, 10 Mo maximum).",
+			Corps:       messageTexte{}, Statut: http.StatusCreated, Reponse: models.Message{},
+			Erreurs: append(erreursRessource, http.StatusTooManyRequests),
+		},
+		openapi.Route{Methode: http.MethodPut, Chemin: "/conversations/:id/lu", Tag: "Messagerie", Auth: openapi.AuthRequise, Resume: "Marquer une conversation comme lue", Reponse: reponseMessageTotal{}, Erreurs: erreursRessource},
+		openapi.Route{Methode: http.MethodGet, Chemin: "/conversations/:id/messages/:message_id/piece_jointe", Tag: "Messagerie", Auth: openapi.AuthRequise, Resume: "Télécharger une pièce jointe", TypeReponse: "application/octet-stream", Erreurs: erreursRessource},
+	)
+}
+
+// ajouterRoutesAdministration décrit les routes /admin
+func ajouterRoutesAdministration(d *openapi.Document) {
+	const tag = "Administration"
+	motif := query("motif", "motif de l'action, tracé dans le journal d'audit", texte())
+	d.Ajouter(
+		openapi.Route{
+			Methode: http.MethodGet, Chemin: "/admin/moderation", Tag: tag, Auth: openapi.AuthRequise, Resume: "File de modération",
+			Parametres: []openapi.Parameter{query("statut", "état de modération", texte()), query("annonce_type", "", texte(typesAnnonce...))},
+			Reponse:    []models.ElementModeration{},
+		},
+		openapi.Route{Methode: http.MethodGet, Chemin: "/admin/audit", Tag: tag, Auth: openapi.AuthRequise, Resume: "Journal d'audit (200 dernières entrées)",
+			Parametres: []openapi.Parameter{
+				query("action", "", texte()),
+				query("acteur_id", "", &openapi.Schema{Type: "string", Format: "uuid"}),
+				query("cible_id", "", &openapi.Schema{Type: "string", Format: "uuid"}),
+			},
+			Reponse: []models.JournalAudit{},
+		},
+
+		openapi.Route{
+			Methode: http.MethodGet, Chemin: "/admin/annonces/:type", Tag: tag, Auth: openapi.AuthRequise, Resume: "Toutes les annonces, masquées comprises",
+			Query: models.FiltreAnnonce{},
+			Parametres: append([]openapi.Parameter{
+				paramTypeAnnonce,
+				query("q", "recherche dans la description", texte()),
+				query("moderation", "état de modération", texte()),
+			}, paramsPagination...),
+			Reponse: reponseAdminAnnonces{}, Erreurs: []int{http.StatusBadRequest},
+		},
+		openapi.Route{Methode: http.MethodPut, Chemin: "/admin/annonces/:type/:id", Tag: tag, Auth: openapi.AuthRequise, Resume: "Modifier d'office une annonce (champs fournis uniquement)", Description: "Réponse : l'annonce au format de la liste publique de son type.", Parametres: []openapi.Parameter{paramTypeAnnonce}, Corps: controllers.AdminAnnonceInput{}, Reponse: map[string]interface{}{}, Erreurs: erreursRessource},
+		openapi.Route{Methode: http.MethodDelete, Chemin: "/admin/annonces/:type/:id", Tag: tag, Auth: openapi.AuthRequise, Resume: "Supprimer d'office une annonce", Parametres: []openapi.Parameter{paramTypeAnnonce, motif}, Reponse: reponseMessage{}, Erreurs: erreursRessource},
+
+		openapi.Route{Methode: http.MethodGet, Chemin: "/admin/types_culture", Tag: tag, Auth: openapi.AuthRequise, Resume: "Lister les types de culture", Reponse: []models.TypeCulture{}},
+		openapi.Route{Methode: http.MethodPost, Chemin: "/admin/types_culture", Tag: tag, Auth: openapi.AuthRequise, Resume: "Créer un type de culture", Corps: controllers.TypeCultureInput{}, Statut: http.StatusCreated, Reponse: models.TypeCulture{}, Erreurs: []int{http.StatusBadRequest, http.StatusConflict}},
+		openapi.Route{Methode: http.MethodPut, Chemin: "/admin/types_culture/:id", Tag: tag, Auth: openapi.AuthRequise, Resume: "Renommer un type de culture", Corps: controllers.TypeCultureInput{}, Reponse: models.TypeCulture{}, Erreurs: append(erreursRessource, http.StatusConflict)},
+		openapi.Route{Methode: http.MethodDelete, Chemin: "/admin/types_culture/:id", Tag: tag, Auth: openapi.AuthRequise, Resume: "Supprimer un type de culture inutilisé", Reponse: reponseMessage{}, Erreurs: append(erreursRessource, http.StatusConflict)},
+
+		openapi.Route{
+			Methode: http.MethodGet, Chemin: "/admin/utilisateurs", Tag: tag, Auth: openapi.AuthRequise, Resume: "Lister les utilisateurs",
+			Parametres: append([]openapi.Parameter{
+				query("q", "recherche dans le nom", texte()),
+				query("suspendu", "true : utilisateurs suspendus uniquement", &openapi.Schema{Type: "boolean"}),
+			}, paramsPagination...),
+			Reponse: reponseAdminUtilisateurs{},
+		},
+		openapi.Route{Methode: http.MethodGet, Chemin: "/admin/utilisateurs/:id", Tag: tag, Auth: openapi.AuthRequise, Resume: "Activité d'un utilisateur", Reponse: models.ActiviteUtilisateur{}, Erreurs: erreursRessource},
+		openapi.Route{Methode: http.MethodPost, Chemin: "/admin/utilisateurs/:id/suspension", Tag: tag, Auth: openapi.AuthRequise, Resume: "Suspendre un utilisateur", Corps: controllers.SuspensionInput{}, Reponse: models.Suspension{}, Erreurs: erreursRessource},
+		openapi.Route{Methode: http.MethodDelete, Chemin: "/admin/utilisateurs/:id/suspension", Tag: tag, Auth: openapi.AuthRequise, Resume: "Lever une suspension", Parametres: []openapi.Parameter{motif}, Reponse: reponseMessage{}, Erreurs: erreursRessource},
+
+		openapi.Route{Methode: http.MethodGet, Chemin: "/admin/statistiques", Tag: tag, Auth: openapi.AuthRequise, Resume: "Compteurs de la plateforme", Reponse: models.StatistiquesPlateforme{}},
+	)
+
+	for _, decision := range []struct{ action, resume string }{
+		{"approuver", "Approuver une annonce signalée"},
+		{"masquer", "Masquer une annonce"},
+		{"bannir", "Masquer l'annonce et suspendre son auteur (duree_jours vide : définitif)"},
+	} {
+		d.Ajouter(openapi.Route{
+			Methode: http.MethodPost, Chemin: "/admin/moderation/:type/:id/" + decision.action, Tag: tag, Auth: openapi.AuthRequise,
+			Resume: decision.resume, Description: "Corps facultatif.", Parametres: []openapi.Parameter{paramTypeAnnonce},
+			Corps: controllers.DecisionModerationInput{}, Reponse: models.ModerationAnnonce{}, Erreurs: erreursRessource,
+		})
+	}
+}
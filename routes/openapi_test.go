@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Steph-business/annonce_de_vente/controllers"
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/openapi"
+)
+
+// montages associe chaque préfixe de SetupRoutes à la version de l'API qu'il sert
+var montages = []struct {
+	prefixe string
+	version int
+}{
+	{"/v1", models.VersionAPI1},
+	{"/v2", models.VersionAPI2},
+	{"", models.VersionAPI1},
+}
+
+// Chaque route enregistrée par SetupRoutes doit être décrite dans la spécification OpenAPI
+// de sa version, et chaque route décrite doit être enregistrée sous chaque préfixe
+func TestOpenAPICouvreToutesLesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := SetupRoutes(controllers.Dependances{Depots: depots.NouvelleMemoire().Depots()})
+
+	enregistrees := map[string]bool{}
+	for _, route := range r.Routes() {
+		enregistrees[route.Method+" "+openapi.CheminOpenAPI(route.Path)] = true
+	}
+
+	for _, m := range montages {
+		doc := documentAPI(m.version)
+		for _, route := range r.Routes() {
+			chemin, ok := strings.CutPrefix(route.Path, m.prefixe+"/")
+			// Les sondes de l'orchestrateur et les métriques ne font pas partie de l'API
+			if !ok || m.prefixe == "" && (strings.HasPrefix(chemin, "v1/") || strings.HasPrefix(chemin, "v2/")) ||
+				route.Path == cheminVivacite || route.Path == cheminDisponibilite || route.Path == cheminMetriques {
+				continue
+			}
+			if !doc.Contient(route.Method, "/"+chemin) {
+				t.Errorf("route absente de la spécification OpenAPI v%d : %s %s", m.version, route.Method, route.Path)
+			}
+		}
+		for _, route := range doc.Routes() {
+			methode, chemin, _ := strings.Cut(route, " ")
+			if !enregistrees[methode+" "+m.prefixe+chemin] {
+				t.Errorf("route décrite dans la spécification mais non enregistrée : %s %s%s", methode, m.prefixe, chemin)
+			}
+		}
+	}
+}
+
+// Les références $ref des spécifications servies doivent toutes désigner un schéma déclaré
+func TestOpenAPIReferencesResolues(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := SetupRoutes(controllers.Dependances{Depots: depots.NouvelleMemoire().Depots()})
+
+	for _, m := range montages {
+		specification := m.prefixe + cheminSpecification
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, specification, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s : statut %d", specification, w.Code)
+		}
+
+		var spec struct {
+			OpenAPI string `json:"openapi"`
+			Servers []struct {
+				URL string `json:"url"`
+			} `json:"servers"`
+			Components struct {
+				Schemas map[string]json.RawMessage `json:"schemas"`
+			} `json:"components"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+			t.Fatalf("spécification illisible : %v", err)
+		}
+		if spec.OpenAPI != openapi.VersionOpenAPI {
+			t.Errorf("%s : version openapi = %q", specification, spec.OpenAPI)
+		}
+		if len(spec.Servers) != 1 || spec.Servers[0].URL != fmt.Sprintf("/v%d", m.version) {
+			t.Errorf("%s : servers = %+v", specification, spec.Servers)
+		}
+
+		const prefixe = `"$ref":"#/components/schemas/`
+		corps := w.Body.String()
+		for i := strings.Index(corps, prefixe); i >= 0; i = strings.Index(corps, prefixe) {
+			corps = corps[i+len(prefixe):]
+			nom := corps[:strings.Index(corps, `"`)]
+			if _, ok := spec.Components.Schemas[nom]; !ok {
+				t.Errorf("%s : schéma référencé mais non déclaré : %s", specification, nom)
+			}
+		}
+
+		explorateur := m.prefixe + cheminExplorateur
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, explorateur, nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"`+specification+`"`) {
+			t.Errorf("GET %s : statut %d", explorateur, w.Code)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Steph-business/annonce_de_vente/config"
+	"github.com/Steph-business/annonce_de_vente/middleware"
+)
+
+// Budgets par défaut du limiteur de débit, modifiables par les paramètres
+// rate_limit_<nom> et rate_limit_<nom>_ip (voir middleware.ChargerBudget)
+var (
+	budgetLecture = middleware.BudgetRoute{
+		Nom:         "lecture",
+		Anonyme:     config.RegleLimite{Capacite: 60, Periode: time.Minute},
+		Authentifie: config.RegleLimite{Capacite: 300, Periode: time.Minute},
+	}
+	budgetAPI = middleware.BudgetRoute{
+		Nom:         "api",
+		Authentifie: config.RegleLimite{Capacite: 600, Periode: time.Minute},
+	}
+	budgetCreation = middleware.BudgetRoute{
+		Nom:         "creation",
+		Authentifie: config.RegleLimite{Capacite: 10, Periode: time.Minute},
+	}
+	budgetImport = middleware.BudgetRoute{
+		Nom:         "import",
+		Authentifie: config.RegleLimite{Capacite: 5, Periode: time.Hour},
+	}
+	budgetMessages = middleware.BudgetRoute{
+		Nom:         "messages",
+		Authentifie: config.RegleLimite{Capacite: 60, Periode: time.Minute},
+	}
+	budgetSignalements = middleware.BudgetRoute{
+		Nom:         "signalements",
+		Authentifie: config.RegleLimite{Capacite: 20, Periode: time.Hour},
+	}
+)
+
+// stockageLimites choisit le stockage des seaux : en mémoire par défaut, en base
+// (partagé entre instances) avec rate_limit_stockage=base si une base est disponible
+func stockageLimites(db *gorm.DB, stockage string) middleware.StockageLimites {
+	if stockage == config.StockageBase && db != nil {
+		return middleware.NewStockageLimitesSQL(db)
+	}
+	return middleware.NewStockageLimitesMemoire()
+}
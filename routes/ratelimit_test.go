@@ -0,0 +1,84 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Steph-business/annonce_de_vente/config"
+	"github.com/Steph-business/annonce_de_vente/controllers"
+	"github.com/Steph-business/annonce_de_vente/depots"
+	"github.com/Steph-business/annonce_de_vente/middleware"
+)
+
+// lireAnnonces envoie des requêtes anonymes depuis la même connexion, chacune avec un
+// X-Forwarded-For différent, et renvoie les statuts obtenus
+func lireAnnonces(r *gin.Engine, n int) []int {
+	statuts := make([]int, n)
+	for i := range statuts {
+		req := httptest.NewRequest(http.MethodGet, "/v1/annonces_vente", nil)
+		req.RemoteAddr = "203.0.113.7:4000"
+		req.Header.Set("X-Forwarded-For", "198.51.100."+strconv.Itoa(i+1))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		statuts[i] = w.Code
+	}
+	return statuts
+}
+
+func routeurLimite(proxies []string) *gin.Engine {
+	cfg := config.Defaut()
+	cfg.Serveur.ProxiesDeConfiance = proxies
+	cfg.Limites.Regles["lecture_ip"] = config.RegleLimite{Capacite: 2, Periode: time.Minute}
+	return SetupRoutes(controllers.Dependances{Depots: depots.NouvelleMemoire().Depots(), Config: &cfg})
+}
+
+// Un X-Forwarded-For forgé ne contourne pas la limite : sans proxy de confiance, la clé
+// est l'adresse de la connexion
+func TestLimiteXForwardedForForge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	statuts := lireAnnonces(routeurLimite(nil), 3)
+	if statuts[0] != http.StatusOK || statuts[1] != http.StatusOK || statuts[2] != http.StatusTooManyRequests {
+		t.Errorf("sans proxy de confiance : statuts %v, attendu [200 200 429]", statuts)
+	}
+
+	// Derrière un proxy de confiance, chaque client a son propre budget
+	for _, statut := range lireAnnonces(routeurLimite([]string{"203.0.113.0/24"}), 3) {
+		if statut != http.StatusOK {
+			t.Errorf("derrière un proxy de confiance : statut %d, attendu 200", statut)
+		}
+	}
+}
+
+// Les routes d'administration consomment le budget "api" de l'utilisateur, comme les
+// autres routes authentifiées
+func TestLimiteAdministration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Defaut()
+	cfg.Auth.SecretJWT = "secret-test"
+	cfg.Auth.AdminProfilIDs = []int{1}
+	cfg.Limites.Regles["api"] = config.RegleLimite{Capacite: 2, Periode: time.Minute}
+	r := SetupRoutes(controllers.Dependances{Depots: depots.NouvelleMemoire().Depots(), Config: &cfg})
+
+	jeton, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{UserID: 7, ProfilID: 1}).SignedString([]byte(cfg.Auth.SecretJWT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuts := make([]int, 3)
+	for i := range statuts {
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/types_culture", nil)
+		req.Header.Set("Authorization", "Bearer "+jeton)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		statuts[i] = w.Code
+	}
+	if statuts[0] == http.StatusTooManyRequests || statuts[1] == http.StatusTooManyRequests || statuts[2] != http.StatusTooManyRequests {
+		t.Errorf("statuts %v, attendu 429 à la troisième requête", statuts)
+	}
+}
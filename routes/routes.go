@@ -1,43 +1,229 @@
 package routes
 
 import (
+	"log"
+	"path"
+
 	"github.com/gin-gonic/gin"
+
+	"github.com/Steph-business/annonce_de_vente/config"
 	"github.com/Steph-business/annonce_de_vente/controllers"
+	"github.com/Steph-business/annonce_de_vente/erreurs"
+	"github.com/Steph-business/annonce_de_vente/metriques"
 	"github.com/Steph-business/annonce_de_vente/middleware"
+	"github.com/Steph-business/annonce_de_vente/models"
+	"github.com/Steph-business/annonce_de_vente/openapi"
 )
 
-func SetupRoutes() *gin.Engine {
-	r := gin.Default()
-	
-	// Routes publiques (lecture)
-	r.GET("/annonces_vente", controllers.GetAllAnnonceVente)
-	r.GET("/annonces_vente/:id", controllers.GetAnnonceByID)
-	
-	r.GET("/annonces_achat", controllers.GetAllAnnonceAchat)
-	r.GET("/annonces_achat/:id", controllers.GetAnnonceAchatByID)
-	
-	r.GET("/annonces_pref", controllers.GetAllAnnoncePref)
-	r.GET("/annonces_pref/:id", controllers.GetAnnoncePrefByID)
+// SetupRoutes construit le routeur ; les handlers reçoivent les dépendances construites
+// dans main.go (dépôts GORM) ou par les tests (dépôts en mémoire, sans base)
+func SetupRoutes(d controllers.Dependances) *gin.Engine {
+	r := gin.New()
+	r.Use(middleware.RequestIDMiddleware(), middleware.LoggerMiddleware(), middleware.MetriquesMiddleware(), middleware.RecoveryMiddleware(), controllers.Injecter(d))
+	r.NoRoute(func(c *gin.Context) {
+		erreurs.Repondre(c, erreurs.RouteIntrouvable)
+	})
+
+	cfg := config.Defaut()
+	if d.Config != nil {
+		cfg = *d.Config
+	}
+	// Sans proxy de confiance, X-Forwarded-For est ignoré : l'adresse du client (clé du
+	// limiteur de débit) est celle de la connexion
+	if err := r.SetTrustedProxies(cfg.Serveur.ProxiesDeConfiance); err != nil {
+		log.Fatal("Proxies de confiance invalides :", err)
+	}
+
+	// Sondes de l'orchestrateur et métriques Prometheus, hors versions de l'API et sans
+	// limite de débit
+	r.GET(cheminVivacite, controllers.Vivacite)
+	r.GET(cheminDisponibilite, controllers.Disponibilite)
+	r.GET(cheminMetriques, middleware.JetonMiddleware(cfg.Metriques.Jeton), metriques.Handler(metriques.Defaut))
+
+	stockage := stockageLimites(d.DB, cfg.Limites.Stockage)
+	m := intermediaires{
+		limite: func(b middleware.BudgetRoute) gin.HandlerFunc {
+			return middleware.RateLimitMiddleware(stockage, middleware.ChargerBudget(b, cfg.Limites.Regles))
+		},
+		auth:            middleware.AuthMiddleware(cfg.Auth.SecretJWT),
+		authOptionnelle: middleware.OptionalAuthMiddleware(cfg.Auth.SecretJWT),
+		admin:           middleware.AdminMiddleware(cfg.Auth.AdminProfilIDs),
+		partenaire:      middleware.PartenaireMiddleware(append(append([]int{}, cfg.Auth.PartenaireProfilIDs...), cfg.Auth.AdminProfilIDs...)),
+		suspension:      middleware.SuspensionMiddleware(d.Depots.Utilisateurs),
+		// Les clés d'idempotence sont conservées en base : sans base, l'en-tête est ignoré
+		idempotence: func(c *gin.Context) { c.Next() },
+	}
+	if d.DB != nil {
+		m.idempotence = middleware.IdempotencyMiddleware(d.DB, cfg.Idempotence.Duree)
+	}
+
+	// Versions de l'API : mêmes handlers, contrat de réponse propre à chaque version
+	v1 := r.Group("/v1", middleware.VersionAPIMiddleware(models.VersionAPI1))
+	enregistrerRoutes(v1, models.VersionAPI1, m)
+	v2 := r.Group("/v2", middleware.VersionAPIMiddleware(models.VersionAPI2))
+	enregistrerRoutes(v2, models.VersionAPI2, m)
+
+	// Routes sans préfixe : alias déprécié de /v1, conservé pour les clients existants
+	alias := r.Group("/", middleware.VersionAPIMiddleware(models.VersionAPI1), middleware.DepreciationMiddleware("/v1"))
+	enregistrerRoutes(alias, models.VersionAPI1, m)
+
+	return r
+}
+
+// Adresses des sondes de vivacité et de disponibilité, et des métriques
+const (
+	cheminVivacite      = "/healthz"
+	cheminDisponibilite = "/readyz"
+	cheminMetriques     = "/metrics"
+)
+
+// intermediaires regroupe les middlewares partagés par les groupes de routes, construits
+// une fois à partir de la configuration
+type intermediaires struct {
+	limite          func(middleware.BudgetRoute) gin.HandlerFunc
+	auth            gin.HandlerFunc
+	authOptionnelle gin.HandlerFunc
+	admin           gin.HandlerFunc
+	partenaire      gin.HandlerFunc
+	suspension      gin.HandlerFunc
+	idempotence     gin.HandlerFunc
+}
+
+// enregistrerRoutes enregistre toutes les routes de l'API dans le groupe d'une version
+func enregistrerRoutes(g *gin.RouterGroup, version int, m intermediaires) {
+	limite, suspension, idempotence := m.limite, m.suspension, m.idempotence
+
+	// Documentation de l'API : spécification OpenAPI et explorateur interactif
+	g.GET(cheminSpecification, openapi.Handler(documentAPI(version)))
+	g.GET(cheminExplorateur, openapi.Explorateur(path.Join(g.BasePath(), cheminSpecification)))
+
+	// Routes publiques (lecture) : l'utilisateur est identifié si un token est fourni
+	public := g.Group("/")
+	public.Use(m.authOptionnelle, limite(budgetLecture))
+	{
+		public.GET("/annonces_vente", controllers.GetAllAnnonceVente)
+		public.GET("/annonces_vente/:id", controllers.GetAnnonceByID)
+
+		public.GET("/annonces_achat", controllers.GetAllAnnonceAchat)
+		public.GET("/annonces_achat/:id", controllers.GetAnnonceAchatByID)
+
+		public.GET("/annonces_pref", controllers.GetAllAnnoncePref)
+		public.GET("/annonces_pref/:id", controllers.GetAnnoncePrefByID)
+
+		// Avis reçus par un utilisateur
+		public.GET("/utilisateurs/:id/avis", controllers.GetAvisUtilisateur)
+
+		// Flux temps réel (Server-Sent Events)
+		public.GET("/annonces/stream", controllers.StreamAnnonces)
+	}
 
 	// Routes protégées (nécessitent authentification)
-	protected := r.Group("/")
-	protected.Use(middleware.AuthMiddleware())
+	protected := g.Group("/")
+	protected.Use(m.auth, suspension, limite(budgetAPI))
 	{
 		// Annonces de vente
-		protected.POST("/annonces_vente", controllers.CreateAnnonceVente)
+		protected.POST("/annonces_vente", limite(budgetCreation), idempotence, controllers.CreateAnnonceVente)
 		protected.PUT("/annonces_vente/:id", controllers.UpdateAnnonceVente)
+		protected.PATCH("/annonces_vente/:id", controllers.PatchAnnonceVente)
 		protected.DELETE("/annonces_vente/:id", controllers.DeleteAnnonceVente)
 
 		// Annonces d'achat
-		protected.POST("/annonces_achat", controllers.CreateAnnonceAchat)
+		protected.POST("/annonces_achat", limite(budgetCreation), idempotence, controllers.CreateAnnonceAchat)
 		protected.PUT("/annonces_achat/:id", controllers.UpdateAnnonceAchat)
+		protected.PATCH("/annonces_achat/:id", controllers.PatchAnnonceAchat)
 		protected.DELETE("/annonces_achat/:id", controllers.DeleteAnnonceAchat)
 
 		// Annonces de préfinancement
-		protected.POST("/annonces_pref", controllers.CreateAnnoncePref)
+		protected.POST("/annonces_pref", limite(budgetCreation), idempotence, controllers.CreateAnnoncePref)
 		protected.PUT("/annonces_pref/:id", controllers.UpdateAnnoncePref)
+		protected.PATCH("/annonces_pref/:id", controllers.PatchAnnoncePref)
 		protected.DELETE("/annonces_pref/:id", controllers.DeleteAnnoncePref)
+
+		// Favoris
+		protected.GET("/favoris", controllers.GetMesFavoris)
+		protected.POST("/annonces_vente/:id/favori", controllers.AddFavoriAnnonceVente)
+		protected.DELETE("/annonces_vente/:id/favori", controllers.RemoveFavoriAnnonceVente)
+		protected.POST("/annonces_pref/:id/favori", controllers.AddFavoriAnnoncePref)
+		protected.DELETE("/annonces_pref/:id/favori", controllers.RemoveFavoriAnnoncePref)
+
+		// Recherches sauvegardées (alertes)
+		protected.GET("/recherches", controllers.GetMesRecherches)
+		protected.POST("/recherches", controllers.CreateRecherche)
+		protected.GET("/recherches/:id", controllers.GetRechercheByID)
+		protected.PUT("/recherches/:id", controllers.UpdateRecherche)
+		protected.DELETE("/recherches/:id", controllers.DeleteRecherche)
+
+		// Notifications
+		protected.GET("/notifications", controllers.GetMesNotifications)
+		protected.PUT("/notifications/lu", controllers.MarquerToutesNotificationsLues)
+		protected.PUT("/notifications/:id/lu", controllers.MarquerNotificationLue)
+		protected.GET("/notifications/preferences", controllers.GetPreferencesNotification)
+		protected.PUT("/notifications/preferences", controllers.UpdatePreferencesNotification)
+
+		// Webhooks des partenaires : réservés aux profils partenaires et administrateurs
+		webhooks := protected.Group("/webhooks", m.partenaire)
+		webhooks.GET("", controllers.GetMesWebhooks)
+		webhooks.POST("", controllers.CreateWebhook)
+		webhooks.GET("/:id", controllers.GetWebhookByID)
+		webhooks.PUT("/:id", controllers.UpdateWebhook)
+		webhooks.DELETE("/:id", controllers.DeleteWebhook)
+		webhooks.GET("/:id/livraisons", controllers.GetWebhookLivraisons)
+		webhooks.POST("/:id/livraisons/:livraison_id/rejouer", controllers.RejouerWebhookLivraison)
+
+		// Messagerie
+		protected.POST("/annonces_vente/:id/conversations", controllers.StartConversationAnnonceVente)
+		protected.POST("/annonces_pref/:id/conversations", controllers.StartConversationAnnoncePref)
+		protected.GET("/conversations", controllers.GetMesConversations)
+		protected.GET("/conversations/ws", controllers.ConversationsWebSocket)
+		protected.GET("/conversations/:id/messages", controllers.GetConversationMessages)
+		protected.POST("/conversations/:id/messages", limite(budgetMessages), controllers.PostConversationMessage)
+		protected.PUT("/conversations/:id/lu", controllers.MarquerConversationLue)
+		protected.GET("/conversations/:id/messages/:message_id/piece_jointe", controllers.GetPieceJointeMessage)
+
+		// Avis après transaction
+		protected.POST("/conversations/:id/conclure", controllers.ConclureConversation)
+		protected.POST("/conversations/:id/avis", controllers.CreateAvis)
+		protected.PUT("/avis/:id", controllers.UpdateAvis)
+		protected.POST("/avis/:id/reponse", controllers.RepondreAvis)
+
+		// Import en masse (CSV)
+		protected.POST("/annonces/import", limite(budgetImport), controllers.ImportAnnonces)
+
+		// Signalements
+		protected.POST("/annonces_vente/:id/signaler", limite(budgetSignalements), controllers.SignalerAnnonceVente)
+		protected.POST("/annonces_achat/:id/signaler", limite(budgetSignalements), controllers.SignalerAnnonceAchat)
+		protected.POST("/annonces_pref/:id/signaler", limite(budgetSignalements), controllers.SignalerAnnoncePref)
 	}
 
-	return r
+	// Routes d'administration (profil administrateur requis)
+	admin := g.Group("/admin")
+	admin.Use(m.auth, m.admin, limite(budgetAPI))
+	{
+		// Modération (type : vente, achat ou pref)
+		admin.GET("/moderation", controllers.GetFileModeration)
+		admin.POST("/moderation/:type/:id/approuver", controllers.ApprouverAnnonce)
+		admin.POST("/moderation/:type/:id/masquer", controllers.MasquerAnnonce)
+		admin.POST("/moderation/:type/:id/bannir", controllers.BannirAuteurAnnonce)
+		admin.GET("/audit", controllers.GetJournalAudit)
+
+		// Annonces (type : vente, achat ou pref)
+		admin.GET("/annonces/:type", controllers.AdminGetAnnonces)
+		admin.PUT("/annonces/:type/:id", controllers.AdminUpdateAnnonce)
+		admin.DELETE("/annonces/:type/:id", controllers.AdminDeleteAnnonce)
+
+		// Catalogue des types de culture
+		admin.GET("/types_culture", controllers.AdminGetTypesCulture)
+		admin.POST("/types_culture", controllers.AdminCreateTypeCulture)
+		admin.PUT("/types_culture/:id", controllers.AdminUpdateTypeCulture)
+		admin.DELETE("/types_culture/:id", controllers.AdminDeleteTypeCulture)
+
+		// Utilisateurs
+		admin.GET("/utilisateurs", controllers.AdminGetUtilisateurs)
+		admin.GET("/utilisateurs/:id", controllers.AdminGetUtilisateur)
+		admin.POST("/utilisateurs/:id/suspension", controllers.AdminSuspendreUtilisateur)
+		admin.DELETE("/utilisateurs/:id/suspension", controllers.AdminReactiverUtilisateur)
+
+		// Compteurs de la plateforme
+		admin.GET("/statistiques", controllers.AdminGetStatistiques)
+	}
 }
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sous-dossiers des uploads réservés (servis uniquement par des routes protégées)
+var dossiersPrives = []string{"/messages"}
+
+type fichiersPublics struct {
+	fs http.FileSystem
+}
+
+func (f fichiersPublics) Open(name string) (http.File, error) {
+	chemin := path.Clean("/" + name)
+	for _, dossier := range dossiersPrives {
+		if chemin == dossier || strings.HasPrefix(chemin, dossier+"/") {
+			return nil, os.ErrNotExist
+		}
+	}
+	return f.fs.Open(name)
+}
+
+// FichiersPublics expose le dossier d'uploads (sans listing) en masquant les
+// sous-dossiers privés, comme les pièces jointes de la messagerie
+func FichiersPublics(root string) http.FileSystem {
+	return fichiersPublics{fs: gin.Dir(root, false)}
+}
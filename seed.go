@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm/logger"
+
+	"github.com/Steph-business/annonce_de_vente/config"
+	"github.com/Steph-business/annonce_de_vente/database"
+)
+
+// commandeSeed peuple la base configurée (db_driver) avec le jeu de données du profil
+func commandeSeed(args []string) {
+	options := flag.NewFlagSet("seed", flag.ExitOnError)
+	options.Usage = func() {
+		fmt.Fprintln(options.Output(), "Usage : annonces seed [-profil base|demo] [-migrer] [options]")
+		options.PrintDefaults()
+	}
+	profil := options.String("profil", database.ProfilBase, "jeu de données : base (référentiels et quelques annonces) ou demo (des centaines d'annonces)")
+	migrer := options.Bool("migrer", false, "applique les migrations SQL en attente avant le peuplement")
+	cfg, err := config.Charger(options, args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db := database.Connecter(cfg.Base)
+	db.Logger = logger.Default.LogMode(logger.Warn)
+
+	if *migrer {
+		if _, err := database.MigrerHaut(db, 0); err != nil {
+			log.Fatal("Erreur lors des migrations :", err)
+		}
+	}
+
+	rapport, err := database.Peupler(db, *profil)
+	if err != nil {
+		log.Fatal("Erreur lors du peuplement :", err)
+	}
+	fmt.Printf("Profil %s : lignes ajoutées (les lignes déjà présentes sont conservées)\n", *profil)
+	fmt.Printf("  types de culture        %d\n", rapport.TypesCulture)
+	fmt.Printf("  utilisateurs            %d\n", rapport.Utilisateurs)
+	fmt.Printf("  parcelles               %d\n", rapport.Parcelles)
+	fmt.Printf("  annonces de vente       %d\n", rapport.Ventes)
+	fmt.Printf("  annonces d'achat        %d\n", rapport.Achats)
+	fmt.Printf("  annonces de préfinanc.  %d\n", rapport.Prefinancements)
+}
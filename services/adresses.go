@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// plagesReservees complète les tests de netip : plages non routables sur Internet ou
+// internes aux opérateurs, vers lesquelles aucun webhook ne doit partir
+var plagesReservees = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // « ce réseau »
+	netip.MustParsePrefix("100.64.0.0/10"),  // NAT des opérateurs (CGNAT)
+	netip.MustParsePrefix("192.0.0.0/24"),   // affectations du protocole IETF
+	netip.MustParsePrefix("198.18.0.0/15"),  // tests de performance
+	netip.MustParsePrefix("240.0.0.0/4"),    // réservé, dont la diffusion 255.255.255.255
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, qui joindrait une adresse IPv4 interne
+	netip.MustParsePrefix("64:ff9b:1::/48"), // NAT64 local
+	netip.MustParsePrefix("2001:db8::/32"),  // documentation
+	netip.MustParsePrefix("2002::/16"),      // 6to4, qui joindrait une adresse IPv4 interne
+}
+
+// AdressePublique indique si une adresse IP est joignable sur Internet : les adresses de
+// boucle locale, privées (RFC 1918, fc00::/7), lien local (dont 169.254.169.254, service
+// de métadonnées des clouds), multicast et réservées sont refusées
+func AdressePublique(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, plage := range plagesReservees {
+		if plage.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// HoteInterdit indique si l'hôte d'une URL de webhook désigne à coup sûr une adresse non
+// publique (adresse IP littérale ou nom localhost). Les autres noms sont vérifiés à chaque
+// livraison, après résolution.
+func HoteInterdit(hote string) bool {
+	hote = strings.TrimSuffix(strings.ToLower(hote), ".")
+	if hote == "localhost" || strings.HasSuffix(hote, ".localhost") {
+		return true
+	}
+	if ip, err := netip.ParseAddr(strings.Trim(hote, "[]")); err == nil {
+		return !AdressePublique(ip)
+	}
+	return false
+}
+
+// controlerAdresse refuse la connexion à une adresse non publique. Appelée par le dialer
+// après la résolution DNS, pour l'adresse effectivement contactée : un nom qui change
+// d'adresse entre l'enregistrement et la livraison (DNS rebinding) est aussi bloqué.
+func controlerAdresse(network, adresse string, _ syscall.RawConn) error {
+	ipPort, err := netip.ParseAddrPort(adresse)
+	if err != nil {
+		return err
+	}
+	if !AdressePublique(ipPort.Addr()) {
+		return fmt.Errorf("adresse non publique refusée : %s", ipPort.Addr())
+	}
+	return nil
+}
+
+// clientWebhooks renvoie le client HTTP des livraisons : connexions limitées aux adresses
+// publiques, sans proxy ni redirection (une redirection pourrait viser une adresse interne
+// et rejouerait le corps signé vers une autre URL)
+func clientWebhooks(delai time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: controlerAdresse}
+	return &http.Client{
+		Timeout: delai,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        20,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return fmt.Errorf("redirection refusée vers %s", req.URL.Redacted())
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestAdressePublique(t *testing.T) {
+	cas := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+	}
+	for adresse, publique := range cas {
+		if AdressePublique(netip.MustParseAddr(adresse)) != publique {
+			t.Errorf("AdressePublique(%s) = %v", adresse, !publique)
+		}
+	}
+
+	for _, hote := range []string{"localhost", "api.localhost", "127.0.0.1", "[::1]", "169.254.169.254"} {
+		if !HoteInterdit(hote) {
+			t.Errorf("hôte %s accepté", hote)
+		}
+	}
+	if HoteInterdit("partenaire.example.com") {
+		t.Error("nom d'hôte public refusé")
+	}
+}
+
+// Le client des livraisons refuse les adresses internes, quel que soit le nom de l'URL
+func TestClientWebhooksAdresseInterne(t *testing.T) {
+	serveur := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer serveur.Close()
+
+	if _, err := clientWebhooks(time.Second).Post(serveur.URL, "application/json", nil); err == nil {
+		t.Error("livraison envoyée à une adresse de boucle locale")
+	}
+}
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// Journaliser enregistre une action d'administration dans le journal d'audit.
+// Elle doit être appelée avec la transaction de l'action : l'action et sa trace
+// sont validées ensemble.
+func Journaliser(tx *gorm.DB, entree models.JournalAudit) error {
+	entree.ID = uuid.New()
+	return tx.Create(&entree).Error
+}
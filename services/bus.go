@@ -0,0 +1,99 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// BusAnnonces est un bus d'événements en mémoire : les contrôleurs y publient les
+// créations et modifications d'annonces, le flux SSE s'y abonne. Les derniers
+// événements sont conservés pour permettre la reprise d'un client déconnecté.
+type BusAnnonces struct {
+	mu        sync.Mutex
+	dernierID uint64
+	tampon    []models.EvenementAnnonce
+	capacite  int
+	abonnes   map[chan models.EvenementAnnonce]struct{}
+}
+
+// Bus est le bus partagé par les contrôleurs
+var Bus = NewBusAnnonces(1000)
+
+// NewBusAnnonces crée un bus qui conserve les capacite derniers événements
+func NewBusAnnonces(capacite int) *BusAnnonces {
+	return &BusAnnonces{
+		capacite: capacite,
+		abonnes:  map[chan models.EvenementAnnonce]struct{}{},
+	}
+}
+
+// Publier attribue un ID à l'événement, le conserve et le transmet aux abonnés.
+// Un abonné trop lent est déconnecté (son canal est fermé) plutôt que de bloquer
+// la publication ; il peut se reconnecter et reprendre avec le dernier ID reçu.
+func (b *BusAnnonces) Publier(e models.EvenementAnnonce) models.EvenementAnnonce {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dernierID++
+	e.ID = b.dernierID
+	if e.CreeLe.IsZero() {
+		e.CreeLe = time.Now()
+	}
+
+	b.tampon = append(b.tampon, e)
+	if len(b.tampon) > b.capacite {
+		b.tampon = b.tampon[len(b.tampon)-b.capacite:]
+	}
+
+	for ch := range b.abonnes {
+		select {
+		case ch <- e:
+		default:
+			delete(b.abonnes, ch)
+			close(ch)
+		}
+	}
+	return e
+}
+
+// Abonner renvoie un canal recevant les événements publiés ensuite, et la fonction
+// de désabonnement à appeler quand le client se déconnecte
+func (b *BusAnnonces) Abonner() (<-chan models.EvenementAnnonce, func()) {
+	ch := make(chan models.EvenementAnnonce, 64)
+
+	b.mu.Lock()
+	b.abonnes[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.abonnes[ch]; ok {
+			delete(b.abonnes, ch)
+			close(ch)
+		}
+	}
+}
+
+// Depuis renvoie les événements conservés dont l'ID est supérieur à id
+func (b *BusAnnonces) Depuis(id uint64) []models.EvenementAnnonce {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var evenements []models.EvenementAnnonce
+	for _, e := range b.tampon {
+		if e.ID > id {
+			evenements = append(evenements, e)
+		}
+	}
+	return evenements
+}
+
+// DernierID renvoie l'ID du dernier événement publié
+func (b *BusAnnonces) DernierID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dernierID
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// ClientTempsReel est une connexion temps réel (WebSocket) d'un utilisateur
+type ClientTempsReel interface {
+	Envoyer(e models.EvenementMessagerie) error
+	Fermer() error
+}
+
+// HubMessagerie distribue les événements de messagerie aux connexions ouvertes
+// de chaque utilisateur (un utilisateur peut être connecté sur plusieurs appareils)
+type HubMessagerie struct {
+	mu      sync.RWMutex
+	clients map[uuid.UUID]map[ClientTempsReel]struct{}
+}
+
+// Hub est le hub partagé par les contrôleurs
+var Hub = NewHubMessagerie()
+
+func NewHubMessagerie() *HubMessagerie {
+	return &HubMessagerie{clients: map[uuid.UUID]map[ClientTempsReel]struct{}{}}
+}
+
+// Connecter enregistre une connexion et renvoie la fonction qui la retire
+func (h *HubMessagerie) Connecter(userID uuid.UUID, client ClientTempsReel) func() {
+	h.mu.Lock()
+	if h.clients[userID] == nil {
+		h.clients[userID] = map[ClientTempsReel]struct{}{}
+	}
+	h.clients[userID][client] = struct{}{}
+	h.mu.Unlock()
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.clients[userID], client)
+		if len(h.clients[userID]) == 0 {
+			delete(h.clients, userID)
+		}
+	}
+}
+
+// EstConnecte indique si l'utilisateur a au moins une connexion ouverte
+func (h *HubMessagerie) EstConnecte(userID uuid.UUID) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userID]) > 0
+}
+
+// Diffuser envoie l'événement à toutes les connexions des utilisateurs indiqués
+func (h *HubMessagerie) Diffuser(e models.EvenementMessagerie, userIDs ...uuid.UUID) {
+	h.mu.RLock()
+	var destinataires []ClientTempsReel
+	for _, userID := range userIDs {
+		for client := range h.clients[userID] {
+			destinataires = append(destinataires, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range destinataires {
+		if err := client.Envoyer(e); err != nil {
+			log.Printf("Messagerie : erreur d'envoi temps réel : %v\n", err)
+		}
+	}
+}
+
+// Fermer ferme toutes les connexions ouvertes, à l'arrêt du serveur : les clients se
+// reconnectent à une autre instance
+func (h *HubMessagerie) Fermer() {
+	h.mu.RLock()
+	var clients []ClientTempsReel
+	for _, connexions := range h.clients {
+		for client := range connexions {
+			clients = append(clients, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		client.Fermer()
+	}
+}
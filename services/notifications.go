@@ -0,0 +1,87 @@
+package services
+
+import (
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// Envoyeur diffuse une notification sur un canal externe (email, SMS...)
+type Envoyeur interface {
+	Envoyer(n models.Notification) error
+}
+
+var (
+	envoyeursMu sync.RWMutex
+	envoyeurs   = map[string]Envoyeur{}
+)
+
+// RegisterEnvoyeur branche un canal de diffusion externe. Sans envoyeur enregistré,
+// les préférences du canal sont conservées mais rien n'est envoyé.
+func RegisterEnvoyeur(canal string, e Envoyeur) {
+	envoyeursMu.Lock()
+	defer envoyeursMu.Unlock()
+	envoyeurs[canal] = e
+}
+
+// CanauxActifs renvoie les canaux sur lesquels l'utilisateur accepte un type de notification
+func CanauxActifs(db *gorm.DB, userID uuid.UUID, typeNotification string) ([]string, error) {
+	var preferences []models.PreferenceNotification
+	if err := db.Where("user_id = ? AND type = ?", userID, typeNotification).Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+
+	configures := map[string]bool{}
+	for _, p := range preferences {
+		configures[p.Canal] = p.Active
+	}
+
+	var canaux []string
+	for _, canal := range models.CanauxNotification {
+		active, ok := configures[canal]
+		if !ok {
+			active = models.CanalActifParDefaut(canal)
+		}
+		if active {
+			canaux = append(canaux, canal)
+		}
+	}
+	return canaux, nil
+}
+
+// Notifier enregistre la notification dans la boîte de réception de l'utilisateur
+// et la diffuse sur les canaux externes, selon ses préférences
+func Notifier(db *gorm.DB, n models.Notification) error {
+	canaux, err := CanauxActifs(db, n.UserID, n.Type)
+	if err != nil {
+		return err
+	}
+
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+
+	for _, canal := range canaux {
+		if canal == models.CanalInApp {
+			if err := db.Create(&n).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		envoyeursMu.RLock()
+		envoyeur, ok := envoyeurs[canal]
+		envoyeursMu.RUnlock()
+		if !ok {
+			continue
+		}
+		if err := envoyeur.Envoyer(n); err != nil {
+			log.Printf("Erreur envoi de la notification %s sur le canal %s : %v\n", n.ID, canal, err)
+		}
+	}
+	return nil
+}
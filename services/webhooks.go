@@ -0,0 +1,293 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// En-têtes envoyés avec chaque livraison. La signature est un HMAC-SHA256 de
+// "<timestamp>.<corps>" calculé avec le secret de l'endpoint.
+const (
+	EnteteEvenement  = "X-Agrobloc-Event"
+	EnteteLivraison  = "X-Agrobloc-Delivery"
+	EnteteTimestamp  = "X-Agrobloc-Timestamp"
+	EnteteSignature  = "X-Agrobloc-Signature"
+	prefixeSignature = "sha256="
+)
+
+// EmettreEvenement ajoute à l'outbox une livraison par endpoint abonné à l'événement.
+// Elle doit être appelée avec la transaction qui modifie l'annonce : l'événement n'est
+// enregistré que si la modification est validée, et n'est jamais perdu si elle l'est.
+func EmettreEvenement(tx *gorm.DB, evenement string, annonceType string, annonceID uuid.UUID, donnees interface{}) error {
+	var endpoints []models.WebhookEndpoint
+	if err := tx.Where("active = ?", true).Find(&endpoints).Error; err != nil {
+		return err
+	}
+
+	maintenant := time.Now()
+	var livraisons []models.WebhookLivraison
+	for _, e := range endpoints {
+		if !e.Evenements.Contient(evenement) || !e.AnnonceTypes.Contient(annonceType) {
+			continue
+		}
+
+		id := uuid.New()
+		payload, err := json.Marshal(models.EvenementWebhook{
+			ID:          id.String(),
+			Evenement:   evenement,
+			AnnonceType: annonceType,
+			AnnonceID:   annonceID.String(),
+			CreeLe:      maintenant,
+			Donnees:     donnees,
+		})
+		if err != nil {
+			return err
+		}
+
+		livraisons = append(livraisons, models.WebhookLivraison{
+			ID:                 id,
+			EndpointID:         e.ID,
+			Evenement:          evenement,
+			Payload:            string(payload),
+			Statut:             models.LivraisonEnAttente,
+			ProchaineTentative: maintenant,
+		})
+	}
+
+	if len(livraisons) == 0 {
+		return nil
+	}
+	return tx.Create(&livraisons).Error
+}
+
+// EvenementsModification renvoie les événements à émettre pour la modification d'une annonce :
+// toujours "modifiee", plus "vendue" ou "financee" quand le statut passe à cette valeur
+func EvenementsModification(annonceType string, ancienStatut string, nouveauStatut string) []string {
+	evenements := []string{models.EvenementAnnonceModifiee}
+	if ancienStatut == nouveauStatut {
+		return evenements
+	}
+	if nouveauStatut == models.StatutVendue && annonceType != models.AnnonceTypePref {
+		evenements = append(evenements, models.EvenementAnnonceVendue)
+	}
+	if nouveauStatut == models.StatutFinancee && annonceType == models.AnnonceTypePref {
+		evenements = append(evenements, models.EvenementAnnonceFinancee)
+	}
+	return evenements
+}
+
+// SignerPayload calcule la valeur de l'en-tête de signature d'une livraison
+func SignerPayload(secret string, timestamp int64, corps []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(corps)
+	return prefixeSignature + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WorkerWebhooks livre les événements de l'outbox avec des tentatives espacées
+// exponentiellement. Au-delà de MaxTentatives, la livraison passe en dead letter
+// (statut "abandonnee") et peut être rejouée manuellement.
+type WorkerWebhooks struct {
+	DB            *gorm.DB
+	Client        *http.Client
+	Intervalle    time.Duration
+	TailleLot     int
+	MaxTentatives int
+	DelaiBase     time.Duration
+	DelaiMax      time.Duration
+	// Bail : durée pendant laquelle une livraison réservée n'est pas reprise par un autre worker
+	Bail time.Duration
+}
+
+// NewWorkerWebhooks crée un worker avec les réglages par défaut
+func NewWorkerWebhooks(db *gorm.DB) *WorkerWebhooks {
+	return &WorkerWebhooks{
+		DB:            db,
+		Client:        clientWebhooks(10 * time.Second),
+		Intervalle:    5 * time.Second,
+		TailleLot:     20,
+		MaxTentatives: 8,
+		DelaiBase:     30 * time.Second,
+		DelaiMax:      6 * time.Hour,
+		Bail:          time.Minute,
+	}
+}
+
+// Run traite l'outbox jusqu'à l'annulation du contexte, puis rend la main une fois la
+// livraison en cours terminée
+func (w *WorkerWebhooks) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Intervalle)
+	defer ticker.Stop()
+
+	for {
+		w.traiterLot(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reserverLot sélectionne les livraisons dues et repousse leur prochaine tentative
+// de la durée du bail, pour qu'aucun autre worker ne les envoie en parallèle
+func (w *WorkerWebhooks) reserverLot() ([]models.WebhookLivraison, error) {
+	var livraisons []models.WebhookLivraison
+	err := w.DB.Transaction(func(tx *gorm.DB) error {
+		maintenant := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("statut = ? AND prochaine_tentative <= ?", models.LivraisonEnAttente, maintenant).
+			Order("prochaine_tentative").
+			Limit(w.TailleLot).
+			Find(&livraisons).Error; err != nil {
+			return err
+		}
+		if len(livraisons) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(livraisons))
+		for i, l := range livraisons {
+			ids[i] = l.ID
+		}
+		return tx.Model(&models.WebhookLivraison{}).
+			Where("id IN ?", ids).
+			Update("prochaine_tentative", maintenant.Add(w.Bail)).Error
+	})
+	return livraisons, err
+}
+
+func (w *WorkerWebhooks) traiterLot(ctx context.Context) {
+	livraisons, err := w.reserverLot()
+	if err != nil {
+		log.Printf("Webhooks : erreur de réservation des livraisons : %v\n", err)
+		return
+	}
+
+	endpoints := map[uuid.UUID]*models.WebhookEndpoint{}
+	for _, l := range livraisons {
+		if ctx.Err() != nil {
+			// Arrêt demandé : les livraisons restantes seront reprises à l'expiration du bail
+			return
+		}
+
+		endpoint, ok := endpoints[l.EndpointID]
+		if !ok {
+			endpoint = &models.WebhookEndpoint{}
+			if err := w.DB.First(endpoint, "id = ?", l.EndpointID).Error; err != nil {
+				endpoint = nil
+			}
+			endpoints[l.EndpointID] = endpoint
+		}
+
+		// Une livraison commencée va à son terme (borné par le timeout du client HTTP) même
+		// si l'arrêt est demandé, pour ne pas renvoyer plus tard un événement déjà reçu
+		w.livrer(context.WithoutCancel(ctx), l, endpoint)
+	}
+}
+
+// livrer envoie une livraison et enregistre le résultat de la tentative. L'enregistrement
+// est conditionné au statut et au nombre de tentatives lus à la réservation : une livraison
+// rejouée entre-temps (RejouerWebhookLivraison) n'est pas écrasée.
+func (w *WorkerWebhooks) livrer(ctx context.Context, l models.WebhookLivraison, endpoint *models.WebhookEndpoint) {
+	tentatives := l.Tentatives + 1
+	champs := map[string]interface{}{"tentatives": tentatives}
+
+	code, err := w.envoyer(ctx, l, endpoint)
+	champs["dernier_code_http"] = code
+	if err == nil {
+		champs["statut"] = models.LivraisonLivree
+		champs["livree_le"] = time.Now()
+		champs["derniere_erreur"] = ""
+	} else {
+		champs["derniere_erreur"] = err.Error()
+		if tentatives >= w.MaxTentatives || endpoint == nil {
+			champs["statut"] = models.LivraisonAbandonne
+		} else {
+			champs["prochaine_tentative"] = time.Now().Add(w.delai(tentatives))
+		}
+	}
+
+	res := w.DB.Model(&models.WebhookLivraison{}).
+		Where("id = ? AND statut = ? AND tentatives = ?", l.ID, l.Statut, l.Tentatives).
+		Updates(champs)
+	if res.Error != nil {
+		log.Printf("Webhooks : erreur d'enregistrement de la livraison %s : %v\n", l.ID, res.Error)
+	} else if res.RowsAffected == 0 {
+		log.Printf("Webhooks : livraison %s modifiée pendant l'envoi, résultat ignoré\n", l.ID)
+	}
+}
+
+func (w *WorkerWebhooks) envoyer(ctx context.Context, l models.WebhookLivraison, endpoint *models.WebhookEndpoint) (int, error) {
+	if endpoint == nil {
+		return 0, fmt.Errorf("endpoint %s introuvable", l.EndpointID)
+	}
+	if !endpoint.Active {
+		return 0, fmt.Errorf("endpoint %s désactivé", endpoint.ID)
+	}
+
+	corps := []byte(l.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(corps))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EnteteEvenement, l.Evenement)
+	req.Header.Set(EnteteLivraison, l.ID.String())
+	req.Header.Set(EnteteTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(EnteteSignature, SignerPayload(endpoint.Secret, timestamp, corps))
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("réponse HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// delai renvoie l'attente avant la tentative suivante : DelaiBase * 2^(tentatives-1), plafonné
+func (w *WorkerWebhooks) delai(tentatives int) time.Duration {
+	delai := w.DelaiBase
+	for i := 1; i < tentatives; i++ {
+		delai *= 2
+		if delai >= w.DelaiMax {
+			return w.DelaiMax
+		}
+	}
+	return delai
+}
+
+// EmettreModification émet les événements liés à la modification d'une annonce
+func EmettreModification(tx *gorm.DB, annonceType string, annonceID uuid.UUID, ancienStatut string, nouveauStatut string, donnees interface{}) error {
+	for _, evenement := range EvenementsModification(annonceType, ancienStatut, nouveauStatut) {
+		if err := EmettreEvenement(tx, evenement, annonceType, annonceID, donnees); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/Steph-business/annonce_de_vente/models"
+)
+
+// requeteRecue est une livraison reçue par le partenaire de test
+type requeteRecue struct {
+	entetes http.Header
+	corps   []byte
+}
+
+// partenaireTest sert les livraisons : reponse choisit le statut de chaque requête reçue
+// (à partir de 1)
+type partenaireTest struct {
+	mu      sync.Mutex
+	recues  []requeteRecue
+	reponse func(n int) int
+}
+
+func (p *partenaireTest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	corps, _ := io.ReadAll(r.Body)
+	p.mu.Lock()
+	p.recues = append(p.recues, requeteRecue{entetes: r.Header.Clone(), corps: corps})
+	n := len(p.recues)
+	p.mu.Unlock()
+	w.WriteHeader(p.reponse(n))
+}
+
+// outboxTest crée une base SQLite avec un endpoint abonné à tous les événements et un
+// worker qui livre au serveur de test (le client par défaut refuse les adresses locales)
+func outboxTest(t *testing.T, partenaire *partenaireTest) (*gorm.DB, *WorkerWebhooks, models.WebhookEndpoint) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.WebhookEndpoint{}, &models.WebhookLivraison{}); err != nil {
+		t.Fatal(err)
+	}
+	serveur := httptest.NewServer(partenaire)
+	t.Cleanup(serveur.Close)
+
+	endpoint := models.WebhookEndpoint{ID: uuid.New(), UserID: uuid.New(), URL: serveur.URL, Secret: "secret-partenaire", Active: true}
+	if err := db.Create(&endpoint).Error; err != nil {
+		t.Fatal(err)
+	}
+	w := NewWorkerWebhooks(db)
+	w.Client = serveur.Client()
+	return db, w, endpoint
+}
+
+func livraisonTest(t *testing.T, db *gorm.DB) models.WebhookLivraison {
+	t.Helper()
+	var livraisons []models.WebhookLivraison
+	if err := db.Find(&livraisons).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(livraisons) != 1 {
+		t.Fatalf("%d livraisons dans l'outbox, attendu 1", len(livraisons))
+	}
+	return livraisons[0]
+}
+
+// La signature se vérifie côté partenaire avec le secret, le timestamp et le corps reçus
+func TestSignatureWebhook(t *testing.T) {
+	partenaire := &partenaireTest{reponse: func(int) int { return http.StatusNoContent }}
+	db, w, endpoint := outboxTest(t, partenaire)
+	if err := EmettreEvenement(db, models.EvenementAnnonceCreee, models.AnnonceTypeVente, uuid.New(), map[string]string{"statut": "active"}); err != nil {
+		t.Fatal(err)
+	}
+	w.traiterLot(context.Background())
+
+	if len(partenaire.recues) != 1 {
+		t.Fatalf("%d requêtes reçues, attendu 1", len(partenaire.recues))
+	}
+	recue := partenaire.recues[0]
+	mac := hmac.New(sha256.New, []byte(endpoint.Secret))
+	mac.Write([]byte(recue.entetes.Get(EnteteTimestamp) + "." + string(recue.corps)))
+	if attendue := "sha256=" + hex.EncodeToString(mac.Sum(nil)); recue.entetes.Get(EnteteSignature) != attendue {
+		t.Errorf("signature %q, attendu %q", recue.entetes.Get(EnteteSignature), attendue)
+	}
+	livraison := livraisonTest(t, db)
+	if recue.entetes.Get(EnteteEvenement) != models.EvenementAnnonceCreee || recue.entetes.Get(EnteteLivraison) != livraison.ID.String() {
+		t.Errorf("en-têtes %v", recue.entetes)
+	}
+	if livraison.Statut != models.LivraisonLivree || livraison.Tentatives != 1 || livraison.LivreeLe == nil {
+		t.Errorf("livraison %+v", livraison)
+	}
+}
+
+func TestDelaiWebhooks(t *testing.T) {
+	w := &WorkerWebhooks{DelaiBase: 30 * time.Second, DelaiMax: 6 * time.Hour}
+	attendus := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		6:  16 * time.Minute,
+		10: 256 * time.Minute,
+		11: 6 * time.Hour,
+		40: 6 * time.Hour,
+	}
+	for tentatives, attendu := range attendus {
+		if d := w.delai(tentatives); d != attendu {
+			t.Errorf("delai(%d) = %v, attendu %v", tentatives, d, attendu)
+		}
+	}
+}
+
+// Une livraison échouée, ou réservée par un worker arrêté avant l'envoi, est renvoyée avec
+// le même identifiant jusqu'à son acceptation
+func TestRelivraisonWebhook(t *testing.T) {
+	partenaire := &partenaireTest{reponse: func(n int) int {
+		if n == 1 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}}
+	db, w, _ := outboxTest(t, partenaire)
+	if err := EmettreEvenement(db, models.EvenementAnnonceVendue, models.AnnonceTypeVente, uuid.New(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	w.traiterLot(context.Background())
+	livraison := livraisonTest(t, db)
+	if livraison.Statut != models.LivraisonEnAttente || livraison.Tentatives != 1 || livraison.DernierCodeHTTP != http.StatusServiceUnavailable {
+		t.Fatalf("après un échec : %+v", livraison)
+	}
+	if !livraison.ProchaineTentative.After(time.Now().Add(w.DelaiBase / 2)) {
+		t.Errorf("prochaine tentative %v, attendu dans %v", livraison.ProchaineTentative, w.DelaiBase)
+	}
+
+	// Échéance atteinte : la livraison est réservée puis le worker s'arrête sans l'envoyer
+	db.Model(&livraison).Update("prochaine_tentative", time.Now().Add(-time.Second))
+	w.Bail = 10 * time.Millisecond
+	if lot, err := w.reserverLot(); err != nil || len(lot) != 1 {
+		t.Fatalf("réservation : %d livraisons, %v", len(lot), err)
+	}
+	if lot, err := w.reserverLot(); err != nil || len(lot) != 0 {
+		t.Fatalf("livraison réservée reprise pendant le bail : %d, %v", len(lot), err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// À l'expiration du bail, un autre worker la reprend
+	w.traiterLot(context.Background())
+	livraison = livraisonTest(t, db)
+	if livraison.Statut != models.LivraisonLivree || livraison.Tentatives != 2 {
+		t.Errorf("après relivraison : %+v", livraison)
+	}
+	if len(partenaire.recues) != 2 || partenaire.recues[1].entetes.Get(EnteteLivraison) != partenaire.recues[0].entetes.Get(EnteteLivraison) {
+		t.Errorf("%d requêtes reçues, attendu 2 avec le même identifiant de livraison", len(partenaire.recues))
+	}
+}
+
+// Une livraison rejouée pendant un envoi garde l'état du rejeu
+func TestLivraisonRejoueePendantEnvoi(t *testing.T) {
+	var db *gorm.DB
+	var id uuid.UUID
+	partenaire := &partenaireTest{reponse: func(int) int {
+		// Rejeu manuel pendant que le worker attend la réponse
+		db.Model(&models.WebhookLivraison{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"statut": models.LivraisonEnAttente, "tentatives": 0, "derniere_erreur": ""})
+		return http.StatusInternalServerError
+	}}
+	db, w, endpoint := outboxTest(t, partenaire)
+	livraison := models.WebhookLivraison{
+		ID: uuid.New(), EndpointID: endpoint.ID, Evenement: models.EvenementAnnonceCreee, Payload: "{}",
+		Statut: models.LivraisonEnAttente, Tentatives: 3, ProchaineTentative: time.Now().Add(-time.Second),
+	}
+	id = livraison.ID
+	if err := db.Create(&livraison).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	w.traiterLot(context.Background())
+	livraison = livraisonTest(t, db)
+	if livraison.Tentatives != 0 || livraison.DerniereErreur != "" || livraison.DernierCodeHTTP != 0 {
+		t.Errorf("rejeu écrasé par le worker : %+v", livraison)
+	}
+}